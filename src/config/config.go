@@ -12,6 +12,7 @@ var (
 	AppHost             string
 	AppPort             int
 	FrontendURL         string
+	DefaultTimezone     string
 	DBHost              string
 	DBUser              string
 	DBPassword          string
@@ -20,26 +21,59 @@ var (
 	ProductTokenExpDays string
 	LogMealBaseUrl      string
 	LogMealApiKey       string
-	JWTSecret           string
-	JWTAccessExp        int
-	JWTRefreshExp       int
-	JWTResetPasswordExp int
-	JWTVerifyEmailExp   int
-	SMTPHost            string
-	SMTPPort            int
-	SMTPUsername        string
-	SMTPPassword        string
-	EmailFrom           string
-	GoogleClientID      string
-	GoogleClientSecret  string
-	RedirectURL         string
-	MidtransServerKey   string
-	MidtransStatus      string
-	GRPC_HOST           string
-	GRPC_PORT           string
-	SentryDSN           string
-	SentryEnvironment   string
-	SentryDebug         bool
+	FoodRecognizer      string
+	MealScanWorkers     int
+	MealScanMaxAttempts int
+	// FoodCatalogTTLMinutes is how long the TKPI catalog used to match scanned foods is cached
+	FoodCatalogTTLMinutes int
+	// ArticlePublishInterval is how often, in seconds, due scheduled articles are marked published
+	ArticlePublishInterval int
+	JWTSecret              string
+	JWTAccessExp           int
+	JWTRefreshExp          int
+	JWTResetPasswordExp    int
+	JWTVerifyEmailExp      int
+	SMTPHost               string
+	SMTPPort               int
+	SMTPUsername           string
+	SMTPPassword           string
+	EmailFrom              string
+	GoogleClientID         string
+	GoogleClientSecret     string
+	RedirectURL            string
+	MidtransServerKey      string
+	MidtransStatus         string
+	GRPC_HOST              string
+	GRPC_PORT              string
+	SentryDSN              string
+	SentryEnvironment      string
+	SentryDebug            bool
+
+	// Uploaded images are kept in UploadStorage: local || s3
+	UploadStorage    string
+	UploadDir        string
+	UploadBaseURL    string
+	UploadMaxSizeMB  int
+	UploadGCInterval int
+	UploadGraceHours int
+	S3Endpoint       string
+	S3AccessKey      string
+	S3SecretKey      string
+	S3Bucket         string
+	S3Region         string
+	S3UseSSL         bool
+	S3PublicURL      string
+
+	// Lapsed paid subscriptions keep access for SubscriptionGraceDays before they expire
+	SubscriptionGraceDays     int
+	SubscriptionSweepInterval int
+
+	// Orders pending longer than PaymentReconcileStaleMinutes are checked with Midtrans, MidtransAPIURL
+	// replaces the Midtrans API host, e.g. with a local stand-in
+	MidtransAPIURL               string
+	PaymentReconcileInterval     int
+	PaymentReconcileStaleMinutes int
+	PaymentPendingExpiryHours    int
 )
 
 func init() {
@@ -60,6 +94,12 @@ func init() {
 
 	FrontendURL = viper.GetString("FRONTEND_URL")
 
+	// timezone of users who haven't picked one, days are counted in the user's timezone
+	DefaultTimezone = viper.GetString("DEFAULT_TIMEZONE")
+	if DefaultTimezone == "" {
+		DefaultTimezone = "Asia/Jakarta"
+	}
+
 	// database configuration
 	DBHost = viper.GetString("DB_HOST")
 	DBUser = viper.GetString("DB_USER")
@@ -91,6 +131,92 @@ func init() {
 	LogMealBaseUrl = viper.GetString("LOG_MEAL_BASE_URL")
 	LogMealApiKey = viper.GetString("LOG_MEAL_API_KEY")
 
+	// food recognition provider: logmeal || fake
+	FoodRecognizer = viper.GetString("FOOD_RECOGNIZER")
+	if FoodRecognizer == "" {
+		FoodRecognizer = "logmeal"
+	}
+
+	// asynchronous meal scan jobs
+	MealScanWorkers = viper.GetInt("MEAL_SCAN_WORKERS")
+	MealScanMaxAttempts = viper.GetInt("MEAL_SCAN_MAX_ATTEMPTS")
+	if MealScanWorkers == 0 {
+		MealScanWorkers = 2
+	}
+	if MealScanMaxAttempts == 0 {
+		MealScanMaxAttempts = 3
+	}
+	FoodCatalogTTLMinutes = viper.GetInt("FOOD_CATALOG_TTL_MINUTES")
+	if FoodCatalogTTLMinutes == 0 {
+		FoodCatalogTTLMinutes = 60
+	}
+
+	// scheduled article publishing
+	ArticlePublishInterval = viper.GetInt("ARTICLE_PUBLISH_INTERVAL_SECONDS")
+	if ArticlePublishInterval == 0 {
+		ArticlePublishInterval = 60
+	}
+
+	// uploaded images
+	UploadStorage = viper.GetString("UPLOAD_STORAGE")
+	UploadDir = viper.GetString("UPLOAD_DIR")
+	UploadBaseURL = viper.GetString("UPLOAD_BASE_URL")
+	UploadMaxSizeMB = viper.GetInt("UPLOAD_MAX_SIZE_MB")
+	UploadGCInterval = viper.GetInt("UPLOAD_GC_INTERVAL_MINUTES")
+	UploadGraceHours = viper.GetInt("UPLOAD_GRACE_HOURS")
+	if UploadStorage == "" {
+		UploadStorage = "local"
+	}
+	if UploadDir == "" {
+		UploadDir = "./uploads"
+	}
+	if UploadBaseURL == "" {
+		UploadBaseURL = "/uploads"
+	}
+	if UploadMaxSizeMB == 0 {
+		UploadMaxSizeMB = 5
+	}
+	if UploadGCInterval == 0 {
+		UploadGCInterval = 60
+	}
+	if UploadGraceHours == 0 {
+		UploadGraceHours = 24
+	}
+
+	// S3 compatible storage, used when UPLOAD_STORAGE is s3
+	S3Endpoint = viper.GetString("S3_ENDPOINT")
+	S3AccessKey = viper.GetString("S3_ACCESS_KEY")
+	S3SecretKey = viper.GetString("S3_SECRET_KEY")
+	S3Bucket = viper.GetString("S3_BUCKET")
+	S3Region = viper.GetString("S3_REGION")
+	S3UseSSL = viper.GetBool("S3_USE_SSL")
+	S3PublicURL = viper.GetString("S3_PUBLIC_URL")
+
+	// subscription lifecycle configuration
+	SubscriptionGraceDays = viper.GetInt("SUBSCRIPTION_GRACE_DAYS")
+	SubscriptionSweepInterval = viper.GetInt("SUBSCRIPTION_SWEEP_INTERVAL_MINUTES")
+	if SubscriptionGraceDays == 0 {
+		SubscriptionGraceDays = 3
+	}
+	if SubscriptionSweepInterval == 0 {
+		SubscriptionSweepInterval = 5
+	}
+
+	// payment reconciliation configuration
+	MidtransAPIURL = viper.GetString("MIDTRANS_API_URL")
+	PaymentReconcileInterval = viper.GetInt("PAYMENT_RECONCILE_INTERVAL_MINUTES")
+	PaymentReconcileStaleMinutes = viper.GetInt("PAYMENT_RECONCILE_STALE_MINUTES")
+	PaymentPendingExpiryHours = viper.GetInt("PAYMENT_PENDING_EXPIRY_HOURS")
+	if PaymentReconcileInterval == 0 {
+		PaymentReconcileInterval = 15
+	}
+	if PaymentReconcileStaleMinutes == 0 {
+		PaymentReconcileStaleMinutes = 30
+	}
+	if PaymentPendingExpiryHours == 0 {
+		PaymentPendingExpiryHours = 24
+	}
+
 	// jwt configuration
 	JWTSecret = viper.GetString("JWT_SECRET")
 	JWTAccessExp = viper.GetInt("JWT_ACCESS_EXP_MINUTES")
@@ -8,6 +8,12 @@ import (
 )
 
 func FiberConfig() fiber.Config {
+	// Leave room for an image of the maximum upload size and the rest of the multipart form
+	bodyLimit := (UploadMaxSizeMB + 1) * 1024 * 1024
+	if bodyLimit < fiber.DefaultBodyLimit {
+		bodyLimit = fiber.DefaultBodyLimit
+	}
+
 	return fiber.Config{
 		Prefork:       false,
 		CaseSensitive: true,
@@ -15,6 +21,7 @@ func FiberConfig() fiber.Config {
 		ServerHeader:  "Fiber",
 		AppName:       "Fiber API",
 		ErrorHandler:  utils.ErrorHandler,
+		BodyLimit:     bodyLimit,
 		JSONEncoder:   sonic.Marshal,
 		JSONDecoder:   sonic.Unmarshal,
 	}
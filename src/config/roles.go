@@ -8,6 +8,8 @@ var allRoles = map[string][]string{
 		"getUserDetails", "updateUser",
 		"getSubscriptions", "manageSubscriptions", "viewTransactions", "updatePaymentStatus",
 		"getSubscriptionPlans",
+		"manageFoodAliases",
+		"moderateReviews",
 	},
 }
 
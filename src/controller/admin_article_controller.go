@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AdminArticleController struct {
+	ArticleService service.ArticlesService
+}
+
+func NewAdminArticleController(articleService service.ArticlesService) *AdminArticleController {
+	return &AdminArticleController{
+		ArticleService: articleService,
+	}
+}
+
+// @Tags         Admin
+// @Summary      Get all articles
+// @Description  Returns the articles of every status for editors, drafts and scheduled ones included
+// @Produce      json
+// @Security     BearerAuth
+// @Param        status        query  string  false  "Filter by status (all, draft, scheduled, published, archived)"  default(all)
+// @Param        page          query  int     false  "Page number"  default(1)
+// @Param        limit         query  int     false  "Maximum number of articles"  default(10)
+// @Param        search        query  string  false  "Full-text search over title and content"
+// @Param        category_ids  query  string  false  "Comma separated category IDs"
+// @Param        tags          query  string  false  "Comma separated tags"
+// @Param        sort          query  string  false  "newest, rating, popular or relevance"
+// @Router       /admin/articles [get]
+// @Success      200  {object}  response.SuccessWithPaginate[model.ArticleResponse]
+// @Failure      403  {object}  response.ErrorResponse
+func (c *AdminArticleController) GetAllArticles(ctx *fiber.Ctx) error {
+	query := articleQuery(ctx)
+	query.Status = ctx.Query("status", service.ArticleStatusAll)
+
+	articles, totalResults, err := c.ArticleService.GetArticles(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).
+		JSON(response.SuccessWithPaginate[model.ArticleResponse]{
+			Status:       "success",
+			Message:      "Articles retrieved successfully",
+			Results:      articles,
+			Page:         query.Page,
+			Limit:        query.Limit,
+			TotalPages:   int64(math.Ceil(float64(totalResults) / float64(query.Limit))),
+			TotalResults: totalResults,
+		})
+}
+
+// @Tags         Admin
+// @Summary      Get article details
+// @Description  Returns an article whatever its status
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Article ID"
+// @Router       /admin/articles/{id} [get]
+// @Success      200  {object}  response.SuccessWithArticle
+// @Failure      403  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+func (c *AdminArticleController) GetArticleByID(ctx *fiber.Ctx) error {
+	articleID := ctx.Params("id")
+	if _, err := uuid.Parse(articleID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid article ID format")
+	}
+
+	article, err := c.ArticleService.GetArticleByID(ctx, articleID, true)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithArticle{
+		Status:  "success",
+		Message: "Article details retrieved successfully",
+		Data:    *article,
+	})
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AdminReviewController struct {
+	EngagementService service.EngagementService
+}
+
+func NewAdminReviewController(engagementService service.EngagementService) *AdminReviewController {
+	return &AdminReviewController{
+		EngagementService: engagementService,
+	}
+}
+
+// @Tags         Admin
+// @Summary      Get all reviews
+// @Description  Returns the ratings and reviews of recipes and articles for moderation, hidden ones included, newest first
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page         query  int     false  "Page number"  default(1)
+// @Param        limit        query  int     false  "Maximum number of reviews"  default(10)
+// @Param        target_type  query  string  false  "Filter by content (recipe, article)"
+// @Param        hidden       query  string  false  "Filter by moderation (true, false)"
+// @Router       /admin/reviews [get]
+// @Success      200  {object}  response.SuccessWithPaginate[model.UsersStar]
+// @Failure      403  {object}  response.ErrorResponse
+func (c *AdminReviewController) GetAllReviews(ctx *fiber.Ctx) error {
+	query := &validation.QueryReview{
+		Page:       ctx.QueryInt("page", 1),
+		Limit:      ctx.QueryInt("limit", 10),
+		TargetType: ctx.Query("target_type"),
+		Hidden:     ctx.Query("hidden"),
+	}
+
+	reviews, totalResults, err := c.EngagementService.GetAllReviews(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).
+		JSON(response.SuccessWithPaginate[model.UsersStar]{
+			Status:       "success",
+			Message:      "Reviews retrieved successfully",
+			Results:      reviews,
+			Page:         query.Page,
+			Limit:        query.Limit,
+			TotalPages:   int64(math.Ceil(float64(totalResults) / float64(query.Limit))),
+			TotalResults: totalResults,
+		})
+}
+
+// @Tags         Admin
+// @Summary      Hide a review
+// @Description  Takes down an abusive review, it's left out of the content's reviews and rating
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id       path  string                 true  "Review ID"
+// @Param        request  body  validation.HideReview  true  "Reason"
+// @Router       /admin/reviews/{id}/hide [patch]
+// @Success      200  {object}  response.SuccessWithReview
+// @Failure      403  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+func (c *AdminReviewController) HideReview(ctx *fiber.Ctx) error {
+	reviewID := ctx.Params("id")
+	if _, err := uuid.Parse(reviewID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid review ID format")
+	}
+	admin := ctx.Locals("user").(*model.User)
+
+	req := new(validation.HideReview)
+	if err := ctx.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	review, err := c.EngagementService.HideReview(ctx, reviewID, admin.ID, req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithReview{
+		Status:  "success",
+		Message: "Review hidden successfully",
+		Data:    *review,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Unhide a review
+// @Description  Puts a hidden review back
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id  path  string  true  "Review ID"
+// @Router       /admin/reviews/{id}/unhide [patch]
+// @Success      200  {object}  response.SuccessWithReview
+// @Failure      403  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+func (c *AdminReviewController) UnhideReview(ctx *fiber.Ctx) error {
+	reviewID := ctx.Params("id")
+	if _, err := uuid.Parse(reviewID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid review ID format")
+	}
+
+	review, err := c.EngagementService.UnhideReview(ctx, reviewID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithReview{
+		Status:  "success",
+		Message: "Review unhidden successfully",
+		Data:    *review,
+	})
+}
@@ -9,6 +9,8 @@ import (
 
 	"encoding/json"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -244,6 +246,55 @@ func (c *AdminSubscriptionController) GetTransactionLogs(ctx *fiber.Ctx) error {
 	})
 }
 
+// @Tags         Admin
+// @Summary      Get subscription transitions
+// @Description  Returns the lifecycle history of a user subscription, oldest first
+// @Produce      json
+// @Security     BearerAuth
+// @Param        subscription_id   path  string  true  "Subscription ID"
+// @Router       /admin/subscriptions/{subscription_id}/transitions [get]
+// @Success      200  {object}  response.SuccessWithSubscriptionTransitions
+// @Failure      403  {object}  response.ErrorResponse
+// @Failure      404  {object}  response.ErrorResponse
+func (c *AdminSubscriptionController) GetSubscriptionTransitions(ctx *fiber.Ctx) error {
+	subscriptionID, err := uuid.Parse(ctx.Params("subscription_id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid subscription ID format")
+	}
+
+	transitions, err := c.SubscriptionService.GetSubscriptionTransitions(ctx, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithSubscriptionTransitions{
+		Status:  "success",
+		Message: "Subscription transitions retrieved successfully",
+		Data:    transitions,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Sweep subscriptions
+// @Description  Expires lapsed subscriptions and starts queued ones now, which is otherwise done periodically
+// @Produce      json
+// @Security     BearerAuth
+// @Router       /admin/subscriptions/sweep [post]
+// @Success      200  {object}  response.SuccessWithSubscriptionSweepReport
+// @Failure      403  {object}  response.ErrorResponse
+func (c *AdminSubscriptionController) SweepSubscriptions(ctx *fiber.Ctx) error {
+	report, err := c.SubscriptionService.SweepSubscriptions(ctx.Context(), time.Now())
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithSubscriptionSweepReport{
+		Status:  "success",
+		Message: "Subscriptions swept successfully",
+		Data:    *report,
+	})
+}
+
 // @Tags         Admin
 // @Summary      Update payment status
 // @Description  Updates the payment status of a user subscription
@@ -347,6 +398,92 @@ func (c *AdminSubscriptionController) GetAllTransactions(ctx *fiber.Ctx) error {
 	})
 }
 
+// @Tags         Admin
+// @Summary      Refund subscription
+// @Description  Refund a paid subscription through Midtrans, in full or for the time left, and revoke its access
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        subscription_id  path  string  true  "Subscription ID"
+// @Param        request  body  validation.RefundSubscription  true  "Refund type and reason"
+// @Router       /admin/subscriptions/{subscription_id}/refund [post]
+// @Success      200  {object}  response.SuccessWithTransaction
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      409  {object}  response.ErrorResponse
+func (c *AdminSubscriptionController) RefundSubscription(ctx *fiber.Ctx) error {
+	subscriptionID, err := uuid.Parse(ctx.Params("subscription_id"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid subscription ID format")
+	}
+
+	req := new(validation.RefundSubscription)
+	if err := ctx.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	refund, err := c.SubscriptionService.RefundSubscription(ctx, subscriptionID, req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithTransaction{
+		Status:  "success",
+		Message: "Subscription refunded successfully",
+		Data:    *refund,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Get payment reconciliations
+// @Description  Returns the reports of the payment reconciliation with the pending orders whose status differed at Midtrans, latest first
+// @Produce      json
+// @Security     BearerAuth
+// @Param        page     query     int     false   "Page number"  default(1)
+// @Param        limit    query     int     false   "Maximum number of reports"    default(10)
+// @Router       /admin/transactions/reconciliations [get]
+// @Success      200  {object}  response.SuccessWithPaymentReconciliations
+// @Failure      403  {object}  response.ErrorResponse
+func (c *AdminSubscriptionController) GetPaymentReconciliations(ctx *fiber.Ctx) error {
+	page := ctx.QueryInt("page", 1)
+	limit := ctx.QueryInt("limit", 10)
+
+	reconciliations, totalResults, err := c.SubscriptionService.GetPaymentReconciliations(ctx, page, limit)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithPaymentReconciliations{
+		Status:       "success",
+		Message:      "Payment reconciliations retrieved successfully",
+		Data:         reconciliations,
+		Page:         page,
+		Limit:        limit,
+		TotalPages:   int64(math.Ceil(float64(totalResults) / float64(limit))),
+		TotalResults: totalResults,
+	})
+}
+
+// @Tags         Admin
+// @Summary      Reconcile payments
+// @Description  Checks the stale pending orders with Midtrans now, which is otherwise done periodically
+// @Produce      json
+// @Security     BearerAuth
+// @Router       /admin/transactions/reconcile [post]
+// @Success      200  {object}  response.SuccessWithPaymentReconciliation
+// @Failure      403  {object}  response.ErrorResponse
+func (c *AdminSubscriptionController) ReconcilePayments(ctx *fiber.Ctx) error {
+	report, err := c.SubscriptionService.ReconcilePayments(ctx.Context(), time.Now())
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithPaymentReconciliation{
+		Status:  "success",
+		Message: "Payments reconciled successfully",
+		Data:    *report,
+	})
+}
+
 // @Tags         Admin
 // @Summary      Get transaction details
 // @Description  Returns details of a specific transaction
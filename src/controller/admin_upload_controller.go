@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"app/src/response"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AdminUploadController struct {
+	UploadService service.UploadService
+}
+
+func NewAdminUploadController(uploadService service.UploadService) *AdminUploadController {
+	return &AdminUploadController{
+		UploadService: uploadService,
+	}
+}
+
+// @Tags         Admin
+// @Summary      Collect orphaned uploads
+// @Description  Deletes now the uploaded images nothing links to anymore, which is otherwise done periodically
+// @Produce      json
+// @Security     BearerAuth
+// @Router       /admin/uploads/collect [post]
+// @Success      200  {object}  response.SuccessWithUploadGCReport
+// @Failure      403  {object}  response.ErrorResponse
+func (c *AdminUploadController) CollectOrphans(ctx *fiber.Ctx) error {
+	report, err := c.UploadService.CollectOrphans(ctx.Context())
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithUploadGCReport{
+		Status:  "success",
+		Message: "Orphaned uploads collected successfully",
+		Data:    *report,
+	})
+}
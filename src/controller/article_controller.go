@@ -4,6 +4,9 @@ import (
 	"app/src/model"
 	"app/src/response"
 	"app/src/service"
+	"app/src/validation"
+	"math"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -47,7 +50,7 @@ func (c *ArticleController) CreateArticle(ctx *fiber.Ctx) error {
 	}
 
 	// After creating the article, fetch it with category info
-	articleResponse, err := c.ArticleService.GetArticleByID(ctx, article.ID.String())
+	articleResponse, err := c.ArticleService.GetArticleByID(ctx, article.ID.String(), true)
 	if err != nil {
 		return err
 	}
@@ -61,27 +64,119 @@ func (c *ArticleController) CreateArticle(ctx *fiber.Ctx) error {
 
 // @Tags         Articles
 // @Summary      Get all articles
-// @Description  Get all articles
+// @Description  Search the published articles by title and content, filter them by category or tag and page through the results. Articles come with an excerpt instead of their content.
 // @Security     BearerAuth
 // @Produce      json
+// @Param        page          query  int     false  "Page number"  default(1)
+// @Param        limit         query  int     false  "Maximum number of articles per page"  default(10)
+// @Param        search        query  string  false  "Full-text search, quoted phrases and -word exclusions are supported"
+// @Param        category_ids  query  string  false  "Comma separated category IDs, an article in any of them matches"
+// @Param        tags          query  string  false  "Comma separated tags, an article with any of them matches"
+// @Param        sort          query  string  false  "newest, rating, popular or relevance, defaults to relevance when searching and newest otherwise"
 // @Router       /articles [get]
-// @Success      200  {object}  response.SuccessWithArticleList
+// @Success      200  {object}  response.SuccessWithPaginate[model.ArticleResponse]
+// @Failure      400  {object}  response.ErrorResponse  "Invalid query"
 func (c *ArticleController) GetArticles(ctx *fiber.Ctx) error {
-	articles, err := c.ArticleService.GetArticles(ctx)
+	query := articleQuery(ctx)
+
+	articles, totalResults, err := c.ArticleService.GetArticles(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).
+		JSON(response.SuccessWithPaginate[model.ArticleResponse]{
+			Status:       "success",
+			Message:      "Articles fetched successfully",
+			Results:      articles,
+			Page:         query.Page,
+			Limit:        query.Limit,
+			TotalPages:   int64(math.Ceil(float64(totalResults) / float64(query.Limit))),
+			TotalResults: totalResults,
+		})
+}
+
+// @Tags         Articles
+// @Summary      Get related articles
+// @Description  Get published articles sharing the article's category or words of its title, the most related first
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id     path   string  true   "Article ID"
+// @Param        limit  query  int     false  "Maximum number of articles, up to 20"  default(5)
+// @Router       /articles/{id}/related [get]
+// @Success      200  {object}  response.SuccessWithArticleList
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+func (c *ArticleController) GetRelatedArticles(ctx *fiber.Ctx) error {
+	articleID := ctx.Params("id")
+	if _, err := uuid.Parse(articleID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid article ID")
+	}
+
+	articles, err := c.ArticleService.GetRelatedArticles(ctx, articleID, ctx.QueryInt("limit", 5))
 	if err != nil {
 		return err
 	}
 
 	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithArticleList{
 		Status:  "success",
-		Message: "Articles fetched successfully",
-		Data:    articles, // No conversion needed, already ArticleResponse
+		Message: "Related articles fetched successfully",
+		Data:    articles,
+	})
+}
+
+// @Tags         Articles
+// @Summary      Get recommended articles
+// @Description  Get published articles matching the user's medical history, intake of the last two weeks, goal and reading history. Articles already read come after new ones, popular articles fill up the list.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        limit  query  int  false  "Maximum number of articles, up to 20"  default(10)
+// @Router       /articles/recommended [get]
+// @Success      200  {object}  response.SuccessWithArticleRecommendations
+func (c *ArticleController) GetRecommendedArticles(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	articles, err := c.ArticleService.GetRecommendedArticles(ctx, user.ID, ctx.QueryInt("limit", 10))
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithArticleRecommendations{
+		Status:  "success",
+		Message: "Recommended articles fetched successfully",
+		Data:    articles,
+	})
+}
+
+// @Tags         Articles
+// @Summary      Get article by slug
+// @Description  Get a published article by its slug, the article is recorded as read by the user
+// @Security     BearerAuth
+// @Produce      json
+// @Param        slug  path  string  true  "Article slug"
+// @Router       /articles/slug/{slug} [get]
+// @Success      200  {object}  response.SuccessWithArticle
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+func (c *ArticleController) GetArticleBySlug(ctx *fiber.Ctx) error {
+	article, err := c.ArticleService.GetArticleBySlug(ctx, ctx.Params("slug"), false)
+	if err != nil {
+		return err
+	}
+
+	user := ctx.Locals("user").(*model.User)
+	if err := c.ArticleService.RecordArticleRead(ctx, user.ID, article.ID); err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithArticle{
+		Status:  "success",
+		Message: "Article fetched successfully",
+		Data:    *article,
 	})
 }
 
 // @Tags         Articles
 // @Summary      Get article by ID
-// @Description  Get article by ID
+// @Description  Get a published article by ID, the article is recorded as read by the user
 // @Security     BearerAuth
 // @Produce      json
 // @Param        id  path  string  true  "Article ID"
@@ -96,11 +191,16 @@ func (c *ArticleController) GetArticleByID(ctx *fiber.Ctx) error {
 		})
 	}
 
-	article, err := c.ArticleService.GetArticleByID(ctx, articleID)
+	article, err := c.ArticleService.GetArticleByID(ctx, articleID, false)
 	if err != nil {
 		return err
 	}
 
+	user := ctx.Locals("user").(*model.User)
+	if err := c.ArticleService.RecordArticleRead(ctx, user.ID, article.ID); err != nil {
+		return err
+	}
+
 	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithArticle{
 		Status:  "success",
 		Message: "Article fetched successfully",
@@ -144,7 +244,7 @@ func (c *ArticleController) UpdateArticle(ctx *fiber.Ctx) error {
 	}
 
 	// After updating, fetch the article with category info
-	articleResponse, err := c.ArticleService.GetArticleByID(ctx, articleID)
+	articleResponse, err := c.ArticleService.GetArticleByID(ctx, articleID, true)
 	if err != nil {
 		return err
 	}
@@ -183,6 +283,159 @@ func (c *ArticleController) DeleteArticle(ctx *fiber.Ctx) error {
 	})
 }
 
+// @Tags         Articles
+// @Summary      Change article status
+// @Description  Move an article between draft, scheduled, published and archived. Scheduling needs a published_at in the future.
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                          true  "Article ID"
+// @Param        request  body  validation.UpdateArticleStatus  true  "New status"
+// @Router       /articles/{id}/status [patch]
+// @Success      200  {object}  response.SuccessWithArticle
+// @Failure      400  {object}  response.ErrorResponse  "Invalid status or publication date"
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+// @Failure      409  {object}  response.ErrorResponse  "Transition not allowed"
+func (c *ArticleController) UpdateArticleStatus(ctx *fiber.Ctx) error {
+	articleID := ctx.Params("id")
+	if _, err := uuid.Parse(articleID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid article ID")
+	}
+
+	req := new(validation.UpdateArticleStatus)
+	if err := ctx.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	article, err := c.ArticleService.UpdateArticleStatus(ctx, articleID, req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithArticle{
+		Status:  "success",
+		Message: "Article status updated successfully",
+		Data:    *article,
+	})
+}
+
+// @Tags         Articles
+// @Summary      Get article revisions
+// @Description  Get the versions a published article had before it was edited, the latest first
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Article ID"
+// @Router       /articles/{id}/revisions [get]
+// @Success      200  {object}  response.SuccessWithArticleRevisions
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+func (c *ArticleController) GetArticleRevisions(ctx *fiber.Ctx) error {
+	articleID := ctx.Params("id")
+	if _, err := uuid.Parse(articleID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid article ID")
+	}
+
+	revisions, err := c.ArticleService.GetArticleRevisions(ctx, articleID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithArticleRevisions{
+		Status:  "success",
+		Message: "Article revisions fetched successfully",
+		Data:    revisions,
+	})
+}
+
+// @Tags         Articles
+// @Summary      Diff an article revision
+// @Description  Compare a revision with the current article, with a unified diff of the content
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id          path  string  true  "Article ID"
+// @Param        revisionId  path  string  true  "Revision ID"
+// @Router       /articles/{id}/revisions/{revisionId}/diff [get]
+// @Success      200  {object}  response.SuccessWithArticleRevisionDiff
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+func (c *ArticleController) DiffArticleRevision(ctx *fiber.Ctx) error {
+	articleID, revisionID, err := articleRevisionParams(ctx)
+	if err != nil {
+		return err
+	}
+
+	diff, err := c.ArticleService.DiffArticleRevision(ctx, articleID, revisionID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithArticleRevisionDiff{
+		Status:  "success",
+		Message: "Article revision diff fetched successfully",
+		Data:    *diff,
+	})
+}
+
+// @Tags         Articles
+// @Summary      Restore an article revision
+// @Description  Put back the title, category, image and content of a revision. The replaced version is kept as a new revision.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id          path  string  true  "Article ID"
+// @Param        revisionId  path  string  true  "Revision ID"
+// @Router       /articles/{id}/revisions/{revisionId}/restore [post]
+// @Success      200  {object}  response.SuccessWithArticle
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+func (c *ArticleController) RestoreArticleRevision(ctx *fiber.Ctx) error {
+	articleID, revisionID, err := articleRevisionParams(ctx)
+	if err != nil {
+		return err
+	}
+	user := ctx.Locals("user").(*model.User)
+
+	article, err := c.ArticleService.RestoreArticleRevision(ctx, articleID, revisionID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithArticle{
+		Status:  "success",
+		Message: "Article revision restored successfully",
+		Data:    *article,
+	})
+}
+
+func articleRevisionParams(ctx *fiber.Ctx) (string, string, error) {
+	articleID := ctx.Params("id")
+	if _, err := uuid.Parse(articleID); err != nil {
+		return "", "", fiber.NewError(fiber.StatusBadRequest, "Invalid article ID")
+	}
+	revisionID := ctx.Params("revisionId")
+	if _, err := uuid.Parse(revisionID); err != nil {
+		return "", "", fiber.NewError(fiber.StatusBadRequest, "Invalid revision ID")
+	}
+	return articleID, revisionID, nil
+}
+
+// articleQuery reads the paging, search and filters of an article listing
+func articleQuery(ctx *fiber.Ctx) *validation.QueryArticle {
+	query := &validation.QueryArticle{
+		Page:   ctx.QueryInt("page", 1),
+		Limit:  ctx.QueryInt("limit", 10),
+		Search: ctx.Query("search"),
+		Sort:   ctx.Query("sort"),
+	}
+	for _, categoryID := range strings.Split(ctx.Query("category_ids"), ",") {
+		if categoryID = strings.TrimSpace(categoryID); categoryID != "" {
+			query.CategoryIDs = append(query.CategoryIDs, categoryID)
+		}
+	}
+	for _, tag := range strings.Split(ctx.Query("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			query.Tags = append(query.Tags, tag)
+		}
+	}
+	return query
+}
+
 // Categories endpoints
 // @Tags         Article Categories
 // @Summary      Create new article category
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type BodyMetricsController struct {
+	BodyMetricsService service.BodyMetricsService
+}
+
+func NewBodyMetricsController(service service.BodyMetricsService) *BodyMetricsController {
+	return &BodyMetricsController{
+		BodyMetricsService: service,
+	}
+}
+
+// @Tags         Statistics
+// @Summary      Get body metrics
+// @Description  Logged in users whose plan has the bmi_check feature can fetch the metrics of their weight and height records: BMI with its Asia-Pacific category, ideal weight range and BMR, plus the waist-to-height ratio and fat and lean mass of records with a waist circumference or body fat percentage.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        from        query   string  false  "First day (YYYY-MM-DD)"
+// @Param        to          query   string  false  "Last day (YYYY-MM-DD)"
+// @Param        X-Timezone  header  string  false  "IANA timezone overriding the profile's, e.g. Asia/Makassar"
+// @Router       /body-metrics [get]
+// @Success      200  {object}  response.SuccessWithBodyMetrics
+// @Failure      400  {object}  response.ErrorResponse  "Invalid range"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.Forbidden  "Plan without bmi_check"
+func (c *BodyMetricsController) GetBodyMetrics(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	query := &validation.QueryBodyMetrics{
+		From: ctx.Query("from"),
+		To:   ctx.Query("to"),
+	}
+
+	metrics, err := c.BodyMetricsService.GetBodyMetrics(ctx, user.ID, query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithBodyMetrics{
+		Status:  "success",
+		Message: "Body metrics fetched successfully",
+		Data:    *metrics,
+	})
+}
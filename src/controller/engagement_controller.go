@@ -0,0 +1,339 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+	"math"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type EngagementController struct {
+	EngagementService service.EngagementService
+}
+
+func NewEngagementController(service service.EngagementService) *EngagementController {
+	return &EngagementController{
+		EngagementService: service,
+	}
+}
+
+// @Tags         Recipes
+// @Summary      Favorite a recipe
+// @Description  Logged in users can add a recipe to their favorites, adding it again changes nothing.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Recipe ID"
+// @Router       /recipes/{id}/favorite [post]
+// @Success      200  {object}  response.Common
+// @Failure      404  {object}  response.ErrorResponse  "Recipe not found"
+func (c *EngagementController) FavoriteRecipe(ctx *fiber.Ctx) error {
+	return c.addFavorite(ctx, model.TargetRecipe, "recipe")
+}
+
+// @Tags         Recipes
+// @Summary      Unfavorite a recipe
+// @Description  Logged in users can remove a recipe from their favorites.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Recipe ID"
+// @Router       /recipes/{id}/favorite [delete]
+// @Success      200  {object}  response.Common
+// @Failure      404  {object}  response.ErrorResponse  "Recipe not found"
+func (c *EngagementController) UnfavoriteRecipe(ctx *fiber.Ctx) error {
+	return c.removeFavorite(ctx, model.TargetRecipe, "recipe")
+}
+
+// @Tags         Recipes
+// @Summary      Rate a recipe
+// @Description  Logged in users can rate a recipe from 1 to 5 stars with an optional review, rating again replaces their previous rating.
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                  true  "Recipe ID"
+// @Param        request  body  validation.RateContent  true  "Stars and review"
+// @Router       /recipes/{id}/rating [put]
+// @Success      200  {object}  response.SuccessWithReview
+// @Failure      400  {object}  response.ErrorResponse  "Invalid rating"
+// @Failure      404  {object}  response.ErrorResponse  "Recipe not found"
+func (c *EngagementController) RateRecipe(ctx *fiber.Ctx) error {
+	return c.rateContent(ctx, model.TargetRecipe, "recipe")
+}
+
+// @Tags         Recipes
+// @Summary      Delete a recipe rating
+// @Description  Logged in users can remove their rating and review of a recipe.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Recipe ID"
+// @Router       /recipes/{id}/rating [delete]
+// @Success      200  {object}  response.Common
+// @Failure      404  {object}  response.ErrorResponse  "Recipe or rating not found"
+func (c *EngagementController) DeleteRecipeRating(ctx *fiber.Ctx) error {
+	return c.deleteRating(ctx, model.TargetRecipe, "recipe")
+}
+
+// @Tags         Recipes
+// @Summary      Get recipe reviews
+// @Description  Page through the ratings and reviews of a recipe, newest first. Reviews hidden by a moderator are left out.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id     path   string  true   "Recipe ID"
+// @Param        page   query  int     false  "Page number"  default(1)
+// @Param        limit  query  int     false  "Maximum number of reviews per page"  default(10)
+// @Router       /recipes/{id}/reviews [get]
+// @Success      200  {object}  response.SuccessWithPaginate[model.UsersStar]
+// @Failure      404  {object}  response.ErrorResponse  "Recipe not found"
+func (c *EngagementController) GetRecipeReviews(ctx *fiber.Ctx) error {
+	return c.getReviews(ctx, model.TargetRecipe, "recipe")
+}
+
+// @Tags         Articles
+// @Summary      Favorite an article
+// @Description  Logged in users can add an article to their favorites, adding it again changes nothing.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Article ID"
+// @Router       /articles/{id}/favorite [post]
+// @Success      200  {object}  response.Common
+// @Failure      404  {object}  response.ErrorResponse  "Article not found"
+func (c *EngagementController) FavoriteArticle(ctx *fiber.Ctx) error {
+	return c.addFavorite(ctx, model.TargetArticle, "article")
+}
+
+// @Tags         Articles
+// @Summary      Unfavorite an article
+// @Description  Logged in users can remove an article from their favorites.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Article ID"
+// @Router       /articles/{id}/favorite [delete]
+// @Success      200  {object}  response.Common
+// @Failure      404  {object}  response.ErrorResponse  "Article not found"
+func (c *EngagementController) UnfavoriteArticle(ctx *fiber.Ctx) error {
+	return c.removeFavorite(ctx, model.TargetArticle, "article")
+}
+
+// @Tags         Articles
+// @Summary      Rate an article
+// @Description  Logged in users can rate an article from 1 to 5 stars with an optional review, rating again replaces their previous rating.
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                  true  "Article ID"
+// @Param        request  body  validation.RateContent  true  "Stars and review"
+// @Router       /articles/{id}/rating [put]
+// @Success      200  {object}  response.SuccessWithReview
+// @Failure      400  {object}  response.ErrorResponse  "Invalid rating"
+// @Failure      404  {object}  response.ErrorResponse  "Article not found"
+func (c *EngagementController) RateArticle(ctx *fiber.Ctx) error {
+	return c.rateContent(ctx, model.TargetArticle, "article")
+}
+
+// @Tags         Articles
+// @Summary      Delete an article rating
+// @Description  Logged in users can remove their rating and review of an article.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id  path  string  true  "Article ID"
+// @Router       /articles/{id}/rating [delete]
+// @Success      200  {object}  response.Common
+// @Failure      404  {object}  response.ErrorResponse  "Article or rating not found"
+func (c *EngagementController) DeleteArticleRating(ctx *fiber.Ctx) error {
+	return c.deleteRating(ctx, model.TargetArticle, "article")
+}
+
+// @Tags         Articles
+// @Summary      Get article reviews
+// @Description  Page through the ratings and reviews of an article, newest first. Reviews hidden by a moderator are left out.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id     path   string  true   "Article ID"
+// @Param        page   query  int     false  "Page number"  default(1)
+// @Param        limit  query  int     false  "Maximum number of reviews per page"  default(10)
+// @Router       /articles/{id}/reviews [get]
+// @Success      200  {object}  response.SuccessWithPaginate[model.UsersStar]
+// @Failure      404  {object}  response.ErrorResponse  "Article not found"
+func (c *EngagementController) GetArticleReviews(ctx *fiber.Ctx) error {
+	return c.getReviews(ctx, model.TargetArticle, "article")
+}
+
+// @Tags         Favorites
+// @Summary      Get my favorite recipes
+// @Description  Logged in users can page through their favorite recipes, the latest favorite first.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        page   query  int  false  "Page number"  default(1)
+// @Param        limit  query  int  false  "Maximum number of recipes per page"  default(10)
+// @Router       /favorites/recipes [get]
+// @Success      200  {object}  response.SuccessWithPaginate[model.Recipe]
+func (c *EngagementController) GetFavoriteRecipes(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+	query := &validation.QueryFavorite{
+		Page:  ctx.QueryInt("page", 1),
+		Limit: ctx.QueryInt("limit", 10),
+	}
+
+	recipes, totalResults, err := c.EngagementService.GetFavoriteRecipes(ctx, user.ID, query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).
+		JSON(response.SuccessWithPaginate[model.Recipe]{
+			Status:       "success",
+			Message:      "Favorite recipes fetched successfully",
+			Results:      recipes,
+			Page:         query.Page,
+			Limit:        query.Limit,
+			TotalPages:   int64(math.Ceil(float64(totalResults) / float64(query.Limit))),
+			TotalResults: totalResults,
+		})
+}
+
+// @Tags         Favorites
+// @Summary      Get my favorite articles
+// @Description  Logged in users can page through their favorite articles, the latest favorite first.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        page   query  int  false  "Page number"  default(1)
+// @Param        limit  query  int  false  "Maximum number of articles per page"  default(10)
+// @Router       /favorites/articles [get]
+// @Success      200  {object}  response.SuccessWithPaginate[model.ArticleResponse]
+func (c *EngagementController) GetFavoriteArticles(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+	query := &validation.QueryFavorite{
+		Page:  ctx.QueryInt("page", 1),
+		Limit: ctx.QueryInt("limit", 10),
+	}
+
+	articles, totalResults, err := c.EngagementService.GetFavoriteArticles(ctx, user.ID, query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).
+		JSON(response.SuccessWithPaginate[model.ArticleResponse]{
+			Status:       "success",
+			Message:      "Favorite articles fetched successfully",
+			Results:      articles,
+			Page:         query.Page,
+			Limit:        query.Limit,
+			TotalPages:   int64(math.Ceil(float64(totalResults) / float64(query.Limit))),
+			TotalResults: totalResults,
+		})
+}
+
+func (c *EngagementController) addFavorite(ctx *fiber.Ctx, targetType string, name string) error {
+	targetID, err := contentID(ctx, name)
+	if err != nil {
+		return err
+	}
+	user := ctx.Locals("user").(*model.User)
+
+	if err := c.EngagementService.AddFavorite(ctx, user.ID, targetType, targetID); err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Common{
+		Status:  "success",
+		Message: "Added to favorites successfully",
+	})
+}
+
+func (c *EngagementController) removeFavorite(ctx *fiber.Ctx, targetType string, name string) error {
+	targetID, err := contentID(ctx, name)
+	if err != nil {
+		return err
+	}
+	user := ctx.Locals("user").(*model.User)
+
+	if err := c.EngagementService.RemoveFavorite(ctx, user.ID, targetType, targetID); err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Common{
+		Status:  "success",
+		Message: "Removed from favorites successfully",
+	})
+}
+
+func (c *EngagementController) rateContent(ctx *fiber.Ctx, targetType string, name string) error {
+	targetID, err := contentID(ctx, name)
+	if err != nil {
+		return err
+	}
+	user := ctx.Locals("user").(*model.User)
+
+	req := new(validation.RateContent)
+	if err := ctx.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	rating, err := c.EngagementService.RateContent(ctx, user.ID, targetType, targetID, req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithReview{
+		Status:  "success",
+		Message: "Rating saved successfully",
+		Data:    *rating,
+	})
+}
+
+func (c *EngagementController) deleteRating(ctx *fiber.Ctx, targetType string, name string) error {
+	targetID, err := contentID(ctx, name)
+	if err != nil {
+		return err
+	}
+	user := ctx.Locals("user").(*model.User)
+
+	if err := c.EngagementService.DeleteRating(ctx, user.ID, targetType, targetID); err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Common{
+		Status:  "success",
+		Message: "Rating deleted successfully",
+	})
+}
+
+func (c *EngagementController) getReviews(ctx *fiber.Ctx, targetType string, name string) error {
+	targetID, err := contentID(ctx, name)
+	if err != nil {
+		return err
+	}
+	query := &validation.QueryReview{
+		Page:  ctx.QueryInt("page", 1),
+		Limit: ctx.QueryInt("limit", 10),
+	}
+
+	reviews, totalResults, err := c.EngagementService.GetReviews(ctx, targetType, targetID, query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).
+		JSON(response.SuccessWithPaginate[model.UsersStar]{
+			Status:       "success",
+			Message:      "Reviews fetched successfully",
+			Results:      reviews,
+			Page:         query.Page,
+			Limit:        query.Limit,
+			TotalPages:   int64(math.Ceil(float64(totalResults) / float64(query.Limit))),
+			TotalResults: totalResults,
+		})
+}
+
+// contentID reads the id path parameter of a recipe or an article
+func contentID(ctx *fiber.Ctx, name string) (string, error) {
+	id := ctx.Params("id")
+	if _, err := uuid.Parse(id); err != nil {
+		return "", fiber.NewError(fiber.StatusBadRequest, "Invalid "+name+" ID")
+	}
+	return id, nil
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type FoodAliasController struct {
+	FoodMatchingService service.FoodMatchingService
+}
+
+func NewFoodAliasController(service service.FoodMatchingService) *FoodAliasController {
+	return &FoodAliasController{
+		FoodMatchingService: service,
+	}
+}
+
+// @Tags         BahanMakanan
+// @Summary      Get food aliases
+// @Description  Only admins can list the curated aliases that map recognized food names to a bahan makanan kode
+// @Security     BearerAuth
+// @Produce      json
+// @Router       /bahan-makanan/aliases [get]
+// @Success      200  {object}  response.SuccessWithFoodAliases
+// @Failure      403  {object}  example.Forbidden  "Forbidden"
+func (c *FoodAliasController) GetAliases(ctx *fiber.Ctx) error {
+	aliases, err := c.FoodMatchingService.GetAliases(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithFoodAliases{
+		Status:  "success",
+		Message: "Food aliases fetched successfully",
+		Data:    aliases,
+	})
+}
+
+// @Tags         BahanMakanan
+// @Summary      Create a food alias
+// @Description  Only admins can map a recognized food name (e.g. "white rice") to a bahan makanan kode
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body  validation.CreateFoodAlias  true  "Alias and kode"
+// @Router       /bahan-makanan/aliases [post]
+// @Success      201  {object}  response.SuccessWithFoodAlias
+// @Failure      400  {object}  response.ErrorResponse  "Unknown kode"
+// @Failure      409  {object}  response.ErrorResponse  "Alias already exists"
+func (c *FoodAliasController) CreateAlias(ctx *fiber.Ctx) error {
+	req := new(validation.CreateFoodAlias)
+	if err := ctx.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	alias, err := c.FoodMatchingService.CreateAlias(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(response.SuccessWithFoodAlias{
+		Status:  "success",
+		Message: "Food alias created successfully",
+		Data:    *alias,
+	})
+}
+
+// @Tags         BahanMakanan
+// @Summary      Delete a food alias
+// @Description  Only admins can delete food aliases
+// @Security     BearerAuth
+// @Produce      json
+// @Param        aliasId  path  string  true  "Alias ID"
+// @Router       /bahan-makanan/aliases/{aliasId} [delete]
+// @Success      200  {object}  response.Common
+// @Failure      404  {object}  example.NotFound  "Not found"
+func (c *FoodAliasController) DeleteAlias(ctx *fiber.Ctx) error {
+	aliasId := ctx.Params("aliasId")
+
+	if _, err := uuid.Parse(aliasId); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid alias ID")
+	}
+
+	if err := c.FoodMatchingService.DeleteAlias(ctx, aliasId); err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Common{
+		Status:  "success",
+		Message: "Food alias deleted successfully",
+	})
+}
@@ -44,6 +44,7 @@ func NewLoginStreakController(loginStreakService service.LoginStreakService) *Lo
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param X-Timezone header string false "IANA timezone overriding the profile's, e.g. Asia/Makassar"
 // @Success 200 {object} response.CommonResponse "Login streak recorded successfully"
 // @Failure 400 {object} response.ErrorResponse "Bad request"
 // @Failure 401 {object} response.ErrorResponse "Unauthorized"
@@ -54,7 +55,12 @@ func (c *LoginStreakController) RecordLoginStreak(ctx *fiber.Ctx) error {
 	user := ctx.Locals("user").(*model.User)
 	userID := user.ID
 
-	err := c.loginStreakService.RecordLogin(userID)
+	location, err := service.RequestLocation(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	err = c.loginStreakService.RecordLogin(userID, location)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
@@ -75,6 +81,7 @@ func (c *LoginStreakController) RecordLoginStreak(ctx *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param X-Timezone header string false "IANA timezone overriding the profile's, e.g. Asia/Makassar"
 // @Success 200 {object} response.SuccessWithLoginStreak "Login streak data"
 // @Failure 400 {object} response.ErrorResponse "Bad request"
 // @Failure 401 {object} response.ErrorResponse "Unauthorized"
@@ -85,7 +92,12 @@ func (c *LoginStreakController) GetLoginStreak(ctx *fiber.Ctx) error {
 	user := ctx.Locals("user").(*model.User)
 	userID := user.ID
 
-	streakData, err := c.loginStreakService.GetLoginStreak(userID)
+	location, err := service.RequestLocation(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	streakData, err := c.loginStreakService.GetLoginStreak(userID, location)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"status":  "error",
@@ -4,6 +4,8 @@ import (
 	"app/src/model"
 	"app/src/response"
 	"app/src/service"
+	"app/src/validation"
+	"errors"
 	"math"
 
 	"github.com/gofiber/fiber/v2"
@@ -27,8 +29,11 @@ func NewMealController(ms service.MealService) *MealController {
 // @Accept       multipart/form-data
 // @Produce      json
 // @Param        image  formData  file      true  "Meal's image"
+// @Param        async  query     bool      false "Queue the scan and return a job to poll instead of waiting for the result"
 // @Router       /meals/scan [post]
 // @Success      200  {object}  example.MealScanResponse
+// @Success      202  {object}  response.SuccessWithMealScanJob
+// @Failure      429  {object}  response.ErrorResponse  "AI scan quota of the current plan is used up"
 func (mc *MealController) ScanMeal(c *fiber.Ctx) error {
 	file, err := c.FormFile("image")
 	if err != nil {
@@ -41,17 +46,65 @@ func (mc *MealController) ScanMeal(c *fiber.Ctx) error {
 	user := c.Locals("user")
 	userData := user.(*model.User)
 
+	if c.QueryBool("async", false) {
+		job, err := mc.MealService.CreateScanJob(c, file, userData.ID)
+		if err != nil {
+			return scanError(c, err)
+		}
+
+		return c.Status(fiber.StatusAccepted).JSON(response.SuccessWithMealScanJob{
+			Status:  "success",
+			Message: "Meal scan queued successfully",
+			Job:     *job,
+		})
+	}
+
 	result, err := mc.MealService.ScanMeal(c, file, userData.ID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(response.Common{
-			Status:  "error",
-			Message: err.Error(),
-		})
+		return scanError(c, err)
 	}
 
 	return c.Status(fiber.StatusOK).JSON(result)
 }
 
+// scanError keeps the status of errors about the upload itself, like an unsupported image, and reports
+// every other scan failure as a server error
+func scanError(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return err
+	}
+
+	return c.Status(fiber.StatusInternalServerError).JSON(response.Common{
+		Status:  "error",
+		Message: err.Error(),
+	})
+}
+
+// @Tags         Meals
+// @Summary      Get a meal scan job
+// @Description  Poll the status of an asynchronous meal scan. The result and meal_history_id are set once the job is completed.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        jobId  path  string  true  "Scan job id"
+// @Router       /meals/scan/jobs/{jobId} [get]
+// @Success      200  {object}  response.SuccessWithMealScanJob
+// @Failure      404  {object}  example.NotFound  "Not found"
+func (mc *MealController) GetScanJob(c *fiber.Ctx) error {
+	user := c.Locals("user").(*model.User)
+
+	job, err := mc.MealService.GetScanJob(c, c.Params("jobId"), user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithMealScanJob{
+		Status:  "success",
+		Message: "Get meal scan job successfully",
+		Job:     *job,
+	})
+}
+
 // @Tags         Meals
 // @Summary      Get a user's meals
 // @Description  Logged in users can fetch only their own meals information.
@@ -188,9 +241,47 @@ func (mc *MealController) AddMealScanDetail(c *fiber.Ctx) error {
 	})
 }
 
+// @Tags         Meals
+// @Summary      Confirm or correct a meal scan
+// @Description  Pick the right candidate for each scanned segment and set the portion in grams. The meal's nutrients are recomputed from the selection.
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        mealId   path      string                      true  "Meal ID"
+// @Param        request  body      validation.ConfirmMealScan  true  "Selected food and portion per segment"
+// @Router       /meals/{mealId}/scan-detail/confirm [post]
+// @Success      200  {object}  example.UpdateMealResponse
+// @Failure      400  {object}  response.ErrorResponse  "Invalid selection"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      403  {object}  example.Forbidden  "Forbidden"
+// @Failure      404  {object}  example.NotFound  "Not found"
+func (mc *MealController) ConfirmMealScan(c *fiber.Ctx) error {
+	mealId := c.Params("mealId")
+
+	if _, err := uuid.Parse(mealId); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid meal ID")
+	}
+
+	req := new(validation.ConfirmMealScan)
+	if err := c.BodyParser(req); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	meal, err := mc.MealService.ConfirmMealScan(c, mealId, req)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(response.SuccessWithMeal{
+		Status:  "success",
+		Message: "Meal scan confirmed successfully",
+		Meal:    *meal,
+	})
+}
+
 // @Tags         Meals
 // @Summary      Add a new meal
-// @Description  Logged in users can add a new meal.
+// @Description  Logged in users can add a new meal. Send ingredients as bahan makanan kode and grams to have the nutrients computed from TKPI.
 // @Security     BearerAuth
 // @Accept       json
 // @Produce      json
@@ -213,10 +304,7 @@ func (mc *MealController) AddMeal(c *fiber.Ctx) error {
 
 	meal, err := mc.MealService.AddMeal(c, &request)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(response.Common{
-			Status:  "error",
-			Message: err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(fiber.StatusCreated).JSON(response.SuccessWithMeal{
@@ -228,7 +316,7 @@ func (mc *MealController) AddMeal(c *fiber.Ctx) error {
 
 // @Tags         Meals
 // @Summary      Update a meal
-// @Description  Logged in users can update their own meal. Only admins can update other user's meal.
+// @Description  Logged in users can update their own meal. Only admins can update other user's meal. Sending ingredients recomputes the nutrients.
 // @Security     BearerAuth
 // @Accept       json
 // @Produce      json
@@ -296,9 +384,10 @@ func (mc *MealController) DeleteMeal(c *fiber.Ctx) error {
 
 // @Tags         Statistics
 // @Summary      Get home statistics
-// @Description  Logged in users can fetch their home statistics including today's consumed calories and weight/height info
+// @Description  Logged in users can fetch their home statistics including today's consumed calories and weight/height info. Today is counted in the user's timezone.
 // @Security     BearerAuth
 // @Produce      json
+// @Param        X-Timezone  header  string  false  "IANA timezone overriding the profile's, e.g. Asia/Makassar"
 // @Router       /home/statistic [get]
 // @Success      200  {object}  example.GetHomeStatisticsResponse
 // @Failure      401  {object}  example.Unauthorized  "Unauthorized"
@@ -307,10 +396,7 @@ func (mc *MealController) GetHomeStatistics(c *fiber.Ctx) error {
 
 	homeStats, err := mc.MealService.GetHomeStatistics(c, user.ID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(response.Common{
-			Status:  "error",
-			Message: err.Error(),
-		})
+		return err
 	}
 
 	return c.Status(fiber.StatusOK).JSON(response.SuccessWithHomeStatistics{
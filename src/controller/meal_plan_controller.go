@@ -0,0 +1,245 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type MealPlanController struct {
+	MealPlanService service.MealPlanService
+}
+
+func NewMealPlanController(service service.MealPlanService) *MealPlanController {
+	return &MealPlanController{
+		MealPlanService: service,
+	}
+}
+
+// @Tags         Meal Plans
+// @Summary      Generate a meal plan
+// @Description  Logged in users can generate a 7-day plan of breakfast, lunch and dinner recipes fitted to their daily calorie and macro targets. Recipes mentioning an excluded ingredient are skipped.
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request     body    validation.CreateMealPlan  false  "First day and excluded ingredients"
+// @Param        X-Timezone  header  string  false  "IANA timezone overriding the profile's, e.g. Asia/Makassar"
+// @Router       /meal-plans [post]
+// @Success      201  {object}  response.SuccessWithMealPlan
+// @Failure      400  {object}  response.ErrorResponse  "Invalid request"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      422  {object}  response.ErrorResponse  "No recipe can be planned"
+func (c *MealPlanController) GenerateMealPlan(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	req := new(validation.CreateMealPlan)
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	plan, err := c.MealPlanService.GenerateMealPlan(ctx, user.ID, req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(response.SuccessWithMealPlan{
+		Status:  "success",
+		Message: "Meal plan generated successfully",
+		Data:    *plan,
+	})
+}
+
+// @Tags         Meal Plans
+// @Summary      Get meal plans
+// @Description  Logged in users can list their meal plans, newest first and without slots.
+// @Security     BearerAuth
+// @Produce      json
+// @Router       /meal-plans [get]
+// @Success      200  {object}  response.SuccessWithMealPlans
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+func (c *MealPlanController) GetMealPlans(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	plans, err := c.MealPlanService.GetMealPlans(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithMealPlans{
+		Status:  "success",
+		Message: "Meal plans fetched successfully",
+		Data:    plans,
+	})
+}
+
+// @Tags         Meal Plans
+// @Summary      Get the current meal plan
+// @Description  Logged in users can fetch the latest plan covering today in their timezone.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        X-Timezone  header  string  false  "IANA timezone overriding the profile's, e.g. Asia/Makassar"
+// @Router       /meal-plans/current [get]
+// @Success      200  {object}  response.SuccessWithMealPlan
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      404  {object}  response.ErrorResponse  "No plan for today"
+func (c *MealPlanController) GetCurrentMealPlan(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	plan, err := c.MealPlanService.GetCurrentMealPlan(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithMealPlan{
+		Status:  "success",
+		Message: "Meal plan fetched successfully",
+		Data:    *plan,
+	})
+}
+
+// @Tags         Meal Plans
+// @Summary      Get a meal plan
+// @Description  Logged in users can fetch one of their plans with its slots and recipes.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        planId  path  string  true  "Meal plan ID"
+// @Router       /meal-plans/{planId} [get]
+// @Success      200  {object}  response.SuccessWithMealPlan
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+func (c *MealPlanController) GetMealPlanByID(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	planID := ctx.Params("planId")
+	if _, err := uuid.Parse(planID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid meal plan ID")
+	}
+
+	plan, err := c.MealPlanService.GetMealPlanByID(ctx, user.ID, planID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithMealPlan{
+		Status:  "success",
+		Message: "Meal plan fetched successfully",
+		Data:    *plan,
+	})
+}
+
+// @Tags         Meal Plans
+// @Summary      Delete a meal plan
+// @Description  Logged in users can delete one of their plans, meals already logged from it are kept.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        planId  path  string  true  "Meal plan ID"
+// @Router       /meal-plans/{planId} [delete]
+// @Success      200  {object}  response.Common
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+func (c *MealPlanController) DeleteMealPlan(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	planID := ctx.Params("planId")
+	if _, err := uuid.Parse(planID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid meal plan ID")
+	}
+
+	if err := c.MealPlanService.DeleteMealPlan(ctx, user.ID, planID); err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.Common{
+		Status:  "success",
+		Message: "Meal plan deleted successfully",
+	})
+}
+
+// @Tags         Meal Plans
+// @Summary      Swap a planned meal
+// @Description  Logged in users can replace the recipe of a slot that isn't logged yet, either with a recipe of their choice or with the next best fit when no recipe is given.
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        planId   path  string                       true   "Meal plan ID"
+// @Param        slotId   path  string                       true   "Slot ID"
+// @Param        request  body  validation.SwapMealPlanSlot  false  "Recipe to use instead"
+// @Router       /meal-plans/{planId}/slots/{slotId}/swap [post]
+// @Success      200  {object}  response.SuccessWithMealPlanSlot
+// @Failure      400  {object}  response.ErrorResponse  "Recipe contains an excluded ingredient"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+// @Failure      409  {object}  response.ErrorResponse  "Slot already logged"
+func (c *MealPlanController) SwapSlot(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	planID := ctx.Params("planId")
+	slotID := ctx.Params("slotId")
+	if _, err := uuid.Parse(planID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid meal plan ID")
+	}
+	if _, err := uuid.Parse(slotID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid meal plan slot ID")
+	}
+
+	req := new(validation.SwapMealPlanSlot)
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(req); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	slot, err := c.MealPlanService.SwapSlot(ctx, user.ID, planID, slotID, req)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithMealPlanSlot{
+		Status:  "success",
+		Message: "Meal plan slot swapped successfully",
+		Data:    *slot,
+	})
+}
+
+// @Tags         Meal Plans
+// @Summary      Log a planned meal
+// @Description  Logged in users can add a planned meal of today or an earlier day to their meal history in one tap. Each slot can be logged once.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        planId  path  string  true  "Meal plan ID"
+// @Param        slotId  path  string  true  "Slot ID"
+// @Router       /meal-plans/{planId}/slots/{slotId}/log [post]
+// @Success      201  {object}  response.SuccessWithMeal
+// @Failure      400  {object}  response.ErrorResponse  "Slot planned for a later day"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+// @Failure      409  {object}  response.ErrorResponse  "Slot already logged"
+func (c *MealPlanController) LogSlot(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	planID := ctx.Params("planId")
+	slotID := ctx.Params("slotId")
+	if _, err := uuid.Parse(planID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid meal plan ID")
+	}
+	if _, err := uuid.Parse(slotID); err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Invalid meal plan slot ID")
+	}
+
+	meal, err := c.MealPlanService.LogSlot(ctx, user.ID, planID, slotID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(response.SuccessWithMeal{
+		Status:  "success",
+		Message: "Planned meal logged successfully",
+		Meal:    *meal,
+	})
+}
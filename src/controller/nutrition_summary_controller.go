@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type NutritionSummaryController struct {
+	NutritionSummaryService service.NutritionSummaryService
+}
+
+func NewNutritionSummaryController(service service.NutritionSummaryService) *NutritionSummaryController {
+	return &NutritionSummaryController{
+		NutritionSummaryService: service,
+	}
+}
+
+// @Tags         Statistics
+// @Summary      Get nutrition history
+// @Description  Logged in users can fetch their intake per day, week or month between two dates, counted in their timezone. Periods without meals are returned with zeros and every period is compared with the daily targets.
+// @Security     BearerAuth
+// @Produce      json
+// @Param        from        query   string  false  "First day (YYYY-MM-DD), defaults to a few buckets before to"
+// @Param        to          query   string  false  "Last day (YYYY-MM-DD), defaults to today"
+// @Param        bucket      query   string  false  "day, week or month"  default(day)
+// @Param        X-Timezone  header  string  false  "IANA timezone overriding the profile's, e.g. Asia/Makassar"
+// @Router       /nutrition/summary [get]
+// @Success      200  {object}  response.SuccessWithNutritionSummary
+// @Failure      400  {object}  response.ErrorResponse  "Invalid range"
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+func (c *NutritionSummaryController) GetSummary(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	query := &validation.QueryNutritionSummary{
+		From:   ctx.Query("from"),
+		To:     ctx.Query("to"),
+		Bucket: ctx.Query("bucket", model.SummaryBucketDay),
+	}
+
+	summary, err := c.NutritionSummaryService.GetSummary(ctx, user.ID, query)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithNutritionSummary{
+		Status:  "success",
+		Message: "Nutrition summary fetched successfully",
+		Data:    *summary,
+	})
+}
@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type NutritionTargetController struct {
+	NutritionTargetService service.NutritionTargetService
+}
+
+func NewNutritionTargetController(service service.NutritionTargetService) *NutritionTargetController {
+	return &NutritionTargetController{
+		NutritionTargetService: service,
+	}
+}
+
+// @Tags         Statistics
+// @Summary      Get daily nutrition targets
+// @Description  Logged in users can fetch their daily targets computed from their profile (BMR, TDEE and activity level) and their latest weight target
+// @Security     BearerAuth
+// @Produce      json
+// @Router       /home/targets [get]
+// @Success      200  {object}  response.SuccessWithNutritionTargets
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+func (c *NutritionTargetController) GetNutritionTargets(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	targets, err := c.NutritionTargetService.GetTargets(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithNutritionTargets{
+		Status:  "success",
+		Message: "Nutrition targets fetched successfully",
+		Data:    *targets,
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"app/src/model"
 	"app/src/response"
 	"app/src/service"
+	"app/src/validation"
+	"math"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -21,15 +23,19 @@ func NewRecipesController(service service.RecipesService) *RecipesController {
 
 // @Tags         Recipes
 // @Summary      Create new recipe
-// @Description  Create new recipe
+// @Description  Create new recipe. Ingredient lines reference bahan makanan kodes, which are checked against the bahan makanan service, and the nutrients per serving are computed from them.
 // @Security     BearerAuth
 // @Accept       json
 // @Produce      json
-// @Param        request  body      example.CreateRecipeRequest  true  "Recipe data"
+// @Param        request  body      validation.CreateRecipe  true  "Recipe data"
 // @Router       /recipes [post]
 // @Success      201  {object}  response.SuccessWithRecipe
+// @Failure      400  {object}  response.ErrorResponse  "Invalid recipe or unknown bahan makanan kode"
+// @Failure      409  {object}  response.ErrorResponse  "Slug is already in use"
 func (c *RecipesController) CreateRecipe(ctx *fiber.Ctx) error {
-	var request model.Recipe
+	user := ctx.Locals("user").(*model.User)
+
+	var request validation.CreateRecipe
 	if err := ctx.BodyParser(&request); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(response.ErrorDetails{
 			Status:  "error",
@@ -38,7 +44,7 @@ func (c *RecipesController) CreateRecipe(ctx *fiber.Ctx) error {
 		})
 	}
 
-	recipe, err := c.RecipeService.CreateRecipe(ctx, &request)
+	recipe, err := c.RecipeService.CreateRecipe(ctx, user.ID, &request)
 	if err != nil {
 		return err
 	}
@@ -51,28 +57,57 @@ func (c *RecipesController) CreateRecipe(ctx *fiber.Ctx) error {
 }
 
 // @Tags         Recipes
-// @Summary      Get all recipes
-// @Description  Get all recipes
+// @Summary      Search recipes
+// @Description  Search recipes by name, description and ingredients, filter them and page through the results.
 // @Security     BearerAuth
 // @Produce      json
+// @Param        page          query  int     false  "Page number"  default(1)
+// @Param        limit         query  int     false  "Maximum number of recipes per page"  default(10)
+// @Param        search        query  string  false  "Full-text search, quoted phrases and -word exclusions are supported"
+// @Param        day           query  string  false  "sunday to saturday"
+// @Param        label         query  string  false  "Label such as breakfast, lunch or dinner"
+// @Param        min_calories  query  number  false  "Minimum calories per serving"
+// @Param        max_calories  query  number  false  "Maximum calories per serving"
+// @Param        contains      query  string  false  "Comma separated ingredient names or kodes that must all be in the recipe"
+// @Param        excludes      query  string  false  "Comma separated ingredient names or kodes that must not be in the recipe"
+// @Param        sort          query  string  false  "newest, oldest, name, calories_asc, calories_desc, relevance, rating or popular, defaults to relevance when searching and newest otherwise"
 // @Router       /recipes [get]
-// @Success      200  {object}  response.SuccessWithRecipeList
+// @Success      200  {object}  example.GetAllRecipesResponse
+// @Failure      400  {object}  response.ErrorResponse  "Invalid query"
 func (c *RecipesController) GetRecipes(ctx *fiber.Ctx) error {
-	recipes, err := c.RecipeService.GetRecipes(ctx)
+	query := &validation.QueryRecipe{
+		Page:        ctx.QueryInt("page", 1),
+		Limit:       ctx.QueryInt("limit", 10),
+		Search:      ctx.Query("search"),
+		Day:         ctx.Query("day"),
+		Label:       ctx.Query("label"),
+		MinCalories: ctx.QueryFloat("min_calories", 0),
+		MaxCalories: ctx.QueryFloat("max_calories", 0),
+		Contains:    ctx.Query("contains"),
+		Excludes:    ctx.Query("excludes"),
+		Sort:        ctx.Query("sort"),
+	}
+
+	recipes, totalResults, err := c.RecipeService.GetRecipes(ctx, query)
 	if err != nil {
 		return err
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithRecipeList{
-		Status:  "success",
-		Message: "Recipes fetched successfully",
-		Data:    recipes,
-	})
+	return ctx.Status(fiber.StatusOK).
+		JSON(response.SuccessWithPaginate[model.Recipe]{
+			Status:       "success",
+			Message:      "Recipes fetched successfully",
+			Results:      recipes,
+			Page:         query.Page,
+			Limit:        query.Limit,
+			TotalPages:   int64(math.Ceil(float64(totalResults) / float64(query.Limit))),
+			TotalResults: totalResults,
+		})
 }
 
 // @Tags         Recipes
 // @Summary      Get recipe by ID
-// @Description  Get recipe by ID
+// @Description  Get recipe by ID with its ingredient lines, steps and nutrients per serving
 // @Security     BearerAuth
 // @Produce      json
 // @Param        id  path  string  true  "Recipe ID"
@@ -99,16 +134,40 @@ func (c *RecipesController) GetRecipeByID(ctx *fiber.Ctx) error {
 	})
 }
 
+// @Tags         Recipes
+// @Summary      Get recipe by slug
+// @Description  Get recipe by slug with its ingredient lines, steps and nutrients per serving
+// @Security     BearerAuth
+// @Produce      json
+// @Param        slug  path  string  true  "Recipe slug"
+// @Router       /recipes/slug/{slug} [get]
+// @Success      200  {object}  response.SuccessWithRecipe
+// @Failure      404  {object}  response.ErrorResponse  "Not found"
+func (c *RecipesController) GetRecipeBySlug(ctx *fiber.Ctx) error {
+	recipe, err := c.RecipeService.GetRecipeBySlug(ctx, ctx.Params("slug"))
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithRecipe{
+		Status:  "success",
+		Message: "Recipe fetched successfully",
+		Data:    *recipe,
+	})
+}
+
 // @Tags         Recipes
 // @Summary      Update recipe
-// @Description  Update recipe
+// @Description  Update recipe. Ingredient lines and steps are replaced when given, and the nutrients per serving are recomputed when the lines or the servings change.
 // @Security     BearerAuth
 // @Accept       json
 // @Produce      json
 // @Param        id  path  string  true  "Recipe ID"
-// @Param        request  body      example.UpdateRecipeRequest  true  "Recipe data"
+// @Param        request  body      validation.UpdateRecipe  true  "Recipe data"
 // @Router       /recipes/{id} [put]
 // @Success      200  {object}  response.SuccessWithRecipe
+// @Failure      400  {object}  response.ErrorResponse  "Invalid recipe or unknown bahan makanan kode"
+// @Failure      409  {object}  response.ErrorResponse  "Slug is already in use"
 func (c *RecipesController) UpdateRecipe(ctx *fiber.Ctx) error {
 	recipeID := ctx.Params("id")
 	if _, err := uuid.Parse(recipeID); err != nil {
@@ -118,7 +177,7 @@ func (c *RecipesController) UpdateRecipe(ctx *fiber.Ctx) error {
 		})
 	}
 
-	var request model.Recipe
+	var request validation.UpdateRecipe
 	if err := ctx.BodyParser(&request); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(response.ErrorDetails{
 			Status:  "error",
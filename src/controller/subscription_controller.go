@@ -7,6 +7,7 @@ import (
 	"app/src/utils"
 
 	"encoding/json"
+	"errors"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
@@ -52,6 +53,7 @@ func (c *SubscriptionController) GetPlans(ctx *fiber.Ctx) error {
 // @Param        request  body  model.PurchaseSubscriptionRequest  false  "Payment data (optional)"
 // @Router       /subscriptions/purchase/{planID} [post]
 // @Success      200  {object}  response.PaymentResponse
+// @Failure      409  {object}  response.ErrorResponse "Another upgrade or downgrade is waiting for payment"
 func (c *SubscriptionController) PurchasePlan(ctx *fiber.Ctx) error {
 	planID := ctx.Params("planID")
 	if _, err := uuid.Parse(planID); err != nil {
@@ -65,6 +67,9 @@ func (c *SubscriptionController) PurchasePlan(ctx *fiber.Ctx) error {
 
 	user := ctx.Locals("user").(*model.User)
 	paymentResponse, err := c.Service.PurchasePlan(ctx, user.ID, uuid.MustParse(planID), req.PaymentMethod)
+	if errors.Is(err, service.ErrPlanChangePending) {
+		return utils.APIError(ctx, fiber.StatusConflict, "plan_change_pending", "Finish or wait out the payment of your other plan change first")
+	}
 	if err != nil {
 		return utils.APIError(ctx, fiber.StatusInternalServerError, "purchase_failed", err.Error())
 	}
@@ -105,6 +110,50 @@ func (c *SubscriptionController) GetMySubscription(ctx *fiber.Ctx) error {
 	})
 }
 
+// @Tags         Subscription
+// @Summary      Cancel subscription
+// @Description  Cancel the user's subscription at the end of its period, access stays until then
+// @Security     BearerAuth
+// @Produce      json
+// @Router       /subscriptions/cancel [post]
+// @Success      200  {object}  response.UserSubscriptionResponse
+// @Failure      404  {object}  response.ErrorResponse
+func (c *SubscriptionController) CancelSubscription(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+	subscription, err := c.Service.CancelSubscription(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(response.UserSubscriptionResponse{
+		Status:  "success",
+		Message: "Subscription will be cancelled at the end of its period",
+		Data:    *subscription,
+	})
+}
+
+// @Tags         Subscription
+// @Summary      Resume subscription
+// @Description  Take back the cancellation of the user's subscription before its period ends
+// @Security     BearerAuth
+// @Produce      json
+// @Router       /subscriptions/resume [post]
+// @Success      200  {object}  response.UserSubscriptionResponse
+// @Failure      404  {object}  response.ErrorResponse
+func (c *SubscriptionController) ResumeSubscription(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+	subscription, err := c.Service.ResumeSubscription(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(response.UserSubscriptionResponse{
+		Status:  "success",
+		Message: "Subscription resumed successfully",
+		Data:    *subscription,
+	})
+}
+
 // @Tags         Subscription
 // @Summary      Check feature access
 // @Description  Check if user has access to a feature
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type UploadController struct {
+	UploadService service.UploadService
+}
+
+func NewUploadController(uploadService service.UploadService) *UploadController {
+	return &UploadController{
+		UploadService: uploadService,
+	}
+}
+
+// @Tags         Uploads
+// @Summary      Upload an article or recipe image
+// @Description  Stores a JPEG, PNG or WebP image without its metadata, with a thumbnail and WebP versions. Put the URL of a variant in the image of the article or recipe, images left unused are deleted after a while.
+// @Security     BearerAuth
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file     formData  file    true  "Image"
+// @Param        purpose  formData  string  true  "article or recipe"
+// @Router       /uploads/images [post]
+// @Success      201  {object}  response.SuccessWithUpload
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      413  {object}  response.ErrorResponse  "Image too large"
+// @Failure      415  {object}  response.ErrorResponse  "Not a JPEG, PNG or WebP image"
+func (c *UploadController) UploadImage(ctx *fiber.Ctx) error {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Image file is required")
+	}
+	user := ctx.Locals("user").(*model.User)
+
+	req := &validation.UploadImage{Purpose: ctx.FormValue("purpose")}
+	upload, err := c.UploadService.UploadImage(ctx, user.ID, req, file)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(response.SuccessWithUpload{
+		Status:  "success",
+		Message: "Image uploaded successfully",
+		Data:    *upload,
+	})
+}
+
+// @Tags         Uploads
+// @Summary      Upload my profile picture
+// @Description  Stores a JPEG, PNG or WebP image without its metadata and makes it the profile picture
+// @Security     BearerAuth
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file  formData  file  true  "Image"
+// @Router       /uploads/profile-picture [post]
+// @Success      201  {object}  response.SuccessWithUpload
+// @Failure      400  {object}  response.ErrorResponse
+// @Failure      413  {object}  response.ErrorResponse  "Image too large"
+// @Failure      415  {object}  response.ErrorResponse  "Not a JPEG, PNG or WebP image"
+func (c *UploadController) UploadProfilePicture(ctx *fiber.Ctx) error {
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Image file is required")
+	}
+	user := ctx.Locals("user").(*model.User)
+
+	upload, err := c.UploadService.UploadProfilePicture(ctx, user.ID, file)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(response.SuccessWithUpload{
+		Status:  "success",
+		Message: "Profile picture updated successfully",
+		Data:    *upload,
+	})
+}
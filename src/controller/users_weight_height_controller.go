@@ -28,6 +28,7 @@ func NewUsersWeightHeightController(service service.UsersWeightHeightService) *U
 // @Param        request  body      example.AddWeightHeightRequest  true  "Weight and height data"
 // @Router       /weight-height [post]
 // @Success      201  {object}  example.AddWeightHeightResponse
+// @Failure      400  {object}  response.ErrorResponse  "Waist or body fat out of range"
 // @Failure      401  {object}  example.Unauthorized  "Unauthorized"
 func (c *UsersWeightHeightController) AddWeightHeight(ctx *fiber.Ctx) error {
 	var request model.UsersWeightHeightHistory
@@ -43,10 +44,7 @@ func (c *UsersWeightHeightController) AddWeightHeight(ctx *fiber.Ctx) error {
 
 	result, err := c.UsersWeightHeightService.AddWeightHeight(ctx, &request)
 	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(response.Common{
-			Status:  "error",
-			Message: err.Error(),
-		})
+		return err
 	}
 
 	return ctx.Status(fiber.StatusCreated).JSON(response.SuccessWithWeightHeight{
@@ -366,3 +364,27 @@ func (c *UsersWeightHeightController) DeleteWeightHeightTarget(ctx *fiber.Ctx) e
 		Message: "Weight and height record target deleted successfully",
 	})
 }
+
+// @Tags         Weight Height Record
+// @Summary      Get the progress towards the weight target
+// @Description  Compares the weight records since the latest target was set against it: percent complete, weekly rate from a linear regression, projected completion date, whether the user is on track and warnings for unsafe targets.
+// @Security     BearerAuth
+// @Produce      json
+// @Router       /weight-height/target/progress [get]
+// @Success      200  {object}  response.SuccessWithWeightProgress
+// @Failure      401  {object}  example.Unauthorized  "Unauthorized"
+// @Failure      404  {object}  example.NotFound  "Not found"
+func (c *UsersWeightHeightController) GetWeightTargetProgress(ctx *fiber.Ctx) error {
+	user := ctx.Locals("user").(*model.User)
+
+	progress, err := c.UsersWeightHeightService.GetWeightTargetProgress(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return ctx.Status(fiber.StatusOK).JSON(response.SuccessWithWeightProgress{
+		Status:  "success",
+		Message: "Weight target progress fetched successfully",
+		Data:    *progress,
+	})
+}
@@ -20,21 +20,53 @@ func MigrateAndSeed(db *gorm.DB) {
 		&model.Token{},
 		&model.Article{},
 		&model.ArticleCategory{},
+		&model.ArticleRevision{},
+		&model.ArticleTag{},
+		&model.ArticleRead{},
 		&model.MealHistory{},
 		&model.MealHistoryDetail{},
 		&model.ProductToken{},
 		&model.Recipe{},
+		&model.RecipeIngredient{},
+		&model.RecipeStep{},
+		&model.RecipeNutrient{},
 		&model.UsersStar{},
+		&model.UserFavorite{},
 		&model.UsersWeightHeightHistory{},
 		&model.UsersWeightHeightTarget{},
 		&model.SubscriptionPlan{},
 		&model.UserSubscription{},
 		&model.TransactionDetail{},
+		&model.SubscriptionTransition{},
+		&model.PaymentReconciliation{},
+		&model.PaymentReconciliationItem{},
 		&model.LoginStreak{},
+		&model.MealScanJob{},
+		&model.FoodAlias{},
+		&model.MealHistoryFood{},
+		&model.MealHistoryNutrient{},
+		&model.MealHistoryIngredient{},
+		&model.MealPlan{},
+		&model.MealPlanSlot{},
+		&model.Upload{},
+		&model.UploadVariant{},
 	); err != nil {
 		log.Fatalf("Failed to auto-migrate database: %v", err)
 	}
 
+	// Migrations that need the auto-migrated tables
+	if err := migrations.BackfillMealHistoryNutrients(db); err != nil {
+		utils.Log.Warnf("Failed to backfill meal history nutrients: %v", err)
+	}
+
+	if err := migrations.AddRecipeSearchVector(db); err != nil {
+		log.Fatalf("Failed to add recipe search vector: %v", err)
+	}
+
+	if err := migrations.AddArticleSearchVector(db); err != nil {
+		log.Fatalf("Failed to add article search vector: %v", err)
+	}
+
 	// Run seeders
 	seeders.RunSeeder(db)
 }
@@ -51,6 +83,26 @@ func runCustomMigrations(db *gorm.DB) {
 		utils.Log.Warnf("Failed to clean invalid user_id in product_tokens: %v", err)
 	}
 
+	// Ratings from before users_stars had a target, so the target columns can be added
+	if err := migrations.ClearUntargetedUsersStars(db); err != nil {
+		log.Fatalf("Failed to clear untargeted users stars: %v", err)
+	}
+
+	// Articles keep their visibility when the lifecycle status is added
+	if err := migrations.AddArticleStatus(db); err != nil {
+		log.Fatalf("Failed to add article status: %v", err)
+	}
+
+	// Subscriptions keep their access when the lifecycle status is added
+	if err := migrations.AddSubscriptionStatus(db); err != nil {
+		log.Fatalf("Failed to add subscription status: %v", err)
+	}
+
+	// Repeated notifications are rejected by a unique index from now on
+	if err := migrations.DedupeTransactionDetails(db); err != nil {
+		log.Fatalf("Failed to dedupe transaction details: %v", err)
+	}
+
 	// Run custom enum day migration
 	if err := migrations.CreateEnumDay(db); err != nil {
 		log.Fatalf("Failed to create enum type: %v", err)
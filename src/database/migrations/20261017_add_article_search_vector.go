@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"app/src/utils"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AddArticleSearchVector adds the generated tsvector articles are searched with and its GIN index, with
+// the same simple configuration as recipes
+func AddArticleSearchVector(db *gorm.DB) error {
+	utils.Log.Info("Running migration: Add search_vector to articles")
+
+	if err := db.Exec(`
+		ALTER TABLE articles ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+		) STORED
+	`).Error; err != nil {
+		return fmt.Errorf("failed to add article search vector: %w", err)
+	}
+
+	if err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_articles_search_vector ON articles USING GIN (search_vector)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to index article search vector: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"app/src/utils"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AddArticleStatus adds the lifecycle status to existing articles before auto-migration would default them
+// all to draft. Articles without PublishedAt become drafts, the ones dated in the future are scheduled and
+// the rest stay published.
+func AddArticleStatus(db *gorm.DB) error {
+	if !db.Migrator().HasTable("articles") || db.Migrator().HasColumn("articles", "status") {
+		return nil
+	}
+
+	utils.Log.Info("Running migration: Add status to articles")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE articles ADD COLUMN status varchar(20) NOT NULL DEFAULT 'draft'").Error; err != nil {
+			return fmt.Errorf("failed to add article status: %w", err)
+		}
+
+		result := tx.Exec(`
+			UPDATE articles SET status = CASE
+				WHEN published_at IS NULL THEN 'draft'
+				WHEN published_at > NOW() THEN 'scheduled'
+				ELSE 'published'
+			END
+		`)
+		if result.Error != nil {
+			return fmt.Errorf("failed to backfill article status: %w", result.Error)
+		}
+
+		utils.Log.Infof("Set the status of %d articles", result.RowsAffected)
+		return nil
+	})
+}
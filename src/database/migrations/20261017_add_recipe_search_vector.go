@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"app/src/utils"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AddRecipeSearchVector adds the generated tsvector recipes are searched with and its GIN index. The
+// simple configuration is used because Postgres has no Indonesian dictionary to stem with.
+func AddRecipeSearchVector(db *gorm.DB) error {
+	utils.Log.Info("Running migration: Add search_vector to recipes")
+
+	if err := db.Exec(`
+		ALTER TABLE recipes ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(description, '')), 'B') ||
+			setweight(to_tsvector('simple', coalesce(ingredients, '')), 'C')
+		) STORED
+	`).Error; err != nil {
+		return fmt.Errorf("failed to add recipe search vector: %w", err)
+	}
+
+	if err := db.Exec(`
+		CREATE INDEX IF NOT EXISTS idx_recipes_search_vector ON recipes USING GIN (search_vector)
+	`).Error; err != nil {
+		return fmt.Errorf("failed to index recipe search vector: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"app/src/utils"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AddSubscriptionStatus adds the lifecycle status to existing subscriptions before auto-migration would
+// default them all to pending. Paid subscriptions that are still flagged active become active, so the
+// lifecycle sweeper moves the lapsed ones on, and the rest follow their payment status.
+func AddSubscriptionStatus(db *gorm.DB) error {
+	if !db.Migrator().HasTable("user_subscriptions") || db.Migrator().HasColumn("user_subscriptions", "status") {
+		return nil
+	}
+
+	utils.Log.Info("Running migration: Add status to user subscriptions")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("ALTER TABLE user_subscriptions ADD COLUMN status varchar(20) NOT NULL DEFAULT 'pending'").Error; err != nil {
+			return fmt.Errorf("failed to add subscription status: %w", err)
+		}
+
+		result := tx.Exec(`
+			UPDATE user_subscriptions SET status = CASE
+				WHEN payment_status IN ('success', 'completed') AND is_active THEN 'active'
+				WHEN payment_status IN ('success', 'completed') THEN 'expired'
+				WHEN payment_status = 'failed' THEN 'cancelled'
+				ELSE 'pending'
+			END
+		`)
+		if result.Error != nil {
+			return fmt.Errorf("failed to backfill subscription status: %w", result.Error)
+		}
+
+		utils.Log.Infof("Set the status of %d subscriptions", result.RowsAffected)
+		return nil
+	})
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"app/src/utils"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// BackfillMealHistoryNutrients copies the macro columns of existing meals into meal_history_nutrients
+// so per-nutrient summaries include meals logged before the table existed. Meals store their nutrients
+// since then, so the backfill only runs while the table is still empty.
+func BackfillMealHistoryNutrients(db *gorm.DB) error {
+	var backfilled bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM meal_history_nutrients)").Scan(&backfilled).Error; err != nil {
+		return fmt.Errorf("failed to check meal history nutrients: %w", err)
+	}
+	if backfilled {
+		return nil
+	}
+
+	utils.Log.Info("Running migration: Backfill meal_history_nutrients from meal_histories")
+
+	result := db.Exec(`
+		INSERT INTO meal_history_nutrients (id, meal_history_id, code, quantity, unit, created_at, updated_at)
+		SELECT uuid_generate_v4(), meal_histories.id, macros.code, macros.quantity, macros.unit, NOW(), NOW()
+		FROM meal_histories
+		CROSS JOIN LATERAL (VALUES
+			('ENERC_KCAL', meal_histories.calories, 'kcal'),
+			('PROCNT', meal_histories.protein, 'g'),
+			('CHOCDF', meal_histories.carbs, 'g'),
+			('FAT', meal_histories.fat, 'g')
+		) AS macros(code, quantity, unit)
+		ON CONFLICT (meal_history_id, code) DO NOTHING
+	`)
+	if result.Error != nil {
+		return fmt.Errorf("failed to backfill meal history nutrients: %w", result.Error)
+	}
+
+	utils.Log.Infof("Backfilled %d meal history nutrient rows", result.RowsAffected)
+	return nil
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"app/src/utils"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ClearUntargetedUsersStars removes ratings stored before users_stars knew what was rated. They can't be
+// attributed to a recipe or an article, and would keep the new NOT NULL target columns from being added.
+func ClearUntargetedUsersStars(db *gorm.DB) error {
+	if !db.Migrator().HasTable("users_stars") || db.Migrator().HasColumn("users_stars", "target_type") {
+		return nil
+	}
+
+	utils.Log.Info("Running migration: Clear users_stars rows without a target")
+
+	result := db.Exec("DELETE FROM users_stars")
+	if result.Error != nil {
+		return fmt.Errorf("failed to clear untargeted users stars: %w", result.Error)
+	}
+
+	utils.Log.Infof("Cleared %d untargeted users stars", result.RowsAffected)
+	return nil
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"app/src/utils"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// DedupeTransactionDetails removes the rows repeated Midtrans notifications left behind, keeping the first of
+// each (order_id, transaction_id, transaction_status), so the unique index that now rejects them can be added.
+func DedupeTransactionDetails(db *gorm.DB) error {
+	if !db.Migrator().HasTable("transaction_details") ||
+		db.Migrator().HasIndex("transaction_details", "idx_transaction_details_notification") {
+		return nil
+	}
+
+	utils.Log.Info("Running migration: Dedupe transaction details")
+
+	result := db.Exec(`
+		DELETE FROM transaction_details
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (
+					PARTITION BY order_id, transaction_id, transaction_status
+					ORDER BY created_at, id
+				) AS position
+				FROM transaction_details
+			) AS numbered
+			WHERE position > 1
+		)
+	`)
+	if result.Error != nil {
+		return fmt.Errorf("failed to dedupe transaction details: %w", result.Error)
+	}
+
+	utils.Log.Infof("Deleted %d duplicate transaction details", result.RowsAffected)
+	return nil
+}
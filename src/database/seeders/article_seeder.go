@@ -313,6 +313,7 @@ Healthy living involves making conscious choices that promote overall well-being
 			Slug:        slug,
 			Image:       &imageURL,
 			Content:     content,
+			Status:      model.ArticleStatusPublished,
 			PublishedAt: &publishedAt,
 			CreatedAt:   publishedAt,
 			UpdatedAt:   publishedAt,
@@ -331,6 +332,7 @@ Healthy living involves making conscious choices that promote overall well-being
 				Slug:        utils.Slugify(modifiedTitle),
 				Image:       &imageURL,
 				Content:     content,
+				Status:      model.ArticleStatusPublished,
 				PublishedAt: &publishedAt,
 				CreatedAt:   publishedAt,
 				UpdatedAt:   publishedAt,
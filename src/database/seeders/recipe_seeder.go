@@ -50,6 +50,12 @@ func SeedRecipes(db *gorm.DB) {
 		imageIndex := rand.Intn(len(dummyImages))
 		imageURL := dummyImages[imageIndex]
 
+		// Nutrients per serving so the meal planner has something to work with
+		calories := float64(300 + rand.Intn(400))
+		protein := calories * 0.2 / 4
+		carbs := calories * 0.5 / 4
+		fat := calories * 0.3 / 9
+
 		recipes = append(recipes, model.Recipe{
 			ID:           uuid.New(),
 			UserID:       uuid.New(),
@@ -61,6 +67,10 @@ func SeedRecipes(db *gorm.DB) {
 			Label:        &labels[rand.Intn(len(labels))],
 			Day:          days[rand.Intn(len(days))],
 			Image:        &imageURL,
+			Calories:     &calories,
+			Protein:      &protein,
+			Carbs:        &carbs,
+			Fat:          &fat,
 		})
 	}
 
@@ -72,7 +72,10 @@ func main() {
 
 	// Setup routes
 	utils.Log.Info("Setting up API routes...")
-	setupRoutes(app, db)
+	background := setupRoutes(app, db)
+
+	// Background loops run until shutdown cancels ctx, before the database is closed
+	background.Start(ctx)
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -90,6 +93,7 @@ func main() {
 	serverErrors := make(chan error, 1)
 	go startServer(app, address, serverErrors)
 	handleGracefulShutdown(ctx, app, serverErrors)
+	cancel()
 }
 
 func setupFiberApp() *fiber.App {
@@ -108,7 +112,7 @@ func setupFiberApp() *fiber.App {
 	app.Use(cors.New())
 	app.Use(middleware.RecoverConfig())
 
-	app.Static("/uploads", "./uploads")
+	app.Static("/uploads", config.UploadDir)
 
 	return app
 }
@@ -118,9 +122,10 @@ func setupDatabase() *gorm.DB {
 	return db
 }
 
-func setupRoutes(app *fiber.App, db *gorm.DB) {
-	router.Routes(app, db)
+func setupRoutes(app *fiber.App, db *gorm.DB) *router.Background {
+	background := router.Routes(app, db)
 	app.Use(utils.NotFoundHandler)
+	return background
 }
 
 func startServer(app *fiber.App, address string, errs chan<- error) {
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"app/src/config"
+	"app/src/model"
 	"app/src/service"
 	"app/src/utils"
 	"strconv"
@@ -38,8 +39,8 @@ func FreemiumOrAccess(userService service.UserService, productTokenService servi
 		// Check for active subscription first (including freemium)
 		subscription, err := subscriptionService.GetUserActiveSubscription(c, user.ID)
 		if err == nil && subscription != nil {
-			// Check if subscription is still active (not expired)
-			if subscription.IsActive && time.Now().Before(subscription.EndDate) {
+			// Check if subscription is still active, paid plans keep access during their grace period
+			if subscription.IsActive && (time.Now().Before(subscription.EndDate) || subscription.Status == model.SubscriptionStatusGrace) {
 				hasAccess = true
 				accessType = "subscription"
 
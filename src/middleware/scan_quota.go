@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"app/src/utils"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ScanQuota reserves one AI scan before the handler runs and refunds it when the scan fails.
+// The reserved quota is stored in c.Locals("scanQuota") so asynchronous scans can refund it later.
+func ScanQuota(subService service.SubscriptionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user := c.Locals("user").(*model.User)
+
+		quota, err := subService.ReserveScan(c, user.ID)
+		if errors.Is(err, service.ErrScanQuotaExceeded) {
+			setScanQuotaHeaders(c, quota)
+			return utils.APIError(c, fiber.StatusTooManyRequests,
+				"scan_quota_exceeded",
+				"You have used all AI scans of your current plan",
+				map[string]interface{}{
+					"remaining":   quota.Remaining,
+					"limit":       quota.Limit,
+					"reset_at":    quota.ResetAt,
+					"upgrade_url": "/v1/subscriptions/plans",
+				})
+		}
+		if errors.Is(err, service.ErrNoActiveSubscription) {
+			return utils.APIError(c, fiber.StatusForbidden,
+				"subscription_required",
+				"Active subscription required",
+				map[string]interface{}{
+					"upgrade_url": "/v1/subscriptions/plans",
+				})
+		}
+		if err != nil {
+			return utils.APIError(c, fiber.StatusInternalServerError,
+				"server_error",
+				"Failed to check the scan quota")
+		}
+
+		c.Locals("scanQuota", quota)
+
+		err = c.Next()
+
+		if err != nil || c.Response().StatusCode() >= fiber.StatusBadRequest {
+			if refundErr := subService.RefundScan(c, quota.SubscriptionID); refundErr == nil {
+				quota.Used--
+				quota.Remaining = quota.RemainingScans()
+			}
+		}
+
+		setScanQuotaHeaders(c, quota)
+
+		return err
+	}
+}
+
+// ScanQuotaHeaders exposes the scan quota on responses that don't reserve a scan, like polling a scan job.
+// The headers are left out when the quota can't be read, the response itself doesn't depend on it.
+func ScanQuotaHeaders(subService service.SubscriptionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		user := c.Locals("user").(*model.User)
+
+		sub, subErr := subService.GetUserActiveSubscription(c, user.ID)
+		if subErr != nil || sub == nil {
+			return err
+		}
+		remaining, subErr := subService.GetRemainingScans(c, user.ID)
+		if subErr != nil {
+			return err
+		}
+
+		setScanQuotaHeaders(c, &model.ScanQuota{
+			SubscriptionID: sub.ID,
+			Limit:          sub.Plan.AIscanLimit,
+			Used:           sub.AIscansUsed,
+			Remaining:      remaining,
+			ResetAt:        sub.EndDate,
+		})
+
+		return err
+	}
+}
+
+func setScanQuotaHeaders(c *fiber.Ctx, quota *model.ScanQuota) {
+	c.Set("X-Scan-Quota-Limit", strconv.Itoa(quota.Limit))
+	c.Set("X-Scan-Quota-Remaining", strconv.Itoa(quota.Remaining))
+	c.Set("X-Scan-Quota-Reset", quota.ResetAt.UTC().Format(time.RFC3339))
+}
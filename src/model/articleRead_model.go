@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ArticleRead records that a user opened an article, once per user and article
+type ArticleRead struct {
+	ID          uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID      uuid.UUID `gorm:"not null;uniqueIndex:idx_article_read_user" json:"user_id"`
+	ArticleID   uuid.UUID `gorm:"not null;uniqueIndex:idx_article_read_user;index" json:"article_id"`
+	ReadCount   int       `gorm:"not null;default:1" json:"read_count"`
+	FirstReadAt time.Time `gorm:"not null" json:"first_read_at"`
+	LastReadAt  time.Time `gorm:"not null" json:"last_read_at"`
+}
+
+func (articleRead *ArticleRead) BeforeCreate(_ *gorm.DB) error {
+	articleRead.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ArticleRevision is a snapshot of a published article taken before it was edited or restored. Version
+// counts up from 1 per article.
+type ArticleRevision struct {
+	ID         uuid.UUID  `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	ArticleID  uuid.UUID  `gorm:"not null;uniqueIndex:idx_article_revision_version" json:"article_id"`
+	Version    int        `gorm:"not null;uniqueIndex:idx_article_revision_version" json:"version"`
+	Title      string     `gorm:"not null" json:"title"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	Image      *string    `json:"image,omitempty"`
+	Content    string     `gorm:"type:text;not null" json:"content"`
+	EditedBy   uuid.UUID  `gorm:"not null" json:"edited_by"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+}
+
+// ArticleRevisionDiff compares a revision with the current version of its article
+type ArticleRevisionDiff struct {
+	RevisionID    uuid.UUID `json:"revision_id"`
+	Version       int       `json:"version"`
+	ChangedFields []string  `json:"changed_fields"`
+	// ContentDiff is a unified diff from the revision's content to the current content
+	ContentDiff string `json:"content_diff"`
+}
+
+func (revision *ArticleRevision) BeforeCreate(_ *gorm.DB) error {
+	revision.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,37 @@
+package model
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tags the recommendations look for, editors may use any other tag too
+const (
+	TagDiabetes      = "diabetes"
+	TagHypertension  = "hypertension"
+	TagCholesterol   = "cholesterol"
+	TagHeartDisease  = "heart-disease"
+	TagKidneyDisease = "kidney-disease"
+	TagGout          = "gout"
+	TagAnemia        = "anemia"
+	TagDigestive     = "digestive-health"
+	TagWeightLoss    = "weight-loss"
+	TagWeightGain    = "weight-gain"
+	TagHealthyEating = "healthy-eating"
+	TagLowSodium     = "low-sodium"
+	TagLowSugar      = "low-sugar"
+	TagHighFiber     = "high-fiber"
+	TagProtein       = "protein"
+)
+
+// ArticleTag is one tag of an article, tags are lowercase words joined by dashes
+type ArticleTag struct {
+	ID        uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"-"`
+	ArticleID uuid.UUID `gorm:"not null;uniqueIndex:idx_article_tag" json:"-"`
+	Tag       string    `gorm:"size:40;not null;uniqueIndex:idx_article_tag;index" json:"tag"`
+}
+
+func (articleTag *ArticleTag) BeforeCreate(_ *gorm.DB) error {
+	articleTag.ID = uuid.New()
+	return nil
+}
@@ -7,33 +7,65 @@ import (
 	"gorm.io/gorm"
 )
 
+// Article lifecycle, only published articles and scheduled ones whose PublishedAt has passed are public
+const (
+	ArticleStatusDraft     = "draft"
+	ArticleStatusScheduled = "scheduled"
+	ArticleStatusPublished = "published"
+	ArticleStatusArchived  = "archived"
+)
+
 type Article struct {
-	ID          uuid.UUID        `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
-	UserID      uuid.UUID        `gorm:"not null" json:"user_id"`
-	Title       string           `gorm:"not null" json:"title"`
-	CategoryID  *uuid.UUID       `json:"category_id,omitempty"`
-	Category    *ArticleCategory `gorm:"foreignKey:CategoryID" json:"-"` // Add relationship to ArticleCategory
-	Slug        string           `gorm:"unique;not null" json:"slug"`
-	Image       *string          `json:"image,omitempty"`
-	Content     string           `gorm:"type:text;not null" json:"content"`
-	PublishedAt *time.Time       `json:"published_at,omitempty"`
-	CreatedAt   time.Time        `gorm:"autoCreateTime:milli" json:"-"`
-	UpdatedAt   time.Time        `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+	ID          uuid.UUID         `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID      uuid.UUID         `gorm:"not null" json:"user_id"`
+	Title       string            `gorm:"not null" json:"title"`
+	CategoryID  *uuid.UUID        `json:"category_id,omitempty"`
+	Category    *ArticleCategory  `gorm:"foreignKey:CategoryID" json:"-"` // Add relationship to ArticleCategory
+	Slug        string            `gorm:"unique;not null" json:"slug"`
+	Image       *string           `json:"image,omitempty"`
+	Content     string            `gorm:"type:text;not null" json:"content"`
+	Status      string            `gorm:"size:20;not null;default:draft;index" json:"status"`
+	PublishedAt *time.Time        `json:"published_at,omitempty"`
+	Revisions   []ArticleRevision `gorm:"foreignKey:ArticleID;constraint:OnDelete:CASCADE" json:"-"`
+	// Tags are set from the request, ArticleTags is how they are stored
+	Tags        []string      `gorm:"-" json:"tags,omitempty"`
+	ArticleTags []ArticleTag  `gorm:"foreignKey:ArticleID;constraint:OnDelete:CASCADE" json:"-"`
+	Reads       []ArticleRead `gorm:"foreignKey:ArticleID;constraint:OnDelete:CASCADE" json:"-"`
+	// Aggregates kept up to date by the engagement service, hidden ratings aren't counted
+	RatingAverage float64   `gorm:"type:decimal(3,2);not null;default:0" json:"rating_average"`
+	RatingCount   int       `gorm:"not null;default:0" json:"rating_count"`
+	FavoriteCount int       `gorm:"not null;default:0" json:"favorite_count"`
+	CreatedAt     time.Time `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt     time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
 }
 
 // ArticleResponse is used for API responses with category name
 type ArticleResponse struct {
-	ID           uuid.UUID  `json:"id"`
-	UserID       uuid.UUID  `json:"user_id"`
-	Title        string     `json:"title"`
-	CategoryID   *uuid.UUID `json:"category_id,omitempty"`
-	CategoryName string     `json:"category_name,omitempty"`
-	Slug         string     `json:"slug"`
-	Image        *string    `json:"image,omitempty"`
-	Content      string     `json:"content"`
-	PublishedAt  *time.Time `json:"published_at,omitempty"`
-	CreatedAt    time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
+	ID            uuid.UUID  `json:"id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	Title         string     `json:"title"`
+	CategoryID    *uuid.UUID `json:"category_id,omitempty"`
+	CategoryName  string     `json:"category_name,omitempty"`
+	Slug          string     `json:"slug"`
+	Tags          []string   `json:"tags"`
+	Image         *string    `json:"image,omitempty"`
+	Content       string     `json:"content,omitempty"`
+	Excerpt       string     `json:"excerpt,omitempty"` // Listings send an excerpt instead of the content
+	Status        string     `json:"status"`
+	PublishedAt   *time.Time `json:"published_at,omitempty"`
+	RatingAverage float64    `json:"rating_average"`
+	RatingCount   int        `json:"rating_count"`
+	FavoriteCount int        `json:"favorite_count"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ArticleRecommendation is an article recommended to a user, Reasons are the tags it was matched on
+type ArticleRecommendation struct {
+	ArticleResponse
+	Score   float64  `json:"score"`
+	Reasons []string `json:"reasons"`
+	Read    bool     `json:"read"`
 }
 
 func (article *Article) BeforeCreate(_ *gorm.DB) error {
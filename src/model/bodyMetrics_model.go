@@ -0,0 +1,53 @@
+package model
+
+import "time"
+
+// BMI categories of the WHO Asia-Pacific cutoffs, lower than the international ones as the health risks
+// of Asian populations rise at a lower BMI
+const (
+	BMIUnderweight = "underweight"
+	BMINormal      = "normal"
+	BMIOverweight  = "overweight"
+	BMIObese1      = "obese_1"
+	BMIObese2      = "obese_2"
+)
+
+// Waist-to-height ratio categories, 0.5 being where the cardiometabolic risk starts
+const (
+	WaistRatioHealthy   = "healthy"
+	WaistRatioIncreased = "increased"
+	WaistRatioHigh      = "high"
+)
+
+// BodyMetricsEntry are the metrics derived from one weight and height record. The waist and body fat
+// metrics are only there when the record has them.
+type BodyMetricsEntry struct {
+	RecordID           string    `json:"record_id"`
+	RecordedAt         time.Time `json:"recorded_at"`
+	Weight             float64   `json:"weight"`
+	Height             float64   `json:"height"`
+	BMI                float64   `json:"bmi"`
+	BMICategory        string    `json:"bmi_category"`
+	IdealWeightMin     float64   `json:"ideal_weight_min"`
+	IdealWeightMax     float64   `json:"ideal_weight_max"`
+	BMR                float64   `json:"bmr"`
+	WaistCircumference *float64  `json:"waist_circumference,omitempty"`
+	WaistToHeightRatio *float64  `json:"waist_to_height_ratio,omitempty"`
+	WaistCategory      string    `json:"waist_category,omitempty"`
+	CentralObesity     *bool     `json:"central_obesity,omitempty"`
+	BodyFatPercentage  *float64  `json:"body_fat_percentage,omitempty"`
+	FatMass            *float64  `json:"fat_mass,omitempty"`
+	LeanMass           *float64  `json:"lean_mass,omitempty"`
+}
+
+// BodyMetrics is the classified time series of a user's body metrics, oldest first. The BMR is estimated
+// when the birth date or gender it depends on are missing from the profile.
+type BodyMetrics struct {
+	From      *time.Time         `json:"from"`
+	To        *time.Time         `json:"to"`
+	Timezone  string             `json:"timezone"`
+	Latest    *BodyMetricsEntry  `json:"latest"`
+	Series    []BodyMetricsEntry `json:"series"`
+	Estimated bool               `json:"estimated"`
+	Missing   []string           `json:"missing,omitempty"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FoodAlias maps a recognized food name (e.g. "white rice") straight to a TKPI bahan makanan kode
+type FoodAlias struct {
+	ID        uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	Alias     string    `gorm:"size:255;not null;uniqueIndex" json:"alias"`
+	Kode      string    `gorm:"size:20;not null" json:"kode"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+}
+
+func (foodAlias *FoodAlias) BeforeCreate(_ *gorm.DB) error {
+	foodAlias.ID = uuid.New()
+	return nil
+}
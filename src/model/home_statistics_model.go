@@ -6,11 +6,12 @@ import (
 
 // DailyNutrition represents the nutritional summary for a day
 type DailyNutrition struct {
-	Date     time.Time `json:"date"`
-	Calories float64   `json:"calories"`
-	Protein  float64   `json:"protein"`
-	Carbs    float64   `json:"carbs"`
-	Fat      float64   `json:"fat"`
+	Date      time.Time       `json:"date"`
+	Calories  float64         `json:"calories"`
+	Protein   float64         `json:"protein"`
+	Carbs     float64         `json:"carbs"`
+	Fat       float64         `json:"fat"`
+	Nutrients []NutrientTotal `json:"nutrients"`
 }
 
 // WeightHeightStatistics represents the weight and height statistics
@@ -24,5 +25,7 @@ type WeightHeightStatistics struct {
 // HomeStatistics combines multiple statistics for the home page
 type HomeStatistics struct {
 	DailyNutrition         *DailyNutrition         `json:"daily_nutrition"`
+	NutritionTargets       *NutritionTargets       `json:"nutrition_targets"`
+	NutritionProgress      []NutrientProgress      `json:"nutrition_progress"`
 	WeightHeightStatistics *WeightHeightStatistics `json:"weight_height_statistics"`
 }
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MealHistoryFood links a scanned segment of a meal to a TKPI bahan makanan entry.
+// Rank 1 is the best match of a segment, the others are kept as alternatives.
+type MealHistoryFood struct {
+	ID               uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	MealHistoryID    uuid.UUID `gorm:"not null;index" json:"meal_history_id"`
+	Segment          int       `gorm:"not null" json:"segment"`
+	Rank             int       `gorm:"not null;default:1" json:"rank"`
+	Kode             string    `gorm:"size:20;not null" json:"kode"`
+	NamaBahanMakanan string    `json:"nama_bahan_makanan"`
+	MatchedName      string    `json:"matched_name"`
+	Score            float64   `gorm:"type:decimal(5,4)" json:"score"`
+	Grams            *float64  `gorm:"type:decimal(8,2)" json:"grams,omitempty"`
+	CreatedAt        time.Time `gorm:"autoCreateTime:milli" json:"-"`
+}
+
+func (mealHistoryFood *MealHistoryFood) BeforeCreate(_ *gorm.DB) error {
+	mealHistoryFood.ID = uuid.New()
+	return nil
+}
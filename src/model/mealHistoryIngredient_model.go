@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MealHistoryIngredient is one bahan makanan line of a composite meal. Grams is the weight as bought or
+// served, EdibleGrams the part left after applying BddPersen, which is what the nutrients are computed from.
+type MealHistoryIngredient struct {
+	ID               uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	MealHistoryID    uuid.UUID `gorm:"not null;index" json:"-"`
+	Position         int       `gorm:"not null" json:"position"`
+	Kode             string    `gorm:"size:20;not null" json:"kode"`
+	NamaBahanMakanan string    `json:"nama_bahan_makanan"`
+	Grams            float64   `gorm:"type:decimal(8,2);not null" json:"grams"`
+	BddPersen        float64   `gorm:"type:decimal(5,2);not null" json:"bdd_persen"`
+	EdibleGrams      float64   `gorm:"type:decimal(8,2);not null" json:"edible_grams"`
+	Calories         float64   `gorm:"type:decimal(8,2);not null" json:"calories"`
+	Protein          float64   `gorm:"type:decimal(8,2);not null" json:"protein"`
+	Carbs            float64   `gorm:"type:decimal(8,2);not null" json:"carbs"`
+	Fat              float64   `gorm:"type:decimal(8,2);not null" json:"fat"`
+	CreatedAt        time.Time `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt        time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+}
+
+func (mealHistoryIngredient *MealHistoryIngredient) BeforeCreate(_ *gorm.DB) error {
+	mealHistoryIngredient.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Nutrient codes follow the tagnames LogMeal returns so scan results can be stored as they are
+const (
+	NutrientEnergy       = "ENERC_KCAL"
+	NutrientProtein      = "PROCNT"
+	NutrientCarbs        = "CHOCDF"
+	NutrientFat          = "FAT"
+	NutrientFiber        = "FIBTG"
+	NutrientSugar        = "SUGAR"
+	NutrientWater        = "WATER"
+	NutrientAsh          = "ASH"
+	NutrientSodium       = "NA"
+	NutrientPotassium    = "K"
+	NutrientCalcium      = "CA"
+	NutrientPhosphorus   = "P"
+	NutrientIron         = "FE"
+	NutrientZinc         = "ZN"
+	NutrientCopper       = "CU"
+	NutrientCholesterol  = "CHOLE"
+	NutrientSaturated    = "FASAT"
+	NutrientRetinol      = "VITA_RAE"
+	NutrientBetaCarotene = "CARTB"
+	NutrientCarotene     = "CARTOT"
+	NutrientThiamin      = "THIA"
+	NutrientRiboflavin   = "RIBF"
+	NutrientNiacin       = "NIA"
+	NutrientVitaminC     = "VITC"
+)
+
+// NutrientDefinition describes a nutrient code and the unit its quantities are stored in
+type NutrientDefinition struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	Unit string `json:"unit"`
+}
+
+// NutrientDefinitions lists every code accepted in MealHistoryNutrient, in display order
+var NutrientDefinitions = []NutrientDefinition{
+	{NutrientEnergy, "Energy", "kcal"},
+	{NutrientProtein, "Protein", "g"},
+	{NutrientCarbs, "Carbohydrate", "g"},
+	{NutrientFat, "Fat", "g"},
+	{NutrientFiber, "Fiber", "g"},
+	{NutrientSugar, "Sugar", "g"},
+	{NutrientWater, "Water", "g"},
+	{NutrientAsh, "Ash", "g"},
+	{NutrientSodium, "Sodium", "mg"},
+	{NutrientPotassium, "Potassium", "mg"},
+	{NutrientCalcium, "Calcium", "mg"},
+	{NutrientPhosphorus, "Phosphorus", "mg"},
+	{NutrientIron, "Iron", "mg"},
+	{NutrientZinc, "Zinc", "mg"},
+	{NutrientCopper, "Copper", "mg"},
+	{NutrientCholesterol, "Cholesterol", "mg"},
+	{NutrientSaturated, "Saturated fat", "g"},
+	{NutrientRetinol, "Vitamin A (retinol)", "µg"},
+	{NutrientBetaCarotene, "Beta carotene", "µg"},
+	{NutrientCarotene, "Total carotene", "µg"},
+	{NutrientThiamin, "Vitamin B1 (thiamin)", "mg"},
+	{NutrientRiboflavin, "Vitamin B2 (riboflavin)", "mg"},
+	{NutrientNiacin, "Niacin", "mg"},
+	{NutrientVitaminC, "Vitamin C", "mg"},
+}
+
+// TrackedNutrients are always present in daily summaries, even when nothing was eaten,
+// because users with hypertension and anemia watch them every day
+var TrackedNutrients = []string{NutrientSodium, NutrientIron}
+
+// GetNutrientDefinition returns the definition of code and whether it is known
+func GetNutrientDefinition(code string) (NutrientDefinition, bool) {
+	for _, definition := range NutrientDefinitions {
+		if definition.Code == code {
+			return definition, true
+		}
+	}
+	return NutrientDefinition{}, false
+}
+
+// MealHistoryNutrient is one nutrient amount of a meal, keyed by nutrient code
+type MealHistoryNutrient struct {
+	ID            uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"-"`
+	MealHistoryID uuid.UUID `gorm:"not null;uniqueIndex:idx_meal_history_nutrient_code" json:"-"`
+	Code          string    `gorm:"size:20;not null;uniqueIndex:idx_meal_history_nutrient_code" json:"code"`
+	Quantity      float64   `gorm:"type:decimal(10,3);not null" json:"quantity"`
+	Unit          string    `gorm:"size:10;not null" json:"unit"`
+	CreatedAt     time.Time `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt     time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+}
+
+func (mealHistoryNutrient *MealHistoryNutrient) BeforeCreate(_ *gorm.DB) error {
+	mealHistoryNutrient.ID = uuid.New()
+	return nil
+}
+
+// NutrientTotal is the summed amount of one nutrient over several meals
+type NutrientTotal struct {
+	Code     string  `json:"code"`
+	Name     string  `json:"name"`
+	Quantity float64 `json:"quantity"`
+	Unit     string  `json:"unit"`
+}
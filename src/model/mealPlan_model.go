@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Meal labels a plan is built from, in the order they're eaten
+const (
+	MealLabelBreakfast = "breakfast"
+	MealLabelLunch     = "lunch"
+	MealLabelDinner    = "dinner"
+)
+
+// MealPlanDays is the length of a generated plan
+const MealPlanDays = 7
+
+// MealPlan is a week of recipes picked for a user, one per meal label and day
+type MealPlan struct {
+	ID                  uuid.UUID      `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID              uuid.UUID      `gorm:"not null;index" json:"user_id"`
+	StartDate           time.Time      `gorm:"not null" json:"start_date"`
+	EndDate             time.Time      `gorm:"not null" json:"end_date"`
+	Timezone            string         `gorm:"size:64;not null" json:"timezone"`
+	CalorieTarget       float64        `gorm:"type:decimal(8,2);not null" json:"calorie_target"`
+	ProteinTarget       float64        `gorm:"type:decimal(8,2);not null" json:"protein_target"`
+	CarbsTarget         float64        `gorm:"type:decimal(8,2);not null" json:"carbs_target"`
+	FatTarget           float64        `gorm:"type:decimal(8,2);not null" json:"fat_target"`
+	ExcludedIngredients JSON           `gorm:"type:jsonb" json:"excluded_ingredients" swaggertype:"array,string"`
+	Slots               []MealPlanSlot `gorm:"foreignKey:MealPlanID;constraint:OnDelete:CASCADE" json:"slots,omitempty"`
+	CreatedAt           time.Time      `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt           time.Time      `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+}
+
+func (mealPlan *MealPlan) BeforeCreate(_ *gorm.DB) error {
+	mealPlan.ID = uuid.New()
+	return nil
+}
+
+// MealPlanSlot is one planned meal, the nutrients are the recipe's scaled by Servings
+type MealPlanSlot struct {
+	ID            uuid.UUID  `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	MealPlanID    uuid.UUID  `gorm:"not null;index" json:"meal_plan_id"`
+	Date          time.Time  `gorm:"not null" json:"date"`
+	Day           string     `gorm:"type:varchar(10);not null" json:"day"`
+	Label         string     `gorm:"type:varchar(20);not null" json:"label"`
+	Position      int        `gorm:"not null" json:"position"`
+	RecipeID      uuid.UUID  `gorm:"not null" json:"recipe_id"`
+	Recipe        *Recipe    `gorm:"foreignKey:RecipeID;constraint:OnDelete:CASCADE" json:"recipe,omitempty"`
+	Servings      float64    `gorm:"type:decimal(4,2);not null" json:"servings"`
+	Calories      float64    `gorm:"type:decimal(8,2);not null" json:"calories"`
+	Protein       float64    `gorm:"type:decimal(8,2);not null" json:"protein"`
+	Carbs         float64    `gorm:"type:decimal(8,2);not null" json:"carbs"`
+	Fat           float64    `gorm:"type:decimal(8,2);not null" json:"fat"`
+	MealHistoryID *uuid.UUID `json:"meal_history_id,omitempty"`
+	LoggedAt      *time.Time `json:"logged_at,omitempty"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt     time.Time  `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+}
+
+func (slot *MealPlanSlot) BeforeCreate(_ *gorm.DB) error {
+	slot.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ScanJobQueued     = "queued"
+	ScanJobProcessing = "processing"
+	ScanJobCompleted  = "completed"
+	ScanJobFailed     = "failed"
+
+	ScanStepSegmentation = "segmentation"
+	ScanStepNutrition    = "nutrition"
+	ScanStepSaving       = "saving"
+)
+
+// MealScanJob tracks an asynchronous meal scan so clients can poll for the result
+type MealScanJob struct {
+	ID     uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID uuid.UUID `gorm:"not null;index" json:"user_id"`
+	// SubscriptionID is the subscription the scan was reserved against, refunded if the job fails
+	SubscriptionID *uuid.UUID `json:"-"`
+	Status         string     `gorm:"size:20;not null;default:'queued';index" json:"status"`
+	Step           string     `gorm:"size:20" json:"step"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	ImagePath      string     `gorm:"not null" json:"-"`
+	Filename       string     `json:"-"`
+	Recognition    JSON       `gorm:"type:jsonb" json:"-"`
+	Result         JSON       `gorm:"type:jsonb" json:"result,omitempty"`
+	Error          *string    `gorm:"type:text" json:"error,omitempty"`
+	MealHistoryID  *uuid.UUID `json:"meal_history_id,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
+}
+
+func (job *MealScanJob) BeforeCreate(_ *gorm.DB) error {
+	job.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Buckets a nutrition summary can be grouped by
+const (
+	SummaryBucketDay   = "day"
+	SummaryBucketWeek  = "week"
+	SummaryBucketMonth = "month"
+)
+
+// NutritionSummary is the intake of a user grouped per day, week or month
+type NutritionSummary struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Bucket   string            `json:"bucket"`
+	Timezone string            `json:"timezone"`
+	Targets  *NutritionTargets `json:"targets"`
+	Buckets  []NutritionBucket `json:"buckets"`
+}
+
+// NutritionBucket is the intake of one period. Adherence is the share of targeted nutrients that were on target.
+type NutritionBucket struct {
+	Start     time.Time               `json:"start"`
+	End       time.Time               `json:"end"`
+	Days      int                     `json:"days"`
+	Meals     int                     `json:"meals"`
+	Adherence float64                 `json:"adherence"`
+	Nutrients []NutritionBucketAmount `json:"nutrients"`
+}
+
+// NutritionBucketAmount is the intake of one nutrient in a bucket, compared with the target over the bucket's days
+type NutritionBucketAmount struct {
+	Code         string   `json:"code"`
+	Name         string   `json:"name"`
+	Unit         string   `json:"unit"`
+	Quantity     float64  `json:"quantity"`
+	DailyAverage float64  `json:"daily_average"`
+	Target       *float64 `json:"target,omitempty"`
+	Percent      *float64 `json:"percent,omitempty"`
+	Limit        bool     `json:"limit"`
+	OnTarget     *bool    `json:"on_target,omitempty"`
+}
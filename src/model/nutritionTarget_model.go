@@ -0,0 +1,52 @@
+package model
+
+// Goals a nutrition target can be adjusted for
+const (
+	GoalLoseWeight     = "lose_weight"
+	GoalMaintainWeight = "maintain_weight"
+	GoalGainWeight     = "gain_weight"
+)
+
+// NutrientTarget is the daily amount of one nutrient a user should reach, or stay under when Limit is set
+type NutrientTarget struct {
+	Code   string  `json:"code"`
+	Name   string  `json:"name"`
+	Target float64 `json:"target"`
+	Unit   string  `json:"unit"`
+	Limit  bool    `json:"limit"`
+}
+
+// NutritionTargets are the daily targets computed from a user's profile and active weight target
+type NutritionTargets struct {
+	BMR        float64          `json:"bmr"`
+	TDEE       float64          `json:"tdee"`
+	Adjustment float64          `json:"adjustment"`
+	Calories   float64          `json:"calories"`
+	Goal       string           `json:"goal"`
+	Estimated  bool             `json:"estimated"`
+	Missing    []string         `json:"missing,omitempty"`
+	Nutrients  []NutrientTarget `json:"nutrients"`
+}
+
+// GetNutrientTarget returns the target of code, or nil when there is none
+func (targets *NutritionTargets) GetNutrientTarget(code string) *NutrientTarget {
+	for i := range targets.Nutrients {
+		if targets.Nutrients[i].Code == code {
+			return &targets.Nutrients[i]
+		}
+	}
+	return nil
+}
+
+// NutrientProgress compares what was eaten today with the target of one nutrient
+type NutrientProgress struct {
+	Code      string  `json:"code"`
+	Name      string  `json:"name"`
+	Unit      string  `json:"unit"`
+	Consumed  float64 `json:"consumed"`
+	Target    float64 `json:"target"`
+	Remaining float64 `json:"remaining"`
+	Percent   float64 `json:"percent"`
+	Limit     bool    `json:"limit"`
+	Exceeded  bool    `json:"exceeded"`
+}
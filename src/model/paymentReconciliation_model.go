@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// What the reconciliation did with a pending order whose status differs at Midtrans
+const (
+	ReconciliationActionApplied = "applied"
+	ReconciliationActionExpired = "expired"
+	ReconciliationActionSkipped = "skipped"
+	ReconciliationActionError   = "error"
+)
+
+// PaymentReconciliation is the report of one run of the payment reconciliation
+type PaymentReconciliation struct {
+	ID         uuid.UUID                   `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	Checked    int                         `gorm:"not null;default:0" json:"checked"`
+	Mismatches int                         `gorm:"not null;default:0" json:"mismatches"`
+	Applied    int                         `gorm:"not null;default:0" json:"applied"`
+	Errors     int                         `gorm:"not null;default:0" json:"errors"`
+	StartedAt  time.Time                   `gorm:"not null;index" json:"started_at"`
+	FinishedAt time.Time                   `gorm:"not null" json:"finished_at"`
+	Items      []PaymentReconciliationItem `gorm:"foreignKey:ReconciliationID;constraint:OnDelete:CASCADE" json:"items"`
+}
+
+// PaymentReconciliationItem is a pending order whose status at Midtrans didn't match ours
+type PaymentReconciliationItem struct {
+	ID                 uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	ReconciliationID   uuid.UUID `gorm:"not null;index" json:"-"`
+	UserSubscriptionID uuid.UUID `gorm:"not null" json:"user_subscription_id"`
+	OrderID            string    `gorm:"size:100;not null" json:"order_id"`
+	LocalStatus        string    `gorm:"size:50;not null" json:"local_status"`
+	GatewayStatus      string    `gorm:"size:50" json:"gateway_status"`
+	Action             string    `gorm:"size:20;not null" json:"action"`
+	Error              string    `json:"error,omitempty"`
+}
+
+func (reconciliation *PaymentReconciliation) BeforeCreate(_ *gorm.DB) error {
+	reconciliation.ID = uuid.New()
+	return nil
+}
+
+func (item *PaymentReconciliationItem) BeforeCreate(_ *gorm.DB) error {
+	item.ID = uuid.New()
+	return nil
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Units a recipe ingredient can be measured in, with their weight in grams. Liquids are counted as water.
+var RecipeUnitGrams = map[string]float64{
+	"g":   1,
+	"kg":  1000,
+	"ml":  1,
+	"l":   1000,
+	"sdm": 15,
+	"sdt": 5,
+}
+
+// RecipeIngredient is one bahan makanan line of a recipe for all its servings. Grams is Quantity converted
+// from Unit, EdibleGrams what's left after applying BddPersen and what the nutrients are computed from.
+type RecipeIngredient struct {
+	ID               uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	RecipeID         uuid.UUID `gorm:"not null;index" json:"-"`
+	Position         int       `gorm:"not null" json:"position"`
+	Kode             string    `gorm:"size:20;not null" json:"kode"`
+	NamaBahanMakanan string    `json:"nama_bahan_makanan"`
+	Quantity         float64   `gorm:"type:decimal(10,2);not null" json:"quantity"`
+	Unit             string    `gorm:"size:10;not null" json:"unit"`
+	Note             *string   `json:"note,omitempty"`
+	Grams            float64   `gorm:"type:decimal(10,2);not null" json:"grams"`
+	BddPersen        float64   `gorm:"type:decimal(5,2);not null" json:"bdd_persen"`
+	EdibleGrams      float64   `gorm:"type:decimal(10,2);not null" json:"edible_grams"`
+	Calories         float64   `gorm:"type:decimal(8,2);not null" json:"calories"`
+	Protein          float64   `gorm:"type:decimal(8,2);not null" json:"protein"`
+	Carbs            float64   `gorm:"type:decimal(8,2);not null" json:"carbs"`
+	Fat              float64   `gorm:"type:decimal(8,2);not null" json:"fat"`
+	CreatedAt        time.Time `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt        time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+}
+
+func (recipeIngredient *RecipeIngredient) BeforeCreate(_ *gorm.DB) error {
+	recipeIngredient.ID = uuid.New()
+	return nil
+}
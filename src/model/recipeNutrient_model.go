@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecipeNutrient is the amount of one nutrient in a single serving of a recipe, computed from its
+// ingredient lines
+type RecipeNutrient struct {
+	ID        uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"-"`
+	RecipeID  uuid.UUID `gorm:"not null;uniqueIndex:idx_recipe_nutrient_code" json:"-"`
+	Code      string    `gorm:"size:20;not null;uniqueIndex:idx_recipe_nutrient_code" json:"code"`
+	Quantity  float64   `gorm:"type:decimal(10,3);not null" json:"quantity"`
+	Unit      string    `gorm:"size:10;not null" json:"unit"`
+	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+}
+
+func (recipeNutrient *RecipeNutrient) BeforeCreate(_ *gorm.DB) error {
+	recipeNutrient.ID = uuid.New()
+	return nil
+}
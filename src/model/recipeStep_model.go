@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RecipeStep is one instruction of a recipe, steps are followed in Position order
+type RecipeStep struct {
+	ID          uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"-"`
+	RecipeID    uuid.UUID `gorm:"not null;index" json:"-"`
+	Position    int       `gorm:"not null" json:"position"`
+	Instruction string    `gorm:"type:text;not null" json:"instruction"`
+	CreatedAt   time.Time `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt   time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+}
+
+func (recipeStep *RecipeStep) BeforeCreate(_ *gorm.DB) error {
+	recipeStep.ID = uuid.New()
+	return nil
+}
@@ -18,8 +18,23 @@ type Recipe struct {
 	Instructions string    `gorm:"type:text;not null" json:"instructions"`
 	Label        *string   `json:"label,omitempty"`
 	Day          string    `gorm:"type:varchar(10);not null;check(day IN ('sunday', 'monday', 'tuesday', 'wednesday', 'thursday', 'friday', 'saturday'))" json:"day"`
-	CreatedAt    time.Time `gorm:"autoCreateTime:milli" json:"-"`
-	UpdatedAt    time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+	// Servings the ingredient lines make, the nutrients below are for one of them
+	Servings        int                `gorm:"not null;default:1" json:"servings"`
+	IngredientLines []RecipeIngredient `gorm:"foreignKey:RecipeID;constraint:OnDelete:CASCADE" json:"ingredient_lines,omitempty"`
+	Steps           []RecipeStep       `gorm:"foreignKey:RecipeID;constraint:OnDelete:CASCADE" json:"steps,omitempty"`
+	Nutrients       []RecipeNutrient   `gorm:"foreignKey:RecipeID;constraint:OnDelete:CASCADE" json:"nutrients,omitempty"`
+	// Nutrients of one serving, computed from the ingredient lines when there are any. Recipes without
+	// calories can't be planned.
+	Calories *float64 `gorm:"type:decimal(8,2)" json:"calories,omitempty"`
+	Protein  *float64 `gorm:"type:decimal(8,2)" json:"protein,omitempty"`
+	Carbs    *float64 `gorm:"type:decimal(8,2)" json:"carbs,omitempty"`
+	Fat      *float64 `gorm:"type:decimal(8,2)" json:"fat,omitempty"`
+	// Aggregates kept up to date by the engagement service, hidden ratings aren't counted
+	RatingAverage float64   `gorm:"type:decimal(3,2);not null;default:0" json:"rating_average"`
+	RatingCount   int       `gorm:"not null;default:0" json:"rating_count"`
+	FavoriteCount int       `gorm:"not null;default:0" json:"favorite_count"`
+	CreatedAt     time.Time `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt     time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
 }
 
 func (recipe *Recipe) BeforeCreate(_ *gorm.DB) error {
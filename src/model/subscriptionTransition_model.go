@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubscriptionTransition is the audit trail of a subscription's lifecycle, FromStatus is empty when it was created
+type SubscriptionTransition struct {
+	ID                 uuid.UUID  `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserSubscriptionID uuid.UUID  `gorm:"not null;index" json:"user_subscription_id"`
+	FromStatus         string     `gorm:"size:20" json:"from_status"`
+	ToStatus           string     `gorm:"size:20;not null" json:"to_status"`
+	Reason             string     `gorm:"size:100" json:"reason"`
+	ActorID            *uuid.UUID `json:"actor_id,omitempty"`
+	CreatedAt          time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// SubscriptionSweepReport counts the transitions made by one run of the lifecycle sweeper
+type SubscriptionSweepReport struct {
+	Grace     int `json:"grace"`
+	Expired   int `json:"expired"`
+	Activated int `json:"activated"`
+	Cancelled int `json:"cancelled"`
+}
+
+func (transition *SubscriptionTransition) BeforeCreate(_ *gorm.DB) error {
+	transition.ID = uuid.New()
+	return nil
+}
@@ -10,14 +10,23 @@ import (
 	"gorm.io/gorm"
 )
 
-// TransactionDetail stores all payment-related information from Midtrans
+// Kinds of transaction details, payment events come from Midtrans and refunds are made by admins
+const (
+	TransactionTypePayment = "payment"
+	TransactionTypeRefund  = "refund"
+)
+
+// TransactionDetail stores all payment-related information from Midtrans. A notification is stored once,
+// repeats of the same order, transaction and status are rejected by idx_transaction_details_notification.
+// Refunds are stored with the refund key as their TransactionID.
 type TransactionDetail struct {
 	ID                 uuid.UUID        `gorm:"primaryKey;default:uuid_generate_v4()"`
 	UserSubscriptionID uuid.UUID        `gorm:"not null"`
 	UserSubscription   UserSubscription `gorm:"foreignKey:UserSubscriptionID"`
-	OrderID            string           `gorm:"size:100;index"`
-	TransactionID      string           `gorm:"size:100"`
-	TransactionStatus  string           `gorm:"size:50"`
+	Type               string           `gorm:"size:20;not null;default:'payment'"`
+	OrderID            string           `gorm:"size:100;index;uniqueIndex:idx_transaction_details_notification"`
+	TransactionID      string           `gorm:"size:100;uniqueIndex:idx_transaction_details_notification"`
+	TransactionStatus  string           `gorm:"size:50;uniqueIndex:idx_transaction_details_notification"`
 	TransactionTime    time.Time
 	StatusCode         string `gorm:"size:10"`
 	StatusMessage      string
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// What an image is uploaded for, each purpose has its own folder in the blob store
+const (
+	UploadPurposeArticle = "article"
+	UploadPurposeRecipe  = "recipe"
+	UploadPurposeProfile = "profile"
+)
+
+// Upload is an uploaded image. Its variants are stored under Prefix, it's garbage-collected once no
+// article, revision, recipe or profile picture links to any of them.
+type Upload struct {
+	ID        uuid.UUID       `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID    uuid.UUID       `gorm:"not null;index" json:"user_id"`
+	Purpose   string          `gorm:"size:20;not null" json:"purpose"`
+	Prefix    string          `gorm:"not null;uniqueIndex" json:"-"`
+	Filename  string          `json:"filename"`
+	Width     int             `gorm:"not null" json:"width"`
+	Height    int             `gorm:"not null" json:"height"`
+	Size      int64           `gorm:"not null" json:"size"`
+	Variants  []UploadVariant `gorm:"foreignKey:UploadID;constraint:OnDelete:CASCADE" json:"variants"`
+	CreatedAt time.Time       `gorm:"autoCreateTime:milli" json:"created_at"`
+}
+
+// UploadVariant is one stored version of an upload: the image itself, its thumbnail and their WebP versions
+type UploadVariant struct {
+	ID          uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"-"`
+	UploadID    uuid.UUID `gorm:"not null;uniqueIndex:idx_upload_variant_name" json:"-"`
+	Name        string    `gorm:"size:20;not null;uniqueIndex:idx_upload_variant_name" json:"name"`
+	Key         string    `gorm:"not null" json:"-"`
+	URL         string    `gorm:"not null" json:"url"`
+	ContentType string    `gorm:"size:50;not null" json:"content_type"`
+	Width       int       `gorm:"not null" json:"width"`
+	Height      int       `gorm:"not null" json:"height"`
+	Size        int64     `gorm:"not null" json:"size"`
+}
+
+// UploadGCReport tells what a garbage collection of uploaded files removed
+type UploadGCReport struct {
+	Uploads int `json:"uploads"`
+	Files   int `json:"files"`
+}
+
+// BeforeCreate keeps an ID set beforehand, the files are stored under it before the upload is saved
+func (upload *Upload) BeforeCreate(_ *gorm.DB) error {
+	if upload.ID == uuid.Nil {
+		upload.ID = uuid.New()
+	}
+	return nil
+}
+
+func (variant *UploadVariant) BeforeCreate(_ *gorm.DB) error {
+	variant.ID = uuid.New()
+	return nil
+}
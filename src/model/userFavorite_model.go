@@ -0,0 +1,22 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserFavorite marks a recipe or an article as one of a user's favorites
+type UserFavorite struct {
+	ID         uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID     uuid.UUID `gorm:"not null;uniqueIndex:idx_user_favorite_target" json:"user_id"`
+	TargetType string    `gorm:"size:20;not null;uniqueIndex:idx_user_favorite_target" json:"target_type"`
+	TargetID   uuid.UUID `gorm:"not null;uniqueIndex:idx_user_favorite_target;index" json:"target_id"`
+	CreatedAt  time.Time `gorm:"autoCreateTime:milli" json:"created_at"`
+}
+
+func (userFavorite *UserFavorite) BeforeCreate(_ *gorm.DB) error {
+	userFavorite.ID = uuid.New()
+	return nil
+}
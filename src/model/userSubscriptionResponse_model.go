@@ -8,16 +8,31 @@ import (
 )
 
 type UserSubscriptionResponse struct {
-	ID            uuid.UUID                `json:"id"`
-	UserID        uuid.UUID                `json:"user_id"`
-	Plan          SubscriptionPlanResponse `json:"plan"`
-	AIscansUsed   int                      `json:"ai_scans_used"`
-	StartDate     time.Time                `json:"start_date"`
-	EndDate       time.Time                `json:"end_date"`
-	IsActive      bool                     `json:"is_active"`
-	PaymentMethod string                   `json:"payment_method"`
-	PaymentStatus string                   `json:"payment_status"`
-	CreatedAt     time.Time                `json:"created_at"`
+	ID                uuid.UUID                `json:"id"`
+	UserID            uuid.UUID                `json:"user_id"`
+	Plan              SubscriptionPlanResponse `json:"plan"`
+	AIscansUsed       int                      `json:"ai_scans_used"`
+	StartDate         time.Time                `json:"start_date"`
+	EndDate           time.Time                `json:"end_date"`
+	IsActive          bool                     `json:"is_active"`
+	PaymentMethod     string                   `json:"payment_method"`
+	PaymentStatus     string                   `json:"payment_status"`
+	Status            string                   `json:"status"`
+	CancelAtPeriodEnd bool                     `json:"cancel_at_period_end"`
+	ChangeType        string                   `json:"change_type"`
+	CreditAmount      int                      `json:"credit_amount"`
+	// Upcoming are the paid subscriptions queued to start after this one, in order
+	Upcoming  []UpcomingSubscriptionResponse `json:"upcoming,omitempty"`
+	CreatedAt time.Time                      `json:"created_at"`
+}
+
+// UpcomingSubscriptionResponse is a subscription waiting for the current one to end
+type UpcomingSubscriptionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	PlanID    uuid.UUID `json:"plan_id"`
+	PlanName  string    `json:"plan_name"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
 }
 
 func (userSubscriptionPlanResponse *UserSubscriptionResponse) BeforeCreate(_ *gorm.DB) error {
@@ -7,6 +7,28 @@ import (
 	"gorm.io/gorm"
 )
 
+// Subscription lifecycle. Pending subscriptions wait for payment and queued ones are paid but start after
+// another subscription ends. Active and grace subscriptions give access, the others are final.
+const (
+	SubscriptionStatusPending   = "pending"
+	SubscriptionStatusQueued    = "queued"
+	SubscriptionStatusActive    = "active"
+	SubscriptionStatusGrace     = "grace"
+	SubscriptionStatusExpired   = "expired"
+	SubscriptionStatusCancelled = "cancelled"
+	SubscriptionStatusRefunded  = "refunded"
+)
+
+// How a purchase changes the plan of a user who already has a paid subscription. Upgrades start right away
+// with the unused value of the old plan credited, downgrades and renewals are queued to start when
+// the subscription they follow ends.
+const (
+	PlanChangeNew       = "new"
+	PlanChangeUpgrade   = "upgrade"
+	PlanChangeDowngrade = "downgrade"
+	PlanChangeRenewal   = "renewal"
+)
+
 type UserSubscription struct {
 	ID            uuid.UUID        `gorm:"primaryKey;default:uuid_generate_v4()"`
 	UserID        uuid.UUID        `gorm:"not null"`
@@ -20,7 +42,39 @@ type UserSubscription struct {
 	PaymentMethod string           `gorm:"size:50"`
 	TransactionID string           `gorm:"size:100"`
 	PaymentStatus string           `gorm:"size:50;default:'pending'"`
-	CreatedAt     time.Time        `gorm:"autoCreateTime"`
+	Status        string           `gorm:"size:20;not null;default:'pending';index"`
+	// CancelAtPeriodEnd ends the subscription as cancelled at its end date instead of giving it a grace period
+	CancelAtPeriodEnd bool `gorm:"not null;default:false"`
+	// ChangeType is one of the PlanChange values, ReplacesID the subscription an upgrade, downgrade or renewal changes
+	ChangeType   string     `gorm:"size:20;not null;default:'new'"`
+	ReplacesID   *uuid.UUID `gorm:"type:uuid"`
+	CreditAmount int        `gorm:"not null;default:0"` // in Rupiah, taken off the price of an upgrade
+	// LastReconciledAt is when the payment reconciler last checked the pending order with the gateway
+	LastReconciledAt *time.Time
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+}
+
+// UnlimitedScans is the AIscanLimit value for plans without a scan quota
+const UnlimitedScans = -1
+
+// ScanQuota is the scan allowance of a subscription after a reservation
+type ScanQuota struct {
+	SubscriptionID uuid.UUID `json:"-"`
+	Limit          int       `json:"limit"`
+	Used           int       `json:"used"`
+	Remaining      int       `json:"remaining"`
+	ResetAt        time.Time `json:"reset_at"`
+}
+
+// RemainingScans returns the scans left, or UnlimitedScans when the plan has no limit
+func (quota *ScanQuota) RemainingScans() int {
+	if quota.Limit == UnlimitedScans {
+		return UnlimitedScans
+	}
+	if remaining := quota.Limit - quota.Used; remaining > 0 {
+		return remaining
+	}
+	return 0
 }
 
 type PurchaseSubscriptionRequest struct {
@@ -46,6 +100,9 @@ type PaymentResponse struct {
 	TransactionToken string `json:"transaction_token"`
 	RedirectURL      string `json:"redirect_url"`
 	OrderID          string `json:"order_id"`
+	ChangeType       string `json:"change_type"`
+	Amount           int    `json:"amount"`
+	CreditAmount     int    `json:"credit_amount"`
 }
 
 func (userSubscription *UserSubscription) BeforeCreate(_ *gorm.DB) error {
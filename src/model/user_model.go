@@ -35,6 +35,7 @@ type User struct {
 	Gender         *GenderType    `gorm:"type:varchar(10);default:null" json:"gender"`
 	ActivityLevel  *ActivityLevel `gorm:"type:varchar(10);default:null" json:"activity_level"`
 	MedicalHistory *string        `gorm:"type:text;default:null" json:"medical_history"`
+	Timezone       string         `gorm:"size:64;not null;default:Asia/Jakarta" json:"timezone"`
 	CreatedAt      time.Time      `gorm:"autoCreateTime:milli" json:"-"`
 	UpdatedAt      time.Time      `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
 	Token          []Token        `gorm:"foreignKey:user_id;references:id" json:"-"`
@@ -7,12 +7,28 @@ import (
 	"gorm.io/gorm"
 )
 
+// Content users can favorite, rate and review
+const (
+	TargetRecipe  = "recipe"
+	TargetArticle = "article"
+)
+
+// UsersStar is a user's 1 to 5 star rating of a recipe or an article, with an optional review. Hidden
+// ratings were taken down by a moderator and are left out of listings and rating aggregates.
 type UsersStar struct {
-	ID        uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
-	UserID    uuid.UUID `gorm:"not null" json:"user_id"`
-	Stars     int       `gorm:"not null" json:"stars"`
-	CreatedAt time.Time `gorm:"autoCreateTime:milli" json:"-"`
-	UpdatedAt time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+	ID           uuid.UUID  `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID       uuid.UUID  `gorm:"not null;uniqueIndex:idx_users_star_target" json:"user_id"`
+	UserName     string     `gorm:"->;-:migration" json:"user_name,omitempty"`
+	TargetType   string     `gorm:"size:20;not null;uniqueIndex:idx_users_star_target;index:idx_users_star_lookup" json:"target_type"`
+	TargetID     uuid.UUID  `gorm:"not null;uniqueIndex:idx_users_star_target;index:idx_users_star_lookup" json:"target_id"`
+	Stars        int        `gorm:"not null;check:stars BETWEEN 1 AND 5" json:"stars"`
+	Review       *string    `gorm:"type:text" json:"review,omitempty"`
+	Hidden       bool       `gorm:"not null;default:false" json:"hidden"`
+	HiddenReason *string    `json:"hidden_reason,omitempty"`
+	HiddenBy     *uuid.UUID `json:"hidden_by,omitempty"`
+	HiddenAt     *time.Time `json:"hidden_at,omitempty"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime:milli" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"updated_at"`
 }
 
 func (usersStar *UsersStar) BeforeCreate(_ *gorm.DB) error {
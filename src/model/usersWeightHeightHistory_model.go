@@ -7,14 +7,18 @@ import (
 	"gorm.io/gorm"
 )
 
+// UsersWeightHeightHistory is one measurement of a user, the waist circumference in cm and the body fat
+// percentage are optional
 type UsersWeightHeightHistory struct {
-	ID         uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
-	UserID     uuid.UUID `gorm:"not null" json:"user_id"`
-	Weight     float64   `gorm:"type:decimal(5,2);not null" json:"weight"`
-	Height     float64   `gorm:"type:decimal(5,2);not null" json:"height"`
-	RecordedAt time.Time `gorm:"autoCreateTime" json:"recorded_at"`
-	CreatedAt  time.Time `gorm:"autoCreateTime:milli" json:"-"`
-	UpdatedAt  time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
+	ID                 uuid.UUID `gorm:"primaryKey;default:uuid_generate_v4()" json:"id"`
+	UserID             uuid.UUID `gorm:"not null" json:"user_id"`
+	Weight             float64   `gorm:"type:decimal(5,2);not null" json:"weight"`
+	Height             float64   `gorm:"type:decimal(5,2);not null" json:"height"`
+	WaistCircumference *float64  `gorm:"type:decimal(5,2);default:null" json:"waist_circumference,omitempty"`
+	BodyFatPercentage  *float64  `gorm:"type:decimal(4,1);default:null" json:"body_fat_percentage,omitempty"`
+	RecordedAt         time.Time `gorm:"autoCreateTime" json:"recorded_at"`
+	CreatedAt          time.Time `gorm:"autoCreateTime:milli" json:"-"`
+	UpdatedAt          time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
 }
 
 func (usersWeightHeightHistory *UsersWeightHeightHistory) BeforeCreate(_ *gorm.DB) error {
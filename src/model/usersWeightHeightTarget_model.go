@@ -14,7 +14,7 @@ type UsersWeightHeightTarget struct {
 	WeightHistory float64   `gorm:"type:decimal(5,2);not null" json:"weight_history"`
 	Height        float64   `gorm:"type:decimal(5,2);not null" json:"height"`
 	HeightHistory float64   `gorm:"type:decimal(5,2);not null" json:"height_history"`
-	TargetDate    time.Time `gorm:"autoCreateTime" json:"target_date"`
+	TargetDate    time.Time `gorm:"not null" json:"target_date"`
 	RecordDate    time.Time `gorm:"autoCreateTime" json:"record_date"`
 	CreatedAt     time.Time `gorm:"autoCreateTime:milli" json:"-"`
 	UpdatedAt     time.Time `gorm:"autoCreateTime:milli;autoUpdateTime:milli" json:"-"`
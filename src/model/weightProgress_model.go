@@ -0,0 +1,52 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Trends of the weight history
+const (
+	WeightTrendLosing  = "losing"
+	WeightTrendGaining = "gaining"
+	WeightTrendStable  = "stable"
+)
+
+// Warnings raised for a weight target that isn't safe to follow
+const (
+	WeightWarningLossTooFast  = "loss_too_fast"
+	WeightWarningUnderweight  = "goal_underweight"
+	WeightWarningTargetPassed = "target_date_passed"
+)
+
+// WeightTargetWarning explains why a weight target or the current pace isn't safe
+type WeightTargetWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// WeightProgress compares the weight history since a target was set against that target. WeeklyRate is
+// the slope of a linear regression over the weight records, nil while there are too few of them, and
+// RequiredWeeklyRate is the pace that still reaches the target by its date.
+type WeightProgress struct {
+	TargetID           uuid.UUID             `json:"target_id"`
+	StartWeight        float64               `json:"start_weight"`
+	CurrentWeight      float64               `json:"current_weight"`
+	TargetWeight       float64               `json:"target_weight"`
+	StartDate          time.Time             `json:"start_date"`
+	TargetDate         time.Time             `json:"target_date"`
+	Change             float64               `json:"change"`
+	Remaining          float64               `json:"remaining"`
+	PercentComplete    float64               `json:"percent_complete"`
+	WeeklyRate         *float64              `json:"weekly_rate"`
+	RequiredWeeklyRate *float64              `json:"required_weekly_rate"`
+	Trend              string                `json:"trend,omitempty"`
+	ProjectedDate      *time.Time            `json:"projected_date"`
+	Achieved           bool                  `json:"achieved"`
+	OnTrack            bool                  `json:"on_track"`
+	TargetBMI          *float64              `json:"target_bmi"`
+	Safe               bool                  `json:"safe"`
+	Warnings           []WeightTargetWarning `json:"warnings"`
+	Records            int                   `json:"records"`
+}
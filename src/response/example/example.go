@@ -130,6 +130,13 @@ type AddMealRequest struct {
 	Protein  float64   `json:"protein" example:"20.0"`
 	Carbs    float64   `json:"carbs" example:"60.0"`
 	Fat      float64   `json:"fat" example:"15.0"`
+	// Ingredients replace the numbers above, which are then computed from TKPI
+	Ingredients []MealIngredientRequest `json:"ingredients,omitempty"`
+}
+
+type MealIngredientRequest struct {
+	Kode  string  `json:"kode" example:"AR001"`
+	Grams float64 `json:"grams" example:"150"`
 }
 
 type UpdateMealRequest struct {
@@ -140,6 +147,8 @@ type UpdateMealRequest struct {
 	Protein  float64   `json:"protein,omitempty" example:"25.0"`
 	Carbs    float64   `json:"carbs,omitempty" example:"65.0"`
 	Fat      float64   `json:"fat,omitempty" example:"18.0"`
+	// Ingredients replace the lines of a composite meal, an empty list turns it back into a manual meal
+	Ingredients []MealIngredientRequest `json:"ingredients,omitempty"`
 }
 
 type NutrientDetail struct {
@@ -184,11 +193,13 @@ type MealHistory struct {
 }
 
 type UsersWeightHeightHistory struct {
-	ID         string    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
-	UserID     string    `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
-	Weight     float64   `json:"weight" example:"50.5"`
-	Height     float64   `json:"height" example:"180.8"`
-	RecordedAt time.Time `json:"recorded_at" example:"2023-10-01T12:00:00Z"`
+	ID                 string    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	UserID             string    `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
+	Weight             float64   `json:"weight" example:"50.5"`
+	Height             float64   `json:"height" example:"180.8"`
+	WaistCircumference *float64  `json:"waist_circumference,omitempty" example:"78.5"`
+	BodyFatPercentage  *float64  `json:"body_fat_percentage,omitempty" example:"22.5"`
+	RecordedAt         time.Time `json:"recorded_at" example:"2023-10-01T12:00:00Z"`
 }
 
 type AddWeightHeightResponse struct {
@@ -216,15 +227,19 @@ type UpdateWeightHeightResponse struct {
 }
 
 type AddWeightHeightRequest struct {
-	Weight     float64    `json:"weight,omitempty" example:"70.5"`
-	Height     float64    `json:"height,omitempty" example:"175.0"`
-	RecordedAt *time.Time `json:"recorded_at,omitempty" example:"2023-10-10T12:00:00Z"`
+	Weight             float64    `json:"weight,omitempty" example:"70.5"`
+	Height             float64    `json:"height,omitempty" example:"175.0"`
+	WaistCircumference *float64   `json:"waist_circumference,omitempty" example:"78.5"`
+	BodyFatPercentage  *float64   `json:"body_fat_percentage,omitempty" example:"22.5"`
+	RecordedAt         *time.Time `json:"recorded_at,omitempty" example:"2023-10-10T12:00:00Z"`
 }
 
 type UpdateWeightHeightRequest struct {
-	Weight     float64    `json:"weight,omitempty" example:"70.5"`
-	Height     float64    `json:"height,omitempty" example:"175.0"`
-	RecordedAt *time.Time `json:"recorded_at,omitempty" example:"2023-10-10T12:00:00Z"`
+	Weight             float64    `json:"weight,omitempty" example:"70.5"`
+	Height             float64    `json:"height,omitempty" example:"175.0"`
+	WaistCircumference *float64   `json:"waist_circumference,omitempty" example:"78.5"`
+	BodyFatPercentage  *float64   `json:"body_fat_percentage,omitempty" example:"22.5"`
+	RecordedAt         *time.Time `json:"recorded_at,omitempty" example:"2023-10-10T12:00:00Z"`
 }
 
 type DeleteWeightHeightResponse struct {
@@ -323,6 +338,7 @@ type CreateArticleRequest struct {
 	Slug        string     `json:"slug" example:"my-first-article"`
 	Image       *string    `json:"image,omitempty" example:"https://example.com/image.jpg"`
 	Content     string     `json:"content" example:"This is the content of my article"`
+	Tags        []string   `json:"tags,omitempty" example:"diabetes,low-sugar"`
 	PublishedAt *time.Time `json:"published_at,omitempty" example:"2023-10-10T12:00:00Z"`
 }
 
@@ -332,6 +348,7 @@ type UpdateArticleRequest struct {
 	Slug        string     `json:"slug,omitempty" example:"updated-article-slug"`
 	Image       *string    `json:"image,omitempty" example:"https://example.com/new-image.jpg"`
 	Content     string     `json:"content,omitempty" example:"Updated content of my article"`
+	Tags        []string   `json:"tags,omitempty" example:"hypertension,low-sodium"`
 	PublishedAt *time.Time `json:"published_at,omitempty" example:"2023-10-11T12:00:00Z"`
 }
 
@@ -346,8 +363,10 @@ type ArticleResponse struct {
 	CategoryID   *string    `json:"category_id,omitempty" example:"550e8400-e29b-41d4-a716-446655440000"`
 	CategoryName string     `json:"category_name,omitempty" example:"Technology"`
 	Slug         string     `json:"slug" example:"my-first-article"`
+	Tags         []string   `json:"tags" example:"diabetes,low-sugar"`
 	Image        *string    `json:"image,omitempty" example:"https://example.com/image.jpg"`
 	Content      string     `json:"content" example:"This is the content of my article"`
+	Status       string     `json:"status" example:"published"`
 	PublishedAt  *time.Time `json:"published_at,omitempty" example:"2023-10-10T12:00:00Z"`
 	CreatedAt    time.Time  `json:"created_at" example:"2023-10-10T12:00:00Z"`
 	UpdatedAt    time.Time  `json:"updated_at" example:"2023-10-10T12:00:00Z"`
@@ -360,28 +379,6 @@ type ArticleCategoryResponse struct {
 	CreatedAt time.Time `json:"created_at" example:"2023-10-10T12:00:00Z"`
 }
 
-type CreateRecipeRequest struct {
-	Name         string  `json:"name" example:"Nasi Goreng Spesial"`
-	Slug         string  `json:"slug" example:"nasi-goreng-spesial"`
-	Image        *string `json:"image,omitempty" example:"https://example.com/nasi-goreng.jpg"`
-	Description  string  `json:"description" example:"Nasi goreng dengan bumbu rahasia"`
-	Ingredients  string  `json:"ingredients" example:"Nasi, telur, bawang, kecap"`
-	Instructions string  `json:"instructions" example:"1. Tumis bawang..."`
-	Label        *string `json:"label,omitempty" example:"Main Course"`
-	Day          string  `json:"day" example:"monday"`
-}
-
-type UpdateRecipeRequest struct {
-	Name         string  `json:"name,omitempty" example:"Nasi Goreng Premium"`
-	Slug         string  `json:"slug,omitempty" example:"nasi-goreng-premium"`
-	Image        *string `json:"image,omitempty" example:"https://example.com/nasi-goreng-premium.jpg"`
-	Description  string  `json:"description,omitempty" example:"Nasi goreng dengan bumbu premium"`
-	Ingredients  string  `json:"ingredients,omitempty" example:"Nasi, telur, bawang, kecap, ayam"`
-	Instructions string  `json:"instructions,omitempty" example:"1. Tumis bawang... 2. Masukkan ayam..."`
-	Label        *string `json:"label,omitempty" example:"Special Menu"`
-	Day          string  `json:"day,omitempty" example:"tuesday"`
-}
-
 type RecipeResponse struct {
 	ID           string    `json:"id" example:"550e8400-e29b-41d4-a716-446655440000"`
 	UserID       string    `json:"user_id" example:"550e8400-e29b-41d4-a716-446655440000"`
@@ -394,6 +391,21 @@ type RecipeResponse struct {
 	Label        *string   `json:"label,omitempty" example:"Main Course"`
 	CreatedAt    time.Time `json:"created_at" example:"2023-10-10T12:00:00Z"`
 	Day          string    `json:"day" example:"monday"`
+	Servings     int       `json:"servings" example:"2"`
+	Calories     *float64  `json:"calories,omitempty" example:"520"`
+	Protein      *float64  `json:"protein,omitempty" example:"18.5"`
+	Carbs        *float64  `json:"carbs,omitempty" example:"68"`
+	Fat          *float64  `json:"fat,omitempty" example:"19.2"`
+}
+
+type GetAllRecipesResponse struct {
+	Status       string           `json:"status" example:"success"`
+	Message      string           `json:"message" example:"Recipes fetched successfully"`
+	Results      []RecipeResponse `json:"results"`
+	Page         int              `json:"page" example:"1"`
+	Limit        int              `json:"limit" example:"10"`
+	TotalPages   int64            `json:"total_pages" example:"3"`
+	TotalResults int64            `json:"total_results" example:"25"`
 }
 
 type PurchaseSubscriptionRequest struct {
@@ -430,11 +442,20 @@ type UserSubscriptionResponse struct {
 
 // DailyNutrition represents the nutritional summary for a day
 type DailyNutrition struct {
-	Date     time.Time `json:"date" example:"2023-10-10T00:00:00Z"`
-	Calories float64   `json:"calories" example:"1250.5"`
-	Protein  float64   `json:"protein" example:"85.2"`
-	Carbs    float64   `json:"carbs" example:"150.3"`
-	Fat      float64   `json:"fat" example:"45.1"`
+	Date      time.Time       `json:"date" example:"2023-10-10T00:00:00Z"`
+	Calories  float64         `json:"calories" example:"1250.5"`
+	Protein   float64         `json:"protein" example:"85.2"`
+	Carbs     float64         `json:"carbs" example:"150.3"`
+	Fat       float64         `json:"fat" example:"45.1"`
+	Nutrients []NutrientTotal `json:"nutrients"`
+}
+
+// NutrientTotal represents the summed amount of one nutrient
+type NutrientTotal struct {
+	Code     string  `json:"code" example:"NA"`
+	Name     string  `json:"name" example:"Sodium"`
+	Quantity float64 `json:"quantity" example:"1840.5"`
+	Unit     string  `json:"unit" example:"mg"`
 }
 
 // WeightHeightStatistics represents weight and height statistics
@@ -448,9 +469,23 @@ type WeightHeightStatistics struct {
 // HomeStatistics combines multiple statistics for the home page
 type HomeStatistics struct {
 	DailyNutrition         *DailyNutrition         `json:"daily_nutrition"`
+	NutritionProgress      []NutrientProgress      `json:"nutrition_progress"`
 	WeightHeightStatistics *WeightHeightStatistics `json:"weight_height_statistics"`
 }
 
+// NutrientProgress compares today's intake of a nutrient with its daily target
+type NutrientProgress struct {
+	Code      string  `json:"code" example:"ENERC_KCAL"`
+	Name      string  `json:"name" example:"Energy"`
+	Unit      string  `json:"unit" example:"kcal"`
+	Consumed  float64 `json:"consumed" example:"1250.5"`
+	Target    float64 `json:"target" example:"2150"`
+	Remaining float64 `json:"remaining" example:"899.5"`
+	Percent   float64 `json:"percent" example:"58.2"`
+	Limit     bool    `json:"limit" example:"false"`
+	Exceeded  bool    `json:"exceeded" example:"false"`
+}
+
 // GetHomeStatisticsResponse represents the response for home statistics
 type GetHomeStatisticsResponse struct {
 	Status  string         `json:"status" example:"success"`
@@ -22,6 +22,12 @@ type SuccessWithMeal struct {
 	Meal    model.MealHistory `json:"meal_history"`
 }
 
+type SuccessWithMealScanJob struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Job     model.MealScanJob `json:"job"`
+}
+
 type SuccessWithProductTokens struct {
 	Status  string               `json:"status"`
 	Message string               `json:"message"`
@@ -52,6 +58,18 @@ type SuccessWithWeightHeightTarget struct {
 	Data    model.UsersWeightHeightTarget `json:"data"`
 }
 
+type SuccessWithWeightProgress struct {
+	Status  string               `json:"status"`
+	Message string               `json:"message"`
+	Data    model.WeightProgress `json:"data"`
+}
+
+type SuccessWithBodyMetrics struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Data    model.BodyMetrics `json:"data"`
+}
+
 type SuccessWithWeightHeightList struct {
 	Status  string                           `json:"status" example:"success"`
 	Message string                           `json:"message" example:"Operation completed successfully"`
@@ -123,6 +141,24 @@ type SuccessWithArticleList struct {
 	Data    []model.ArticleResponse `json:"data"`
 }
 
+type SuccessWithArticleRecommendations struct {
+	Status  string                        `json:"status"`
+	Message string                        `json:"message"`
+	Data    []model.ArticleRecommendation `json:"data"`
+}
+
+type SuccessWithArticleRevisions struct {
+	Status  string                  `json:"status"`
+	Message string                  `json:"message"`
+	Data    []model.ArticleRevision `json:"data"`
+}
+
+type SuccessWithArticleRevisionDiff struct {
+	Status  string                    `json:"status"`
+	Message string                    `json:"message"`
+	Data    model.ArticleRevisionDiff `json:"data"`
+}
+
 type SuccessWithArticleCategory struct {
 	Status  string                `json:"status"`
 	Message string                `json:"message"`
@@ -141,16 +177,16 @@ type SuccessWithRecipe struct {
 	Data    model.Recipe `json:"data"`
 }
 
-type SuccessWithRecipeList struct {
-	Status  string         `json:"status"`
-	Message string         `json:"message"`
-	Data    []model.Recipe `json:"data"`
-}
-
 type Recipe struct {
 	Day string `json:"day" example:"monday"`
 }
 
+type SuccessWithReview struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    model.UsersStar `json:"data"`
+}
+
 type SubscriptionPlansResponse struct {
 	Status  string                           `json:"status"`
 	Message string                           `json:"message"`
@@ -191,9 +227,63 @@ type SuccessWithBahanMakananList struct {
 	Data    []model.BahanMakanan `json:"data"`
 }
 
+type SuccessWithFoodAlias struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    model.FoodAlias `json:"data"`
+}
+
+type SuccessWithFoodAliases struct {
+	Status  string            `json:"status"`
+	Message string            `json:"message"`
+	Data    []model.FoodAlias `json:"data"`
+}
+
+type SuccessWithNutritionTargets struct {
+	Status  string                 `json:"status"`
+	Message string                 `json:"message"`
+	Data    model.NutritionTargets `json:"data"`
+}
+
+type SuccessWithNutritionSummary struct {
+	Status  string                 `json:"status"`
+	Message string                 `json:"message"`
+	Data    model.NutritionSummary `json:"data"`
+}
+
+type SuccessWithMealPlan struct {
+	Status  string         `json:"status"`
+	Message string         `json:"message"`
+	Data    model.MealPlan `json:"data"`
+}
+
+type SuccessWithMealPlans struct {
+	Status  string           `json:"status"`
+	Message string           `json:"message"`
+	Data    []model.MealPlan `json:"data"`
+}
+
+type SuccessWithMealPlanSlot struct {
+	Status  string             `json:"status"`
+	Message string             `json:"message"`
+	Data    model.MealPlanSlot `json:"data"`
+}
+
 // SuccessWithHomeStatistics represents a successful response with home statistics
 type SuccessWithHomeStatistics struct {
 	Status  string               `json:"status"`
 	Message string               `json:"message"`
 	Data    model.HomeStatistics `json:"data"`
 }
+
+type SuccessWithUpload struct {
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Data    model.Upload `json:"data"`
+}
+
+type SuccessWithUploadGCReport struct {
+	Status  string               `json:"status"`
+	Message string               `json:"message"`
+	Data    model.UploadGCReport `json:"data"`
+}
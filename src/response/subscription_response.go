@@ -55,6 +55,38 @@ type SuccessWithTransactions struct {
 	TotalResults int64                     `json:"total_results,omitempty"`
 }
 
+// SuccessWithSubscriptionTransitions is a response for the lifecycle history of a subscription
+type SuccessWithSubscriptionTransitions struct {
+	Status  string                         `json:"status"`
+	Message string                         `json:"message"`
+	Data    []model.SubscriptionTransition `json:"data"`
+}
+
+// SuccessWithSubscriptionSweepReport is a response for a run of the subscription lifecycle sweeper
+type SuccessWithSubscriptionSweepReport struct {
+	Status  string                        `json:"status"`
+	Message string                        `json:"message"`
+	Data    model.SubscriptionSweepReport `json:"data"`
+}
+
+// SuccessWithPaymentReconciliation is a response for a run of the payment reconciliation
+type SuccessWithPaymentReconciliation struct {
+	Status  string                      `json:"status"`
+	Message string                      `json:"message"`
+	Data    model.PaymentReconciliation `json:"data"`
+}
+
+// SuccessWithPaymentReconciliations is a response for the payment reconciliation reports
+type SuccessWithPaymentReconciliations struct {
+	Status       string                        `json:"status"`
+	Message      string                        `json:"message"`
+	Data         []model.PaymentReconciliation `json:"data"`
+	Page         int                           `json:"page"`
+	Limit        int                           `json:"limit"`
+	TotalPages   int64                         `json:"total_pages"`
+	TotalResults int64                         `json:"total_results"`
+}
+
 // SuccessWithTransaction is a response for a single transaction
 type SuccessWithTransaction struct {
 	Status  string                  `json:"status"`
@@ -8,9 +8,12 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func AdminRoutes(v1 fiber.Router, userService service.UserService, tokenService service.TokenService, subscriptionService service.SubscriptionService) {
+func AdminRoutes(v1 fiber.Router, userService service.UserService, tokenService service.TokenService, subscriptionService service.SubscriptionService, engagementService service.EngagementService, articleService service.ArticlesService, uploadService service.UploadService) {
 	adminUserController := controller.NewAdminUserController(userService, tokenService)
 	adminSubscriptionController := controller.NewAdminSubscriptionController(subscriptionService)
+	adminReviewController := controller.NewAdminReviewController(engagementService)
+	adminArticleController := controller.NewAdminArticleController(articleService)
+	adminUploadController := controller.NewAdminUploadController(uploadService)
 
 	admin := v1.Group("/admin", m.Auth(userService, nil))
 
@@ -23,12 +26,15 @@ func AdminRoutes(v1 fiber.Router, userService service.UserService, tokenService
 	// Subscription routes
 	subscriptions := admin.Group("/subscriptions", m.Auth(userService, nil, "getSubscriptions"))
 	subscriptions.Get("/", adminSubscriptionController.GetAllUserSubscriptions)
+	subscriptions.Post("/sweep", m.Auth(userService, nil, "manageSubscriptions"), adminSubscriptionController.SweepSubscriptions)
 
 	// Specific subscription routes
 	subscription := subscriptions.Group("/:subscription_id")
 	subscription.Get("/", adminSubscriptionController.GetUserSubscriptionDetails)
 	subscription.Patch("/", adminSubscriptionController.UpdateUserSubscription, m.Auth(userService, nil, "manageSubscriptions"))
 	subscription.Get("/transactions", adminSubscriptionController.GetTransactionLogs, m.Auth(userService, nil, "viewTransactions"))
+	subscription.Get("/transitions", adminSubscriptionController.GetSubscriptionTransitions)
+	subscription.Post("/refund", m.Auth(userService, nil, "manageSubscriptions"), adminSubscriptionController.RefundSubscription)
 	subscription.Patch("/payment-status", adminSubscriptionController.UpdatePaymentStatus, m.Auth(userService, nil, "updatePaymentStatus"))
 
 	// Subscription plans routes
@@ -40,5 +46,22 @@ func AdminRoutes(v1 fiber.Router, userService service.UserService, tokenService
 	// All transactions route
 	transactions := admin.Group("/transactions", m.Auth(userService, nil, "viewTransactions"))
 	transactions.Get("/", adminSubscriptionController.GetAllTransactions)
+	transactions.Get("/reconciliations", adminSubscriptionController.GetPaymentReconciliations)
+	transactions.Post("/reconcile", m.Auth(userService, nil, "updatePaymentStatus"), adminSubscriptionController.ReconcilePayments)
 	transactions.Get("/:id", adminSubscriptionController.GetTransactionByID)
+
+	// Review moderation routes
+	reviews := admin.Group("/reviews", m.Auth(userService, nil, "moderateReviews"))
+	reviews.Get("/", adminReviewController.GetAllReviews)
+	reviews.Patch("/:id/hide", adminReviewController.HideReview)
+	reviews.Patch("/:id/unhide", adminReviewController.UnhideReview)
+
+	// Article editing routes, drafts and scheduled articles included
+	articles := admin.Group("/articles", m.Auth(userService, nil, "manageUsers"))
+	articles.Get("/", adminArticleController.GetAllArticles)
+	articles.Get("/:id", adminArticleController.GetArticleByID)
+
+	// Uploaded images
+	uploads := admin.Group("/uploads", m.Auth(userService, nil, "manageUsers"))
+	uploads.Post("/collect", adminUploadController.CollectOrphans)
 }
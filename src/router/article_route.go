@@ -14,9 +14,16 @@ func ArticleRoutes(v1 fiber.Router, u service.UserService, ss service.Subscripti
 	articles := v1.Group("/articles")
 	articles.Get("/", m.FreemiumOrAccess(u, nil, ss), articleController.GetArticles)
 	articles.Post("/", m.Auth(u, nil, "manageUsers"), articleController.CreateArticle)
+	articles.Get("/recommended", m.FreemiumOrAccess(u, nil, ss), articleController.GetRecommendedArticles)
+	articles.Get("/slug/:slug", m.FreemiumOrAccess(u, nil, ss), articleController.GetArticleBySlug)
 	articles.Get("/:id", m.FreemiumOrAccess(u, nil, ss), articleController.GetArticleByID)
+	articles.Get("/:id/related", m.FreemiumOrAccess(u, nil, ss), articleController.GetRelatedArticles)
 	articles.Put("/:id", m.Auth(u, nil, "manageUsers"), articleController.UpdateArticle)
 	articles.Delete("/:id", m.Auth(u, nil, "manageUsers"), articleController.DeleteArticle)
+	articles.Patch("/:id/status", m.Auth(u, nil, "manageUsers"), articleController.UpdateArticleStatus)
+	articles.Get("/:id/revisions", m.Auth(u, nil, "manageUsers"), articleController.GetArticleRevisions)
+	articles.Get("/:id/revisions/:revisionId/diff", m.Auth(u, nil, "manageUsers"), articleController.DiffArticleRevision)
+	articles.Post("/:id/revisions/:revisionId/restore", m.Auth(u, nil, "manageUsers"), articleController.RestoreArticleRevision)
 
 	categories := v1.Group("/article-categories")
 	categories.Get("/", m.FreemiumOrAccess(u, nil, ss), articleController.GetArticleCategories)
@@ -8,10 +8,14 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func BahanMakananRoutes(v1 fiber.Router, u service.UserService, ss service.SubscriptionService, bahanMakananService service.BahanMakananService) {
+func BahanMakananRoutes(v1 fiber.Router, u service.UserService, ss service.SubscriptionService, bahanMakananService service.BahanMakananService, foodMatchingService service.FoodMatchingService) {
 	bahanMakananController := controller.NewBahanMakananController(bahanMakananService)
+	foodAliasController := controller.NewFoodAliasController(foodMatchingService)
 
 	bahanMakanan := v1.Group("/bahan-makanan")
+	bahanMakanan.Get("/aliases", m.Auth(u, nil, "manageFoodAliases"), foodAliasController.GetAliases)
+	bahanMakanan.Post("/aliases", m.Auth(u, nil, "manageFoodAliases"), foodAliasController.CreateAlias)
+	bahanMakanan.Delete("/aliases/:aliasId", m.Auth(u, nil, "manageFoodAliases"), foodAliasController.DeleteAlias)
 	bahanMakanan.Get("/", m.FreemiumOrAccess(u, nil, ss), bahanMakananController.GetAllBahanMakanan)
 	bahanMakanan.Get("/:id", m.FreemiumOrAccess(u, nil, ss), bahanMakananController.GetBahanMakananById)
 	bahanMakanan.Get("/kode/:kode", m.FreemiumOrAccess(u, nil, ss), bahanMakananController.GetBahanMakananByKode)
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func BodyMetricsRoutes(v1 fiber.Router, u service.UserService, ss service.SubscriptionService, bms service.BodyMetricsService) {
+	bodyMetricsController := controller.NewBodyMetricsController(bms)
+
+	bodyMetrics := v1.Group("/body-metrics")
+
+	bodyMetrics.Get("/", m.FreemiumOrAccess(u, nil, ss), m.SubscriptionRequired(ss, "bmi_check"), bodyMetricsController.GetBodyMetrics)
+}
@@ -0,0 +1,31 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func EngagementRoutes(v1 fiber.Router, u service.UserService, ss service.SubscriptionService, engagementService service.EngagementService) {
+	engagementController := controller.NewEngagementController(engagementService)
+
+	recipes := v1.Group("/recipes/:id")
+	recipes.Post("/favorite", m.FreemiumOrAccess(u, nil, ss), engagementController.FavoriteRecipe)
+	recipes.Delete("/favorite", m.FreemiumOrAccess(u, nil, ss), engagementController.UnfavoriteRecipe)
+	recipes.Put("/rating", m.FreemiumOrAccess(u, nil, ss), engagementController.RateRecipe)
+	recipes.Delete("/rating", m.FreemiumOrAccess(u, nil, ss), engagementController.DeleteRecipeRating)
+	recipes.Get("/reviews", m.FreemiumOrAccess(u, nil, ss), engagementController.GetRecipeReviews)
+
+	articles := v1.Group("/articles/:id")
+	articles.Post("/favorite", m.FreemiumOrAccess(u, nil, ss), engagementController.FavoriteArticle)
+	articles.Delete("/favorite", m.FreemiumOrAccess(u, nil, ss), engagementController.UnfavoriteArticle)
+	articles.Put("/rating", m.FreemiumOrAccess(u, nil, ss), engagementController.RateArticle)
+	articles.Delete("/rating", m.FreemiumOrAccess(u, nil, ss), engagementController.DeleteArticleRating)
+	articles.Get("/reviews", m.FreemiumOrAccess(u, nil, ss), engagementController.GetArticleReviews)
+
+	favorites := v1.Group("/favorites", m.FreemiumOrAccess(u, nil, ss))
+	favorites.Get("/recipes", engagementController.GetFavoriteRecipes)
+	favorites.Get("/articles", engagementController.GetFavoriteArticles)
+}
@@ -8,10 +8,12 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func HomeRoutes(v1 fiber.Router, u service.UserService, ss service.SubscriptionService, ml service.MealService) {
+func HomeRoutes(v1 fiber.Router, u service.UserService, ss service.SubscriptionService, ml service.MealService, nt service.NutritionTargetService) {
 	mealController := controller.NewMealController(ml)
+	nutritionTargetController := controller.NewNutritionTargetController(nt)
 
 	home := v1.Group("/home")
 
 	home.Get("/statistic", m.FreemiumOrAccess(u, nil, ss), mealController.GetHomeStatistics)
+	home.Get("/targets", m.FreemiumOrAccess(u, nil, ss), nutritionTargetController.GetNutritionTargets)
 }
@@ -0,0 +1,23 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func MealPlanRoutes(v1 fiber.Router, u service.UserService, ss service.SubscriptionService, mp service.MealPlanService) {
+	mealPlanController := controller.NewMealPlanController(mp)
+
+	mealPlans := v1.Group("/meal-plans")
+
+	mealPlans.Post("/", m.FreemiumOrAccess(u, nil, ss), mealPlanController.GenerateMealPlan)
+	mealPlans.Get("/", m.FreemiumOrAccess(u, nil, ss), mealPlanController.GetMealPlans)
+	mealPlans.Get("/current", m.FreemiumOrAccess(u, nil, ss), mealPlanController.GetCurrentMealPlan)
+	mealPlans.Get("/:planId", m.FreemiumOrAccess(u, nil, ss), mealPlanController.GetMealPlanByID)
+	mealPlans.Delete("/:planId", m.FreemiumOrAccess(u, nil, ss), mealPlanController.DeleteMealPlan)
+	mealPlans.Post("/:planId/slots/:slotId/swap", m.FreemiumOrAccess(u, nil, ss), mealPlanController.SwapSlot)
+	mealPlans.Post("/:planId/slots/:slotId/log", m.FreemiumOrAccess(u, nil, ss), mealPlanController.LogSlot)
+}
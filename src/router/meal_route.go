@@ -15,10 +15,12 @@ func MealRoutes(v1 fiber.Router, u service.UserService, ml service.MealService,
 
 	meal.Get("/", m.FreemiumOrAccess(u, nil, ss), m.SubscriptionRequired(ss, "health_info"), mealController.GetMeals)
 	meal.Post("/", m.FreemiumOrAccess(u, nil, ss), mealController.AddMeal)
-	meal.Post("/scan", m.FreemiumOrAccess(u, nil, ss), mealController.ScanMeal)
+	meal.Post("/scan", m.FreemiumOrAccess(u, nil, ss), m.ScanQuota(ss), mealController.ScanMeal)
+	meal.Get("/scan/jobs/:jobId", m.FreemiumOrAccess(u, nil, ss), m.ScanQuotaHeaders(ss), mealController.GetScanJob)
 	meal.Get("/:mealId", m.FreemiumOrAccess(u, nil, ss), mealController.GetMealByID)
 	meal.Put("/:mealId", m.FreemiumOrAccess(u, nil, ss), mealController.UpdateMeal)
 	meal.Delete("/:mealId", m.FreemiumOrAccess(u, nil, ss), mealController.DeleteMeal)
 	meal.Get("/:mealId/scan-detail", m.FreemiumOrAccess(u, nil, ss), mealController.GetMealScanDetailByID)
 	meal.Post("/:mealId/scan-detail", m.FreemiumOrAccess(u, nil, ss), mealController.AddMealScanDetail)
+	meal.Post("/:mealId/scan-detail/confirm", m.FreemiumOrAccess(u, nil, ss), mealController.ConfirmMealScan)
 }
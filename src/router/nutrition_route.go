@@ -0,0 +1,17 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func NutritionRoutes(v1 fiber.Router, u service.UserService, ss service.SubscriptionService, ns service.NutritionSummaryService) {
+	nutritionSummaryController := controller.NewNutritionSummaryController(ns)
+
+	nutrition := v1.Group("/nutrition")
+
+	nutrition.Get("/summary", m.FreemiumOrAccess(u, nil, ss), nutritionSummaryController.GetSummary)
+}
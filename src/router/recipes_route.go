@@ -14,6 +14,7 @@ func RecipeRoutes(v1 fiber.Router, u service.UserService, ss service.Subscriptio
 	recipes := v1.Group("/recipes")
 	recipes.Get("/", m.FreemiumOrAccess(u, nil, ss), recipeController.GetRecipes)
 	recipes.Post("/", m.Auth(u, nil, "manageUsers"), recipeController.CreateRecipe)
+	recipes.Get("/slug/:slug", m.FreemiumOrAccess(u, nil, ss), recipeController.GetRecipeBySlug)
 	recipes.Get("/:id", m.FreemiumOrAccess(u, nil, ss), recipeController.GetRecipeByID)
 	recipes.Put("/:id", m.Auth(u, nil, "manageUsers"), recipeController.UpdateRecipe)
 	recipes.Delete("/:id", m.Auth(u, nil, "manageUsers"), recipeController.DeleteRecipe)
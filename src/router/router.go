@@ -4,14 +4,37 @@ import (
 	"app/src/config"
 	"app/src/grpc"
 	"app/src/service"
+	"app/src/utils"
 	"app/src/validation"
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
-func Routes(app *fiber.App, db *gorm.DB) {
+// Background holds the services whose loops run beside the API: sweeping subscriptions, reconciling
+// payments, processing scan jobs, publishing scheduled articles and collecting orphaned uploads
+type Background struct {
+	Subscriptions service.SubscriptionService
+	Meals         service.MealService
+	Articles      service.ArticlesService
+	Uploads       service.UploadService
+}
+
+// Start starts the background loops, they stop when ctx is done
+func (b *Background) Start(ctx context.Context) {
+	b.Subscriptions.StartLifecycleSweeper(ctx, time.Duration(config.SubscriptionSweepInterval)*time.Minute)
+	b.Subscriptions.StartPaymentReconciler(ctx, time.Duration(config.PaymentReconcileInterval)*time.Minute)
+	b.Meals.StartScanWorkers(ctx, config.MealScanWorkers, config.MealScanMaxAttempts)
+	b.Articles.StartPublishScheduler(ctx, time.Duration(config.ArticlePublishInterval)*time.Second)
+	b.Uploads.StartOrphanCollector(ctx, time.Duration(config.UploadGCInterval)*time.Minute)
+}
+
+// Routes wires the services to the API. Their background loops are left to the caller to start with
+// Background.Start, so they run under the caller's lifetime.
+func Routes(app *fiber.App, db *gorm.DB) *Background {
 	validate := validation.Validator()
 	grpcServerAddr := fmt.Sprintf("%s:%s", config.GRPC_HOST, config.GRPC_PORT)
 	client, _ := grpc.NewBahanMakananClient(grpcServerAddr)
@@ -23,13 +46,28 @@ func Routes(app *fiber.App, db *gorm.DB) {
 	userService := service.NewUserService(db, validate, subscriptionService)
 	tokenService := service.NewTokenService(db, validate, userService, subscriptionService)
 	authService := service.NewAuthService(db, validate, userService, tokenService, subscriptionService)
-	mealService := service.NewMealService(db, config.LogMealApiKey, config.LogMealBaseUrl)
+	foodRecognizer, err := service.NewFoodRecognizer(config.FoodRecognizer, config.LogMealApiKey, config.LogMealBaseUrl)
+	if err != nil {
+		utils.Log.Fatalf("Failed to set up the food recognizer: %v", err)
+	}
+	foodMatchingService := service.NewFoodMatchingService(db, validate, service.NewGrpcFoodCatalog(client))
 	uwhService := service.NewUsersWeightHeightService(db)
-	articleService := service.NewArticlesService(db)
-	recipesService := service.NewRecipesService(db)
+	nutritionTargetService := service.NewNutritionTargetService(db)
+	nutritionSummaryService := service.NewNutritionSummaryService(db, validate, nutritionTargetService)
+	bodyMetricsService := service.NewBodyMetricsService(db, validate)
+	mealPlanService := service.NewMealPlanService(db, validate, nutritionTargetService)
+	articleService := service.NewArticlesService(db, validate)
+	engagementService := service.NewEngagementService(db, validate)
 	loginStreakService := service.NewLoginStreakService(db, validate)
 	bahanMakananService := service.NewBahanMakananService(client)
+	recipesService := service.NewRecipesService(db, validate, bahanMakananService)
+	mealService := service.NewMealService(db, validate, foodRecognizer, foodMatchingService, bahanMakananService)
 	productTokenService := service.NewProductTokenService(db, validate)
+	blobStore, err := service.NewBlobStore(context.Background(), config.UploadStorage)
+	if err != nil {
+		utils.Log.Fatalf("Failed to set up %s upload storage: %v", config.UploadStorage, err)
+	}
+	uploadService := service.NewUploadService(db, validate, blobStore, int64(config.UploadMaxSizeMB)*1024*1024, time.Duration(config.UploadGraceHours)*time.Hour)
 
 	v1 := app.Group("/v1")
 
@@ -42,10 +80,15 @@ func Routes(app *fiber.App, db *gorm.DB) {
 	RecipeRoutes(v1, userService, subscriptionService, recipesService)
 	SubscriptionRoutes(v1, userService, subscriptionService)
 	ProductTokenRoutes(v1, userService, productTokenService)
-	AdminRoutes(v1, userService, tokenService, subscriptionService)
+	AdminRoutes(v1, userService, tokenService, subscriptionService, engagementService, articleService, uploadService)
 	LoginStreakRoutes(v1, userService, subscriptionService, loginStreakService)
-	BahanMakananRoutes(v1, userService, subscriptionService, bahanMakananService)
-	HomeRoutes(v1, userService, subscriptionService, mealService)
+	BahanMakananRoutes(v1, userService, subscriptionService, bahanMakananService, foodMatchingService)
+	HomeRoutes(v1, userService, subscriptionService, mealService, nutritionTargetService)
+	NutritionRoutes(v1, userService, subscriptionService, nutritionSummaryService)
+	BodyMetricsRoutes(v1, userService, subscriptionService, bodyMetricsService)
+	MealPlanRoutes(v1, userService, subscriptionService, mealPlanService)
+	EngagementRoutes(v1, userService, subscriptionService, engagementService)
+	UploadRoutes(v1, userService, uploadService)
 
 	// TODO: add another routes here...
 
@@ -53,4 +96,11 @@ func Routes(app *fiber.App, db *gorm.DB) {
 		DocsRoutes(v1)
 		SentryTestRoutes(v1) // Only add Sentry test routes in development
 	}
+
+	return &Background{
+		Subscriptions: subscriptionService,
+		Meals:         mealService,
+		Articles:      articleService,
+		Uploads:       uploadService,
+	}
 }
@@ -30,6 +30,8 @@ func SubscriptionRoutes(
 			authGroup.Get("/me", subController.GetMySubscription)
 			authGroup.Get("/check-feature", subController.CheckFeatureAccess)
 			authGroup.Post("/purchase/:planID", subController.PurchasePlan)
+			authGroup.Post("/cancel", subController.CancelSubscription)
+			authGroup.Post("/resume", subController.ResumeSubscription)
 
 		}
 	}
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"app/src/controller"
+	m "app/src/middleware"
+	"app/src/service"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func UploadRoutes(v1 fiber.Router, u service.UserService, uploadService service.UploadService) {
+	uploadController := controller.NewUploadController(uploadService)
+
+	uploads := v1.Group("/uploads")
+	uploads.Post("/images", m.Auth(u, nil, "manageUsers"), uploadController.UploadImage)
+	uploads.Post("/profile-picture", m.Auth(u, nil), uploadController.UploadProfilePicture)
+}
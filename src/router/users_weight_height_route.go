@@ -15,6 +15,7 @@ func UsersWeightHeightRoutes(v1 fiber.Router, u service.UserService, ss service.
 
 	uwh.Get("/target/", m.FreemiumOrAccess(u, nil, ss), uwhController.GetWeightHeightsTarget)
 	uwh.Post("/target/", m.FreemiumOrAccess(u, nil, ss), uwhController.AddWeightHeightTarget)
+	uwh.Get("/target/progress", m.FreemiumOrAccess(u, nil, ss), uwhController.GetWeightTargetProgress)
 	uwh.Get("/target/:uwhId", m.FreemiumOrAccess(u, nil, ss), uwhController.GetWeightHeightTargetByID)
 	uwh.Put("/target/:uwhId", m.FreemiumOrAccess(u, nil, ss), uwhController.UpdateWeightHeightTarget)
 	uwh.Delete("/target/:uwhId", m.FreemiumOrAccess(u, nil, ss), uwhController.DeleteWeightHeightTarget)
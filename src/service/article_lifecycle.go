@@ -0,0 +1,331 @@
+package service
+
+import (
+	"app/src/model"
+	"app/src/validation"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/pmezard/go-difflib/difflib"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// articleTransitions lists the statuses an article can move to from each status. A scheduled article can
+// be scheduled again to change its publication date.
+var articleTransitions = map[string][]string{
+	model.ArticleStatusDraft:     {model.ArticleStatusScheduled, model.ArticleStatusPublished, model.ArticleStatusArchived},
+	model.ArticleStatusScheduled: {model.ArticleStatusDraft, model.ArticleStatusScheduled, model.ArticleStatusPublished, model.ArticleStatusArchived},
+	model.ArticleStatusPublished: {model.ArticleStatusDraft, model.ArticleStatusArchived},
+	model.ArticleStatusArchived:  {model.ArticleStatusDraft, model.ArticleStatusPublished},
+}
+
+// UpdateArticleStatus moves the article through its lifecycle. Scheduling needs a PublishedAt in the
+// future, publishing uses the given PublishedAt in the past or keeps the original date when republishing,
+// and moving back to draft clears it.
+func (s *articlesService) UpdateArticleStatus(ctx *fiber.Ctx, articleID string, req *validation.UpdateArticleStatus) (*model.ArticleResponse, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	article := new(model.Article)
+	if err := s.DB.WithContext(ctx.Context()).Where("id = ?", articleID).First(article).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Article not found")
+		}
+		s.Log.Errorf("Failed to find article: %+v", err)
+		return nil, err
+	}
+
+	now := time.Now()
+	current := article.Status
+	if articleLive(article, now) {
+		current = model.ArticleStatusPublished
+	}
+	if !articleTransitionAllowed(current, req.Status) {
+		return nil, fiber.NewError(fiber.StatusConflict, fmt.Sprintf("Can't move a %s article to %s", current, req.Status))
+	}
+
+	updates := map[string]interface{}{"status": req.Status}
+	switch req.Status {
+	case model.ArticleStatusScheduled:
+		if req.PublishedAt == nil || !req.PublishedAt.After(now) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "published_at must be in the future to schedule an article")
+		}
+		updates["published_at"] = req.PublishedAt
+	case model.ArticleStatusPublished:
+		publishedAt := now
+		if req.PublishedAt != nil {
+			if req.PublishedAt.After(now) {
+				return nil, fiber.NewError(fiber.StatusBadRequest, "Schedule the article to publish it in the future")
+			}
+			publishedAt = *req.PublishedAt
+		} else if article.PublishedAt != nil && !article.PublishedAt.After(now) {
+			publishedAt = *article.PublishedAt
+		}
+		updates["published_at"] = publishedAt
+	case model.ArticleStatusDraft:
+		updates["published_at"] = nil
+	}
+
+	if err := s.DB.WithContext(ctx.Context()).Model(article).Updates(updates).Error; err != nil {
+		s.Log.Errorf("Failed to update article status: %+v", err)
+		return nil, err
+	}
+
+	return s.GetArticleByID(ctx, articleID, true)
+}
+
+// GetArticleRevisions returns the revisions of the article, the latest first
+func (s *articlesService) GetArticleRevisions(ctx *fiber.Ctx, articleID string) ([]model.ArticleRevision, error) {
+	if err := s.findArticle(ctx, articleID); err != nil {
+		return nil, err
+	}
+
+	var revisions []model.ArticleRevision
+	if err := s.DB.WithContext(ctx.Context()).
+		Where("article_id = ?", articleID).
+		Order("version DESC").
+		Find(&revisions).Error; err != nil {
+		s.Log.Errorf("Failed to get article revisions: %+v", err)
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// DiffArticleRevision compares the revision with the current article
+func (s *articlesService) DiffArticleRevision(ctx *fiber.Ctx, articleID string, revisionID string) (*model.ArticleRevisionDiff, error) {
+	article := new(model.Article)
+	if err := s.DB.WithContext(ctx.Context()).Where("id = ?", articleID).First(article).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Article not found")
+		}
+		s.Log.Errorf("Failed to find article: %+v", err)
+		return nil, err
+	}
+
+	revision, err := s.getArticleRevision(s.DB.WithContext(ctx.Context()), articleID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	contentDiff, err := DiffArticleContent(revision, article)
+	if err != nil {
+		s.Log.Errorf("Failed to diff article revision: %+v", err)
+		return nil, err
+	}
+
+	return &model.ArticleRevisionDiff{
+		RevisionID:    revision.ID,
+		Version:       revision.Version,
+		ChangedFields: articleRevisionChanges(revision, article),
+		ContentDiff:   contentDiff,
+	}, nil
+}
+
+// RestoreArticleRevision puts the revision's title, category, image and content back. The slug stays so
+// links to the article keep working, and the version being replaced is kept as a revision so the restore
+// can be undone.
+func (s *articlesService) RestoreArticleRevision(ctx *fiber.Ctx, articleID string, revisionID string, editorID uuid.UUID) (*model.ArticleResponse, error) {
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		article, err := lockArticle(tx, articleID)
+		if err != nil {
+			return err
+		}
+
+		revision, err := s.getArticleRevision(tx, articleID, revisionID)
+		if err != nil {
+			return err
+		}
+
+		if err := saveArticleRevision(tx, article, editorID); err != nil {
+			return err
+		}
+		return tx.Model(article).Updates(map[string]interface{}{
+			"title":       revision.Title,
+			"category_id": revision.CategoryID,
+			"image":       revision.Image,
+			"content":     revision.Content,
+		}).Error
+	})
+	if err != nil {
+		var fiberErr *fiber.Error
+		if !errors.As(err, &fiberErr) {
+			s.Log.Errorf("Failed to restore article revision: %+v", err)
+		}
+		return nil, err
+	}
+
+	return s.GetArticleByID(ctx, articleID, true)
+}
+
+// PublishDueArticles marks the scheduled articles whose PublishedAt has passed as published
+func (s *articlesService) PublishDueArticles(ctx context.Context) (int64, error) {
+	result := s.DB.WithContext(ctx).
+		Model(&model.Article{}).
+		Where("status = ? AND published_at <= ?", model.ArticleStatusScheduled, time.Now()).
+		Update("status", model.ArticleStatusPublished)
+	if result.Error != nil {
+		s.Log.Errorf("Failed to publish scheduled articles: %+v", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
+// StartPublishScheduler publishes due scheduled articles now and then every interval until ctx is done.
+// Listings don't wait for it, it only keeps the stored status in step.
+func (s *articlesService) StartPublishScheduler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if published, err := s.PublishDueArticles(ctx); err == nil && published > 0 {
+				s.Log.Infof("Published %d scheduled articles", published)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *articlesService) findArticle(ctx *fiber.Ctx, articleID string) error {
+	var count int64
+	if err := s.DB.WithContext(ctx.Context()).Model(&model.Article{}).Where("id = ?", articleID).Count(&count).Error; err != nil {
+		s.Log.Errorf("Failed to find article: %+v", err)
+		return err
+	}
+	if count == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "Article not found")
+	}
+	return nil
+}
+
+func (s *articlesService) getArticleRevision(db *gorm.DB, articleID string, revisionID string) (*model.ArticleRevision, error) {
+	revision := new(model.ArticleRevision)
+	if err := db.Where("id = ? AND article_id = ?", revisionID, articleID).First(revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Revision not found")
+		}
+		s.Log.Errorf("Failed to get article revision: %+v", err)
+		return nil, err
+	}
+	return revision, nil
+}
+
+// lockArticle reads the article and locks its row until tx ends, so concurrent edits wait for each other
+// and each one sees the version the previous one left
+func lockArticle(tx *gorm.DB, articleID string) (*model.Article, error) {
+	article := new(model.Article)
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", articleID).
+		Take(article).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Article not found")
+		}
+		return nil, err
+	}
+	return article, nil
+}
+
+// saveArticleRevision snapshots the article as its next revision. The article must have been read with
+// lockArticle in tx, so concurrent edits snapshot and number their revisions one after the other.
+func saveArticleRevision(tx *gorm.DB, article *model.Article, editorID uuid.UUID) error {
+	var version int
+	if err := tx.Model(&model.ArticleRevision{}).
+		Where("article_id = ?", article.ID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&version).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&model.ArticleRevision{
+		ArticleID:  article.ID,
+		Version:    version + 1,
+		Title:      article.Title,
+		CategoryID: article.CategoryID,
+		Image:      article.Image,
+		Content:    article.Content,
+		EditedBy:   editorID,
+	}).Error
+}
+
+// DiffArticleContent returns a unified diff from the revision's content to the article's
+func DiffArticleContent(revision *model.ArticleRevision, article *model.Article) (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(revision.Content),
+		B:        difflib.SplitLines(article.Content),
+		FromFile: fmt.Sprintf("version %d", revision.Version),
+		ToFile:   "current",
+		Context:  3,
+	})
+}
+
+// articleRevisionChanges lists the fields the article changed since the revision
+func articleRevisionChanges(revision *model.ArticleRevision, article *model.Article) []string {
+	changes := []string{}
+	if revision.Title != article.Title {
+		changes = append(changes, "title")
+	}
+	if !sameUUID(revision.CategoryID, article.CategoryID) {
+		changes = append(changes, "category_id")
+	}
+	if !sameString(revision.Image, article.Image) {
+		changes = append(changes, "image")
+	}
+	if revision.Content != article.Content {
+		changes = append(changes, "content")
+	}
+	return changes
+}
+
+func articleTransitionAllowed(from string, to string) bool {
+	for _, status := range articleTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// publishedStatus is the status of an article publishing at publishedAt
+func publishedStatus(publishedAt time.Time, now time.Time) string {
+	if publishedAt.After(now) {
+		return model.ArticleStatusScheduled
+	}
+	return model.ArticleStatusPublished
+}
+
+// articleLive tells whether the public can read the article, like the articleVisible condition
+func articleLive(article *model.Article, now time.Time) bool {
+	if article.Status != model.ArticleStatusPublished && article.Status != model.ArticleStatusScheduled {
+		return false
+	}
+	return article.PublishedAt != nil && !article.PublishedAt.After(now)
+}
+
+func sameUUID(a *uuid.UUID, b *uuid.UUID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameString(a *string, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
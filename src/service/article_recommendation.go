@@ -0,0 +1,434 @@
+package service
+
+import (
+	"app/src/model"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// MaxArticleTags is how many tags an article can have
+	MaxArticleTags = 10
+
+	// recommendationIntakeDays is how far back meals count towards the intake trends
+	recommendationIntakeDays = 14
+	// recommendationMinLoggedDays is how many days need meals before a trend is trusted
+	recommendationMinLoggedDays = 3
+	// recommendationCandidates bounds the tagged articles scored for one recommendation
+	recommendationCandidates = 200
+	// readArticleFactor lowers the score of articles the user already read so new ones come first
+	readArticleFactor = 0.25
+)
+
+// Weights of the sources of interest, what the user told us outweighs what we infer from their meals
+const (
+	medicalHistoryWeight = 3.0
+	intakeTrendWeight    = 2.0
+	goalWeight           = 1.0
+	readTagWeight        = 0.5
+)
+
+var articleTagPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// medicalHistoryTags maps words of a medical history, in Indonesian and English, to the tags of articles
+// about the condition and the diet that goes with it
+var medicalHistoryTags = map[string][]string{
+	"diabetes":      {model.TagDiabetes, model.TagLowSugar},
+	"kencing manis": {model.TagDiabetes, model.TagLowSugar},
+	"gula darah":    {model.TagDiabetes, model.TagLowSugar},
+	"insulin":       {model.TagDiabetes, model.TagLowSugar},
+	"hipertensi":    {model.TagHypertension, model.TagLowSodium},
+	"hypertension":  {model.TagHypertension, model.TagLowSodium},
+	"darah tinggi":  {model.TagHypertension, model.TagLowSodium},
+	"tekanan darah": {model.TagHypertension, model.TagLowSodium},
+	"kolesterol":    {model.TagCholesterol, model.TagHighFiber},
+	"cholesterol":   {model.TagCholesterol, model.TagHighFiber},
+	"jantung":       {model.TagHeartDisease, model.TagLowSodium},
+	"heart":         {model.TagHeartDisease, model.TagLowSodium},
+	"stroke":        {model.TagHeartDisease, model.TagLowSodium},
+	"ginjal":        {model.TagKidneyDisease, model.TagLowSodium},
+	"kidney":        {model.TagKidneyDisease, model.TagLowSodium},
+	"asam urat":     {model.TagGout},
+	"gout":          {model.TagGout},
+	"anemia":        {model.TagAnemia},
+	"kurang darah":  {model.TagAnemia},
+	"maag":          {model.TagDigestive},
+	"lambung":       {model.TagDigestive},
+	"gerd":          {model.TagDigestive},
+	"gastritis":     {model.TagDigestive},
+	"obesitas":      {model.TagWeightLoss},
+	"obesity":       {model.TagWeightLoss},
+}
+
+// medicalHistoryPhrases rewrites phrases that contain the keyword of another condition, high blood
+// sugar isn't high blood pressure
+var medicalHistoryPhrases = strings.NewReplacer("gula darah tinggi", "gula darah")
+
+// goalTags are the tags of articles helping with each nutrition goal
+var goalTags = map[string]string{
+	model.GoalLoseWeight:     model.TagWeightLoss,
+	model.GoalMaintainWeight: model.TagHealthyEating,
+	model.GoalGainWeight:     model.TagWeightGain,
+}
+
+type intakeRow struct {
+	Code     string
+	Quantity float64
+}
+
+// RecordArticleRead remembers that the user opened the article, counting repeated reads
+func (s *articlesService) RecordArticleRead(ctx *fiber.Ctx, userID uuid.UUID, articleID uuid.UUID) error {
+	now := time.Now()
+	read := &model.ArticleRead{
+		UserID:      userID,
+		ArticleID:   articleID,
+		ReadCount:   1,
+		FirstReadAt: now,
+		LastReadAt:  now,
+	}
+
+	if err := s.DB.WithContext(ctx.Context()).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "article_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"read_count":   gorm.Expr("article_reads.read_count + 1"),
+			"last_read_at": now,
+		}),
+	}).Create(read).Error; err != nil {
+		s.Log.Errorf("Failed to record article read: %+v", err)
+		return err
+	}
+	return nil
+}
+
+// GetRecommendedArticles ranks the public articles by how well their tags match the user's medical
+// history, recent intake, goal and the tags of what they read before. Articles already read come after
+// new ones, and the list is topped up with popular articles when too few match.
+func (s *articlesService) GetRecommendedArticles(ctx *fiber.Ctx, userID uuid.UUID, limit int) ([]model.ArticleRecommendation, error) {
+	if limit < 1 || limit > 20 {
+		limit = 10
+	}
+	db := s.DB.WithContext(ctx.Context())
+
+	user := new(model.User)
+	if err := db.First(user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		s.Log.Errorf("Failed to get user: %+v", err)
+		return nil, err
+	}
+
+	var target *model.UsersWeightHeightTarget
+	latestTarget := new(model.UsersWeightHeightTarget)
+	if err := db.Where("user_id = ?", userID).Order("target_date DESC").First(latestTarget).Error; err == nil {
+		target = latestTarget
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.Log.Errorf("Failed to get weight target: %+v", err)
+		return nil, err
+	}
+	targets := CalculateNutritionTargets(user, target, time.Now())
+
+	intake, loggedDays, err := s.dailyIntake(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	var reads []model.ArticleRead
+	if err := db.Where("user_id = ?", userID).Find(&reads).Error; err != nil {
+		s.Log.Errorf("Failed to get article reads: %+v", err)
+		return nil, err
+	}
+	read := make(map[uuid.UUID]bool, len(reads))
+	readIDs := make([]uuid.UUID, 0, len(reads))
+	for _, articleRead := range reads {
+		read[articleRead.ArticleID] = true
+		readIDs = append(readIDs, articleRead.ArticleID)
+	}
+
+	var readTags []string
+	if len(readIDs) > 0 {
+		if err := db.Model(&model.ArticleTag{}).Where("article_id IN ?", readIDs).Pluck("tag", &readTags).Error; err != nil {
+			s.Log.Errorf("Failed to get tags of read articles: %+v", err)
+			return nil, err
+		}
+	}
+
+	interests := ArticleInterests(user, targets, intake, loggedDays, readTags)
+
+	var candidates []model.Article
+	if len(interests) > 0 {
+		tags := make([]string, 0, len(interests))
+		for tag := range interests {
+			tags = append(tags, tag)
+		}
+		if err := db.Model(&model.Article{}).
+			Select(articleListColumns).
+			Preload("Category").
+			Preload("ArticleTags", orderArticleTags).
+			Where(articleVisible).
+			Where("articles.id IN (?)", db.Model(&model.ArticleTag{}).Select("article_id").Where("tag IN ?", tags)).
+			Order("articles.published_at DESC").
+			Limit(recommendationCandidates).
+			Find(&candidates).Error; err != nil {
+			s.Log.Errorf("Failed to get recommendation candidates: %+v", err)
+			return nil, err
+		}
+	}
+
+	recommendations := RankArticleRecommendations(candidates, interests, read, limit)
+	if len(recommendations) >= limit {
+		return recommendations, nil
+	}
+
+	// Top up with the popular articles the user hasn't read yet
+	exclude := readIDs
+	for _, recommendation := range recommendations {
+		exclude = append(exclude, recommendation.ID)
+	}
+	popularQuery := db.Model(&model.Article{}).
+		Select(articleListColumns).
+		Preload("Category").
+		Preload("ArticleTags", orderArticleTags).
+		Where(articleVisible)
+	if len(exclude) > 0 {
+		popularQuery = popularQuery.Where("articles.id NOT IN ?", exclude)
+	}
+
+	var popular []model.Article
+	if err := popularQuery.
+		Order("articles.favorite_count DESC").
+		Order("articles.rating_average DESC").
+		Order("articles.published_at DESC").
+		Limit(limit - len(recommendations)).
+		Find(&popular).Error; err != nil {
+		s.Log.Errorf("Failed to get popular articles: %+v", err)
+		return nil, err
+	}
+	for _, article := range popular {
+		recommendations = append(recommendations, model.ArticleRecommendation{
+			ArticleResponse: toArticleSummary(article),
+			Reasons:         []string{},
+		})
+	}
+	return recommendations, nil
+}
+
+// dailyIntake averages the nutrients of the user's meals over the days they logged meals on, in the last
+// recommendationIntakeDays days
+func (s *articlesService) dailyIntake(ctx *fiber.Ctx, user *model.User) (map[string]float64, int, error) {
+	location, err := RequestLocation(ctx, user)
+	if err != nil {
+		return nil, 0, err
+	}
+	from := startOfDay(time.Now(), location).AddDate(0, 0, -recommendationIntakeDays+1)
+	db := s.DB.WithContext(ctx.Context())
+
+	var loggedDays int
+	if err := db.Model(&model.MealHistory{}).
+		Select("COUNT(DISTINCT date(meal_time AT TIME ZONE ?))", location.String()).
+		Where("user_id = ? AND meal_time >= ?", user.ID, from).
+		Scan(&loggedDays).Error; err != nil {
+		s.Log.Errorf("Failed to count logged days: %+v", err)
+		return nil, 0, err
+	}
+	if loggedDays == 0 {
+		return map[string]float64{}, 0, nil
+	}
+
+	var rows []intakeRow
+	if err := db.Model(&model.MealHistoryNutrient{}).
+		Select("meal_history_nutrients.code, SUM(meal_history_nutrients.quantity) AS quantity").
+		Joins("JOIN meal_histories ON meal_histories.id = meal_history_nutrients.meal_history_id").
+		Where("meal_histories.user_id = ? AND meal_histories.meal_time >= ?", user.ID, from).
+		Group("meal_history_nutrients.code").
+		Scan(&rows).Error; err != nil {
+		s.Log.Errorf("Failed to sum recent intake: %+v", err)
+		return nil, 0, err
+	}
+
+	intake := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		intake[row.Code] = row.Quantity / float64(loggedDays)
+	}
+	return intake, loggedDays, nil
+}
+
+// ArticleInterests weighs the tags the user is likely interested in. Medical history keywords count most,
+// then intake trends against the daily targets, then the goal, then the tags of articles already read.
+// Intake is the daily average of each nutrient over loggedDays days, too few days are ignored.
+func ArticleInterests(user *model.User, targets *model.NutritionTargets, intake map[string]float64, loggedDays int, readTags []string) map[string]float64 {
+	interests := make(map[string]float64)
+
+	if user.MedicalHistory != nil {
+		// Keywords match whole words, so "heartburn" isn't heart disease
+		history := " " + medicalHistoryPhrases.Replace(normalizeFoodName(*user.MedicalHistory)) + " "
+		matched := make(map[string]bool)
+		for keyword, tags := range medicalHistoryTags {
+			if !strings.Contains(history, " "+keyword+" ") {
+				continue
+			}
+			for _, tag := range tags {
+				matched[tag] = true
+			}
+		}
+		// A condition counts once however many of its keywords appear
+		for tag := range matched {
+			interests[tag] += medicalHistoryWeight
+		}
+	}
+
+	if targets != nil && loggedDays >= recommendationMinLoggedDays {
+		above := func(code string, factor float64) bool {
+			target := targets.GetNutrientTarget(code)
+			return target != nil && target.Target > 0 && intake[code] > target.Target*factor
+		}
+		below := func(code string, factor float64) bool {
+			target := targets.GetNutrientTarget(code)
+			return target != nil && target.Target > 0 && intake[code] < target.Target*factor
+		}
+
+		if above(model.NutrientSodium, 1) {
+			interests[model.TagLowSodium] += intakeTrendWeight
+			interests[model.TagHypertension] += intakeTrendWeight
+		}
+		if above(model.NutrientSugar, 1) {
+			interests[model.TagLowSugar] += intakeTrendWeight
+			interests[model.TagDiabetes] += intakeTrendWeight
+		}
+		if above(model.NutrientEnergy, 1.1) && targets.Goal != model.GoalGainWeight {
+			interests[model.TagWeightLoss] += intakeTrendWeight
+		}
+		if below(model.NutrientFiber, 0.5) {
+			interests[model.TagHighFiber] += intakeTrendWeight
+		}
+		if below(model.NutrientProtein, 0.7) {
+			interests[model.TagProtein] += intakeTrendWeight
+		}
+		if below(model.NutrientIron, 0.7) {
+			interests[model.TagAnemia] += intakeTrendWeight
+		}
+	}
+
+	if targets != nil {
+		if tag, ok := goalTags[targets.Goal]; ok {
+			interests[tag] += goalWeight
+		}
+	}
+	if user.ActivityLevel != nil && *user.ActivityLevel == model.Heavy {
+		interests[model.TagProtein] += goalWeight
+	}
+
+	readCounts := make(map[string]int)
+	for _, tag := range readTags {
+		readCounts[tag]++
+	}
+	for tag, count := range readCounts {
+		// Reading more articles of a tag shows more interest, up to twice the weight
+		interests[tag] += readTagWeight * math.Min(float64(count), 2)
+	}
+
+	return interests
+}
+
+// RankArticleRecommendations scores the articles with the weights of the interests their tags match and
+// returns the best limit of them. Articles already read keep a fraction of their score.
+func RankArticleRecommendations(articles []model.Article, interests map[string]float64, read map[uuid.UUID]bool, limit int) []model.ArticleRecommendation {
+	recommendations := make([]model.ArticleRecommendation, 0, len(articles))
+	for _, article := range articles {
+		var score float64
+		reasons := []string{}
+		for _, tag := range article.ArticleTags {
+			if weight, ok := interests[tag.Tag]; ok {
+				score += weight
+				reasons = append(reasons, tag.Tag)
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		if read[article.ID] {
+			score *= readArticleFactor
+		}
+
+		sort.Slice(reasons, func(i, j int) bool { return interests[reasons[i]] > interests[reasons[j]] })
+		recommendations = append(recommendations, model.ArticleRecommendation{
+			ArticleResponse: toArticleSummary(article),
+			Score:           math.Round(score*100) / 100,
+			Reasons:         reasons,
+			Read:            read[article.ID],
+		})
+	}
+
+	// Stable so equal scores keep the newest first order of the articles
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		return recommendations[i].Score > recommendations[j].Score
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations
+}
+
+// NormalizeArticleTags lowercases the tags, joins their words with dashes and drops duplicates. A nil
+// list stays nil, the tags are left as they are then.
+func NormalizeArticleTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = normalizeArticleTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > 40 || !articleTagPattern.MatchString(tag) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Invalid tag %q, use letters, digits and dashes", tag))
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > MaxArticleTags {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("An article can't have more than %d tags", MaxArticleTags))
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func normalizeArticleTag(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	return strings.Join(strings.FieldsFunc(tag, func(r rune) bool { return r == ' ' || r == '_' || r == '-' }), "-")
+}
+
+// normalizeTagFilter normalizes the tags of a listing filter the way they are stored
+func normalizeTagFilter(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		normalized = append(normalized, normalizeArticleTag(tag))
+	}
+	return normalized
+}
+
+func articleTags(articleID uuid.UUID, tags []string) []model.ArticleTag {
+	articleTags := make([]model.ArticleTag, 0, len(tags))
+	for _, tag := range tags {
+		articleTags = append(articleTags, model.ArticleTag{ArticleID: articleID, Tag: tag})
+	}
+	return articleTags
+}
+
+func orderArticleTags(db *gorm.DB) *gorm.DB {
+	return db.Order("article_tags.tag")
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"app/src/model"
+	"app/src/validation"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ArticleSortRelevance ranks search results, it's the default when searching
+const ArticleSortRelevance = "relevance"
+
+// ArticleExcerptLength is the maximum number of characters of a listing excerpt
+const ArticleExcerptLength = 200
+
+// articleListColumns are the columns listings load, only the start of the content is needed for the excerpt
+const articleListColumns = `articles.id, articles.user_id, articles.title, articles.category_id, articles.slug,
+	articles.image, articles.status, articles.published_at, articles.rating_average, articles.rating_count,
+	articles.favorite_count, articles.created_at, articles.updated_at, LEFT(articles.content, 1000) AS content`
+
+// articleSearchQuery parses web search syntax with the simple configuration the search vector is built with
+const articleSearchQuery = "websearch_to_tsquery('simple', ?)"
+
+// articleRelatedScore scores how related an article is to another one: a point for sharing its category
+// plus the text rank against any word of its title
+const articleRelatedScore = `(CASE WHEN articles.category_id = ? THEN 1 ELSE 0 END) +
+	ts_rank(articles.search_vector, replace(plainto_tsquery('simple', ?)::text, '&', '|')::tsquery)`
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`<[^>]*>`)
+	markdownPattern   = regexp.MustCompile("[#*_`>]+")
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// filterArticles applies the search, category, tag and status filters of query, an article with any of
+// the tags matches
+func filterArticles(db *gorm.DB, query *validation.QueryArticle) *gorm.DB {
+	switch query.Status {
+	case "":
+		db = db.Where(articleVisible)
+	case ArticleStatusAll:
+	default:
+		db = db.Where("articles.status = ?", query.Status)
+	}
+
+	if search := strings.TrimSpace(query.Search); search != "" {
+		db = db.Where("articles.search_vector @@ "+articleSearchQuery, search)
+	}
+	if len(query.CategoryIDs) > 0 {
+		db = db.Where("articles.category_id IN ?", query.CategoryIDs)
+	}
+	if len(query.Tags) > 0 {
+		db = db.Where("articles.id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+			Model(&model.ArticleTag{}).
+			Select("article_id").
+			Where("tag IN ?", normalizeTagFilter(query.Tags)))
+	}
+	return db
+}
+
+// sortArticles orders articles as requested, the latest published first by default
+func sortArticles(db *gorm.DB, query *validation.QueryArticle) *gorm.DB {
+	sort := query.Sort
+	search := strings.TrimSpace(query.Search)
+	if sort == "" {
+		sort = ArticleSortNewest
+		if search != "" {
+			sort = ArticleSortRelevance
+		}
+	}
+
+	switch sort {
+	case ArticleSortRating:
+		db = db.Order("articles.rating_average DESC").Order("articles.rating_count DESC")
+	case ArticleSortPopular:
+		db = db.Order("articles.favorite_count DESC").Order("articles.rating_count DESC")
+	case ArticleSortRelevance:
+		if search != "" {
+			db = db.Clauses(clause.OrderBy{Expression: clause.Expr{
+				SQL:                "ts_rank(articles.search_vector, " + articleSearchQuery + ") DESC",
+				Vars:               []interface{}{search},
+				WithoutParentheses: true,
+			}})
+		}
+	}
+	return db.Order("articles.published_at DESC NULLS LAST").Order("articles.created_at DESC")
+}
+
+// toArticleSummary is the listing response of an article, with an excerpt instead of the content
+func toArticleSummary(article model.Article) model.ArticleResponse {
+	response := toArticleResponse(article)
+	response.Excerpt = ArticleExcerpt(article.Content, ArticleExcerptLength)
+	response.Content = ""
+	return response
+}
+
+// ArticleExcerpt turns the start of an article's content into plain text of at most maxLength
+// characters, cut at a word boundary
+func ArticleExcerpt(content string, maxLength int) string {
+	text := htmlTagPattern.ReplaceAllString(content, " ")
+	text = markdownPattern.ReplaceAllString(text, "")
+	text = strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " "))
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text
+	}
+
+	runes := []rune(text)
+	// Leave room for the ellipsis
+	cut := string(runes[:maxLength-1])
+	if space := strings.LastIndex(cut, " "); space > 0 {
+		cut = cut[:space]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
@@ -2,21 +2,54 @@ package service
 
 import (
 	"app/src/model"
+	"app/src/validation"
+	"context"
 	"errors"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+// Article sort options, newest is the default
+const (
+	ArticleSortNewest  = "newest"
+	ArticleSortRating  = "rating"
+	ArticleSortPopular = "popular"
+)
+
+// ArticleStatusAll lists articles of every status, for editors
+const ArticleStatusAll = "all"
+
+// articleVisible matches the articles the public can read. Scheduled articles are visible as soon as
+// their PublishedAt passes, before the scheduler marks them published.
+const articleVisible = "articles.status IN ('published', 'scheduled') AND articles.published_at <= NOW()"
+
 type ArticlesService interface {
 	// Article methods
 	CreateArticle(ctx *fiber.Ctx, article *model.Article) (*model.Article, error)
-	GetArticles(ctx *fiber.Ctx) ([]model.ArticleResponse, error)
-	GetArticleByID(ctx *fiber.Ctx, articleID string) (*model.ArticleResponse, error)
+	GetArticles(ctx *fiber.Ctx, query *validation.QueryArticle) ([]model.ArticleResponse, int64, error)
+	GetRelatedArticles(ctx *fiber.Ctx, articleID string, limit int) ([]model.ArticleResponse, error)
+	GetArticleByID(ctx *fiber.Ctx, articleID string, includeUnpublished bool) (*model.ArticleResponse, error)
+	GetArticleBySlug(ctx *fiber.Ctx, slug string, includeUnpublished bool) (*model.ArticleResponse, error)
 	UpdateArticle(ctx *fiber.Ctx, articleID string, article *model.Article) (*model.Article, error)
 	DeleteArticle(ctx *fiber.Ctx, articleID string) error
 
+	// Reads and recommendations
+	RecordArticleRead(ctx *fiber.Ctx, userID uuid.UUID, articleID uuid.UUID) error
+	GetRecommendedArticles(ctx *fiber.Ctx, userID uuid.UUID, limit int) ([]model.ArticleRecommendation, error)
+
+	// Publishing workflow and revisions
+	UpdateArticleStatus(ctx *fiber.Ctx, articleID string, req *validation.UpdateArticleStatus) (*model.ArticleResponse, error)
+	GetArticleRevisions(ctx *fiber.Ctx, articleID string) ([]model.ArticleRevision, error)
+	DiffArticleRevision(ctx *fiber.Ctx, articleID string, revisionID string) (*model.ArticleRevisionDiff, error)
+	RestoreArticleRevision(ctx *fiber.Ctx, articleID string, revisionID string, editorID uuid.UUID) (*model.ArticleResponse, error)
+	PublishDueArticles(ctx context.Context) (int64, error)
+	StartPublishScheduler(ctx context.Context, interval time.Duration)
+
 	// Article Category methods
 	CreateArticleCategory(ctx *fiber.Ctx, category *model.ArticleCategory) (*model.ArticleCategory, error)
 	GetArticleCategories(ctx *fiber.Ctx) ([]model.ArticleCategory, error)
@@ -24,68 +57,146 @@ type ArticlesService interface {
 }
 
 type articlesService struct {
-	Log *logrus.Logger
-	DB  *gorm.DB
+	Log      *logrus.Logger
+	DB       *gorm.DB
+	Validate *validator.Validate
 }
 
-func NewArticlesService(db *gorm.DB) ArticlesService {
+func NewArticlesService(db *gorm.DB, validate *validator.Validate) ArticlesService {
 	return &articlesService{
-		Log: logrus.New(),
-		DB:  db,
+		Log:      logrus.New(),
+		DB:       db,
+		Validate: validate,
 	}
 }
 
+// CreateArticle stores a draft, or a scheduled or published article when PublishedAt is set in the
+// future or in the past. Other statuses are reached through UpdateArticleStatus.
 func (s *articlesService) CreateArticle(ctx *fiber.Ctx, article *model.Article) (*model.Article, error) {
+	tags, err := NormalizeArticleTags(article.Tags)
+	if err != nil {
+		return nil, err
+	}
+	article.ArticleTags = articleTags(article.ID, tags)
+
+	article.Status = model.ArticleStatusDraft
+	if article.PublishedAt != nil {
+		article.Status = publishedStatus(*article.PublishedAt, time.Now())
+	}
+
 	if err := s.DB.WithContext(ctx.Context()).Create(article).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fiber.NewError(fiber.StatusConflict, "Slug is already in use")
+		}
 		s.Log.Errorf("Failed to create article: %+v", err)
 		return nil, err
 	}
 	return article, nil
 }
 
-func (s *articlesService) GetArticles(ctx *fiber.Ctx) ([]model.ArticleResponse, error) {
+// GetArticles returns a page of the public articles matching the search and filters of query, or of the
+// articles of query.Status for editors. Articles come with an excerpt instead of their content.
+func (s *articlesService) GetArticles(ctx *fiber.Ctx, query *validation.QueryArticle) ([]model.ArticleResponse, int64, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, 0, err
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = 10
+	}
+
+	db := filterArticles(s.DB.WithContext(ctx.Context()).Model(&model.Article{}), query)
+
+	var totalResults int64
+	if err := db.Session(&gorm.Session{}).Count(&totalResults).Error; err != nil {
+		s.Log.Errorf("Failed to count articles: %+v", err)
+		return nil, 0, err
+	}
+
 	var articles []model.Article
-	if err := s.DB.WithContext(ctx.Context()).
+	if err := sortArticles(db, query).
+		Select(articleListColumns).
 		Preload("Category"). // Preload the Category relationship
-		Order("created_at DESC").
+		Preload("ArticleTags", orderArticleTags).
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
 		Find(&articles).Error; err != nil {
 		s.Log.Errorf("Failed to get articles: %+v", err)
-		return nil, err
+		return nil, 0, err
 	}
 
-	// Transform to response with category name
-	var responses []model.ArticleResponse
+	responses := make([]model.ArticleResponse, 0, len(articles))
 	for _, article := range articles {
-		response := model.ArticleResponse{
-			ID:          article.ID,
-			UserID:      article.UserID,
-			Title:       article.Title,
-			CategoryID:  article.CategoryID,
-			Slug:        article.Slug,
-			Image:       article.Image,
-			Content:     article.Content,
-			PublishedAt: article.PublishedAt,
-			CreatedAt:   article.CreatedAt,
-			UpdatedAt:   article.UpdatedAt,
-		}
+		responses = append(responses, toArticleSummary(article))
+	}
+	return responses, totalResults, nil
+}
+
+// GetRelatedArticles returns up to limit public articles sharing the article's category or words of its
+// title, the most related first
+func (s *articlesService) GetRelatedArticles(ctx *fiber.Ctx, articleID string, limit int) ([]model.ArticleResponse, error) {
+	if limit < 1 || limit > 20 {
+		limit = 5
+	}
 
-		// Add category name if category exists and was preloaded
-		if article.Category != nil {
-			response.CategoryName = article.Category.Name
+	source := new(model.Article)
+	if err := s.DB.WithContext(ctx.Context()).
+		Where("articles.id = ?", articleID).
+		Where(articleVisible).
+		First(source).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Article not found")
 		}
+		s.Log.Errorf("Failed to get article: %+v", err)
+		return nil, err
+	}
 
-		responses = append(responses, response)
+	var articles []model.Article
+	if err := s.DB.WithContext(ctx.Context()).
+		Model(&model.Article{}).
+		Select(articleListColumns+", "+articleRelatedScore+" AS related_score", source.CategoryID, source.Title).
+		Preload("Category").
+		Preload("ArticleTags", orderArticleTags).
+		Where(articleVisible).
+		Where("articles.id <> ?", source.ID).
+		Where("("+articleRelatedScore+") > 0", source.CategoryID, source.Title).
+		Order("related_score DESC").
+		Order("articles.published_at DESC").
+		Limit(limit).
+		Find(&articles).Error; err != nil {
+		s.Log.Errorf("Failed to get related articles: %+v", err)
+		return nil, err
 	}
 
+	responses := make([]model.ArticleResponse, 0, len(articles))
+	for _, article := range articles {
+		responses = append(responses, toArticleSummary(article))
+	}
 	return responses, nil
 }
 
-func (s *articlesService) GetArticleByID(ctx *fiber.Ctx, articleID string) (*model.ArticleResponse, error) {
-	var article model.Article
-	if err := s.DB.WithContext(ctx.Context()).
+func (s *articlesService) GetArticleByID(ctx *fiber.Ctx, articleID string, includeUnpublished bool) (*model.ArticleResponse, error) {
+	return s.getArticle(ctx, "articles.id = ?", articleID, includeUnpublished)
+}
+
+func (s *articlesService) GetArticleBySlug(ctx *fiber.Ctx, slug string, includeUnpublished bool) (*model.ArticleResponse, error) {
+	return s.getArticle(ctx, "articles.slug = ?", slug, includeUnpublished)
+}
+
+// getArticle loads the article matching the condition, unpublished articles are only found for editors
+func (s *articlesService) getArticle(ctx *fiber.Ctx, condition string, value string, includeUnpublished bool) (*model.ArticleResponse, error) {
+	db := s.DB.WithContext(ctx.Context()).
 		Preload("Category"). // Preload the Category relationship
-		Where("id = ?", articleID).
-		First(&article).Error; err != nil {
+		Preload("ArticleTags", orderArticleTags).
+		Where(condition, value)
+	if !includeUnpublished {
+		db = db.Where(articleVisible)
+	}
+
+	var article model.Article
+	if err := db.First(&article).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fiber.NewError(fiber.StatusNotFound, "Article not found")
 		}
@@ -93,18 +204,36 @@ func (s *articlesService) GetArticleByID(ctx *fiber.Ctx, articleID string) (*mod
 		return nil, err
 	}
 
-	// Create response with article data
+	response := toArticleResponse(article)
+	return &response, nil
+}
+
+// toArticleResponse copies an article into its API response, with the category name when the category
+// was preloaded. A scheduled article whose PublishedAt has passed is reported as published.
+func toArticleResponse(article model.Article) model.ArticleResponse {
 	response := model.ArticleResponse{
-		ID:          article.ID,
-		UserID:      article.UserID,
-		Title:       article.Title,
-		CategoryID:  article.CategoryID,
-		Slug:        article.Slug,
-		Image:       article.Image,
-		Content:     article.Content,
-		PublishedAt: article.PublishedAt,
-		CreatedAt:   article.CreatedAt,
-		UpdatedAt:   article.UpdatedAt,
+		ID:            article.ID,
+		UserID:        article.UserID,
+		Title:         article.Title,
+		CategoryID:    article.CategoryID,
+		Slug:          article.Slug,
+		Tags:          make([]string, 0, len(article.ArticleTags)),
+		Image:         article.Image,
+		Content:       article.Content,
+		Status:        article.Status,
+		PublishedAt:   article.PublishedAt,
+		RatingAverage: article.RatingAverage,
+		RatingCount:   article.RatingCount,
+		FavoriteCount: article.FavoriteCount,
+		CreatedAt:     article.CreatedAt,
+		UpdatedAt:     article.UpdatedAt,
+	}
+	if articleLive(&article, time.Now()) {
+		response.Status = model.ArticleStatusPublished
+	}
+
+	for _, tag := range article.ArticleTags {
+		response.Tags = append(response.Tags, tag.Tag)
 	}
 
 	// Add category name if category exists and was preloaded
@@ -112,49 +241,86 @@ func (s *articlesService) GetArticleByID(ctx *fiber.Ctx, articleID string) (*mod
 		response.CategoryName = article.Category.Name
 	}
 
-	return &response, nil
+	return response
 }
 
+// UpdateArticle changes the fields given in the request, article.UserID being the editor. The previous
+// version of a live article is kept as a revision. Changing PublishedAt of a scheduled or published
+// article moves it between the two. Tags are replaced when given, an empty list removes them.
 func (s *articlesService) UpdateArticle(ctx *fiber.Ctx, articleID string, article *model.Article) (*model.Article, error) {
-	existingArticle := new(model.Article)
-	if err := s.DB.WithContext(ctx.Context()).
-		Where("id = ?", articleID).
-		First(existingArticle).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fiber.NewError(fiber.StatusNotFound, "Article not found")
+	var tags []string
+	if article.Tags != nil {
+		normalized, err := NormalizeArticleTags(article.Tags)
+		if err != nil {
+			return nil, err
 		}
-		s.Log.Errorf("Failed to find article: %+v", err)
-		return nil, err
+		tags = normalized
 	}
 
-	updates := make(map[string]interface{})
-	if article.Title != "" {
-		updates["title"] = article.Title
-	}
-	if article.CategoryID != nil {
-		updates["category_id"] = article.CategoryID
-	}
-	if article.Slug != "" {
-		updates["slug"] = article.Slug
-	}
-	if article.Image != nil {
-		updates["image"] = article.Image
-	}
-	if article.Content != "" {
-		updates["content"] = article.Content
-	}
-	if article.PublishedAt != nil {
-		updates["published_at"] = article.PublishedAt
-	}
+	existingArticle := new(model.Article)
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		// The article is read under its lock, so the revision holds the version this edit replaces
+		locked, err := lockArticle(tx, articleID)
+		if err != nil {
+			return err
+		}
+		existingArticle = locked
 
-	if len(updates) == 0 {
-		return existingArticle, nil
-	}
+		updates := make(map[string]interface{})
+		if article.Title != "" && article.Title != existingArticle.Title {
+			updates["title"] = article.Title
+		}
+		if article.CategoryID != nil && !sameUUID(article.CategoryID, existingArticle.CategoryID) {
+			updates["category_id"] = article.CategoryID
+		}
+		if article.Image != nil && !sameString(article.Image, existingArticle.Image) {
+			updates["image"] = article.Image
+		}
+		if article.Content != "" && article.Content != existingArticle.Content {
+			updates["content"] = article.Content
+		}
+		// Only the fields a revision holds are worth one
+		revise := len(updates) > 0 && articleLive(existingArticle, time.Now())
 
-	if err := s.DB.WithContext(ctx.Context()).
-		Model(existingArticle).
-		Updates(updates).Error; err != nil {
-		s.Log.Errorf("Failed to update article: %+v", err)
+		if article.Slug != "" {
+			updates["slug"] = article.Slug
+		}
+
+		if article.PublishedAt != nil {
+			updates["published_at"] = article.PublishedAt
+			if existingArticle.Status == model.ArticleStatusScheduled || existingArticle.Status == model.ArticleStatusPublished {
+				updates["status"] = publishedStatus(*article.PublishedAt, time.Now())
+			}
+		}
+
+		if revise {
+			if err := saveArticleRevision(tx, existingArticle, article.UserID); err != nil {
+				return err
+			}
+		}
+		if tags != nil {
+			if err := tx.Where("article_id = ?", existingArticle.ID).Delete(&model.ArticleTag{}).Error; err != nil {
+				return err
+			}
+			if len(tags) > 0 {
+				if err := tx.Create(articleTags(existingArticle.ID, tags)).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Model(existingArticle).Updates(updates).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fiber.NewError(fiber.StatusConflict, "Slug is already in use")
+		}
+		var fiberErr *fiber.Error
+		if !errors.As(err, &fiberErr) {
+			s.Log.Errorf("Failed to update article: %+v", err)
+		}
 		return nil, err
 	}
 
@@ -162,9 +328,13 @@ func (s *articlesService) UpdateArticle(ctx *fiber.Ctx, articleID string, articl
 }
 
 func (s *articlesService) DeleteArticle(ctx *fiber.Ctx, articleID string) error {
-	if err := s.DB.WithContext(ctx.Context()).
-		Where("id = ?", articleID).
-		Delete(&model.Article{}).Error; err != nil {
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := deleteEngagements(tx, model.TargetArticle, articleID); err != nil {
+			return err
+		}
+		return tx.Where("id = ?", articleID).Delete(&model.Article{}).Error
+	})
+	if err != nil {
 		s.Log.Errorf("Failed to delete article: %+v", err)
 		return err
 	}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type BahanMakananService interface {
@@ -179,13 +181,26 @@ func (s *bahanMakananService) GetBahanMakananByKode(ctx *fiber.Ctx, kode string)
 	response, err := s.Client.GetBahanMakananByKode(ctx.Context(), kode)
 	if err != nil {
 		s.Log.Errorf("Failed to get bahan makanan by kode: %+v", err)
-		return nil, fiber.NewError(fiber.StatusNotFound, "Bahan makanan not found")
+		return nil, bahanMakananLookupError(err)
 	}
 
 	bahanMakanan := ConvertPbToModel(response.BahanMakanan)
 	return &bahanMakanan, nil
 }
 
+// bahanMakananLookupError tells a food the gRPC server doesn't have, a 404, apart from a server that
+// couldn't be reached, a 503, and anything else it failed with
+func bahanMakananLookupError(err error) error {
+	switch status.Code(err) {
+	case codes.NotFound:
+		return fiber.NewError(fiber.StatusNotFound, "Bahan makanan not found")
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled:
+		return fiber.NewError(fiber.StatusServiceUnavailable, "Bahan makanan service is unavailable")
+	default:
+		return fiber.NewError(fiber.StatusInternalServerError, "Failed to get bahan makanan")
+	}
+}
+
 func (s *bahanMakananService) GetBahanMakananById(ctx *fiber.Ctx, id uint32) (*model.BahanMakanan, error) {
 	response, err := s.Client.GetBahanMakananById(ctx.Context(), id)
 	if err != nil {
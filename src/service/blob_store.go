@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"app/src/config"
+)
+
+const (
+	BlobStoreLocal = "local"
+	BlobStoreS3    = "s3"
+)
+
+// ErrBlobNotFound is returned when a key has no blob
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobObject describes a stored blob
+type BlobObject struct {
+	Key        string
+	Size       int64
+	ModifiedAt time.Time
+}
+
+// BlobStore keeps uploaded files under slash separated keys. The local disk implementation serves them
+// through the static uploads route, the S3 one works with any S3 compatible storage such as MinIO.
+type BlobStore interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob, deleting a missing key isn't an error
+	Delete(ctx context.Context, key string) error
+	// List returns every blob whose key starts with prefix
+	List(ctx context.Context, prefix string) ([]BlobObject, error)
+	// URL is the public URL the blob is served from
+	URL(key string) string
+}
+
+// NewBlobStore returns the store selected by storage with its settings from the config, defaulting to
+// the local disk
+func NewBlobStore(ctx context.Context, storage string) (BlobStore, error) {
+	switch strings.ToLower(storage) {
+	case BlobStoreS3:
+		return NewS3BlobStore(ctx, S3BlobStoreConfig{
+			Endpoint:  config.S3Endpoint,
+			AccessKey: config.S3AccessKey,
+			SecretKey: config.S3SecretKey,
+			Bucket:    config.S3Bucket,
+			Region:    config.S3Region,
+			UseSSL:    config.S3UseSSL,
+			PublicURL: config.S3PublicURL,
+		})
+	default:
+		return NewLocalBlobStore(config.UploadDir, config.UploadBaseURL), nil
+	}
+}
+
+// cleanBlobKey rejects keys that would escape the store, such as absolute paths or ones with ..
+func cleanBlobKey(key string) (string, error) {
+	cleaned := path.Clean("/" + key)[1:]
+	if key == "" || cleaned != key || strings.Contains(key, "\\") {
+		return "", errors.New("invalid blob key: " + key)
+	}
+	return cleaned, nil
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"app/src/model"
+	"app/src/validation"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type BodyMetricsService interface {
+	GetBodyMetrics(c *fiber.Ctx, userID uuid.UUID, query *validation.QueryBodyMetrics) (*model.BodyMetrics, error)
+}
+
+type bodyMetricsService struct {
+	Log      *logrus.Logger
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewBodyMetricsService(db *gorm.DB, validate *validator.Validate) BodyMetricsService {
+	return &bodyMetricsService{
+		Log:      logrus.New(),
+		DB:       db,
+		Validate: validate,
+	}
+}
+
+const (
+	// Upper bounds of the Asia-Pacific BMI categories, underweight ends at MinHealthyBMI
+	bmiNormalMax     = 23.0
+	bmiOverweightMax = 25.0
+	bmiObese1Max     = 30.0
+	// idealBMIMax is the top of the normal range the ideal weight is taken from
+	idealBMIMax = 22.9
+
+	// Waist circumferences in cm from which Asian men and women have central obesity
+	centralObesityWaistMale   = 90.0
+	centralObesityWaistFemale = 80.0
+)
+
+// GetBodyMetrics derives the classified metrics of every weight and height record between from and to,
+// both inclusive and counted in the user's timezone. Without dates the whole history is returned.
+func (s *bodyMetricsService) GetBodyMetrics(c *fiber.Ctx, userID uuid.UUID, query *validation.QueryBodyMetrics) (*model.BodyMetrics, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, err
+	}
+
+	user := new(model.User)
+	if err := s.DB.WithContext(c.Context()).First(user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		s.Log.Errorf("Failed to get user: %+v", err)
+		return nil, err
+	}
+
+	location, err := RequestLocation(c, user)
+	if err != nil {
+		return nil, err
+	}
+	metrics := &model.BodyMetrics{Timezone: location.String(), Series: []model.BodyMetricsEntry{}}
+
+	db := s.DB.WithContext(c.Context()).Where("user_id = ?", userID)
+	if query.From != "" {
+		from, err := time.ParseInLocation("2006-01-02", query.From, location)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid from date")
+		}
+		metrics.From = &from
+		db = db.Where("recorded_at >= ?", from)
+	}
+	if query.To != "" {
+		to, err := time.ParseInLocation("2006-01-02", query.To, location)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid to date")
+		}
+		metrics.To = &to
+		db = db.Where("recorded_at < ?", to.AddDate(0, 0, 1))
+	}
+	if metrics.From != nil && metrics.To != nil && metrics.From.After(*metrics.To) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "The from date must not be after the to date")
+	}
+
+	var records []model.UsersWeightHeightHistory
+	if err := db.Order("recorded_at").Find(&records).Error; err != nil {
+		s.Log.Errorf("Failed to get weight and height records: %+v", err)
+		return nil, err
+	}
+
+	for _, record := range records {
+		if record.Height <= 0 || record.Weight <= 0 {
+			continue
+		}
+		metrics.Series = append(metrics.Series, CalculateBodyMetrics(user, record))
+	}
+	if len(metrics.Series) > 0 {
+		metrics.Latest = &metrics.Series[len(metrics.Series)-1]
+	}
+
+	if user.BirthDate == nil {
+		metrics.Missing = append(metrics.Missing, "birth_date")
+	}
+	if user.Gender == nil || (*user.Gender != model.Male && *user.Gender != model.Female) {
+		metrics.Missing = append(metrics.Missing, "gender")
+	}
+	metrics.Estimated = len(metrics.Missing) > 0
+
+	return metrics, nil
+}
+
+// CalculateBodyMetrics derives the BMI, its Asia-Pacific category, the ideal weight range and the BMR at the
+// time of the record, then the waist-to-height ratio and the fat and lean mass when the record has them
+func CalculateBodyMetrics(user *model.User, record model.UsersWeightHeightHistory) model.BodyMetricsEntry {
+	heightM := record.Height / 100
+	bmi := roundTo(record.Weight/(heightM*heightM), 1)
+
+	age := defaultAge
+	if user.BirthDate != nil && user.BirthDate.Before(record.RecordedAt) {
+		age = ageAt(*user.BirthDate, record.RecordedAt)
+	}
+
+	entry := model.BodyMetricsEntry{
+		RecordID:       record.ID.String(),
+		RecordedAt:     record.RecordedAt,
+		Weight:         record.Weight,
+		Height:         record.Height,
+		BMI:            bmi,
+		BMICategory:    ClassifyBMI(bmi),
+		IdealWeightMin: roundTo(MinHealthyBMI*heightM*heightM, 1),
+		IdealWeightMax: roundTo(idealBMIMax*heightM*heightM, 1),
+		BMR:            mifflinStJeorBMR(record.Weight, record.Height, age, user.Gender),
+	}
+
+	if record.WaistCircumference != nil && *record.WaistCircumference > 0 {
+		waist := *record.WaistCircumference
+		ratio := roundTo(waist/record.Height, 2)
+		entry.WaistCircumference = &waist
+		entry.WaistToHeightRatio = &ratio
+		entry.WaistCategory = ClassifyWaistToHeightRatio(ratio)
+
+		if user.Gender != nil && (*user.Gender == model.Male || *user.Gender == model.Female) {
+			cutoff := centralObesityWaistFemale
+			if *user.Gender == model.Male {
+				cutoff = centralObesityWaistMale
+			}
+			centralObesity := waist >= cutoff
+			entry.CentralObesity = &centralObesity
+		}
+	}
+
+	if record.BodyFatPercentage != nil && *record.BodyFatPercentage > 0 {
+		bodyFat := *record.BodyFatPercentage
+		fatMass := roundTo(record.Weight*bodyFat/100, 1)
+		leanMass := roundTo(record.Weight-fatMass, 1)
+		entry.BodyFatPercentage = &bodyFat
+		entry.FatMass = &fatMass
+		entry.LeanMass = &leanMass
+	}
+
+	return entry
+}
+
+// ClassifyBMI returns the WHO Asia-Pacific category of bmi
+func ClassifyBMI(bmi float64) string {
+	switch {
+	case bmi < MinHealthyBMI:
+		return model.BMIUnderweight
+	case bmi < bmiNormalMax:
+		return model.BMINormal
+	case bmi < bmiOverweightMax:
+		return model.BMIOverweight
+	case bmi < bmiObese1Max:
+		return model.BMIObese1
+	default:
+		return model.BMIObese2
+	}
+}
+
+// ClassifyWaistToHeightRatio returns the risk category of a waist-to-height ratio
+func ClassifyWaistToHeightRatio(ratio float64) string {
+	switch {
+	case ratio < 0.5:
+		return model.WaistRatioHealthy
+	case ratio < 0.6:
+		return model.WaistRatioIncreased
+	default:
+		return model.WaistRatioHigh
+	}
+}
+
+// validateBodyComposition rejects waist circumferences and body fat percentages no adult can have
+func validateBodyComposition(record *model.UsersWeightHeightHistory) error {
+	if record.WaistCircumference != nil && (*record.WaistCircumference < 30 || *record.WaistCircumference > 250) {
+		return fiber.NewError(fiber.StatusBadRequest, "The waist circumference must be between 30 and 250 cm")
+	}
+	if record.BodyFatPercentage != nil && (*record.BodyFatPercentage < 2 || *record.BodyFatPercentage > 75) {
+		return fiber.NewError(fiber.StatusBadRequest, "The body fat percentage must be between 2 and 75")
+	}
+	return nil
+}
@@ -0,0 +1,442 @@
+package service
+
+import (
+	"app/src/model"
+	"app/src/validation"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EngagementService handles favorites, ratings and reviews of recipes and articles. The rating average,
+// rating count and favorite count of the content are recomputed in the same transaction as every change,
+// so listings can sort on them without aggregating.
+type EngagementService interface {
+	AddFavorite(ctx *fiber.Ctx, userID uuid.UUID, targetType string, targetID string) error
+	RemoveFavorite(ctx *fiber.Ctx, userID uuid.UUID, targetType string, targetID string) error
+	RateContent(ctx *fiber.Ctx, userID uuid.UUID, targetType string, targetID string, req *validation.RateContent) (*model.UsersStar, error)
+	DeleteRating(ctx *fiber.Ctx, userID uuid.UUID, targetType string, targetID string) error
+	GetReviews(ctx *fiber.Ctx, targetType string, targetID string, query *validation.QueryReview) ([]model.UsersStar, int64, error)
+	GetFavoriteRecipes(ctx *fiber.Ctx, userID uuid.UUID, query *validation.QueryFavorite) ([]model.Recipe, int64, error)
+	GetFavoriteArticles(ctx *fiber.Ctx, userID uuid.UUID, query *validation.QueryFavorite) ([]model.ArticleResponse, int64, error)
+
+	// Moderation
+	GetAllReviews(ctx *fiber.Ctx, query *validation.QueryReview) ([]model.UsersStar, int64, error)
+	HideReview(ctx *fiber.Ctx, reviewID string, adminID uuid.UUID, req *validation.HideReview) (*model.UsersStar, error)
+	UnhideReview(ctx *fiber.Ctx, reviewID string) (*model.UsersStar, error)
+}
+
+type engagementService struct {
+	Log      *logrus.Logger
+	DB       *gorm.DB
+	Validate *validator.Validate
+}
+
+func NewEngagementService(db *gorm.DB, validate *validator.Validate) EngagementService {
+	return &engagementService{
+		Log:      logrus.New(),
+		DB:       db,
+		Validate: validate,
+	}
+}
+
+// engagementTargets maps the content users can engage with to its table, the condition it must meet to be
+// engaged with and its not found message
+var engagementTargets = map[string]struct {
+	table    string
+	visible  string
+	notFound string
+}{
+	model.TargetRecipe:  {table: "recipes", visible: "TRUE", notFound: "Recipe not found"},
+	model.TargetArticle: {table: "articles", visible: articleVisible, notFound: "Article not found"},
+}
+
+// refreshRatingAggregates recomputes the rating average and count of the content from its visible ratings
+const refreshRatingAggregates = `UPDATE %[1]s SET
+	rating_average = COALESCE((SELECT ROUND(AVG(stars)::numeric, 2) FROM users_stars
+		WHERE target_type = ? AND target_id = %[1]s.id AND NOT hidden), 0),
+	rating_count = (SELECT COUNT(*) FROM users_stars
+		WHERE target_type = ? AND target_id = %[1]s.id AND NOT hidden)
+	WHERE id = ?`
+
+// refreshFavoriteCount recomputes how many users have the content in their favorites
+const refreshFavoriteCount = `UPDATE %[1]s SET
+	favorite_count = (SELECT COUNT(*) FROM user_favorites WHERE target_type = ? AND target_id = %[1]s.id)
+	WHERE id = ?`
+
+func (s *engagementService) AddFavorite(ctx *fiber.Ctx, userID uuid.UUID, targetType string, targetID string) error {
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := lockEngagementTarget(tx, targetType, targetID, true); err != nil {
+			return err
+		}
+
+		// Adding a favorite twice keeps the first one
+		favorite := &model.UserFavorite{UserID: userID, TargetType: targetType, TargetID: uuid.MustParse(targetID)}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(favorite).Error; err != nil {
+			return err
+		}
+		return updateFavoriteCount(tx, targetType, targetID)
+	})
+	if err != nil {
+		return s.engagementError("add favorite", err)
+	}
+	return nil
+}
+
+func (s *engagementService) RemoveFavorite(ctx *fiber.Ctx, userID uuid.UUID, targetType string, targetID string) error {
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := lockEngagementTarget(tx, targetType, targetID, false); err != nil {
+			return err
+		}
+
+		if err := tx.
+			Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID).
+			Delete(&model.UserFavorite{}).Error; err != nil {
+			return err
+		}
+		return updateFavoriteCount(tx, targetType, targetID)
+	})
+	if err != nil {
+		return s.engagementError("remove favorite", err)
+	}
+	return nil
+}
+
+// RateContent stores the user's rating of the content, replacing the previous one. A review hidden by a
+// moderator stays hidden when it's edited.
+func (s *engagementService) RateContent(ctx *fiber.Ctx, userID uuid.UUID, targetType string, targetID string, req *validation.RateContent) (*model.UsersStar, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := lockEngagementTarget(tx, targetType, targetID, true); err != nil {
+			return err
+		}
+
+		rating := &model.UsersStar{
+			UserID:     userID,
+			TargetType: targetType,
+			TargetID:   uuid.MustParse(targetID),
+			Stars:      req.Stars,
+			Review:     req.Review,
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "target_type"}, {Name: "target_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"stars", "review", "updated_at"}),
+		}).Create(rating).Error; err != nil {
+			return err
+		}
+		return updateRatingAggregates(tx, targetType, targetID)
+	})
+	if err != nil {
+		return nil, s.engagementError("rate content", err)
+	}
+
+	rating := new(model.UsersStar)
+	if err := reviewsWithUserName(s.DB.WithContext(ctx.Context())).
+		Where("users_stars.user_id = ? AND users_stars.target_type = ? AND users_stars.target_id = ?", userID, targetType, targetID).
+		Take(rating).Error; err != nil {
+		s.Log.Errorf("Failed to get rating: %+v", err)
+		return nil, err
+	}
+	return rating, nil
+}
+
+func (s *engagementService) DeleteRating(ctx *fiber.Ctx, userID uuid.UUID, targetType string, targetID string) error {
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := lockEngagementTarget(tx, targetType, targetID, false); err != nil {
+			return err
+		}
+
+		result := tx.
+			Where("user_id = ? AND target_type = ? AND target_id = ?", userID, targetType, targetID).
+			Delete(&model.UsersStar{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fiber.NewError(fiber.StatusNotFound, "Rating not found")
+		}
+		return updateRatingAggregates(tx, targetType, targetID)
+	})
+	if err != nil {
+		return s.engagementError("delete rating", err)
+	}
+	return nil
+}
+
+// GetReviews returns a page of the visible ratings of the content, newest first
+func (s *engagementService) GetReviews(ctx *fiber.Ctx, targetType string, targetID string, query *validation.QueryReview) ([]model.UsersStar, int64, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, 0, err
+	}
+	normalizeReviewQuery(query)
+
+	target := engagementTargets[targetType]
+	var exists int64
+	if err := s.DB.WithContext(ctx.Context()).Table(target.table).Where("id = ?", targetID).Where(target.visible).Count(&exists).Error; err != nil {
+		s.Log.Errorf("Failed to find %s: %+v", targetType, err)
+		return nil, 0, err
+	}
+	if exists == 0 {
+		return nil, 0, fiber.NewError(fiber.StatusNotFound, target.notFound)
+	}
+
+	db := s.DB.WithContext(ctx.Context()).
+		Model(&model.UsersStar{}).
+		Where("users_stars.target_type = ? AND users_stars.target_id = ? AND NOT users_stars.hidden", targetType, targetID)
+	return s.pageReviews(db, query)
+}
+
+// GetFavoriteRecipes returns a page of the user's favorite recipes, the latest favorite first
+func (s *engagementService) GetFavoriteRecipes(ctx *fiber.Ctx, userID uuid.UUID, query *validation.QueryFavorite) ([]model.Recipe, int64, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, 0, err
+	}
+	normalizeFavoriteQuery(query)
+
+	db := s.DB.WithContext(ctx.Context()).
+		Model(&model.Recipe{}).
+		Joins("JOIN user_favorites ON user_favorites.target_id = recipes.id AND user_favorites.target_type = ? AND user_favorites.user_id = ?",
+			model.TargetRecipe, userID)
+
+	var totalResults int64
+	if err := db.Session(&gorm.Session{}).Count(&totalResults).Error; err != nil {
+		s.Log.Errorf("Failed to count favorite recipes: %+v", err)
+		return nil, 0, err
+	}
+
+	var recipes []model.Recipe
+	if err := db.
+		Order("user_favorites.created_at DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&recipes).Error; err != nil {
+		s.Log.Errorf("Failed to get favorite recipes: %+v", err)
+		return nil, 0, err
+	}
+	return recipes, totalResults, nil
+}
+
+// GetFavoriteArticles returns a page of the user's favorite articles with excerpts, the latest favorite first
+func (s *engagementService) GetFavoriteArticles(ctx *fiber.Ctx, userID uuid.UUID, query *validation.QueryFavorite) ([]model.ArticleResponse, int64, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, 0, err
+	}
+	normalizeFavoriteQuery(query)
+
+	db := s.DB.WithContext(ctx.Context()).
+		Model(&model.Article{}).
+		Joins("JOIN user_favorites ON user_favorites.target_id = articles.id AND user_favorites.target_type = ? AND user_favorites.user_id = ?",
+			model.TargetArticle, userID)
+	// Articles unpublished since they were favorited are left out
+	db = db.Where(articleVisible)
+
+	var totalResults int64
+	if err := db.Session(&gorm.Session{}).Count(&totalResults).Error; err != nil {
+		s.Log.Errorf("Failed to count favorite articles: %+v", err)
+		return nil, 0, err
+	}
+
+	var articles []model.Article
+	if err := db.
+		Select(articleListColumns).
+		Preload("Category").
+		Preload("ArticleTags", orderArticleTags).
+		Order("user_favorites.created_at DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&articles).Error; err != nil {
+		s.Log.Errorf("Failed to get favorite articles: %+v", err)
+		return nil, 0, err
+	}
+
+	responses := make([]model.ArticleResponse, 0, len(articles))
+	for _, article := range articles {
+		responses = append(responses, toArticleSummary(article))
+	}
+	return responses, totalResults, nil
+}
+
+// GetAllReviews returns a page of all ratings for moderation, hidden ones included, newest first
+func (s *engagementService) GetAllReviews(ctx *fiber.Ctx, query *validation.QueryReview) ([]model.UsersStar, int64, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, 0, err
+	}
+	normalizeReviewQuery(query)
+
+	db := s.DB.WithContext(ctx.Context()).Model(&model.UsersStar{})
+	if query.TargetType != "" {
+		db = db.Where("users_stars.target_type = ?", query.TargetType)
+	}
+	if query.Hidden != "" {
+		db = db.Where("users_stars.hidden = ?", query.Hidden == "true")
+	}
+	return s.pageReviews(db, query)
+}
+
+// HideReview takes a rating down, it no longer shows in the content's reviews nor counts in its aggregates
+func (s *engagementService) HideReview(ctx *fiber.Ctx, reviewID string, adminID uuid.UUID, req *validation.HideReview) (*model.UsersStar, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return s.moderateReview(ctx, reviewID, map[string]interface{}{
+		"hidden":        true,
+		"hidden_reason": req.Reason,
+		"hidden_by":     adminID,
+		"hidden_at":     now,
+	})
+}
+
+// UnhideReview puts a hidden rating back
+func (s *engagementService) UnhideReview(ctx *fiber.Ctx, reviewID string) (*model.UsersStar, error) {
+	return s.moderateReview(ctx, reviewID, map[string]interface{}{
+		"hidden":        false,
+		"hidden_reason": nil,
+		"hidden_by":     nil,
+		"hidden_at":     nil,
+	})
+}
+
+// moderateReview applies the moderation updates to the rating and refreshes the aggregates of its content
+func (s *engagementService) moderateReview(ctx *fiber.Ctx, reviewID string, updates map[string]interface{}) (*model.UsersStar, error) {
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		review := new(model.UsersStar)
+		if err := tx.Where("id = ?", reviewID).First(review).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "Review not found")
+			}
+			return err
+		}
+		if err := lockEngagementTarget(tx, review.TargetType, review.TargetID.String(), false); err != nil {
+			return err
+		}
+
+		if err := tx.Model(review).Updates(updates).Error; err != nil {
+			return err
+		}
+		return updateRatingAggregates(tx, review.TargetType, review.TargetID.String())
+	})
+	if err != nil {
+		return nil, s.engagementError("moderate review", err)
+	}
+
+	review := new(model.UsersStar)
+	if err := reviewsWithUserName(s.DB.WithContext(ctx.Context())).
+		Where("users_stars.id = ?", reviewID).
+		Take(review).Error; err != nil {
+		s.Log.Errorf("Failed to get review: %+v", err)
+		return nil, err
+	}
+	return review, nil
+}
+
+// pageReviews counts and loads a page of the ratings selected by db
+func (s *engagementService) pageReviews(db *gorm.DB, query *validation.QueryReview) ([]model.UsersStar, int64, error) {
+	var totalResults int64
+	if err := db.Session(&gorm.Session{}).Count(&totalResults).Error; err != nil {
+		s.Log.Errorf("Failed to count reviews: %+v", err)
+		return nil, 0, err
+	}
+
+	var reviews []model.UsersStar
+	if err := reviewsWithUserName(db).
+		Order("users_stars.created_at DESC").
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
+		Find(&reviews).Error; err != nil {
+		s.Log.Errorf("Failed to get reviews: %+v", err)
+		return nil, 0, err
+	}
+	return reviews, totalResults, nil
+}
+
+// engagementError logs unexpected errors, the ones meant for the client are returned as is
+func (s *engagementService) engagementError(action string, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return err
+	}
+	s.Log.Errorf("Failed to %s: %+v", action, err)
+	return err
+}
+
+// reviewsWithUserName selects ratings along with the name of the user who wrote them
+func reviewsWithUserName(db *gorm.DB) *gorm.DB {
+	return db.
+		Select("users_stars.*, users.name AS user_name").
+		Joins("LEFT JOIN users ON users.id = users_stars.user_id")
+}
+
+// lockEngagementTarget locks the content's row for the rest of the transaction, so concurrent changes
+// recompute its aggregates one after the other, and returns 404 when it doesn't exist. New favorites and
+// ratings need content the public can see, removing them works on unpublished content too.
+func lockEngagementTarget(tx *gorm.DB, targetType string, targetID string, visibleOnly bool) error {
+	target, ok := engagementTargets[targetType]
+	if !ok {
+		return fiber.NewError(fiber.StatusBadRequest, "Unknown content type")
+	}
+
+	db := tx.Table(target.table).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ?", targetID)
+	if visibleOnly {
+		db = db.Where(target.visible)
+	}
+
+	var row struct{ ID uuid.UUID }
+	if err := db.Take(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fiber.NewError(fiber.StatusNotFound, target.notFound)
+		}
+		return err
+	}
+	return nil
+}
+
+func updateRatingAggregates(tx *gorm.DB, targetType string, targetID string) error {
+	table := engagementTargets[targetType].table
+	return tx.Exec(fmt.Sprintf(refreshRatingAggregates, table), targetType, targetType, targetID).Error
+}
+
+func updateFavoriteCount(tx *gorm.DB, targetType string, targetID string) error {
+	table := engagementTargets[targetType].table
+	return tx.Exec(fmt.Sprintf(refreshFavoriteCount, table), targetType, targetID).Error
+}
+
+// deleteEngagements removes the favorites and ratings of content that is being deleted
+func deleteEngagements(tx *gorm.DB, targetType string, targetID string) error {
+	if err := tx.Where("target_type = ? AND target_id = ?", targetType, targetID).Delete(&model.UserFavorite{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("target_type = ? AND target_id = ?", targetType, targetID).Delete(&model.UsersStar{}).Error
+}
+
+func normalizeReviewQuery(query *validation.QueryReview) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = 10
+	}
+}
+
+func normalizeFavoriteQuery(query *validation.QueryFavorite) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = 10
+	}
+}
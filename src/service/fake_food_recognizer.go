@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// FakeFoodRecognizer returns canned results without calling any external API
+type FakeFoodRecognizer struct {
+	Recognition FoodRecognition
+	Nutrition   Nutrient
+	// Per100g holds nutrients per 100 grams by food name, used when confirming selections
+	Per100g map[string]Nutrient
+}
+
+func NewFakeFoodRecognizer() *FakeFoodRecognizer {
+	return &FakeFoodRecognizer{
+		Recognition: FoodRecognition{
+			Segments: []FoodSegment{
+				{Candidates: []FoodCandidate{
+					{Name: "white rice", Probability: 0.92},
+					{Name: "fried rice", Probability: 0.05},
+					{Name: "rice noodles", Probability: 0.02},
+				}},
+				{Candidates: []FoodCandidate{
+					{Name: "fried chicken", Probability: 0.81},
+					{Name: "chicken satay", Probability: 0.11},
+					{Name: "grilled chicken", Probability: 0.06},
+				}},
+			},
+		},
+		Nutrition: Nutrient{
+			Calories: NutrientDetail{Quantity: 545.5, Unit: "kcal"},
+			Protein:  NutrientDetail{Quantity: 31.2, Unit: "g"},
+			Carbs:    NutrientDetail{Quantity: 62.4, Unit: "g"},
+			Fat:      NutrientDetail{Quantity: 18.7, Unit: "g"},
+			Details: map[string]NutrientDetail{
+				"FIBTG": {Quantity: 1.4, Unit: "g"},
+				"NA":    {Quantity: 812, Unit: "mg"},
+				"CA":    {Quantity: 38, Unit: "mg"},
+				"FE":    {Quantity: 2.3, Unit: "mg"},
+			},
+		},
+		Per100g: map[string]Nutrient{
+			"white rice":    fakeNutrient(130, 2.7, 28.2, 0.3, 1, 0.2),
+			"fried rice":    fakeNutrient(163, 6.3, 20.6, 6.2, 400, 0.9),
+			"fried chicken": fakeNutrient(246, 24.5, 8.9, 12.7, 380, 1.2),
+			"chicken satay": fakeNutrient(225, 20.1, 6.4, 13.2, 520, 1.5),
+		},
+	}
+}
+
+func fakeNutrient(calories, protein, carbs, fat, sodium, iron float64) Nutrient {
+	return Nutrient{
+		Calories: NutrientDetail{Quantity: calories, Unit: "kcal"},
+		Protein:  NutrientDetail{Quantity: protein, Unit: "g"},
+		Carbs:    NutrientDetail{Quantity: carbs, Unit: "g"},
+		Fat:      NutrientDetail{Quantity: fat, Unit: "g"},
+		Details: map[string]NutrientDetail{
+			"NA": {Quantity: sodium, Unit: "mg"},
+			"FE": {Quantity: iron, Unit: "mg"},
+		},
+	}
+}
+
+func (f *FakeFoodRecognizer) Segment(_ context.Context, image io.Reader, _ string) (*FoodRecognition, error) {
+	// Drain the upload so callers behave the same as with a real provider
+	if _, err := io.Copy(io.Discard, image); err != nil {
+		return nil, err
+	}
+
+	recognition := f.Recognition
+	recognition.ImageID = uuid.New().String()
+	return &recognition, nil
+}
+
+func (f *FakeFoodRecognizer) Nutrients(_ context.Context, _ *FoodRecognition) (Nutrient, error) {
+	return f.Nutrition, nil
+}
+
+func (f *FakeFoodRecognizer) Confirm(_ context.Context, _ *FoodRecognition, selections []FoodSelection) (Nutrient, error) {
+	total := emptyNutrient()
+	for _, selection := range selections {
+		per100g, ok := f.Per100g[strings.ToLower(selection.Name)]
+		if !ok {
+			per100g = fakeNutrient(150, 5, 20, 5, 100, 0.5)
+		}
+		total = total.Add(per100g.Scale(selection.Grams / 100))
+	}
+	return total, nil
+}
@@ -0,0 +1,517 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/grpc"
+	"app/src/model"
+	"app/src/validation"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+const (
+	foodMatchMinScore     = 0.35
+	foodMatchesPerSegment = 3
+	// foodCatalogFetchTimeout bounds a catalog refresh, it no longer ends with the request that started it
+	foodCatalogFetchTimeout = 30 * time.Second
+)
+
+// FoodCatalog lists the TKPI foods recognized dishes are matched against
+type FoodCatalog interface {
+	ListBahanMakanan(ctx context.Context) ([]model.BahanMakanan, error)
+}
+
+type grpcFoodCatalog struct {
+	Client *grpc.BahanMakananClient
+}
+
+func NewGrpcFoodCatalog(client *grpc.BahanMakananClient) FoodCatalog {
+	return &grpcFoodCatalog{Client: client}
+}
+
+func (g *grpcFoodCatalog) ListBahanMakanan(ctx context.Context) ([]model.BahanMakanan, error) {
+	if g.Client == nil {
+		return nil, errors.New("bahan makanan service is not connected")
+	}
+
+	response, err := g.Client.GetAllBahanMakanan(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bahanMakananList := make([]model.BahanMakanan, 0, len(response.BahanMakanan))
+	for _, pbBahanMakanan := range response.BahanMakanan {
+		bahanMakananList = append(bahanMakananList, ConvertPbToModel(pbBahanMakanan))
+	}
+	return bahanMakananList, nil
+}
+
+type FoodMatchingService interface {
+	MatchRecognition(ctx context.Context, recognition *FoodRecognition) ([]model.MealHistoryFood, error)
+	MatchSelections(ctx context.Context, selections []FoodSelection) ([]model.MealHistoryFood, error)
+	GetBahanMakanan(ctx context.Context, kode string) (*model.BahanMakanan, error)
+	GetAliases(c *fiber.Ctx) ([]model.FoodAlias, error)
+	CreateAlias(c *fiber.Ctx, req *validation.CreateFoodAlias) (*model.FoodAlias, error)
+	DeleteAlias(c *fiber.Ctx, id string) error
+}
+
+type foodMatchingService struct {
+	Log      *logrus.Logger
+	DB       *gorm.DB
+	Validate *validator.Validate
+	Catalog  FoodCatalog
+
+	mu       sync.Mutex
+	catalog  *foodCatalogSnapshot
+	loadedAt time.Time
+	refresh  singleflight.Group
+}
+
+// foodCatalogSnapshot is an immutable copy of the TKPI table with pre-tokenized names
+type foodCatalogSnapshot struct {
+	foods  []model.BahanMakanan
+	tokens [][]string
+	byKode map[string]model.BahanMakanan
+}
+
+func NewFoodMatchingService(db *gorm.DB, validate *validator.Validate, catalog FoodCatalog) FoodMatchingService {
+	return &foodMatchingService{
+		Log:      logrus.New(),
+		DB:       db,
+		Validate: validate,
+		Catalog:  catalog,
+	}
+}
+
+// foodMatch is a scored TKPI entry for one recognized name
+type foodMatch struct {
+	food        model.BahanMakanan
+	matchedName string
+	score       float64
+}
+
+// MatchRecognition maps every segment to its most likely TKPI foods. Candidate scores are weighted by the
+// recognizer's probability so a confident first guess wins over a close name match of an unlikely one.
+func (s *foodMatchingService) MatchRecognition(ctx context.Context, recognition *FoodRecognition) ([]model.MealHistoryFood, error) {
+	var foods []model.MealHistoryFood
+
+	for segmentIndex, segment := range recognition.Segments {
+		best := make(map[string]foodMatch)
+
+		for i, candidate := range segment.Candidates {
+			if i >= foodMatchesPerSegment {
+				break
+			}
+
+			weight := candidate.Probability
+			if weight <= 0 {
+				weight = 1 / float64(i+1)
+			}
+
+			matches, err := s.match(ctx, candidate.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, match := range matches {
+				match.score *= weight
+				if current, ok := best[match.food.Kode]; !ok || match.score > current.score {
+					best[match.food.Kode] = match
+				}
+			}
+		}
+
+		for rank, match := range topMatches(best, foodMatchesPerSegment) {
+			foods = append(foods, model.MealHistoryFood{
+				Segment:          segmentIndex,
+				Rank:             rank + 1,
+				Kode:             match.food.Kode,
+				NamaBahanMakanan: match.food.NamaBahanMakanan,
+				MatchedName:      match.matchedName,
+				Score:            roundScore(match.score),
+			})
+		}
+	}
+
+	return foods, nil
+}
+
+// MatchSelections maps foods the user confirmed to their single best TKPI entry with the chosen portion
+func (s *foodMatchingService) MatchSelections(ctx context.Context, selections []FoodSelection) ([]model.MealHistoryFood, error) {
+	var foods []model.MealHistoryFood
+
+	for _, selection := range selections {
+		matches, err := s.match(ctx, selection.Name)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		grams := selection.Grams
+		foods = append(foods, model.MealHistoryFood{
+			Segment:          selection.Segment,
+			Rank:             1,
+			Kode:             matches[0].food.Kode,
+			NamaBahanMakanan: matches[0].food.NamaBahanMakanan,
+			MatchedName:      selection.Name,
+			Score:            roundScore(matches[0].score),
+			Grams:            &grams,
+		})
+	}
+
+	return foods, nil
+}
+
+// GetBahanMakanan returns the cached TKPI entry for kode, or nil when the catalog doesn't have it
+func (s *foodMatchingService) GetBahanMakanan(ctx context.Context, kode string) (*model.BahanMakanan, error) {
+	catalog, err := s.loadCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	food, ok := catalog.byKode[kode]
+	if !ok {
+		return nil, nil
+	}
+	return &food, nil
+}
+
+// match returns the TKPI foods for a single name, best first. A curated alias always wins.
+func (s *foodMatchingService) match(ctx context.Context, name string) ([]foodMatch, error) {
+	catalog, err := s.loadCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := normalizeFoodName(name)
+
+	alias := new(model.FoodAlias)
+	err = s.DB.WithContext(ctx).First(alias, "alias = ?", normalized).Error
+	if err == nil {
+		if food, ok := catalog.byKode[alias.Kode]; ok {
+			return []foodMatch{{food: food, matchedName: name, score: 1}}, nil
+		}
+		s.Log.Warnf("Food alias %q points to unknown kode %s", alias.Alias, alias.Kode)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	query := translateFoodTokens(strings.Fields(normalized))
+	if len(query) == 0 {
+		return nil, nil
+	}
+
+	candidates := make(map[string]foodMatch)
+	for i, food := range catalog.foods {
+		score := FoodNameSimilarity(query, catalog.tokens[i])
+		if score >= foodMatchMinScore {
+			candidates[food.Kode] = foodMatch{food: food, matchedName: name, score: score}
+		}
+	}
+
+	return topMatches(candidates, foodMatchesPerSegment), nil
+}
+
+// loadCatalog caches the TKPI table, it changes rarely and is needed for every scan. The catalog is fetched
+// without holding s.mu, concurrent callers of an expired cache share one fetch instead of queueing behind it.
+func (s *foodMatchingService) loadCatalog(ctx context.Context) (*foodCatalogSnapshot, error) {
+	cached, fresh := s.cachedCatalog()
+	if fresh {
+		return cached, nil
+	}
+
+	loaded, err, _ := s.refresh.Do("catalog", func() (interface{}, error) {
+		// A fetch that finished since the check above already refreshed it
+		if catalog, fresh := s.cachedCatalog(); fresh {
+			return catalog, nil
+		}
+
+		// The fetch is shared by every waiting scan, so one of them going away mustn't cancel it
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), foodCatalogFetchTimeout)
+		defer cancel()
+
+		foods, err := s.Catalog.ListBahanMakanan(fetchCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		catalog := newFoodCatalogSnapshot(foods)
+
+		s.mu.Lock()
+		s.catalog = catalog
+		s.loadedAt = time.Now()
+		s.mu.Unlock()
+
+		return catalog, nil
+	})
+	if err != nil {
+		if cached != nil {
+			// Keep serving the stale copy rather than failing every scan
+			s.Log.Warnf("Failed to refresh bahan makanan catalog: %+v", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	return loaded.(*foodCatalogSnapshot), nil
+}
+
+// cachedCatalog returns the cached catalog and whether it is younger than FoodCatalogTTLMinutes
+func (s *foodMatchingService) cachedCatalog() (*foodCatalogSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fresh := s.catalog != nil && time.Since(s.loadedAt) < time.Duration(config.FoodCatalogTTLMinutes)*time.Minute
+	return s.catalog, fresh
+}
+
+func newFoodCatalogSnapshot(foods []model.BahanMakanan) *foodCatalogSnapshot {
+	catalog := &foodCatalogSnapshot{
+		foods:  foods,
+		tokens: make([][]string, len(foods)),
+		byKode: make(map[string]model.BahanMakanan, len(foods)),
+	}
+	for i, food := range foods {
+		catalog.byKode[food.Kode] = food
+		catalog.tokens[i] = strings.Fields(normalizeFoodName(food.NamaBahanMakanan))
+	}
+	return catalog
+}
+
+func (s *foodMatchingService) GetAliases(c *fiber.Ctx) ([]model.FoodAlias, error) {
+	var aliases []model.FoodAlias
+
+	if err := s.DB.WithContext(c.Context()).Order("alias ASC").Find(&aliases).Error; err != nil {
+		s.Log.Errorf("Failed to get food aliases: %+v", err)
+		return nil, err
+	}
+
+	return aliases, nil
+}
+
+func (s *foodMatchingService) CreateAlias(c *fiber.Ctx, req *validation.CreateFoodAlias) (*model.FoodAlias, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	catalog, err := s.loadCatalog(c.Context())
+	if err != nil {
+		s.Log.Errorf("Failed to load bahan makanan catalog: %+v", err)
+		return nil, fiber.NewError(fiber.StatusServiceUnavailable, "Bahan makanan service is unavailable")
+	}
+	if _, ok := catalog.byKode[req.Kode]; !ok {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Bahan makanan kode not found")
+	}
+
+	alias := &model.FoodAlias{
+		Alias: normalizeFoodName(req.Alias),
+		Kode:  req.Kode,
+	}
+
+	result := s.DB.WithContext(c.Context()).Create(alias)
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return nil, fiber.NewError(fiber.StatusConflict, "Alias already exists")
+	}
+	if result.Error != nil {
+		s.Log.Errorf("Failed to create food alias: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	return alias, nil
+}
+
+func (s *foodMatchingService) DeleteAlias(c *fiber.Ctx, id string) error {
+	result := s.DB.WithContext(c.Context()).Delete(&model.FoodAlias{}, "id = ?", id)
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed to delete food alias: %+v", result.Error)
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusNotFound, "Food alias not found")
+	}
+
+	return nil
+}
+
+func topMatches(matches map[string]foodMatch, limit int) []foodMatch {
+	sorted := make([]foodMatch, 0, len(matches))
+	for _, match := range matches {
+		sorted = append(sorted, match)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].score != sorted[j].score {
+			return sorted[i].score > sorted[j].score
+		}
+		// Shorter names are more generic, prefer them on a tie
+		return len(sorted[i].food.NamaBahanMakanan) < len(sorted[j].food.NamaBahanMakanan)
+	})
+
+	if len(sorted) > limit {
+		sorted = sorted[:limit]
+	}
+	return sorted
+}
+
+func roundScore(score float64) float64 {
+	return float64(int(score*10000+0.5)) / 10000
+}
+
+// normalizeFoodName lowercases a name and turns punctuation into single spaces
+func normalizeFoodName(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// foodTranslations covers the English words LogMeal returns most often. Phrases are matched before
+// single words so "sweet potato" doesn't become "manis kentang".
+var foodTranslations = map[string]string{
+	"sweet potato":  "ubi jalar",
+	"spring onion":  "daun bawang",
+	"green beans":   "buncis",
+	"bean sprouts":  "tauge",
+	"soy sauce":     "kecap",
+	"peanut sauce":  "bumbu kacang",
+	"coconut milk":  "santan",
+	"white rice":    "nasi",
+	"brown rice":    "beras merah",
+	"fried rice":    "nasi goreng",
+	"fried noodles": "mi goreng",
+	"rice":          "nasi",
+	"noodles":       "mi",
+	"noodle":        "mi",
+	"bread":         "roti",
+	"chicken":       "ayam",
+	"beef":          "daging sapi",
+	"goat":          "kambing",
+	"mutton":        "kambing",
+	"fish":          "ikan",
+	"tuna":          "tongkol",
+	"mackerel":      "kembung",
+	"catfish":       "lele",
+	"milkfish":      "bandeng",
+	"shrimp":        "udang",
+	"prawn":         "udang",
+	"squid":         "cumi",
+	"egg":           "telur",
+	"eggs":          "telur",
+	"tofu":          "tahu",
+	"tempeh":        "tempe",
+	"potato":        "kentang",
+	"potatoes":      "kentang",
+	"cassava":       "singkong",
+	"corn":          "jagung",
+	"spinach":       "bayam",
+	"kale":          "kangkung",
+	"cabbage":       "kubis",
+	"carrot":        "wortel",
+	"carrots":       "wortel",
+	"cucumber":      "ketimun",
+	"tomato":        "tomat",
+	"eggplant":      "terong",
+	"banana":        "pisang",
+	"papaya":        "pepaya",
+	"mango":         "mangga",
+	"pineapple":     "nenas",
+	"orange":        "jeruk",
+	"apple":         "apel",
+	"watermelon":    "semangka",
+	"milk":          "susu",
+	"peanut":        "kacang tanah",
+	"peanuts":       "kacang tanah",
+	"soup":          "sop",
+	"fried":         "goreng",
+	"grilled":       "bakar",
+	"boiled":        "rebus",
+	"steamed":       "kukus",
+	"raw":           "mentah",
+	"white":         "putih",
+	"red":           "merah",
+}
+
+// translateFoodTokens replaces known English words and phrases with their Indonesian TKPI wording
+func translateFoodTokens(tokens []string) []string {
+	var translated []string
+	for i := 0; i < len(tokens); i++ {
+		if i+1 < len(tokens) {
+			if phrase, ok := foodTranslations[tokens[i]+" "+tokens[i+1]]; ok {
+				translated = append(translated, strings.Fields(phrase)...)
+				i++
+				continue
+			}
+		}
+		if word, ok := foodTranslations[tokens[i]]; ok {
+			translated = append(translated, strings.Fields(word)...)
+			continue
+		}
+		translated = append(translated, tokens[i])
+	}
+	return translated
+}
+
+// FoodNameSimilarity scores two tokenized names between 0 and 1. It blends the share of query words
+// found in the food name with a character bigram overlap so spelling variants still score.
+func FoodNameSimilarity(query, name []string) float64 {
+	if len(query) == 0 || len(name) == 0 {
+		return 0
+	}
+
+	nameSet := make(map[string]bool, len(name))
+	for _, token := range name {
+		nameSet[token] = true
+	}
+
+	matched := 0
+	for _, token := range query {
+		if nameSet[token] {
+			matched++
+		}
+	}
+	tokenScore := 2 * float64(matched) / float64(len(query)+len(name))
+
+	return 0.7*tokenScore + 0.3*bigramDice(strings.Join(query, " "), strings.Join(name, " "))
+}
+
+func bigramDice(a, b string) float64 {
+	bigrams := func(s string) map[string]int {
+		counts := make(map[string]int)
+		runes := []rune(s)
+		for i := 0; i+1 < len(runes); i++ {
+			counts[string(runes[i:i+2])]++
+		}
+		return counts
+	}
+
+	aBigrams, bBigrams := bigrams(a), bigrams(b)
+	total, overlap := 0, 0
+	for bigram, count := range aBigrams {
+		total += count
+		if other, ok := bBigrams[bigram]; ok {
+			overlap += min(count, other)
+		}
+	}
+	for _, count := range bBigrams {
+		total += count
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return 2 * float64(overlap) / float64(total)
+}
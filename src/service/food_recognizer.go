@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FoodRecognizerLogMeal = "logmeal"
+	FoodRecognizerFake    = "fake"
+)
+
+// FoodCandidate is a single guess for what a segment of the image contains
+type FoodCandidate struct {
+	Name        string  `json:"name"`
+	Probability float64 `json:"probability"`
+}
+
+// FoodSegment is one recognized region of the image with its candidate foods
+type FoodSegment struct {
+	Candidates []FoodCandidate `json:"candidates"`
+}
+
+// FoodRecognition is the result of segmenting a meal image
+type FoodRecognition struct {
+	ImageID  string        `json:"image_id"`
+	Segments []FoodSegment `json:"segments"`
+}
+
+// FoodSelection is the food the user confirmed for one segment and how much of it they ate
+type FoodSelection struct {
+	Segment int     `json:"segment"`
+	Name    string  `json:"name"`
+	Grams   float64 `json:"grams"`
+}
+
+// FoodRecognizer turns a meal image into candidate foods and candidate foods into nutrients.
+// LogMeal is the production implementation, the fake one is used for tests and local development.
+type FoodRecognizer interface {
+	Segment(ctx context.Context, image io.Reader, filename string) (*FoodRecognition, error)
+	Nutrients(ctx context.Context, recognition *FoodRecognition) (Nutrient, error)
+	// Confirm computes nutrients for the foods and portions the user picked instead of the detected ones
+	Confirm(ctx context.Context, recognition *FoodRecognition, selections []FoodSelection) (Nutrient, error)
+}
+
+// NewFoodRecognizer returns the recognizer selected by provider. Any other value is an error rather than a
+// fallback to LogMeal, so a misspelled provider can't start billed LogMeal calls.
+func NewFoodRecognizer(provider, apiKey, baseURL string) (FoodRecognizer, error) {
+	switch strings.ToLower(provider) {
+	case FoodRecognizerLogMeal:
+		return NewLogMealRecognizer(apiKey, baseURL), nil
+	case FoodRecognizerFake:
+		return NewFakeFoodRecognizer(), nil
+	default:
+		return nil, fmt.Errorf("unknown food recognizer %q, expected %q or %q", provider, FoodRecognizerLogMeal, FoodRecognizerFake)
+	}
+}
+
+// TopFoodNames groups the names of the highest probability candidates per segment
+func (r *FoodRecognition) TopFoodNames(limit int) [][]string {
+	var groupedFoodNames [][]string
+	for _, segment := range r.Segments {
+		var topFoods []string
+		for i := 0; i < len(segment.Candidates) && i < limit; i++ {
+			topFoods = append(topFoods, segment.Candidates[i].Name)
+		}
+		groupedFoodNames = append(groupedFoodNames, topFoods)
+	}
+	return groupedFoodNames
+}
+
+// HasCandidate reports whether name is one of the candidates of the given segment
+func (r *FoodRecognition) HasCandidate(segment int, name string) bool {
+	if segment < 0 || segment >= len(r.Segments) {
+		return false
+	}
+	for _, candidate := range r.Segments[segment].Candidates {
+		if strings.EqualFold(candidate.Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Add sums two nutrient totals, keeping the units of n
+func (n Nutrient) Add(other Nutrient) Nutrient {
+	n.Calories.Quantity += other.Calories.Quantity
+	n.Protein.Quantity += other.Protein.Quantity
+	n.Carbs.Quantity += other.Carbs.Quantity
+	n.Fat.Quantity += other.Fat.Quantity
+
+	details := make(map[string]NutrientDetail, len(n.Details)+len(other.Details))
+	for code, detail := range n.Details {
+		details[code] = detail
+	}
+	for code, detail := range other.Details {
+		current, ok := details[code]
+		if !ok {
+			current.Unit = detail.Unit
+		}
+		current.Quantity += detail.Quantity
+		details[code] = current
+	}
+	n.Details = details
+
+	return n
+}
+
+// Scale multiplies every nutrient quantity by factor
+func (n Nutrient) Scale(factor float64) Nutrient {
+	n.Calories.Quantity *= factor
+	n.Protein.Quantity *= factor
+	n.Carbs.Quantity *= factor
+	n.Fat.Quantity *= factor
+
+	details := make(map[string]NutrientDetail, len(n.Details))
+	for code, detail := range n.Details {
+		detail.Quantity *= factor
+		details[code] = detail
+	}
+	n.Details = details
+
+	return n
+}
+
+func emptyNutrient() Nutrient {
+	return Nutrient{
+		Calories: NutrientDetail{Unit: "kcal"},
+		Protein:  NutrientDetail{Unit: "g"},
+		Carbs:    NutrientDetail{Unit: "g"},
+		Fat:      NutrientDetail{Unit: "g"},
+	}
+}
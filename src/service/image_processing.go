@@ -0,0 +1,269 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Registers the WebP decoder with image.Decode
+)
+
+const (
+	// MaxImagePixels bounds the decoded size of an image, a small file can still decode into a huge bitmap
+	MaxImagePixels = 25_000_000
+	// ImageMaxDimension is the longest side of the stored image
+	ImageMaxDimension = 2048
+	// ThumbnailDimension is the longest side of thumbnails
+	ThumbnailDimension = 320
+
+	jpegQuality = 85
+)
+
+// Names of the variants stored for every image
+const (
+	ImageVariantOriginal      = "original"
+	ImageVariantThumbnail     = "thumbnail"
+	ImageVariantOriginalWebP  = "original_webp"
+	ImageVariantThumbnailWebP = "thumbnail_webp"
+)
+
+// allowedImageTypes are the content types accepted for upload, sniffed from the file itself
+var allowedImageTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/webp": true,
+}
+
+// ImageVariant is one encoded version of an uploaded image
+type ImageVariant struct {
+	Name        string
+	Filename    string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// ProcessedImage is an uploaded image re-encoded without its metadata, with its variants
+type ProcessedImage struct {
+	Width    int
+	Height   int
+	Variants []ImageVariant
+}
+
+// ProcessImage checks that data is a JPEG, PNG or WebP image of a reasonable size and re-encodes it.
+// Re-encoding drops EXIF and every other metadata, the EXIF orientation is applied to the pixels first
+// so photos stay upright. The image is scaled down to ImageMaxDimension, and a thumbnail and WebP
+// versions of both are made. Opaque images are stored as JPEG and the others as PNG to keep transparency.
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	contentType := http.DetectContentType(data)
+	if !allowedImageTypes[contentType] {
+		return nil, fiber.NewError(fiber.StatusUnsupportedMediaType, "Only JPEG, PNG and WebP images are supported")
+	}
+
+	imageConfig, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "The image can't be read")
+	}
+	if imageConfig.Width < 1 || imageConfig.Height < 1 || imageConfig.Width*imageConfig.Height > MaxImagePixels {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("The image can't have more than %d megapixels", MaxImagePixels/1_000_000))
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "The image can't be read")
+	}
+
+	original := fitImage(decoded, ImageMaxDimension)
+	if contentType == "image/jpeg" {
+		original = orientImage(original, jpegOrientation(data))
+	}
+	thumbnail := fitImage(original, ThumbnailDimension)
+
+	opaque := isOpaque(decoded)
+	processed := &ProcessedImage{Width: original.Bounds().Dx(), Height: original.Bounds().Dy()}
+	for _, variant := range []struct {
+		name  string
+		image image.Image
+		webp  bool
+	}{
+		{ImageVariantOriginal, original, false},
+		{ImageVariantThumbnail, thumbnail, false},
+		{ImageVariantOriginalWebP, original, true},
+		{ImageVariantThumbnailWebP, thumbnail, true},
+	} {
+		encoded, err := encodeImageVariant(variant.name, variant.image, variant.webp, opaque)
+		if err != nil {
+			return nil, err
+		}
+		processed.Variants = append(processed.Variants, *encoded)
+	}
+	return processed, nil
+}
+
+func encodeImageVariant(name string, img image.Image, webp bool, opaque bool) (*ImageVariant, error) {
+	base := ImageVariantOriginal
+	if name == ImageVariantThumbnail || name == ImageVariantThumbnailWebP {
+		base = ImageVariantThumbnail
+	}
+
+	variant := &ImageVariant{Name: name, Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+	var buffer bytes.Buffer
+	var err error
+	switch {
+	case webp:
+		variant.Filename, variant.ContentType = base+".webp", "image/webp"
+		err = nativewebp.Encode(&buffer, img, nil)
+	case opaque:
+		variant.Filename, variant.ContentType = base+".jpg", "image/jpeg"
+		err = jpeg.Encode(&buffer, img, &jpeg.Options{Quality: jpegQuality})
+	default:
+		variant.Filename, variant.ContentType = base+".png", "image/png"
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buffer, img)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s variant: %w", name, err)
+	}
+
+	variant.Data = buffer.Bytes()
+	return variant, nil
+}
+
+// fitImage scales img down so its longest side is at most maxDimension, smaller images are kept as they are
+func fitImage(img image.Image, maxDimension int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxDimension && height <= maxDimension {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxDimension/width)
+		width = maxDimension
+	} else {
+		width = max(1, width*maxDimension/height)
+		height = maxDimension
+	}
+
+	scaled := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, bounds, draw.Src, nil)
+	return scaled
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return true
+}
+
+// orientImage turns img upright for the EXIF orientation, 1 being upright already
+func orientImage(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	outWidth, outHeight := width, height
+	if orientation >= 5 {
+		// Orientations 5 to 8 turn the image a quarter
+		outWidth, outHeight = height, width
+	}
+
+	oriented := image.NewNRGBA(image.Rect(0, 0, outWidth, outHeight))
+	for y := 0; y < outHeight; y++ {
+		for x := 0; x < outWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // Mirrored
+				sx, sy = width-1-x, y
+			case 3: // Upside down
+				sx, sy = width-1-x, height-1-y
+			case 4: // Mirrored upside down
+				sx, sy = x, height-1-y
+			case 5: // Mirrored and turned
+				sx, sy = y, x
+			case 6: // Turned counterclockwise, rotate clockwise
+				sx, sy = y, height-1-x
+			case 7: // Mirrored and turned the other way
+				sx, sy = width-1-y, height-1-x
+			case 8: // Turned clockwise, rotate counterclockwise
+				sx, sy = width-1-y, x
+			}
+			oriented.Set(x, y, img.At(bounds.Min.X+sx, bounds.Min.Y+sy))
+		}
+	}
+	return oriented
+}
+
+// jpegOrientation reads the orientation tag of the EXIF segment of a JPEG, 1 when there's none
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	for offset := 2; offset+4 <= len(data); {
+		if data[offset] != 0xFF {
+			return 1
+		}
+		marker := data[offset+1]
+		// The image data starts at SOS, metadata segments all come before it
+		if marker == 0xDA || marker == 0xD9 {
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if length < 2 || offset+2+length > len(data) {
+			return 1
+		}
+
+		segment := data[offset+4 : offset+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		offset += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation finds the orientation tag in the first IFD of a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+
+	entries := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		// Orientation is a SHORT, stored in the first bytes of the value field
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
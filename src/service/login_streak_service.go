@@ -11,8 +11,8 @@ import (
 )
 
 type LoginStreakService interface {
-	RecordLogin(userID uuid.UUID) error
-	GetLoginStreak(userID uuid.UUID) (*model.LoginStreakResponse, error)
+	RecordLogin(userID uuid.UUID, location *time.Location) error
+	GetLoginStreak(userID uuid.UUID, location *time.Location) (*model.LoginStreakResponse, error)
 }
 
 type loginStreakServiceImpl struct {
@@ -27,7 +27,8 @@ func NewLoginStreakService(db *gorm.DB, validate *validator.Validate) LoginStrea
 	}
 }
 
-func (service *loginStreakServiceImpl) RecordLogin(userID uuid.UUID) error {
+// RecordLogin records today's login, where days start at midnight in location
+func (service *loginStreakServiceImpl) RecordLogin(userID uuid.UUID, location *time.Location) error {
 	// Check if user exists
 	var user model.User
 	if err := service.DB.First(&user, "id = ?", userID).Error; err != nil {
@@ -37,8 +38,8 @@ func (service *loginStreakServiceImpl) RecordLogin(userID uuid.UUID) error {
 		return err
 	}
 
-	today := time.Now()
-	todayStart := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	today := time.Now().In(location)
+	todayStart := startOfDay(today, location)
 
 	// Check if user already logged in today
 	var existingStreak model.LoginStreak
@@ -62,9 +63,10 @@ func (service *loginStreakServiceImpl) RecordLogin(userID uuid.UUID) error {
 	if err == nil {
 		// Check if the last login was yesterday
 		yesterday := todayStart.AddDate(0, 0, -1)
-		if latestStreak.LoginDate.Year() == yesterday.Year() &&
-			latestStreak.LoginDate.Month() == yesterday.Month() &&
-			latestStreak.LoginDate.Day() == yesterday.Day() {
+		lastLogin := latestStreak.LoginDate.In(location)
+		if lastLogin.Year() == yesterday.Year() &&
+			lastLogin.Month() == yesterday.Month() &&
+			lastLogin.Day() == yesterday.Day() {
 			// Consecutive login, increment streak
 			currentStreak = latestStreak.CurrentStreak + 1
 		}
@@ -88,7 +90,8 @@ func (service *loginStreakServiceImpl) RecordLogin(userID uuid.UUID) error {
 	return service.DB.Create(&newStreak).Error
 }
 
-func (service *loginStreakServiceImpl) GetLoginStreak(userID uuid.UUID) (*model.LoginStreakResponse, error) {
+// GetLoginStreak returns the streaks and this week's logins, with the week counted in location
+func (service *loginStreakServiceImpl) GetLoginStreak(userID uuid.UUID, location *time.Location) (*model.LoginStreakResponse, error) {
 	// Check if user exists
 	var user model.User
 	if err := service.DB.First(&user, "id = ?", userID).Error; err != nil {
@@ -107,19 +110,19 @@ func (service *loginStreakServiceImpl) GetLoginStreak(userID uuid.UUID) (*model.
 			return &model.LoginStreakResponse{
 				CurrentStreak: 0,
 				LongestStreak: 0,
-				WeeklyStreak:  getEmptyWeeklyStreak(),
+				WeeklyStreak:  getEmptyWeeklyStreak(location),
 			}, nil
 		}
 		return nil, err
 	}
 
 	// Get weekly streak data
-	today := time.Now()
+	today := time.Now().In(location)
 	weekStart := getStartOfWeek(today)
-	weekEnd := weekStart.AddDate(0, 0, 6)
+	weekEnd := weekStart.AddDate(0, 0, 7)
 
 	var weeklyStreaks []model.LoginStreak
-	err = service.DB.Where("user_id = ? AND login_date BETWEEN ? AND ?",
+	err = service.DB.Where("user_id = ? AND login_date >= ? AND login_date < ?",
 		userID, weekStart, weekEnd).
 		Order("login_date ASC").
 		Find(&weeklyStreaks).Error
@@ -160,10 +163,10 @@ func formatWeeklyStreakData(weekStart time.Time, streaks []model.LoginStreak) []
 		}
 	}
 
-	// Mark days with login
+	// Mark days with login, counting days in the week's timezone rather than the one the login was recorded in
 	for _, streak := range streaks {
 		// Convert from Go's weekday (0=Sunday) to our format (1=Monday, 7=Sunday)
-		dayIndex := streak.DayOfWeek
+		dayIndex := int(streak.LoginDate.In(weekStart.Location()).Weekday())
 		if dayIndex == 0 {
 			dayIndex = 6 // Sunday becomes index 6 (7th day)
 		} else {
@@ -179,9 +182,9 @@ func formatWeeklyStreakData(weekStart time.Time, streaks []model.LoginStreak) []
 }
 
 // Helper function to get empty weekly streak data
-func getEmptyWeeklyStreak() []model.LoginStreakDayInfo {
+func getEmptyWeeklyStreak(location *time.Location) []model.LoginStreakDayInfo {
 	weeklyData := make([]model.LoginStreakDayInfo, 7)
-	weekStart := getStartOfWeek(time.Now())
+	weekStart := getStartOfWeek(time.Now().In(location))
 
 	for i := 0; i < 7; i++ {
 		date := weekStart.AddDate(0, 0, i)
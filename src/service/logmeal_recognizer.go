@@ -0,0 +1,221 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type logMealRecognizer struct {
+	ApiKey     string
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+func NewLogMealRecognizer(apiKey, baseURL string) FoodRecognizer {
+	return &logMealRecognizer{
+		ApiKey:     apiKey,
+		BaseURL:    baseURL,
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Segment uploads the image to the LogMeal segmentation API and sorts candidates by probability
+func (r *logMealRecognizer) Segment(ctx context.Context, image io.Reader, filename string) (*FoodRecognition, error) {
+	url := fmt.Sprintf("%s/v2/image/segmentation/complete/v1.1?language=eng", r.BaseURL)
+	buffer := &bytes.Buffer{}
+	writer := multipart.NewWriter(buffer)
+
+	part, err := writer.CreateFormFile("image", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, image); err != nil {
+		return nil, err
+	}
+	writer.Close()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, buffer)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+r.ApiKey)
+
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("failed to upload image")
+	}
+
+	// Struct for response parsing
+	var result struct {
+		ImageID          int `json:"imageId"`
+		SegmentationData []struct {
+			RecognitionResults []FoodCandidate `json:"recognition_results"`
+		} `json:"segmentation_results"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	recognition := &FoodRecognition{ImageID: strconv.Itoa(result.ImageID)}
+	for _, segment := range result.SegmentationData {
+		candidates := segment.RecognitionResults
+
+		// Sort food items by probability (descending)
+		sort.Slice(candidates, func(i, j int) bool {
+			return candidates[i].Probability > candidates[j].Probability
+		})
+
+		recognition.Segments = append(recognition.Segments, FoodSegment{Candidates: candidates})
+	}
+
+	return recognition, nil
+}
+
+// Nutrients fetches the nutrition info LogMeal computed for a previously segmented image
+func (r *logMealRecognizer) Nutrients(ctx context.Context, recognition *FoodRecognition) (Nutrient, error) {
+	url := fmt.Sprintf("%s/v2/nutrition/recipe/nutritionalInfo/v1.1?language=eng", r.BaseURL)
+	payload, _ := json.Marshal(map[string]string{"imageId": recognition.ImageID})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
+	if err != nil {
+		return Nutrient{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.ApiKey)
+
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return Nutrient{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Nutrient{}, errors.New("failed to get nutrition info")
+	}
+
+	var result struct {
+		NutritionalInfo struct {
+			TotalNutrients map[string]NutrientDetail `json:"totalNutrients"`
+		} `json:"nutritional_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Nutrient{}, err
+	}
+
+	totalNutrients := result.NutritionalInfo.TotalNutrients
+
+	return Nutrient{
+		Calories: totalNutrients["ENERC_KCAL"],
+		Protein:  totalNutrients["PROCNT"],
+		Carbs:    totalNutrients["CHOCDF"],
+		Fat:      totalNutrients["FAT"],
+		Details:  normalizeNutrientDetails(totalNutrients),
+	}, nil
+}
+
+// Confirm tells LogMeal which dish each segment really is, then scales the per-item nutrition
+// to the portion the user entered
+func (r *logMealRecognizer) Confirm(ctx context.Context, recognition *FoodRecognition, selections []FoodSelection) (Nutrient, error) {
+	imageID, err := strconv.Atoi(recognition.ImageID)
+	if err != nil {
+		return Nutrient{}, fmt.Errorf("invalid LogMeal image id %q", recognition.ImageID)
+	}
+
+	confirmed := make([]string, 0, len(selections))
+	sources := make([]string, 0, len(selections))
+	positions := make([]int, 0, len(selections))
+	for _, selection := range selections {
+		confirmed = append(confirmed, selection.Name)
+		sources = append(sources, "logmeal")
+		positions = append(positions, selection.Segment+1)
+	}
+
+	payload, _ := json.Marshal(map[string]any{
+		"imageId":            imageID,
+		"confirmedClass":     confirmed,
+		"source":             sources,
+		"food_item_position": positions,
+	})
+	if err := r.post(ctx, "/v2/image/confirm/dish/v1.0?language=eng", payload, nil); err != nil {
+		return Nutrient{}, errors.New("failed to confirm dish")
+	}
+
+	var result struct {
+		NutritionalInfoPerItem []struct {
+			FoodItemPosition int     `json:"food_item_position"`
+			ServingSize      float64 `json:"serving_size"`
+			NutritionalInfo  struct {
+				TotalNutrients map[string]NutrientDetail `json:"totalNutrients"`
+			} `json:"nutritional_info"`
+		} `json:"nutritional_info_per_item"`
+	}
+	payload, _ = json.Marshal(map[string]int{"imageId": imageID})
+	if err := r.post(ctx, "/v2/nutrition/recipe/nutritionalInfo/v1.1?language=eng", payload, &result); err != nil {
+		return Nutrient{}, errors.New("failed to get nutrition info")
+	}
+
+	grams := make(map[int]float64, len(selections))
+	for _, selection := range selections {
+		grams[selection.Segment+1] = selection.Grams
+	}
+
+	total := emptyNutrient()
+	for _, item := range result.NutritionalInfoPerItem {
+		portion, ok := grams[item.FoodItemPosition]
+		if !ok || item.ServingSize <= 0 {
+			continue
+		}
+
+		nutrients := item.NutritionalInfo.TotalNutrients
+		total = total.Add(Nutrient{
+			Calories: nutrients["ENERC_KCAL"],
+			Protein:  nutrients["PROCNT"],
+			Carbs:    nutrients["CHOCDF"],
+			Fat:      nutrients["FAT"],
+			Details:  normalizeNutrientDetails(nutrients),
+		}.Scale(portion / item.ServingSize))
+	}
+
+	return total, nil
+}
+
+// post sends a JSON request to LogMeal and decodes the response into out when it isn't nil
+func (r *logMealRecognizer) post(ctx context.Context, path string, payload []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", r.BaseURL+path, bytes.NewBuffer(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+r.ApiKey)
+
+	resp, err := r.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"app/src/model"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxMealIngredients keeps a single meal from fanning out into an unbounded number of gRPC lookups
+const maxMealIngredients = 50
+
+// resolveMealIngredients looks every line up in TKPI, applies its edible portion and returns the completed
+// lines together with the nutrients of the whole meal
+func (s *mealService) resolveMealIngredients(c *fiber.Ctx, lines []model.MealHistoryIngredient) ([]model.MealHistoryIngredient, Nutrient, error) {
+	if len(lines) > maxMealIngredients {
+		return nil, Nutrient{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("A meal can have at most %d ingredients", maxMealIngredients))
+	}
+
+	foods := newIngredientFoods(s.BahanMakanan)
+	ingredients := make([]model.MealHistoryIngredient, 0, len(lines))
+	total := emptyNutrient()
+
+	for i, line := range lines {
+		kode := strings.TrimSpace(line.Kode)
+		if kode == "" {
+			return nil, Nutrient{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ingredient %d has no kode", i+1))
+		}
+		if line.Grams <= 0 {
+			return nil, Nutrient{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ingredient %s must weigh more than 0 grams", kode))
+		}
+
+		food, err := foods.lookup(c, kode)
+		if err != nil {
+			return nil, Nutrient{}, err
+		}
+
+		bddPersen, edibleGrams := ediblePortion(food, line.Grams)
+		nutrient := bahanMakananNutrients(*food, edibleGrams)
+		total = total.Add(nutrient)
+
+		ingredients = append(ingredients, model.MealHistoryIngredient{
+			Position:         i + 1,
+			Kode:             kode,
+			NamaBahanMakanan: food.NamaBahanMakanan,
+			Grams:            line.Grams,
+			BddPersen:        bddPersen,
+			EdibleGrams:      roundNutrient(edibleGrams),
+			Calories:         roundNutrient(nutrient.Calories.Quantity),
+			Protein:          roundNutrient(nutrient.Protein.Quantity),
+			Carbs:            roundNutrient(nutrient.Carbs.Quantity),
+			Fat:              roundNutrient(nutrient.Fat.Quantity),
+		})
+	}
+
+	return ingredients, total, nil
+}
+
+// ingredientFoods looks up the TKPI foods of ingredient lines, each kode once
+type ingredientFoods struct {
+	service BahanMakananService
+	foods   map[string]*model.BahanMakanan
+}
+
+func newIngredientFoods(service BahanMakananService) *ingredientFoods {
+	return &ingredientFoods{service: service, foods: make(map[string]*model.BahanMakanan)}
+}
+
+// lookup returns the food with kode. A kode TKPI doesn't have is a 400, an unreachable catalog keeps its
+// 503 or 500 since it isn't the client's mistake.
+func (f *ingredientFoods) lookup(c *fiber.Ctx, kode string) (*model.BahanMakanan, error) {
+	if food, ok := f.foods[kode]; ok {
+		return food, nil
+	}
+
+	food, err := f.service.GetBahanMakananByKode(c, kode)
+	if isNotFound(err) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Bahan makanan %s not found", kode))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f.foods[kode] = food
+	return food, nil
+}
+
+// isNotFound reports whether err is the 404 of a lookup that found nothing
+func isNotFound(err error) bool {
+	var fiberErr *fiber.Error
+	return errors.As(err, &fiberErr) && fiberErr.Code == fiber.StatusNotFound
+}
+
+// ediblePortion returns the edible percentage of food and how many of grams are eaten
+func ediblePortion(food *model.BahanMakanan, grams float64) (float64, float64) {
+	bddPersen := food.BddPersen
+	if bddPersen <= 0 || bddPersen > 100 {
+		// TKPI leaves BDD empty for foods that are eaten whole
+		bddPersen = 100
+	}
+	return bddPersen, grams * bddPersen / 100
+}
+
+// applyIngredientTotals sets the macro columns of meal from the summed ingredients and names the meal
+// after them when the client didn't give it a title
+func applyIngredientTotals(meal *model.MealHistory, ingredients []model.MealHistoryIngredient, total Nutrient) {
+	meal.Calories = roundNutrient(total.Calories.Quantity)
+	meal.Protein = roundNutrient(total.Protein.Quantity)
+	meal.Carbs = roundNutrient(total.Carbs.Quantity)
+	meal.Fat = roundNutrient(total.Fat.Quantity)
+
+	if strings.TrimSpace(meal.Title) == "" {
+		names := make([]string, 0, len(ingredients))
+		for _, ingredient := range ingredients {
+			names = append(names, ingredient.NamaBahanMakanan)
+		}
+		meal.Title = strings.Join(names, ", ")
+	}
+}
+
+// saveMealIngredients replaces the ingredient lines of a meal
+func saveMealIngredients(db *gorm.DB, mealHistoryID uuid.UUID, ingredients []model.MealHistoryIngredient) error {
+	if err := db.Where("meal_history_id = ?", mealHistoryID).Delete(&model.MealHistoryIngredient{}).Error; err != nil {
+		return err
+	}
+	if len(ingredients) == 0 {
+		return nil
+	}
+
+	for i := range ingredients {
+		ingredients[i].MealHistoryID = mealHistoryID
+	}
+	return db.Create(&ingredients).Error
+}
+
+func roundNutrient(quantity float64) float64 {
+	return math.Round(quantity*100) / 100
+}
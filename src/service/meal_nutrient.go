@@ -0,0 +1,218 @@
+package service
+
+import (
+	"app/src/model"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var macroNutrientCodes = map[string]bool{
+	model.NutrientEnergy:  true,
+	model.NutrientProtein: true,
+	model.NutrientCarbs:   true,
+	model.NutrientFat:     true,
+}
+
+// unitFactors converts a quantity in the key unit to grams
+var unitFactors = map[string]float64{
+	"g":   1,
+	"mg":  1e-3,
+	"µg":  1e-6,
+	"μg":  1e-6,
+	"ug":  1e-6,
+	"mcg": 1e-6,
+}
+
+// convertNutrientUnit returns quantity expressed in unit to, or false when the units aren't comparable
+func convertNutrientUnit(quantity float64, from, to string) (float64, bool) {
+	if from == to {
+		return quantity, true
+	}
+	fromFactor, okFrom := unitFactors[from]
+	toFactor, okTo := unitFactors[to]
+	if !okFrom || !okTo {
+		return 0, false
+	}
+	return quantity * fromFactor / toFactor, true
+}
+
+// normalizeNutrientDetails keeps the known, non-macro nutrients of a provider result and converts
+// them to the units of model.NutrientDefinitions
+func normalizeNutrientDetails(raw map[string]NutrientDetail) map[string]NutrientDetail {
+	details := make(map[string]NutrientDetail)
+	for code, detail := range raw {
+		definition, ok := model.GetNutrientDefinition(code)
+		if !ok || macroNutrientCodes[code] {
+			continue
+		}
+
+		quantity, ok := convertNutrientUnit(detail.Quantity, detail.Unit, definition.Unit)
+		if !ok {
+			continue
+		}
+		details[code] = NutrientDetail{Quantity: quantity, Unit: definition.Unit}
+	}
+	return details
+}
+
+// bahanMakananNutrients computes the nutrients of grams of an edible TKPI food, whose values are per 100 g
+func bahanMakananNutrients(food model.BahanMakanan, grams float64) Nutrient {
+	optional := map[string]*float64{
+		model.NutrientFiber:        food.SeratG,
+		model.NutrientCalcium:      food.KalsiumCaMg,
+		model.NutrientPhosphorus:   food.FosforPMg,
+		model.NutrientIron:         food.BesiFeMg,
+		model.NutrientSodium:       food.NatriumNaMg,
+		model.NutrientPotassium:    food.KaliumKaMg,
+		model.NutrientCopper:       food.TembagaCuMg,
+		model.NutrientZinc:         food.SengZnMg,
+		model.NutrientRetinol:      food.RetinolVitAMcg,
+		model.NutrientBetaCarotene: food.BetaKarotenMcg,
+		model.NutrientCarotene:     food.KarotenTotalMcg,
+		model.NutrientThiamin:      food.ThiaminVitB1Mg,
+		model.NutrientRiboflavin:   food.RiboflavinVitB2Mg,
+		model.NutrientNiacin:       food.NiasinMg,
+		model.NutrientVitaminC:     food.VitaminCMg,
+	}
+
+	details := map[string]NutrientDetail{
+		model.NutrientWater: {Quantity: food.AirG, Unit: "g"},
+		model.NutrientAsh:   {Quantity: food.AbuG, Unit: "g"},
+	}
+	for code, value := range optional {
+		if value == nil {
+			continue
+		}
+		definition, _ := model.GetNutrientDefinition(code)
+		details[code] = NutrientDetail{Quantity: *value, Unit: definition.Unit}
+	}
+
+	return Nutrient{
+		Calories: NutrientDetail{Quantity: food.EnergiKal, Unit: "kcal"},
+		Protein:  NutrientDetail{Quantity: food.ProteinG, Unit: "g"},
+		Carbs:    NutrientDetail{Quantity: food.KarbohidratG, Unit: "g"},
+		Fat:      NutrientDetail{Quantity: food.LemakG, Unit: "g"},
+		Details:  details,
+	}.Scale(grams / 100)
+}
+
+// buildMealNutrients turns the macro columns of meal plus extra amounts by code into nutrient rows.
+// The macro columns always win so the table and the columns can't disagree.
+func buildMealNutrients(meal *model.MealHistory, extra map[string]float64) ([]model.MealHistoryNutrient, error) {
+	quantities := map[string]float64{
+		model.NutrientEnergy:  meal.Calories,
+		model.NutrientProtein: meal.Protein,
+		model.NutrientCarbs:   meal.Carbs,
+		model.NutrientFat:     meal.Fat,
+	}
+	for code, quantity := range extra {
+		if macroNutrientCodes[code] {
+			continue
+		}
+		if _, ok := model.GetNutrientDefinition(code); !ok {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Unknown nutrient code %s", code))
+		}
+		if quantity < 0 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Nutrient %s can't be negative", code))
+		}
+		quantities[code] = quantity
+	}
+
+	nutrients := make([]model.MealHistoryNutrient, 0, len(quantities))
+	for _, definition := range model.NutrientDefinitions {
+		quantity, ok := quantities[definition.Code]
+		if !ok {
+			continue
+		}
+		nutrients = append(nutrients, model.MealHistoryNutrient{
+			MealHistoryID: meal.ID,
+			Code:          definition.Code,
+			Quantity:      math.Round(quantity*1000) / 1000,
+			Unit:          definition.Unit,
+		})
+	}
+
+	return nutrients, nil
+}
+
+// nutrientQuantities flattens nutrient details into amounts by code
+func nutrientQuantities(details map[string]NutrientDetail) map[string]float64 {
+	quantities := make(map[string]float64, len(details))
+	for code, detail := range details {
+		quantities[code] = detail.Quantity
+	}
+	return quantities
+}
+
+// manualNutrientQuantities reads nutrient rows sent by a client or loaded from the database into amounts by code
+func manualNutrientQuantities(nutrients []model.MealHistoryNutrient) map[string]float64 {
+	quantities := make(map[string]float64, len(nutrients))
+	for _, nutrient := range nutrients {
+		quantities[nutrient.Code] = nutrient.Quantity
+	}
+	return quantities
+}
+
+// saveMealNutrients replaces the nutrient rows of a meal
+func saveMealNutrients(db *gorm.DB, mealHistoryID uuid.UUID, nutrients []model.MealHistoryNutrient) error {
+	if err := db.Where("meal_history_id = ?", mealHistoryID).Delete(&model.MealHistoryNutrient{}).Error; err != nil {
+		return err
+	}
+	if len(nutrients) == 0 {
+		return nil
+	}
+
+	for i := range nutrients {
+		nutrients[i].MealHistoryID = mealHistoryID
+	}
+	return db.Create(&nutrients).Error
+}
+
+// sumMealNutrients aggregates the nutrient rows of a user's meals eaten in [from, to)
+func sumMealNutrients(db *gorm.DB, userID uuid.UUID, from, to time.Time) ([]model.NutrientTotal, error) {
+	var totals []model.NutrientTotal
+
+	err := db.Model(&model.MealHistoryNutrient{}).
+		Select("meal_history_nutrients.code, SUM(meal_history_nutrients.quantity) AS quantity").
+		Joins("JOIN meal_histories ON meal_histories.id = meal_history_nutrients.meal_history_id").
+		Where("meal_histories.user_id = ? AND meal_histories.meal_time >= ? AND meal_histories.meal_time < ?", userID, from, to).
+		Group("meal_history_nutrients.code").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return completeNutrientTotals(totals), nil
+}
+
+// completeNutrientTotals names the totals, adds zero rows for tracked nutrients and sorts them by definition order
+func completeNutrientTotals(totals []model.NutrientTotal) []model.NutrientTotal {
+	byCode := make(map[string]model.NutrientTotal, len(totals))
+	for _, total := range totals {
+		byCode[total.Code] = total
+	}
+	for _, code := range model.TrackedNutrients {
+		if _, ok := byCode[code]; !ok {
+			byCode[code] = model.NutrientTotal{Code: code}
+		}
+	}
+
+	completed := make([]model.NutrientTotal, 0, len(byCode))
+	for _, definition := range model.NutrientDefinitions {
+		total, ok := byCode[definition.Code]
+		if !ok {
+			continue
+		}
+		total.Name = definition.Name
+		total.Unit = definition.Unit
+		total.Quantity = math.Round(total.Quantity*1000) / 1000
+		completed = append(completed, total)
+	}
+
+	return completed
+}
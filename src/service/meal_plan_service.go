@@ -0,0 +1,622 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"app/src/model"
+	"app/src/validation"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type MealPlanService interface {
+	GenerateMealPlan(c *fiber.Ctx, userID uuid.UUID, req *validation.CreateMealPlan) (*model.MealPlan, error)
+	GetMealPlans(c *fiber.Ctx, userID uuid.UUID) ([]model.MealPlan, error)
+	GetCurrentMealPlan(c *fiber.Ctx, userID uuid.UUID) (*model.MealPlan, error)
+	GetMealPlanByID(c *fiber.Ctx, userID uuid.UUID, planID string) (*model.MealPlan, error)
+	DeleteMealPlan(c *fiber.Ctx, userID uuid.UUID, planID string) error
+	SwapSlot(c *fiber.Ctx, userID uuid.UUID, planID, slotID string, req *validation.SwapMealPlanSlot) (*model.MealPlanSlot, error)
+	LogSlot(c *fiber.Ctx, userID uuid.UUID, planID, slotID string) (*model.MealHistory, error)
+}
+
+type mealPlanService struct {
+	Log      *logrus.Logger
+	DB       *gorm.DB
+	Validate *validator.Validate
+	Targets  NutritionTargetService
+}
+
+func NewMealPlanService(db *gorm.DB, validate *validator.Validate, targets NutritionTargetService) MealPlanService {
+	return &mealPlanService{
+		Log:      logrus.New(),
+		DB:       db,
+		Validate: validate,
+		Targets:  targets,
+	}
+}
+
+// mealPlanLabels are the meals planned each day with their share of the daily calories, the rest is left
+// for snacks
+var mealPlanLabels = []struct {
+	Label string
+	Share float64
+	Hour  int
+}{
+	{model.MealLabelBreakfast, 0.25, 7},
+	{model.MealLabelLunch, 0.35, 12},
+	{model.MealLabelDinner, 0.30, 19},
+}
+
+// Servings are planned in half portions between these bounds
+const (
+	minPlanServings = 0.5
+	maxPlanServings = 3
+)
+
+// GenerateMealPlan plans a week starting at the requested day, or today in the user's timezone
+func (s *mealPlanService) GenerateMealPlan(c *fiber.Ctx, userID uuid.UUID, req *validation.CreateMealPlan) (*model.MealPlan, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	location, err := userLocation(c, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := startOfDay(time.Now(), location)
+	if req.StartDate != "" {
+		start, err = time.ParseInLocation("2006-01-02", req.StartDate, location)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid start date")
+		}
+	}
+
+	targets, err := s.Targets.GetTargets(c, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipes []model.Recipe
+	if err := s.DB.WithContext(c.Context()).
+		Where("calories > 0").
+		Preload("IngredientLines").
+		Find(&recipes).Error; err != nil {
+		s.Log.Errorf("Failed to get recipes: %+v", err)
+		return nil, err
+	}
+
+	excluded := normalizeExclusions(req.ExcludedIngredients)
+	excludedJSON, err := json.Marshal(excluded)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &model.MealPlan{
+		UserID:              userID,
+		StartDate:           start,
+		EndDate:             start.AddDate(0, 0, model.MealPlanDays-1),
+		Timezone:            location.String(),
+		CalorieTarget:       targets.Calories,
+		ExcludedIngredients: excludedJSON,
+	}
+	if protein := targets.GetNutrientTarget(model.NutrientProtein); protein != nil {
+		plan.ProteinTarget = math.Round(protein.Target)
+	}
+	if carbs := targets.GetNutrientTarget(model.NutrientCarbs); carbs != nil {
+		plan.CarbsTarget = math.Round(carbs.Target)
+	}
+	if fat := targets.GetNutrientTarget(model.NutrientFat); fat != nil {
+		plan.FatTarget = math.Round(fat.Target)
+	}
+
+	if err := PlanMealSlots(plan, recipes, excluded); err != nil {
+		return nil, err
+	}
+
+	// Recipes are only referenced, the plan is reloaded with them below
+	for i := range plan.Slots {
+		plan.Slots[i].Recipe = nil
+	}
+
+	if err := s.DB.WithContext(c.Context()).Create(plan).Error; err != nil {
+		s.Log.Errorf("Failed to create meal plan: %+v", err)
+		return nil, err
+	}
+
+	return s.GetMealPlanByID(c, userID, plan.ID.String())
+}
+
+func (s *mealPlanService) GetMealPlans(c *fiber.Ctx, userID uuid.UUID) ([]model.MealPlan, error) {
+	var plans []model.MealPlan
+	if err := s.DB.WithContext(c.Context()).
+		Where("user_id = ?", userID).
+		Order("start_date DESC, created_at DESC").
+		Find(&plans).Error; err != nil {
+		s.Log.Errorf("Failed to get meal plans: %+v", err)
+		return nil, err
+	}
+	return plans, nil
+}
+
+// GetCurrentMealPlan returns the latest plan covering today
+func (s *mealPlanService) GetCurrentMealPlan(c *fiber.Ctx, userID uuid.UUID) (*model.MealPlan, error) {
+	location, err := userLocation(c, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+	today := startOfDay(time.Now(), location)
+
+	plan := new(model.MealPlan)
+	if err := s.DB.WithContext(c.Context()).
+		Where("user_id = ? AND start_date <= ? AND end_date >= ?", userID, today, today).
+		Order("created_at DESC").
+		Preload("Slots", orderByPosition).
+		Preload("Slots.Recipe.Nutrients").
+		First(plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "No meal plan for today")
+		}
+		s.Log.Errorf("Failed to get current meal plan: %+v", err)
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (s *mealPlanService) GetMealPlanByID(c *fiber.Ctx, userID uuid.UUID, planID string) (*model.MealPlan, error) {
+	plan := new(model.MealPlan)
+	if err := s.DB.WithContext(c.Context()).
+		Where("id = ? AND user_id = ?", planID, userID).
+		Preload("Slots", orderByPosition).
+		Preload("Slots.Recipe.Nutrients").
+		First(plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Meal plan not found")
+		}
+		s.Log.Errorf("Failed to get meal plan: %+v", err)
+		return nil, err
+	}
+	return plan, nil
+}
+
+// DeleteMealPlan removes the plan, meals already logged from it are kept
+func (s *mealPlanService) DeleteMealPlan(c *fiber.Ctx, userID uuid.UUID, planID string) error {
+	plan, err := s.findMealPlan(c, userID, planID)
+	if err != nil {
+		return err
+	}
+
+	err = s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("meal_plan_id = ?", plan.ID).Delete(&model.MealPlanSlot{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(plan).Error
+	})
+	if err != nil {
+		s.Log.Errorf("Failed to delete meal plan: %+v", err)
+		return err
+	}
+	return nil
+}
+
+// SwapSlot replaces the recipe of a slot with the requested one, or with the best alternative when none
+// is given. Servings are scaled so the slot still fits the plan's targets.
+func (s *mealPlanService) SwapSlot(c *fiber.Ctx, userID uuid.UUID, planID, slotID string, req *validation.SwapMealPlanSlot) (*model.MealPlanSlot, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	plan, err := s.GetMealPlanByID(c, userID, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	slot := findPlanSlot(plan, slotID)
+	if slot == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Meal plan slot not found")
+	}
+	if slot.MealHistoryID != nil {
+		return nil, fiber.NewError(fiber.StatusConflict, "Meal plan slot is already logged")
+	}
+
+	var excluded []string
+	if len(plan.ExcludedIngredients) > 0 {
+		if err := json.Unmarshal(plan.ExcludedIngredients, &excluded); err != nil {
+			s.Log.Errorf("Failed to read excluded ingredients: %+v", err)
+			return nil, err
+		}
+	}
+
+	var recipe *model.Recipe
+	if req.RecipeID != "" {
+		recipe = new(model.Recipe)
+		if err := s.DB.WithContext(c.Context()).Preload("IngredientLines").First(recipe, "id = ?", req.RecipeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fiber.NewError(fiber.StatusNotFound, "Recipe not found")
+			}
+			s.Log.Errorf("Failed to get recipe: %+v", err)
+			return nil, err
+		}
+		if recipe.Calories == nil || *recipe.Calories <= 0 {
+			return nil, fiber.NewError(fiber.StatusUnprocessableEntity, "Recipe has no nutrition information")
+		}
+		if recipeExcluded(recipe, excluded) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Recipe contains an excluded ingredient")
+		}
+	} else {
+		var recipes []model.Recipe
+		if err := s.DB.WithContext(c.Context()).
+			Where("calories > 0 AND id <> ?", slot.RecipeID).
+			Preload("IngredientLines").
+			Find(&recipes).Error; err != nil {
+			s.Log.Errorf("Failed to get recipes: %+v", err)
+			return nil, err
+		}
+
+		planner := newMealPlanner(plan, recipes, excluded)
+		for _, other := range plan.Slots {
+			if other.ID != slot.ID {
+				planner.use(other.RecipeID, other.Date)
+			}
+		}
+		recipe = planner.pick(slot.Label, slot.Date, slot.Day)
+		if recipe == nil {
+			return nil, fiber.NewError(fiber.StatusUnprocessableEntity, "No other recipe fits this slot")
+		}
+	}
+
+	share := mealPlanShare(slot.Label)
+	applySlotRecipe(slot, recipe, plan.CalorieTarget*share)
+
+	if err := s.DB.WithContext(c.Context()).
+		Model(&model.MealPlanSlot{}).
+		Where("id = ?", slot.ID).
+		Updates(map[string]interface{}{
+			"recipe_id": slot.RecipeID,
+			"servings":  slot.Servings,
+			"calories":  slot.Calories,
+			"protein":   slot.Protein,
+			"carbs":     slot.Carbs,
+			"fat":       slot.Fat,
+		}).Error; err != nil {
+		s.Log.Errorf("Failed to swap meal plan slot: %+v", err)
+		return nil, err
+	}
+
+	return slot, nil
+}
+
+// LogSlot records a planned meal in the meal history. Today's meals are logged now and past ones at the
+// usual time of their label, meals planned for later days can't be logged yet.
+func (s *mealPlanService) LogSlot(c *fiber.Ctx, userID uuid.UUID, planID, slotID string) (*model.MealHistory, error) {
+	plan, err := s.GetMealPlanByID(c, userID, planID)
+	if err != nil {
+		return nil, err
+	}
+
+	slot := findPlanSlot(plan, slotID)
+	if slot == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Meal plan slot not found")
+	}
+	if slot.MealHistoryID != nil {
+		return nil, fiber.NewError(fiber.StatusConflict, "Meal plan slot is already logged")
+	}
+
+	location, err := time.LoadLocation(plan.Timezone)
+	if err != nil {
+		location = DefaultLocation()
+	}
+	now := time.Now()
+	today := startOfDay(now, location)
+	slotDay := startOfDay(slot.Date, location)
+	if slotDay.After(today) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Meals planned for a later day can't be logged yet")
+	}
+
+	mealTime := now
+	if slotDay.Before(today) {
+		mealTime = slotDay.Add(time.Duration(mealPlanHour(slot.Label)) * time.Hour)
+	}
+
+	label := slot.Label
+	meal := &model.MealHistory{
+		UserID:   userID,
+		Title:    "Planned Meal",
+		MealTime: mealTime,
+		Label:    &label,
+		Calories: slot.Calories,
+		Protein:  slot.Protein,
+		Carbs:    slot.Carbs,
+		Fat:      slot.Fat,
+	}
+	if slot.Recipe != nil {
+		meal.Title = slot.Recipe.Name
+		if slot.Recipe.Image != nil {
+			meal.MealImage = *slot.Recipe.Image
+		}
+	}
+
+	// Micronutrients come from the recipe's nutrients per serving, the macros from the slot
+	extra := make(map[string]float64)
+	if slot.Recipe != nil {
+		for _, nutrient := range slot.Recipe.Nutrients {
+			extra[nutrient.Code] = nutrient.Quantity * slot.Servings
+		}
+	}
+	nutrients, err := buildMealNutrients(meal, extra)
+	if err != nil {
+		return nil, err
+	}
+	meal.Nutrients = nutrients
+
+	err = s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(meal).Error; err != nil {
+			return err
+		}
+
+		// Only the first of two concurrent taps links the slot
+		result := tx.Model(&model.MealPlanSlot{}).
+			Where("id = ? AND meal_history_id IS NULL", slot.ID).
+			Updates(map[string]interface{}{"meal_history_id": meal.ID, "logged_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fiber.NewError(fiber.StatusConflict, "Meal plan slot is already logged")
+		}
+		return nil
+	})
+	if err != nil {
+		var fiberErr *fiber.Error
+		if !errors.As(err, &fiberErr) {
+			s.Log.Errorf("Failed to log meal plan slot: %+v", err)
+		}
+		return nil, err
+	}
+
+	return meal, nil
+}
+
+func (s *mealPlanService) findMealPlan(c *fiber.Ctx, userID uuid.UUID, planID string) (*model.MealPlan, error) {
+	plan := new(model.MealPlan)
+	if err := s.DB.WithContext(c.Context()).
+		Where("id = ? AND user_id = ?", planID, userID).
+		First(plan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Meal plan not found")
+		}
+		s.Log.Errorf("Failed to get meal plan: %+v", err)
+		return nil, err
+	}
+	return plan, nil
+}
+
+func findPlanSlot(plan *model.MealPlan, slotID string) *model.MealPlanSlot {
+	for i := range plan.Slots {
+		if plan.Slots[i].ID.String() == slotID {
+			return &plan.Slots[i]
+		}
+	}
+	return nil
+}
+
+func mealPlanShare(label string) float64 {
+	for _, planned := range mealPlanLabels {
+		if planned.Label == label {
+			return planned.Share
+		}
+	}
+	return 0
+}
+
+func mealPlanHour(label string) int {
+	for _, planned := range mealPlanLabels {
+		if planned.Label == label {
+			return planned.Hour
+		}
+	}
+	return 12
+}
+
+// PlanMealSlots fills plan with a recipe for every meal of every day. Recipes are picked greedily, closest
+// to the slot's calories and the plan's macro split first, with repeats and recipes meant for another day
+// or meal pushed back. Recipes containing an excluded ingredient are never picked.
+func PlanMealSlots(plan *model.MealPlan, recipes []model.Recipe, excluded []string) error {
+	planner := newMealPlanner(plan, recipes, excluded)
+	if len(planner.recipes) == 0 {
+		return fiber.NewError(fiber.StatusUnprocessableEntity, "No recipes with nutrition information match the excluded ingredients")
+	}
+
+	plan.Slots = make([]model.MealPlanSlot, 0, model.MealPlanDays*len(mealPlanLabels))
+	for day := 0; day < model.MealPlanDays; day++ {
+		date := plan.StartDate.AddDate(0, 0, day)
+		weekday := strings.ToLower(date.Weekday().String())
+
+		for _, planned := range mealPlanLabels {
+			recipe := planner.pick(planned.Label, date, weekday)
+			planner.use(recipe.ID, date)
+
+			slot := model.MealPlanSlot{
+				Date:     date,
+				Day:      weekday,
+				Label:    planned.Label,
+				Position: len(plan.Slots),
+			}
+			applySlotRecipe(&slot, recipe, plan.CalorieTarget*planned.Share)
+			plan.Slots = append(plan.Slots, slot)
+		}
+	}
+
+	return nil
+}
+
+// applySlotRecipe puts recipe in slot with as many servings as bring it closest to calories
+func applySlotRecipe(slot *model.MealPlanSlot, recipe *model.Recipe, calories float64) {
+	servings := planServings(*recipe.Calories, calories)
+
+	slot.RecipeID = recipe.ID
+	slot.Recipe = recipe
+	slot.Servings = servings
+	slot.Calories = roundNutrient(*recipe.Calories * servings)
+	slot.Protein = roundNutrient(recipeNutrient(recipe.Protein) * servings)
+	slot.Carbs = roundNutrient(recipeNutrient(recipe.Carbs) * servings)
+	slot.Fat = roundNutrient(recipeNutrient(recipe.Fat) * servings)
+}
+
+func planServings(perServing, target float64) float64 {
+	if perServing <= 0 || target <= 0 {
+		return 1
+	}
+	servings := math.Round(target/perServing*2) / 2
+	return math.Min(math.Max(servings, minPlanServings), maxPlanServings)
+}
+
+func recipeNutrient(quantity *float64) float64 {
+	if quantity == nil {
+		return 0
+	}
+	return *quantity
+}
+
+// normalizeExclusions lowercases and dedupes excluded ingredients
+func normalizeExclusions(excluded []string) []string {
+	normalized := make([]string, 0, len(excluded))
+	seen := make(map[string]bool, len(excluded))
+	for _, ingredient := range excluded {
+		ingredient = strings.ToLower(strings.TrimSpace(ingredient))
+		if ingredient == "" || seen[ingredient] {
+			continue
+		}
+		seen[ingredient] = true
+		normalized = append(normalized, ingredient)
+	}
+	return normalized
+}
+
+// recipeExcluded reports whether the recipe's name, ingredients or ingredient lines mention one of the excluded
+// ingredients as whole words, so excluding "ayam" leaves "bayam" alone. An ingredient line also matches by kode.
+func recipeExcluded(recipe *model.Recipe, excluded []string) bool {
+	if len(excluded) == 0 {
+		return false
+	}
+	words := []string{normalizeFoodName(recipe.Name), normalizeFoodName(recipe.Ingredients)}
+	for _, line := range recipe.IngredientLines {
+		words = append(words, normalizeFoodName(line.NamaBahanMakanan))
+	}
+	// Padding every part with spaces lets " ayam " only match the whole word
+	text := " " + strings.Join(words, " ") + " "
+
+	for _, ingredient := range excluded {
+		phrase := normalizeFoodName(ingredient)
+		if phrase == "" {
+			continue
+		}
+		if strings.Contains(text, " "+phrase+" ") {
+			return true
+		}
+		for _, line := range recipe.IngredientLines {
+			if strings.EqualFold(line.Kode, strings.TrimSpace(ingredient)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Weights of the planner's penalties, relative to a slot missing its calories by 100%
+const (
+	repeatPenalty       = 0.35
+	sameDayPenalty      = 1
+	otherLabelPenalty   = 0.25
+	otherWeekdayPenalty = 0.1
+)
+
+type mealPlanner struct {
+	plan    *model.MealPlan
+	recipes []model.Recipe
+	uses    map[uuid.UUID]int
+	days    map[uuid.UUID]map[string]bool
+}
+
+func newMealPlanner(plan *model.MealPlan, recipes []model.Recipe, excluded []string) *mealPlanner {
+	planner := &mealPlanner{
+		plan: plan,
+		uses: make(map[uuid.UUID]int),
+		days: make(map[uuid.UUID]map[string]bool),
+	}
+	for _, recipe := range recipes {
+		if recipe.Calories == nil || *recipe.Calories <= 0 || recipeExcluded(&recipe, excluded) {
+			continue
+		}
+		planner.recipes = append(planner.recipes, recipe)
+	}
+	// A stable order keeps plans reproducible when scores tie
+	sort.SliceStable(planner.recipes, func(i, j int) bool {
+		if planner.recipes[i].Name != planner.recipes[j].Name {
+			return planner.recipes[i].Name < planner.recipes[j].Name
+		}
+		return planner.recipes[i].ID.String() < planner.recipes[j].ID.String()
+	})
+	return planner
+}
+
+func (p *mealPlanner) use(recipeID uuid.UUID, date time.Time) {
+	p.uses[recipeID]++
+	if p.days[recipeID] == nil {
+		p.days[recipeID] = make(map[string]bool)
+	}
+	p.days[recipeID][date.Format("2006-01-02")] = true
+}
+
+// pick returns the best scoring recipe for a slot, or nil when there are no candidates
+func (p *mealPlanner) pick(label string, date time.Time, weekday string) *model.Recipe {
+	var best *model.Recipe
+	bestScore := math.Inf(1)
+	for i := range p.recipes {
+		score := p.score(&p.recipes[i], label, date, weekday)
+		if score < bestScore {
+			best = &p.recipes[i]
+			bestScore = score
+		}
+	}
+	return best
+}
+
+func (p *mealPlanner) score(recipe *model.Recipe, label string, date time.Time, weekday string) float64 {
+	slotCalories := p.plan.CalorieTarget * mealPlanShare(label)
+	calories := *recipe.Calories
+
+	score := 0.0
+	if slotCalories > 0 {
+		score += math.Abs(calories*planServings(calories, slotCalories)-slotCalories) / slotCalories
+	}
+
+	// Distance between the recipe's energy split and the plan's, servings don't change it
+	if p.plan.CalorieTarget > 0 {
+		score += math.Abs(recipeNutrient(recipe.Protein)*4/calories - p.plan.ProteinTarget*4/p.plan.CalorieTarget)
+		score += math.Abs(recipeNutrient(recipe.Carbs)*4/calories - p.plan.CarbsTarget*4/p.plan.CalorieTarget)
+		score += math.Abs(recipeNutrient(recipe.Fat)*9/calories - p.plan.FatTarget*9/p.plan.CalorieTarget)
+	}
+
+	score += repeatPenalty * float64(p.uses[recipe.ID])
+	if p.days[recipe.ID][date.Format("2006-01-02")] {
+		score += sameDayPenalty
+	}
+	if recipe.Label != nil && *recipe.Label != "" && *recipe.Label != label {
+		score += otherLabelPenalty
+	}
+	if recipe.Day != "" && recipe.Day != weekday {
+		score += otherWeekdayPenalty
+	}
+
+	return score
+}
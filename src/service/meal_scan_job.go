@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"time"
+
+	"app/src/config"
+	"app/src/model"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MealImageDir is where scanned meal images are kept, inside UPLOAD_DIR so they are served under /uploads/meals
+func MealImageDir() string {
+	return filepath.Join(config.UploadDir, "meals")
+}
+
+const scanJobQueueSize = 100
+
+// scanJobRequeueInterval is how often queued jobs that never reached a worker are handed over again
+const scanJobRequeueInterval = time.Minute
+
+// CreateScanJob stores the image on disk, persists a queued job and hands it to the worker pool
+func (s *mealService) CreateScanJob(c *fiber.Ctx, imageFile *multipart.FileHeader, userID uuid.UUID) (*model.MealScanJob, error) {
+	imagePath, err := s.saveMealImage(c, imageFile)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &model.MealScanJob{
+		UserID:    userID,
+		Status:    model.ScanJobQueued,
+		Step:      model.ScanStepSegmentation,
+		ImagePath: imagePath,
+		Filename:  imageFile.Filename,
+	}
+	if quota, ok := c.Locals("scanQuota").(*model.ScanQuota); ok && quota != nil {
+		job.SubscriptionID = &quota.SubscriptionID
+	}
+
+	if err := s.DB.WithContext(c.Context()).Create(job).Error; err != nil {
+		s.Log.Errorf("Failed to create scan job: %+v", err)
+		_ = os.Remove(imagePath)
+		return nil, err
+	}
+
+	s.enqueueScanJob(job.ID)
+
+	return job, nil
+}
+
+func (s *mealService) GetScanJob(c *fiber.Ctx, id string, userID uuid.UUID) (*model.MealScanJob, error) {
+	job := new(model.MealScanJob)
+
+	result := s.DB.WithContext(c.Context()).First(job, "id = ?", id)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Scan job not found")
+	}
+
+	if result.Error != nil {
+		s.Log.Errorf("Failed get scan job by id: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	if job.UserID != userID {
+		return nil, fiber.NewError(fiber.StatusForbidden, "You don't have permission to access this resource")
+	}
+
+	return job, nil
+}
+
+// StartScanWorkers launches the worker pool and requeues jobs left unfinished by a previous run
+func (s *mealService) StartScanWorkers(ctx context.Context, workers, maxAttempts int) {
+	if workers < 1 {
+		workers = 1
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	s.scanJobs = make(chan uuid.UUID, scanJobQueueSize)
+	s.scanMaxAttempts = maxAttempts
+
+	// Jobs a previous run was processing were interrupted, they are claimed again from queued
+	if err := s.DB.WithContext(ctx).
+		Model(&model.MealScanJob{}).
+		Where("status = ?", model.ScanJobProcessing).
+		Update("status", model.ScanJobQueued).Error; err != nil {
+		s.Log.Errorf("Failed to requeue interrupted scan jobs: %+v", err)
+	}
+
+	for i := 0; i < workers; i++ {
+		go s.runScanWorker(ctx)
+	}
+
+	go s.requeueScanJobs(ctx)
+}
+
+// requeueScanJobs hands the queued jobs to the workers now, and every scanJobRequeueInterval the ones that
+// have waited since the last pass, like jobs that didn't fit in a full queue
+func (s *mealService) requeueScanJobs(ctx context.Context) {
+	ticker := time.NewTicker(scanJobRequeueInterval)
+	defer ticker.Stop()
+
+	queuedBefore := time.Now()
+	for {
+		var pending []model.MealScanJob
+		if err := s.DB.WithContext(ctx).
+			Select("id").
+			Where("status = ? AND updated_at <= ?", model.ScanJobQueued, queuedBefore).
+			Order("created_at ASC").
+			Limit(scanJobQueueSize).
+			Find(&pending).Error; err != nil {
+			s.Log.Errorf("Failed to load pending scan jobs: %+v", err)
+		}
+
+		for _, job := range pending {
+			s.enqueueScanJob(job.ID)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			queuedBefore = now.Add(-scanJobRequeueInterval)
+		}
+	}
+}
+
+func (s *mealService) enqueueScanJob(id uuid.UUID) {
+	if s.scanJobs == nil {
+		// Workers are not running, the job stays queued until the next start
+		return
+	}
+
+	// Never block the caller, a job that doesn't fit stays queued until requeueScanJobs hands it over
+	select {
+	case s.scanJobs <- id:
+	default:
+		s.Log.Warnf("Scan job queue is full, job %s waits for the next requeue", id)
+	}
+}
+
+func (s *mealService) runScanWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.scanJobs:
+			s.processScanJob(ctx, id)
+		}
+	}
+}
+
+// processScanJob runs the remaining steps of a job, retrying with backoff until maxAttempts
+func (s *mealService) processScanJob(ctx context.Context, id uuid.UUID) {
+	// The job is claimed by moving it out of queued, so a job enqueued twice is only run by one worker
+	claim := s.DB.WithContext(ctx).
+		Model(&model.MealScanJob{}).
+		Where("id = ? AND status = ?", id, model.ScanJobQueued).
+		Updates(map[string]interface{}{
+			"status":   model.ScanJobProcessing,
+			"attempts": gorm.Expr("attempts + 1"),
+		})
+	if claim.Error != nil {
+		s.Log.Errorf("Failed to claim scan job %s: %+v", id, claim.Error)
+		return
+	}
+	if claim.RowsAffected == 0 {
+		return
+	}
+
+	job := new(model.MealScanJob)
+	if err := s.DB.WithContext(ctx).First(job, "id = ?", id).Error; err != nil {
+		s.Log.Errorf("Failed to load scan job %s: %+v", id, err)
+		return
+	}
+
+	err := s.runScanJobRecovered(ctx, job)
+	if err == nil {
+		return
+	}
+
+	message := err.Error()
+	job.Error = &message
+
+	if job.Attempts >= s.scanMaxAttempts {
+		s.Log.Errorf("Scan job %s failed after %d attempts: %+v", id, job.Attempts, err)
+		job.Status = model.ScanJobFailed
+		_ = os.Remove(job.ImagePath)
+		s.refundScanJob(ctx, job)
+	} else {
+		job.Status = model.ScanJobQueued
+	}
+
+	if err := s.DB.WithContext(ctx).Save(job).Error; err != nil {
+		s.Log.Errorf("Failed to update scan job %s: %+v", id, err)
+		return
+	}
+
+	if job.Status == model.ScanJobQueued {
+		backoff := time.Duration(1<<(job.Attempts-1)) * 2 * time.Second
+		time.AfterFunc(backoff, func() { s.enqueueScanJob(job.ID) })
+	}
+}
+
+// runScanJobRecovered runs the job's steps and turns a panic in them into the job's error, so the job is
+// retried or failed like any other instead of taking the API down with it
+func (s *mealService) runScanJobRecovered(ctx context.Context, job *model.MealScanJob) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.Log.Errorf("Scan job %s panicked: %v\n%s", job.ID, r, debug.Stack())
+			err = fmt.Errorf("scan job panicked: %v", r)
+		}
+	}()
+
+	return s.runScanJobSteps(ctx, job)
+}
+
+// runScanJobSteps resumes from job.Step so a retry doesn't repeat work that already succeeded
+func (s *mealService) runScanJobSteps(ctx context.Context, job *model.MealScanJob) error {
+	recognition := new(FoodRecognition)
+
+	if job.Step == model.ScanStepSegmentation || len(job.Recognition) == 0 {
+		file, err := os.Open(job.ImagePath)
+		if err != nil {
+			return fmt.Errorf("failed to open scan image: %w", err)
+		}
+		defer file.Close()
+
+		recognition, err = s.Recognizer.Segment(ctx, file, job.Filename)
+		if err != nil {
+			return err
+		}
+
+		recognitionJSON, _ := json.Marshal(recognition)
+		job.Recognition = model.JSON(recognitionJSON)
+		job.Step = model.ScanStepNutrition
+		if err := s.DB.WithContext(ctx).Save(job).Error; err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal(job.Recognition, recognition); err != nil {
+		return err
+	}
+
+	totalNutr, err := s.Recognizer.Nutrients(ctx, recognition)
+	if err != nil {
+		return err
+	}
+
+	job.Step = model.ScanStepSaving
+	resultJSON, _ := json.Marshal(MealScanResponse{
+		Foods:     recognition.TopFoodNames(3),
+		TotalNutr: totalNutr,
+	})
+
+	var foods []model.MealHistoryFood
+	if job.MealHistoryID == nil {
+		foods = s.matchScannedFoods(ctx, recognition)
+	}
+
+	// The meal and the completed job are saved together, so a retry can't save the same scan twice
+	mealHistoryID := job.MealHistoryID
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if mealHistoryID == nil {
+			id, err := s.saveMealHistory(tx, job.UserID, recognition, totalNutr, foods, job.ImagePath)
+			if err != nil {
+				return err
+			}
+			mealHistoryID = &id
+		}
+
+		return tx.Model(job).Updates(map[string]interface{}{
+			"step":            job.Step,
+			"status":          model.ScanJobCompleted,
+			"result":          model.JSON(resultJSON),
+			"meal_history_id": *mealHistoryID,
+			"error":           nil,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	job.Status = model.ScanJobCompleted
+	job.Result = model.JSON(resultJSON)
+	job.MealHistoryID = mealHistoryID
+	job.Error = nil
+	return nil
+}
+
+// refundScanJob gives back the scan reserved by the scan quota middleware for a job that failed for good
+func (s *mealService) refundScanJob(ctx context.Context, job *model.MealScanJob) {
+	if job.SubscriptionID == nil {
+		return
+	}
+
+	if err := s.DB.WithContext(ctx).
+		Model(&model.UserSubscription{}).
+		Where("id = ? AND ai_scans_used > 0", *job.SubscriptionID).
+		Update("ai_scans_used", gorm.Expr("ai_scans_used - 1")).Error; err != nil {
+		s.Log.Errorf("Failed to refund scan for job %s: %+v", job.ID, err)
+	}
+}
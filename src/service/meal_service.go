@@ -1,31 +1,40 @@
 package service
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"sort"
-	"strconv"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
+	"app/src/config"
 	"app/src/model"
+	"app/src/validation"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MealService interface {
 	ScanMeal(c *fiber.Ctx, imageFile *multipart.FileHeader, userID uuid.UUID) (*MealScanResponse, error)
+	CreateScanJob(c *fiber.Ctx, imageFile *multipart.FileHeader, userID uuid.UUID) (*model.MealScanJob, error)
+	GetScanJob(c *fiber.Ctx, id string, userID uuid.UUID) (*model.MealScanJob, error)
+	StartScanWorkers(ctx context.Context, workers, maxAttempts int)
 	GetMeals(c *fiber.Ctx) ([]model.MealHistory, int64, error)
 	GetMealByID(c *fiber.Ctx, id string) (*model.MealHistory, error)
 	GetMealScanDetailByID(c *fiber.Ctx, id string) (*model.MealHistoryDetail, error)
 	AddMealScanDetail(c *fiber.Ctx, mealId string, meal *model.MealHistoryDetail) (*model.MealHistoryDetail, error)
+	ConfirmMealScan(c *fiber.Ctx, mealId string, req *validation.ConfirmMealScan) (*model.MealHistory, error)
 	AddMeal(c *fiber.Ctx, meal *model.MealHistory) (*model.MealHistory, error)
 	UpdateMeal(c *fiber.Ctx, id string, meal *model.MealHistory) (*model.MealHistory, error)
 	DeleteMeal(c *fiber.Ctx, id string) error
@@ -34,18 +43,25 @@ type MealService interface {
 }
 
 type mealService struct {
-	Log     *logrus.Logger
-	DB      *gorm.DB
-	ApiKey  string
-	BaseURL string
+	Log          *logrus.Logger
+	DB           *gorm.DB
+	Validate     *validator.Validate
+	Recognizer   FoodRecognizer
+	Matcher      FoodMatchingService
+	BahanMakanan BahanMakananService
+
+	scanJobs        chan uuid.UUID
+	scanMaxAttempts int
 }
 
-func NewMealService(db *gorm.DB, apiKey, baseURL string) *mealService {
+func NewMealService(db *gorm.DB, validate *validator.Validate, recognizer FoodRecognizer, matcher FoodMatchingService, bahanMakanan BahanMakananService) *mealService {
 	return &mealService{
-		Log:     logrus.New(),
-		DB:      db,
-		ApiKey:  apiKey,
-		BaseURL: baseURL,
+		Log:          logrus.New(),
+		DB:           db,
+		Validate:     validate,
+		Recognizer:   recognizer,
+		Matcher:      matcher,
+		BahanMakanan: bahanMakanan,
 	}
 }
 
@@ -60,6 +76,8 @@ type Nutrient struct {
 	Protein  NutrientDetail `json:"protein"`
 	Carbs    NutrientDetail `json:"carbs"`
 	Fat      NutrientDetail `json:"fat"`
+	// Details holds every other nutrient by code (see model.NutrientDefinitions), in the definition's unit
+	Details map[string]NutrientDetail `json:"details,omitempty"`
 }
 
 type MealScanResponse struct {
@@ -67,158 +85,132 @@ type MealScanResponse struct {
 	TotalNutr Nutrient   `json:"total_nutrient"`
 }
 
+// MealScanResult is what a scan stores in MealHistoryDetail.APIResult. Segments keep every candidate
+// so the user can later confirm or correct the detected foods.
+type MealScanResult struct {
+	ImageID            string          `json:"image_id,omitempty"`
+	Foods              [][]string      `json:"foods"`
+	Segments           []FoodSegment   `json:"segments,omitempty"`
+	Nutrients          Nutrient        `json:"nutrients"`
+	Selections         []FoodSelection `json:"selections,omitempty"`
+	ConfirmedNutrients *Nutrient       `json:"confirmed_nutrients,omitempty"`
+}
+
 // ScanMeal handles the image scanning process
 func (s *mealService) ScanMeal(c *fiber.Ctx, imageFile *multipart.FileHeader, userID uuid.UUID) (*MealScanResponse, error) {
-	file, err := imageFile.Open()
+	imagePath, err := s.saveMealImage(c, imageFile)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(imagePath)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	// Step 1: Upload Image to Segmentation API
-	imageId, foods, err := s.uploadImageToSegmentationAPI(file, imageFile.Filename)
+	// Step 1: Segment the image into candidate foods
+	recognition, err := s.Recognizer.Segment(c.Context(), file, imageFile.Filename)
 	if err != nil {
+		_ = os.Remove(imagePath)
 		return nil, err
 	}
 
-	// Step 2: Convert imageId to string and get Nutrition Info
-	imageIdStr := strconv.Itoa(imageId)
-	totalNutr, err := s.getNutritionInfo(imageIdStr)
+	// Step 2: Get nutrition info for the recognized foods
+	totalNutr, err := s.Recognizer.Nutrients(c.Context(), recognition)
 	if err != nil {
+		_ = os.Remove(imagePath)
 		return nil, err
 	}
 
 	// Step 3: Simpan hasil scan ke database (MealHistory & MealHistoryDetail)
-	if err := s.saveMealHistory(userID, foods, totalNutr); err != nil {
+	foods := s.matchScannedFoods(c.Context(), recognition)
+	if _, err := s.saveMealHistory(s.DB.WithContext(c.Context()), userID, recognition, totalNutr, foods, imagePath); err != nil {
+		_ = os.Remove(imagePath)
 		return nil, err
 	}
 
 	return &MealScanResponse{
-		Foods:     foods,
+		// Group food names per area (taking top 3 highest probability)
+		Foods:     recognition.TopFoodNames(3),
 		TotalNutr: totalNutr,
 	}, nil
 }
 
-// Upload image to segmentation API and extract food names
-func (s *mealService) uploadImageToSegmentationAPI(file io.Reader, filename string) (int, [][]string, error) {
-	url := fmt.Sprintf("%s/v2/image/segmentation/complete/v1.1?language=eng", s.BaseURL)
-	buffer := &bytes.Buffer{}
-	writer := multipart.NewWriter(buffer)
-
-	part, err := writer.CreateFormFile("image", filename)
+// saveMealImage stores the uploaded image under MealImageDir with a random name. The directory is served
+// as is, so the type is sniffed from the file and the extension follows it, not the client's file name.
+func (s *mealService) saveMealImage(c *fiber.Ctx, imageFile *multipart.FileHeader) (string, error) {
+	extension, err := sniffImageExtension(imageFile)
 	if err != nil {
-		return 0, nil, err
+		return "", err
 	}
-	io.Copy(part, file)
-	writer.Close()
 
-	req, err := http.NewRequest("POST", url, buffer)
-	if err != nil {
-		return 0, nil, err
+	if err := os.MkdirAll(MealImageDir(), 0o755); err != nil {
+		s.Log.Errorf("Failed to create meal image dir: %+v", err)
+		return "", err
 	}
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+s.ApiKey)
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return 0, nil, err
+	imagePath := filepath.Join(MealImageDir(), uuid.New().String()+extension)
+	if err := c.SaveFile(imageFile, imagePath); err != nil {
+		s.Log.Errorf("Failed to save meal image: %+v", err)
+		return "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return 0, nil, errors.New("failed to upload image")
-	}
-
-	// Struct for response parsing
-	var result struct {
-		ImageID          int `json:"imageId"`
-		SegmentationData []struct {
-			RecognitionResults []struct {
-				Name        string  `json:"name"`
-				Probability float64 `json:"probability"`
-			} `json:"recognition_results"`
-		} `json:"segmentation_results"`
-	}
-
-	err = json.NewDecoder(resp.Body).Decode(&result)
-	if err != nil {
-		return 0, nil, err
-	}
-
-	// Group food names per area (taking top 3 highest probability)
-	var groupedFoodNames [][]string
-	for _, segment := range result.SegmentationData {
-		recognitionResults := segment.RecognitionResults
-
-		// Sort food items by probability (descending)
-		sort.Slice(recognitionResults, func(i, j int) bool {
-			return recognitionResults[i].Probability > recognitionResults[j].Probability
-		})
 
-		// Take up to 3 highest probability foods
-		var topFoods []string
-		for i := 0; i < len(recognitionResults) && i < 3; i++ {
-			topFoods = append(topFoods, recognitionResults[i].Name)
-		}
-
-		// Append to grouped list
-		groupedFoodNames = append(groupedFoodNames, topFoods)
-	}
-
-	return result.ImageID, groupedFoodNames, nil
+	return imagePath, nil
 }
 
-// Fetch nutrition info based on imageId
-func (s *mealService) getNutritionInfo(imageId string) (Nutrient, error) {
-	url := fmt.Sprintf("%s/v2/nutrition/recipe/nutritionalInfo/v1.1?language=eng", s.BaseURL)
-	payload, _ := json.Marshal(map[string]string{"imageId": imageId})
+// mealImageExtensions are the content types a scanned meal image may have, with the extension it is stored
+// with. They are what the food recognizers accept, apart from the types of the upload subsystem.
+var mealImageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
 
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
+// sniffImageExtension returns the extension of an uploaded JPEG, PNG or WebP image and rejects anything else
+func sniffImageExtension(imageFile *multipart.FileHeader) (string, error) {
+	file, err := imageFile.Open()
 	if err != nil {
-		return Nutrient{}, err
+		return "", err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.ApiKey)
+	defer file.Close()
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return Nutrient{}, err
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", fiber.NewError(fiber.StatusBadRequest, "The image can't be read")
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return Nutrient{}, errors.New("failed to get nutrition info")
+	extension, ok := mealImageExtensions[http.DetectContentType(head[:n])]
+	if !ok {
+		return "", fiber.NewError(fiber.StatusUnsupportedMediaType, "Only JPEG, PNG and WebP images are supported")
 	}
+	return extension, nil
+}
 
-	// Parsing response dengan map[string]interface{}
-	var result map[string]interface{}
-	err = json.NewDecoder(resp.Body).Decode(&result)
+// mealImageURL turns a path under UPLOAD_DIR into the URL served by the static uploads route
+func mealImageURL(imagePath string) string {
+	relative, err := filepath.Rel(config.UploadDir, imagePath)
 	if err != nil {
-		return Nutrient{}, err
+		relative = filepath.Base(imagePath)
 	}
+	return "/uploads/" + filepath.ToSlash(relative)
+}
 
-	nutritionalInfo := result["nutritional_info"].(map[string]interface{})
-	totalNutrients := nutritionalInfo["totalNutrients"].(map[string]interface{})
-
-	extractNutrientDetail := func(nutrientKey string) NutrientDetail {
-		if nutrient, exists := totalNutrients[nutrientKey].(map[string]interface{}); exists {
-			quantity, _ := nutrient["quantity"].(float64)
-			unit, _ := nutrient["unit"].(string)
-			return NutrientDetail{Quantity: quantity, Unit: unit}
-		}
-		return NutrientDetail{Quantity: 0, Unit: ""}
+// matchScannedFoods looks up the TKPI foods of a scan. It calls the catalog service, so it runs before the meal
+// is saved rather than inside the saving transaction.
+func (s *mealService) matchScannedFoods(ctx context.Context, recognition *FoodRecognition) []model.MealHistoryFood {
+	foods, err := s.Matcher.MatchRecognition(ctx, recognition)
+	if err != nil {
+		// The scan result is still useful without TKPI references
+		s.Log.Errorf("Failed to match scanned foods to bahan makanan: %+v", err)
+		return nil
 	}
-
-	return Nutrient{
-		Calories: extractNutrientDetail("ENERC_KCAL"),
-		Protein:  extractNutrientDetail("PROCNT"),
-		Carbs:    extractNutrientDetail("CHOCDF"),
-		Fat:      extractNutrientDetail("FAT"),
-	}, nil
+	return foods
 }
 
-// Save meal history and details
-func (s *mealService) saveMealHistory(userID uuid.UUID, foods [][]string, totalNutr Nutrient) error {
+// Save meal history and details along with the TKPI foods matched by matchScannedFoods
+func (s *mealService) saveMealHistory(db *gorm.DB, userID uuid.UUID, recognition *FoodRecognition, totalNutr Nutrient, foods []model.MealHistoryFood, imagePath string) (uuid.UUID, error) {
 	mealHistory := model.MealHistory{
 		ID:        uuid.New(),
 		UserID:    userID,
@@ -228,18 +220,27 @@ func (s *mealService) saveMealHistory(userID uuid.UUID, foods [][]string, totalN
 		Protein:   totalNutr.Protein.Quantity,
 		Carbs:     totalNutr.Carbs.Quantity,
 		Fat:       totalNutr.Fat.Quantity,
+		MealImage: mealImageURL(imagePath),
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
 
-	if err := s.DB.Create(&mealHistory).Error; err != nil {
-		return err
+	nutrients, err := buildMealNutrients(&mealHistory, nutrientQuantities(totalNutr.Details))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	mealHistory.Nutrients = nutrients
+
+	if err := db.Create(&mealHistory).Error; err != nil {
+		return uuid.Nil, err
 	}
 
-	saveMaps := make(map[string]any)
-	saveMaps["foods"] = foods
-	saveMaps["nutrients"] = totalNutr
-	saveJSON, _ := json.Marshal(saveMaps)
+	saveJSON, _ := json.Marshal(MealScanResult{
+		ImageID:   recognition.ImageID,
+		Foods:     recognition.TopFoodNames(3),
+		Segments:  recognition.Segments,
+		Nutrients: totalNutr,
+	})
 	mealDetail := model.MealHistoryDetail{
 		ID:            uuid.New(),
 		MealHistoryID: mealHistory.ID,
@@ -248,7 +249,33 @@ func (s *mealService) saveMealHistory(userID uuid.UUID, foods [][]string, totalN
 		UpdatedAt:     time.Now(),
 	}
 
-	return s.DB.Create(&mealDetail).Error
+	if err := db.Create(&mealDetail).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	// A savepoint, so a failure here doesn't abort the caller's transaction along with it
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return s.saveMealFoods(tx, mealHistory.ID, foods)
+	}); err != nil {
+		s.Log.Errorf("Failed to save meal foods: %+v", err)
+	}
+
+	return mealHistory.ID, nil
+}
+
+// saveMealFoods replaces the TKPI references of a meal
+func (s *mealService) saveMealFoods(db *gorm.DB, mealHistoryID uuid.UUID, foods []model.MealHistoryFood) error {
+	if err := db.Where("meal_history_id = ?", mealHistoryID).Delete(&model.MealHistoryFood{}).Error; err != nil {
+		return err
+	}
+	if len(foods) == 0 {
+		return nil
+	}
+
+	for i := range foods {
+		foods[i].MealHistoryID = mealHistoryID
+	}
+	return db.Create(&foods).Error
 }
 
 func (s *mealService) GetMeals(c *fiber.Ctx) ([]model.MealHistory, int64, error) {
@@ -290,7 +317,15 @@ func (s *mealService) GetMeals(c *fiber.Ctx) ([]model.MealHistory, int64, error)
 func (s *mealService) GetMealByID(c *fiber.Ctx, id string) (*model.MealHistory, error) {
 	meal := new(model.MealHistory)
 
-	result := s.DB.WithContext(c.Context()).First(meal, "id = ?", id)
+	result := s.DB.WithContext(c.Context()).
+		Preload("Foods", func(db *gorm.DB) *gorm.DB {
+			return db.Order("segment ASC, rank ASC")
+		}).
+		Preload("Nutrients").
+		Preload("Ingredients", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position ASC")
+		}).
+		First(meal, "id = ?", id)
 
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return nil, fiber.NewError(fiber.StatusNotFound, "Meal not found")
@@ -363,6 +398,25 @@ func (s *mealService) AddMeal(c *fiber.Ctx, meal *model.MealHistory) (*model.Mea
 	meal.UserID = user.ID
 	meal.CreatedAt = time.Now()
 	meal.UpdatedAt = time.Now()
+	meal.Foods = nil
+
+	extra := manualNutrientQuantities(meal.Nutrients)
+	if len(meal.Ingredients) > 0 {
+		// Composite meals get every nutrient from TKPI, whatever numbers the client sent
+		ingredients, total, err := s.resolveMealIngredients(c, meal.Ingredients)
+		if err != nil {
+			return nil, err
+		}
+		applyIngredientTotals(meal, ingredients, total)
+		meal.Ingredients = ingredients
+		extra = nutrientQuantities(total.Details)
+	}
+
+	nutrients, err := buildMealNutrients(meal, extra)
+	if err != nil {
+		return nil, err
+	}
+	meal.Nutrients = nutrients
 
 	if err := s.DB.WithContext(c.Context()).Create(meal).Error; err != nil {
 		s.Log.Errorf("Failed to add meal: %+v", err)
@@ -394,20 +448,81 @@ func (s *mealService) UpdateMeal(c *fiber.Ctx, id string, meal *model.MealHistor
 	existingMeal.Title = meal.Title
 	existingMeal.MealTime = meal.MealTime
 	existingMeal.Label = meal.Label
-	existingMeal.Calories = meal.Calories
-	existingMeal.Protein = meal.Protein
-	existingMeal.Carbs = meal.Carbs
-	existingMeal.Fat = meal.Fat
 	existingMeal.UpdatedAt = time.Now()
 
-	if err := s.DB.WithContext(c.Context()).Save(existingMeal).Error; err != nil {
+	var existingIngredients int64
+	if err := s.DB.WithContext(c.Context()).Model(&model.MealHistoryIngredient{}).Where("meal_history_id = ?", existingMeal.ID).Count(&existingIngredients).Error; err != nil {
+		s.Log.Errorf("Failed to count meal ingredients: %+v", err)
+		return nil, err
+	}
+
+	// Micronutrients are kept unless the request sends a new list or new ingredients
+	var extra map[string]float64
+	var err error
+	if len(meal.Ingredients) > 0 {
+		ingredients, total, err := s.resolveMealIngredients(c, meal.Ingredients)
+		if err != nil {
+			return nil, err
+		}
+		applyIngredientTotals(existingMeal, ingredients, total)
+		meal.Ingredients = ingredients
+		extra = nutrientQuantities(total.Details)
+	} else if meal.Ingredients == nil && existingIngredients > 0 {
+		// The totals of a composite meal only change through its ingredients, an empty list drops them
+		if extra, err = s.storedNutrientQuantities(c, existingMeal.ID); err != nil {
+			return nil, err
+		}
+	} else {
+		existingMeal.Calories = meal.Calories
+		existingMeal.Protein = meal.Protein
+		existingMeal.Carbs = meal.Carbs
+		existingMeal.Fat = meal.Fat
+
+		if meal.Nutrients != nil {
+			extra = manualNutrientQuantities(meal.Nutrients)
+		} else if extra, err = s.storedNutrientQuantities(c, existingMeal.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	nutrients, err := buildMealNutrients(existingMeal, extra)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(existingMeal).Error; err != nil {
+			return err
+		}
+		if meal.Ingredients != nil {
+			if err := saveMealIngredients(tx, existingMeal.ID, meal.Ingredients); err != nil {
+				return err
+			}
+		}
+		return saveMealNutrients(tx, existingMeal.ID, nutrients)
+	})
+	if err != nil {
 		s.Log.Errorf("Failed to update meal: %+v", err)
 		return nil, err
 	}
+	if meal.Ingredients != nil {
+		existingMeal.Ingredients = meal.Ingredients
+	}
+	existingMeal.Nutrients = nutrients
 
 	return existingMeal, nil
 }
 
+// storedNutrientQuantities reads the saved nutrient rows of a meal into amounts by code
+func (s *mealService) storedNutrientQuantities(c *fiber.Ctx, mealHistoryID uuid.UUID) (map[string]float64, error) {
+	var nutrients []model.MealHistoryNutrient
+	if err := s.DB.WithContext(c.Context()).Where("meal_history_id = ?", mealHistoryID).Find(&nutrients).Error; err != nil {
+		s.Log.Errorf("Failed to get meal nutrients: %+v", err)
+		return nil, err
+	}
+	return manualNutrientQuantities(nutrients), nil
+}
+
 func (s *mealService) DeleteMeal(c *fiber.Ctx, id string) error {
 	user, ok := c.Locals("user").(*model.User)
 	if !ok || user == nil {
@@ -427,7 +542,25 @@ func (s *mealService) DeleteMeal(c *fiber.Ctx, id string) error {
 		return fiber.NewError(fiber.StatusForbidden, "You don't have permission to delete this meal")
 	}
 
-	if err := s.DB.WithContext(c.Context()).Delete(meal).Error; err != nil {
+	err := s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("meal_history_id = ?", meal.ID).Delete(&model.MealHistoryFood{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("meal_history_id = ?", meal.ID).Delete(&model.MealHistoryNutrient{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("meal_history_id = ?", meal.ID).Delete(&model.MealHistoryIngredient{}).Error; err != nil {
+			return err
+		}
+		// A meal logged from a plan frees its slot so it can be logged again
+		if err := tx.Model(&model.MealPlanSlot{}).
+			Where("meal_history_id = ?", meal.ID).
+			Updates(map[string]interface{}{"meal_history_id": nil, "logged_at": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(meal).Error
+	})
+	if err != nil {
 		s.Log.Errorf("Failed to delete meal: %+v", err)
 		return err
 	}
@@ -449,10 +582,152 @@ func (s *mealService) AddMealScanDetail(c *fiber.Ctx, mealId string, meal *model
 	return meal, nil
 }
 
-// GetTodayNutrition fetches today's nutrition data for a user
+// ConfirmMealScan replaces the detected foods of a scanned meal with the user's choice per segment and
+// recomputes the meal's nutrients from the chosen foods and portions
+func (s *mealService) ConfirmMealScan(c *fiber.Ctx, mealId string, req *validation.ConfirmMealScan) (*model.MealHistory, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	meal, err := s.GetMealByID(c, mealId)
+	if err != nil {
+		return nil, err
+	}
+
+	mealScanDetail := new(model.MealHistoryDetail)
+	if err := s.DB.WithContext(c.Context()).First(mealScanDetail, "meal_history_id = ?", mealId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Meal scan detail not found")
+		}
+		s.Log.Errorf("Failed get meal scan detail: %+v", err)
+		return nil, err
+	}
+
+	scanResult := new(MealScanResult)
+	if err := json.Unmarshal([]byte(mealScanDetail.APIResult), scanResult); err != nil {
+		return nil, fiber.NewError(fiber.StatusUnprocessableEntity, "Meal scan detail can't be confirmed")
+	}
+
+	// Scans saved before candidates were kept only have the top names per segment
+	if len(scanResult.Segments) == 0 {
+		for _, names := range scanResult.Foods {
+			var segment FoodSegment
+			for _, name := range names {
+				segment.Candidates = append(segment.Candidates, FoodCandidate{Name: name})
+			}
+			scanResult.Segments = append(scanResult.Segments, segment)
+		}
+	}
+	recognition := &FoodRecognition{ImageID: scanResult.ImageID, Segments: scanResult.Segments}
+
+	selections := make([]FoodSelection, 0, len(req.Selections))
+	confirmed := make(map[int]bool, len(req.Selections))
+	names := make([]string, 0, len(req.Selections))
+	for _, selection := range req.Selections {
+		if confirmed[selection.Segment] {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Segment %d is selected more than once", selection.Segment))
+		}
+		if !recognition.HasCandidate(selection.Segment, selection.Name) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("%q is not a candidate of segment %d", selection.Name, selection.Segment))
+		}
+		confirmed[selection.Segment] = true
+		selections = append(selections, FoodSelection{Segment: selection.Segment, Name: selection.Name, Grams: selection.Grams})
+		names = append(names, selection.Name)
+	}
+
+	totalNutr, err := s.Recognizer.Confirm(c.Context(), recognition, selections)
+	if err != nil {
+		s.Log.Errorf("Failed to confirm meal scan: %+v", err)
+		return nil, fiber.NewError(fiber.StatusBadGateway, "Failed to compute nutrients for the selected foods")
+	}
+
+	scanResult.Selections = selections
+	scanResult.ConfirmedNutrients = &totalNutr
+	saveJSON, _ := json.Marshal(scanResult)
+
+	meal.Title = strings.Join(names, ", ")
+	meal.Calories = totalNutr.Calories.Quantity
+	meal.Protein = totalNutr.Protein.Quantity
+	meal.Carbs = totalNutr.Carbs.Quantity
+	meal.Fat = totalNutr.Fat.Quantity
+	meal.UpdatedAt = time.Now()
+
+	foods, err := s.Matcher.MatchSelections(c.Context(), selections)
+	if err != nil {
+		// Keep the previous TKPI references rather than failing the confirmation
+		s.Log.Errorf("Failed to match confirmed foods to bahan makanan: %+v", err)
+		foods = nil
+	}
+
+	nutrients, err := buildMealNutrients(meal, s.confirmedNutrientQuantities(c.Context(), totalNutr, foods))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.DB.WithContext(c.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(meal).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(mealScanDetail).Update("api_result", string(saveJSON)).Error; err != nil {
+			return err
+		}
+		meal.Nutrients = nutrients
+		if err := saveMealNutrients(tx, meal.ID, meal.Nutrients); err != nil {
+			return err
+		}
+		if foods == nil {
+			return nil
+		}
+		meal.Foods = foods
+		return s.saveMealFoods(tx, meal.ID, meal.Foods)
+	})
+	if err != nil {
+		s.Log.Errorf("Failed to save confirmed meal scan: %+v", err)
+		return nil, err
+	}
+
+	return meal, nil
+}
+
+// confirmedNutrientQuantities takes the nutrients the recognizer reported and fills the codes it left out
+// from the TKPI values of the matched foods, so sodium and iron are known whenever a food was matched
+func (s *mealService) confirmedNutrientQuantities(ctx context.Context, totalNutr Nutrient, foods []model.MealHistoryFood) map[string]float64 {
+	quantities := nutrientQuantities(totalNutr.Details)
+
+	tkpiNutr := emptyNutrient()
+	for _, food := range foods {
+		if food.Grams == nil {
+			continue
+		}
+		bahanMakanan, err := s.Matcher.GetBahanMakanan(ctx, food.Kode)
+		if err != nil {
+			s.Log.Errorf("Failed to get bahan makanan %s: %+v", food.Kode, err)
+			continue
+		}
+		if bahanMakanan == nil {
+			continue
+		}
+		tkpiNutr = tkpiNutr.Add(bahanMakananNutrients(*bahanMakanan, *food.Grams))
+	}
+
+	for code, detail := range tkpiNutr.Details {
+		if _, ok := quantities[code]; !ok {
+			quantities[code] = detail.Quantity
+		}
+	}
+
+	return quantities
+}
+
+// GetTodayNutrition fetches today's nutrition data for a user, where today is counted in the user's timezone
 func (s *mealService) GetTodayNutrition(c *fiber.Ctx, userID uuid.UUID) (*model.DailyNutrition, error) {
-	todayStart := time.Now().Truncate(24 * time.Hour)
-	todayEnd := todayStart.Add(24 * time.Hour)
+	location, err := userLocation(c, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	todayStart := startOfDay(time.Now(), location)
+	todayEnd := todayStart.AddDate(0, 0, 1)
 
 	var meals []model.MealHistory
 	if err := s.DB.WithContext(c.Context()).
@@ -477,6 +752,13 @@ func (s *mealService) GetTodayNutrition(c *fiber.Ctx, userID uuid.UUID) (*model.
 		dailyNutrition.Fat += meal.Fat
 	}
 
+	nutrients, err := sumMealNutrients(s.DB.WithContext(c.Context()), userID, todayStart, todayEnd)
+	if err != nil {
+		s.Log.Errorf("Failed to sum today's nutrients: %+v", err)
+		return nil, err
+	}
+	dailyNutrition.Nutrients = nutrients
+
 	return dailyNutrition, nil
 }
 
@@ -533,9 +815,14 @@ func (s *mealService) GetHomeStatistics(c *fiber.Ctx, userID uuid.UUID) (*model.
 		LatestWeightTarget: latestTarget,
 	}
 
+	// Targets follow the current profile and the latest weight target
+	nutritionTargets := CalculateNutritionTargets(user, latestTarget, time.Now())
+
 	// Combine all statistics
 	homeStats := &model.HomeStatistics{
 		DailyNutrition:         dailyNutrition,
+		NutritionTargets:       nutritionTargets,
+		NutritionProgress:      CompareNutrition(nutritionTargets, dailyNutrition),
 		WeightHeightStatistics: weightHeightStats,
 	}
 
@@ -11,8 +11,12 @@ func (m *MockPayment) Charge(amount int, method string) (*PaymentResponse, error
 	}, nil
 }
 
-func (m *MockPayment) Refund(transactionID string) error {
-	return nil
+func (m *MockPayment) Refund(orderID string, refund *RefundRequest) (*RefundResponse, error) {
+	return &RefundResponse{
+		TransactionID: "mock_" + uuid.New().String(),
+		RefundKey:     refund.RefundKey,
+		Raw:           []byte(`{"status_code":"200","transaction_status":"refund"}`),
+	}, nil
 }
 
 func (m *MockPayment) CreateTransaction(orderID string, amount int, userDetails map[string]interface{}, paymentMethod string) (*PaymentToken, error) {
@@ -24,8 +28,8 @@ func (m *MockPayment) CreateTransaction(orderID string, amount int, userDetails
 
 func (m *MockPayment) CheckTransactionStatus(transactionID string) (interface{}, error) {
 	return map[string]string{
-		"transaction_id": transactionID,
-		"status":         "settlement",
+		"order_id":           transactionID,
+		"transaction_status": "settlement",
 	}, nil
 }
 
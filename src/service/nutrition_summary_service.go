@@ -0,0 +1,294 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"app/src/model"
+	"app/src/validation"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type NutritionSummaryService interface {
+	GetSummary(c *fiber.Ctx, userID uuid.UUID, query *validation.QueryNutritionSummary) (*model.NutritionSummary, error)
+}
+
+type nutritionSummaryService struct {
+	Log      *logrus.Logger
+	DB       *gorm.DB
+	Validate *validator.Validate
+	Targets  NutritionTargetService
+}
+
+func NewNutritionSummaryService(db *gorm.DB, validate *validator.Validate, targets NutritionTargetService) NutritionSummaryService {
+	return &nutritionSummaryService{
+		Log:      logrus.New(),
+		DB:       db,
+		Validate: validate,
+		Targets:  targets,
+	}
+}
+
+// maxSummaryBuckets bounds the size of a single summary, a year of days
+const maxSummaryBuckets = 366
+
+// defaultSummaryBuckets is how many buckets are returned when the query has no from date
+var defaultSummaryBuckets = map[string]int{
+	model.SummaryBucketDay:   7,
+	model.SummaryBucketWeek:  8,
+	model.SummaryBucketMonth: 6,
+}
+
+type summaryNutrientRow struct {
+	Bucket   time.Time
+	Code     string
+	Quantity float64
+}
+
+type summaryMealRow struct {
+	Bucket time.Time
+	Meals  int
+}
+
+// GetSummary sums a user's meals per bucket between from and to, both inclusive and counted in the user's
+// timezone. Buckets without meals are returned with zeros.
+func (s *nutritionSummaryService) GetSummary(c *fiber.Ctx, userID uuid.UUID, query *validation.QueryNutritionSummary) (*model.NutritionSummary, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, err
+	}
+
+	bucket := query.Bucket
+	if bucket == "" {
+		bucket = model.SummaryBucketDay
+	}
+
+	location, err := userLocation(c, s.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to, err := summaryRange(query, bucket, location)
+	if err != nil {
+		return nil, err
+	}
+	end := to.AddDate(0, 0, 1)
+
+	starts := summaryBucketStarts(from, end, bucket)
+	if len(starts) > maxSummaryBuckets {
+		return nil, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("The range can't have more than %d %s buckets", maxSummaryBuckets, bucket))
+	}
+
+	db := s.DB.WithContext(c.Context())
+	// date_trunc runs on the local time of the user so buckets start at their midnight
+	bucketExpr := "date_trunc(?, meal_histories.meal_time AT TIME ZONE ?)"
+
+	var nutrientRows []summaryNutrientRow
+	if err := db.Model(&model.MealHistoryNutrient{}).
+		Select(bucketExpr+" AS bucket, meal_history_nutrients.code, SUM(meal_history_nutrients.quantity) AS quantity", bucket, location.String()).
+		Joins("JOIN meal_histories ON meal_histories.id = meal_history_nutrients.meal_history_id").
+		Where("meal_histories.user_id = ? AND meal_histories.meal_time >= ? AND meal_histories.meal_time < ?", userID, from, end).
+		Group("1, 2").
+		Scan(&nutrientRows).Error; err != nil {
+		s.Log.Errorf("Failed to sum nutrients per %s: %+v", bucket, err)
+		return nil, err
+	}
+
+	var mealRows []summaryMealRow
+	if err := db.Model(&model.MealHistory{}).
+		Select(bucketExpr+" AS bucket, COUNT(*) AS meals", bucket, location.String()).
+		Where("user_id = ? AND meal_time >= ? AND meal_time < ?", userID, from, end).
+		Group("1").
+		Scan(&mealRows).Error; err != nil {
+		s.Log.Errorf("Failed to count meals per %s: %+v", bucket, err)
+		return nil, err
+	}
+
+	targets, err := s.Targets.GetTargets(c, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.NutritionSummary{
+		From:     from,
+		To:       to,
+		Bucket:   bucket,
+		Timezone: location.String(),
+		Targets:  targets,
+		Buckets:  buildNutritionBuckets(starts, from, end, bucket, nutrientRows, mealRows, targets),
+	}, nil
+}
+
+// summaryRange parses the inclusive dates of the query, defaulting to the last few buckets up to today
+func summaryRange(query *validation.QueryNutritionSummary, bucket string, location *time.Location) (time.Time, time.Time, error) {
+	to := startOfDay(time.Now(), location)
+	if query.To != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", query.To, location)
+		if err != nil {
+			return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "Invalid to date")
+		}
+		to = parsed
+	}
+
+	from := addSummaryBuckets(summaryBucketStart(to, bucket), bucket, 1-defaultSummaryBuckets[bucket])
+	if query.From != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", query.From, location)
+		if err != nil {
+			return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "Invalid from date")
+		}
+		from = parsed
+	}
+
+	if from.After(to) {
+		return time.Time{}, time.Time{}, fiber.NewError(fiber.StatusBadRequest, "The from date must not be after the to date")
+	}
+
+	return from, to, nil
+}
+
+// summaryBucketStart returns the start of the bucket t falls in, weeks start on Monday like date_trunc
+func summaryBucketStart(t time.Time, bucket string) time.Time {
+	switch bucket {
+	case model.SummaryBucketWeek:
+		return getStartOfWeek(t)
+	case model.SummaryBucketMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+func addSummaryBuckets(t time.Time, bucket string, n int) time.Time {
+	switch bucket {
+	case model.SummaryBucketWeek:
+		return t.AddDate(0, 0, 7*n)
+	case model.SummaryBucketMonth:
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// summaryBucketStarts lists the start of every bucket overlapping [from, end), stopping early once there are
+// more than maxSummaryBuckets
+func summaryBucketStarts(from, end time.Time, bucket string) []time.Time {
+	var starts []time.Time
+	for start := summaryBucketStart(from, bucket); start.Before(end); start = addSummaryBuckets(start, bucket, 1) {
+		starts = append(starts, start)
+		if len(starts) > maxSummaryBuckets {
+			break
+		}
+	}
+	return starts
+}
+
+// buildNutritionBuckets zero-fills the SQL rows into one bucket per start and compares every bucket with the
+// daily targets multiplied by the days of the bucket inside the range
+func buildNutritionBuckets(starts []time.Time, from, end time.Time, bucket string, nutrientRows []summaryNutrientRow, mealRows []summaryMealRow, targets *model.NutritionTargets) []model.NutritionBucket {
+	// The bucket column is a local timestamp without a zone, its date is the key
+	bucketKey := func(t time.Time) string { return t.Format("2006-01-02") }
+
+	quantities := make(map[string]map[string]float64)
+	seen := make(map[string]bool)
+	for _, row := range nutrientRows {
+		key := bucketKey(row.Bucket)
+		if quantities[key] == nil {
+			quantities[key] = make(map[string]float64)
+		}
+		quantities[key][row.Code] = row.Quantity
+		seen[row.Code] = true
+	}
+
+	meals := make(map[string]int)
+	for _, row := range mealRows {
+		meals[bucketKey(row.Bucket)] = row.Meals
+	}
+
+	for _, target := range targets.Nutrients {
+		seen[target.Code] = true
+	}
+	for _, code := range model.TrackedNutrients {
+		seen[code] = true
+	}
+
+	buckets := make([]model.NutritionBucket, 0, len(starts))
+	for _, start := range starts {
+		bucketEnd := addSummaryBuckets(start, bucket, 1)
+		periodStart, periodEnd := start, bucketEnd
+		if periodStart.Before(from) {
+			periodStart = from
+		}
+		if periodEnd.After(end) {
+			periodEnd = end
+		}
+		days := int(math.Round(periodEnd.Sub(periodStart).Hours() / 24))
+
+		key := bucketKey(start)
+		result := model.NutritionBucket{
+			Start: start,
+			End:   bucketEnd,
+			Days:  days,
+			Meals: meals[key],
+		}
+
+		targeted, onTarget := 0, 0
+		for _, definition := range model.NutrientDefinitions {
+			if !seen[definition.Code] {
+				continue
+			}
+
+			quantity := quantities[key][definition.Code]
+			amount := model.NutritionBucketAmount{
+				Code:         definition.Code,
+				Name:         definition.Name,
+				Unit:         definition.Unit,
+				Quantity:     math.Round(quantity*10) / 10,
+				DailyAverage: math.Round(quantity/float64(days)*10) / 10,
+			}
+
+			if target := targets.GetNutrientTarget(definition.Code); target != nil && target.Target > 0 {
+				bucketTarget := math.Round(target.Target*float64(days)*10) / 10
+				percent := math.Round(quantity/bucketTarget*1000) / 10
+				amount.Target = &bucketTarget
+				amount.Percent = &percent
+				amount.Limit = target.Limit
+
+				// A bucket without meals wasn't logged, so it isn't judged
+				if result.Meals > 0 {
+					ok := isOnTarget(definition.Code, percent, target.Limit)
+					amount.OnTarget = &ok
+					targeted++
+					if ok {
+						onTarget++
+					}
+				}
+			}
+
+			result.Nutrients = append(result.Nutrients, amount)
+		}
+
+		if targeted > 0 {
+			result.Adherence = math.Round(float64(onTarget)/float64(targeted)*1000) / 10
+		}
+
+		buckets = append(buckets, result)
+	}
+
+	return buckets
+}
+
+// isOnTarget stays under limits, keeps energy within 10% of the target and reaches at least 90% of the rest
+func isOnTarget(code string, percent float64, limit bool) bool {
+	if limit {
+		return percent <= 100
+	}
+	if code == model.NutrientEnergy {
+		return percent >= 90 && percent <= 110
+	}
+	return percent >= 90
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"app/src/model"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type NutritionTargetService interface {
+	GetTargets(c *fiber.Ctx, userID uuid.UUID) (*model.NutritionTargets, error)
+}
+
+type nutritionTargetService struct {
+	Log *logrus.Logger
+	DB  *gorm.DB
+}
+
+func NewNutritionTargetService(db *gorm.DB) NutritionTargetService {
+	return &nutritionTargetService{
+		Log: logrus.New(),
+		DB:  db,
+	}
+}
+
+const (
+	// kcalPerKgBodyWeight is the energy stored in a kilogram of body weight
+	kcalPerKgBodyWeight = 7700
+
+	// Profile values used while the user hasn't filled theirs in
+	defaultWeight = 60.0
+	defaultHeight = 160.0
+	defaultAge    = 30
+)
+
+// activityFactors multiply the BMR into the TDEE, a user without an activity level counts as sedentary
+var activityFactors = map[model.ActivityLevel]float64{
+	model.Light:  1.375,
+	model.Medium: 1.55,
+	model.Heavy:  1.725,
+}
+
+// macroSplits are the shares of the calories from protein, carbohydrate and fat for each goal
+var macroSplits = map[string][3]float64{
+	model.GoalLoseWeight:     {0.25, 0.45, 0.30},
+	model.GoalMaintainWeight: {0.20, 0.50, 0.30},
+	model.GoalGainWeight:     {0.20, 0.55, 0.25},
+}
+
+// GetTargets computes the targets from the user's current profile and latest weight target, so they always
+// follow profile updates and new weight records
+func (s *nutritionTargetService) GetTargets(c *fiber.Ctx, userID uuid.UUID) (*model.NutritionTargets, error) {
+	user := new(model.User)
+	if err := s.DB.WithContext(c.Context()).First(user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		s.Log.Errorf("Failed to get user: %+v", err)
+		return nil, err
+	}
+
+	var target *model.UsersWeightHeightTarget
+	latestTarget := new(model.UsersWeightHeightTarget)
+	err := s.DB.WithContext(c.Context()).
+		Where("user_id = ?", userID).
+		Order("target_date DESC").
+		First(latestTarget).Error
+	if err == nil {
+		target = latestTarget
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		s.Log.Errorf("Failed to get weight target: %+v", err)
+		return nil, err
+	}
+
+	return CalculateNutritionTargets(user, target, time.Now()), nil
+}
+
+// CalculateNutritionTargets derives BMR with Mifflin-St Jeor, TDEE from the activity level, a calorie
+// adjustment towards the weight target and the daily macro and micronutrient targets.
+// Missing profile fields are replaced with defaults and reported in Missing.
+func CalculateNutritionTargets(user *model.User, target *model.UsersWeightHeightTarget, now time.Time) *model.NutritionTargets {
+	targets := &model.NutritionTargets{Goal: model.GoalMaintainWeight}
+
+	weight := defaultWeight
+	if user.Weight != nil && *user.Weight > 0 {
+		weight = *user.Weight
+	} else {
+		targets.Missing = append(targets.Missing, "weight")
+	}
+
+	height := defaultHeight
+	if user.Height != nil && *user.Height > 0 {
+		height = *user.Height
+	} else {
+		targets.Missing = append(targets.Missing, "height")
+	}
+
+	age := defaultAge
+	if user.BirthDate != nil && user.BirthDate.Before(now) {
+		age = ageAt(*user.BirthDate, now)
+	} else {
+		targets.Missing = append(targets.Missing, "birth_date")
+	}
+
+	if user.Gender == nil || (*user.Gender != model.Male && *user.Gender != model.Female) {
+		targets.Missing = append(targets.Missing, "gender")
+	}
+
+	activityFactor := 1.2
+	if user.ActivityLevel != nil {
+		if factor, ok := activityFactors[*user.ActivityLevel]; ok {
+			activityFactor = factor
+		}
+	} else {
+		targets.Missing = append(targets.Missing, "activity_level")
+	}
+
+	targets.Estimated = len(targets.Missing) > 0
+	targets.BMR = mifflinStJeorBMR(weight, height, age, user.Gender)
+	targets.TDEE = math.Round(targets.BMR * activityFactor)
+
+	if target != nil && target.Weight > 0 {
+		targets.Goal, targets.Adjustment = weightGoalAdjustment(weight, target, now)
+	}
+
+	targets.Calories = math.Max(targets.TDEE+targets.Adjustment, minimumCalories(user.Gender))
+	targets.Adjustment = targets.Calories - targets.TDEE
+
+	split := macroSplits[targets.Goal]
+	targets.Nutrients = []model.NutrientTarget{
+		nutrientTarget(model.NutrientEnergy, targets.Calories, false),
+		nutrientTarget(model.NutrientProtein, targets.Calories*split[0]/4, false),
+		nutrientTarget(model.NutrientCarbs, targets.Calories*split[1]/4, false),
+		nutrientTarget(model.NutrientFat, targets.Calories*split[2]/9, false),
+		nutrientTarget(model.NutrientFiber, targets.Calories/1000*14, false),
+		// Daily sugar and sodium limits of the Indonesian GGL guideline
+		nutrientTarget(model.NutrientSugar, 50, true),
+		nutrientTarget(model.NutrientSodium, 2000, true),
+		nutrientTarget(model.NutrientIron, ironTarget(user.Gender, age), false),
+	}
+
+	return targets
+}
+
+// weightGoalAdjustment returns the goal towards target and the daily calorie change that reaches it by
+// its target date, kept within a safe weekly rate
+func weightGoalAdjustment(weight float64, target *model.UsersWeightHeightTarget, now time.Time) (string, float64) {
+	difference := target.Weight - weight
+	if math.Abs(difference) < 0.5 {
+		return model.GoalMaintainWeight, 0
+	}
+
+	goal := model.GoalGainWeight
+	adjustment, minAdjustment, maxAdjustment := 300.0, 250.0, 500.0
+	if difference < 0 {
+		goal = model.GoalLoseWeight
+		adjustment, minAdjustment, maxAdjustment = -500, -1000, -250
+	}
+
+	if days := target.TargetDate.Sub(now).Hours() / 24; days >= 7 {
+		adjustment = math.Min(math.Max(difference*kcalPerKgBodyWeight/days, minAdjustment), maxAdjustment)
+	}
+
+	return goal, math.Round(adjustment)
+}
+
+// mifflinStJeorBMR adds 5 for men and subtracts 161 for women, the midpoint is used when the gender is unknown
+func mifflinStJeorBMR(weight, height float64, age int, gender *model.GenderType) float64 {
+	genderOffset := -78.0
+	if gender != nil && *gender == model.Male {
+		genderOffset = 5
+	} else if gender != nil && *gender == model.Female {
+		genderOffset = -161
+	}
+	return math.Round(10*weight + 6.25*height - 5*float64(age) + genderOffset)
+}
+
+func minimumCalories(gender *model.GenderType) float64 {
+	if gender != nil && *gender == model.Male {
+		return 1500
+	}
+	if gender != nil && *gender == model.Female {
+		return 1200
+	}
+	return 1350
+}
+
+// ironTarget follows the Indonesian AKG for adults
+func ironTarget(gender *model.GenderType, age int) float64 {
+	if gender != nil && *gender == model.Male {
+		return 9
+	}
+	if gender != nil && *gender == model.Female && age >= 50 {
+		return 8
+	}
+	return 18
+}
+
+func nutrientTarget(code string, quantity float64, limit bool) model.NutrientTarget {
+	definition, _ := model.GetNutrientDefinition(code)
+	return model.NutrientTarget{
+		Code:   code,
+		Name:   definition.Name,
+		Target: math.Round(quantity*10) / 10,
+		Unit:   definition.Unit,
+		Limit:  limit,
+	}
+}
+
+func ageAt(birthDate, now time.Time) int {
+	age := now.Year() - birthDate.Year()
+	if now.Month() < birthDate.Month() || (now.Month() == birthDate.Month() && now.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+// CompareNutrition reports consumed, target and remaining amounts for every targeted nutrient
+func CompareNutrition(targets *model.NutritionTargets, daily *model.DailyNutrition) []model.NutrientProgress {
+	consumed := map[string]float64{
+		model.NutrientEnergy:  daily.Calories,
+		model.NutrientProtein: daily.Protein,
+		model.NutrientCarbs:   daily.Carbs,
+		model.NutrientFat:     daily.Fat,
+	}
+	for _, total := range daily.Nutrients {
+		if _, ok := consumed[total.Code]; !ok {
+			consumed[total.Code] = total.Quantity
+		}
+	}
+
+	progress := make([]model.NutrientProgress, 0, len(targets.Nutrients))
+	for _, target := range targets.Nutrients {
+		eaten := math.Round(consumed[target.Code]*10) / 10
+
+		var percent float64
+		if target.Target > 0 {
+			percent = math.Round(eaten/target.Target*1000) / 10
+		}
+
+		progress = append(progress, model.NutrientProgress{
+			Code:      target.Code,
+			Name:      target.Name,
+			Unit:      target.Unit,
+			Consumed:  eaten,
+			Target:    target.Target,
+			Remaining: math.Max(math.Round((target.Target-eaten)*10)/10, 0),
+			Percent:   percent,
+			Limit:     target.Limit,
+			Exceeded:  eaten > target.Target,
+		})
+	}
+
+	return progress
+}
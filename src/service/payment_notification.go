@@ -0,0 +1,168 @@
+package service
+
+import (
+	"app/src/model"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Payment statuses of a subscription, "completed" is used by the trials which are never paid
+const (
+	PaymentStatusPending   = "pending"
+	PaymentStatusSuccess   = "success"
+	PaymentStatusCompleted = "completed"
+	PaymentStatusFailed    = "failed"
+	PaymentStatusRefunded  = "refunded"
+)
+
+// paymentStatusTransitions lists the payment statuses each one may move forward to. Midtrans retries and
+// reorders notifications, so anything else, like a settled payment going back to pending, is stale.
+var paymentStatusTransitions = map[string][]string{
+	PaymentStatusPending:   {PaymentStatusSuccess, PaymentStatusFailed},
+	PaymentStatusSuccess:   {PaymentStatusRefunded},
+	PaymentStatusCompleted: {PaymentStatusRefunded},
+}
+
+// subscriptionStatusForPayment is where a subscription's lifecycle goes when its payment reaches a status
+var subscriptionStatusForPayment = map[string]struct {
+	Status string
+	Reason string
+}{
+	PaymentStatusSuccess:  {model.SubscriptionStatusActive, TransitionReasonPaymentSettled},
+	PaymentStatusFailed:   {model.SubscriptionStatusCancelled, TransitionReasonPaymentFailed},
+	PaymentStatusRefunded: {model.SubscriptionStatusRefunded, TransitionReasonRefunded},
+}
+
+// CanAdvancePaymentStatus reports whether a payment may move from one status to another
+func CanAdvancePaymentStatus(from, to string) bool {
+	for _, allowed := range paymentStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// PaymentStatusForMidtrans maps a Midtrans transaction status to the payment status of a subscription.
+// Captured card payments flagged by the fraud check stay pending until Midtrans decides. The second
+// result is false for statuses this service doesn't know.
+func PaymentStatusForMidtrans(transactionStatus, fraudStatus string) (string, bool) {
+	switch transactionStatus {
+	case "pending", "authorize":
+		return PaymentStatusPending, true
+	case "capture":
+		switch fraudStatus {
+		case "challenge":
+			return PaymentStatusPending, true
+		case "deny":
+			return PaymentStatusFailed, true
+		}
+		return PaymentStatusSuccess, true
+	case "settlement":
+		return PaymentStatusSuccess, true
+	case "deny", "cancel", "expire", "failure":
+		return PaymentStatusFailed, true
+	case "refund", "partial_refund":
+		return PaymentStatusRefunded, true
+	}
+	return "", false
+}
+
+// midtransTransactionStatus is the part of a Midtrans status response the reconciliation needs
+type midtransTransactionStatus struct {
+	TransactionStatus string `json:"transaction_status"`
+	FraudStatus       string `json:"fraud_status"`
+}
+
+// reconcilePaymentStatus asks Midtrans for the current status of an order, for notifications that are
+// unknown or arrive out of order
+func (s *subscriptionService) reconcilePaymentStatus(orderID string) (string, error) {
+	response, err := s.Payment.CheckTransactionStatus(orderID)
+	if err != nil {
+		return "", err
+	}
+
+	// The gateway returns its own response type, both it and the mock encode to Midtrans' JSON
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return "", err
+	}
+	var status midtransTransactionStatus
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return "", err
+	}
+
+	paymentStatus, known := PaymentStatusForMidtrans(status.TransactionStatus, status.FraudStatus)
+	if !known {
+		return "", fmt.Errorf("unknown transaction status %q for order %s", status.TransactionStatus, orderID)
+	}
+
+	return paymentStatus, nil
+}
+
+// applyPaymentStatus records a payment event of the subscription and moves its payment, and with it the
+// lifecycle, forward to paymentStatus. Both happen in one transaction with the subscription locked, so
+// concurrent deliveries for the same order are processed one after the other. It reports whether the
+// payment moved, events recorded before and statuses that aren't a step forward leave it as it is.
+func (s *subscriptionService) applyPaymentStatus(db *gorm.DB, subscription *model.UserSubscription, paymentStatus string, detail *model.TransactionDetail) (bool, error) {
+	applied := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(subscription, "id = ?", subscription.ID).Error; err != nil {
+			return fmt.Errorf("failed to lock subscription: %w", err)
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(detail)
+		if result.Error != nil {
+			s.Log.Errorf("Failed to save transaction details: %v", result.Error)
+			return fmt.Errorf("failed to save transaction details: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			s.Log.Infof("Ignoring repeated %s event for order ID %s", detail.TransactionStatus, detail.OrderID)
+			return nil
+		}
+
+		if !CanAdvancePaymentStatus(subscription.PaymentStatus, paymentStatus) {
+			s.Log.Infof("Subscription %s stays %s, the %s event doesn't move it forward",
+				subscription.ID, subscription.PaymentStatus, detail.TransactionStatus)
+			return nil
+		}
+
+		if err := tx.Model(subscription).Update("payment_status", paymentStatus).Error; err != nil {
+			s.Log.Errorf("Failed to update subscription %s: %v", subscription.ID, err)
+			return fmt.Errorf("failed to update subscription: %w", err)
+		}
+		applied = true
+
+		// A settled purchase starts according to its plan change
+		if paymentStatus == PaymentStatusSuccess && subscription.Status == model.SubscriptionStatusPending {
+			if err := s.activatePurchase(tx, subscription, nil); err != nil {
+				s.Log.Errorf("Failed to activate subscription %s: %v", subscription.ID, err)
+				return fmt.Errorf("failed to update subscription: %w", err)
+			}
+			s.Log.Infof("Successfully updated subscription %s to status: %s", subscription.ID, paymentStatus)
+			return nil
+		}
+
+		next := subscriptionStatusForPayment[paymentStatus]
+		if !CanTransitionSubscription(subscription.Status, next.Status) {
+			s.Log.Warnf("Subscription %s is %s, its %s payment leaves it there", subscription.ID, subscription.Status, paymentStatus)
+			return nil
+		}
+		if err := TransitionSubscription(tx, subscription, next.Status, next.Reason, nil); err != nil {
+			s.Log.Errorf("Failed to move subscription %s to %s: %v", subscription.ID, next.Status, err)
+			return fmt.Errorf("failed to update subscription: %w", err)
+		}
+
+		s.Log.Infof("Successfully updated subscription %s to status: %s", subscription.ID, paymentStatus)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return applied, nil
+}
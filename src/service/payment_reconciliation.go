@@ -0,0 +1,191 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// reconcileBatchSize is how many stale orders one reconciliation run checks
+const reconcileBatchSize = 100
+
+// ReconcilePayments checks the orders that have been pending for too long with the payment gateway, in
+// case their notification never arrived. Orders checked least recently go first, so orders that stay
+// pending at Midtrans don't keep newer ones out of the batch. Statuses that moved on at Midtrans are applied the same way a
+// notification would, and orders Midtrans doesn't know fail once they are past the pending expiry. Every
+// order whose status differs is listed in the report, which is stored when there was anything to check.
+func (s *subscriptionService) ReconcilePayments(ctx context.Context, now time.Time) (*model.PaymentReconciliation, error) {
+	report := &model.PaymentReconciliation{StartedAt: now, Items: []model.PaymentReconciliationItem{}}
+	db := s.DB.WithContext(ctx)
+
+	var pending []model.UserSubscription
+	if err := db.
+		Where("status = ? AND payment_status = ? AND transaction_id <> ''", model.SubscriptionStatusPending, PaymentStatusPending).
+		Where("created_at <= ?", now.Add(-time.Duration(config.PaymentReconcileStaleMinutes)*time.Minute)).
+		Order("last_reconciled_at ASC NULLS FIRST, created_at ASC").
+		Limit(reconcileBatchSize).
+		Find(&pending).Error; err != nil {
+		s.Log.Errorf("Failed to find stale pending orders: %+v", err)
+		return nil, err
+	}
+
+	for i := range pending {
+		subscription := &pending[i]
+		report.Checked++
+
+		item := s.reconcilePayment(ctx, subscription, now)
+		if err := db.Model(&model.UserSubscription{}).
+			Where("id = ?", subscription.ID).
+			UpdateColumn("last_reconciled_at", now).Error; err != nil {
+			s.Log.Errorf("Failed to mark order ID %s as reconciled: %+v", subscription.TransactionID, err)
+		}
+		if item == nil {
+			continue
+		}
+
+		report.Items = append(report.Items, *item)
+		switch item.Action {
+		case model.ReconciliationActionApplied, model.ReconciliationActionExpired:
+			report.Applied++
+		case model.ReconciliationActionError:
+			report.Errors++
+		}
+	}
+
+	report.Mismatches = len(report.Items)
+	report.FinishedAt = time.Now()
+	if report.Checked == 0 {
+		return report, nil
+	}
+	if err := db.Create(report).Error; err != nil {
+		s.Log.Errorf("Failed to save payment reconciliation report: %+v", err)
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// reconcilePayment checks one pending order, it returns nil when Midtrans agrees the order is pending
+func (s *subscriptionService) reconcilePayment(ctx context.Context, subscription *model.UserSubscription, now time.Time) *model.PaymentReconciliationItem {
+	item := &model.PaymentReconciliationItem{
+		UserSubscriptionID: subscription.ID,
+		OrderID:            subscription.TransactionID,
+		LocalStatus:        subscription.PaymentStatus,
+	}
+	failed := func(err error) *model.PaymentReconciliationItem {
+		s.Log.Errorf("Failed to reconcile order ID %s: %+v", subscription.TransactionID, err)
+		item.Action = model.ReconciliationActionError
+		item.Error = err.Error()
+		return item
+	}
+
+	response, err := s.Payment.CheckTransactionStatus(subscription.TransactionID)
+	if errors.Is(err, ErrPaymentNotFound) {
+		// The user never picked a payment method, the order can't be paid anymore once Snap expired it
+		if now.Before(subscription.CreatedAt.Add(time.Duration(config.PaymentPendingExpiryHours) * time.Hour)) {
+			return nil
+		}
+
+		item.GatewayStatus = "not_found"
+		item.Action = model.ReconciliationActionExpired
+		detail := &model.TransactionDetail{
+			UserSubscriptionID: subscription.ID,
+			OrderID:            subscription.TransactionID,
+			TransactionStatus:  "expire",
+			StatusMessage:      "Order not found at the payment gateway",
+			TransactionTime:    now,
+		}
+		if _, err := s.applyPaymentStatus(s.DB.WithContext(ctx), subscription, PaymentStatusFailed, detail); err != nil {
+			return failed(err)
+		}
+		return item
+	}
+	if err != nil {
+		return failed(err)
+	}
+
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return failed(err)
+	}
+	var notification map[string]interface{}
+	if err := json.Unmarshal(raw, &notification); err != nil {
+		return failed(err)
+	}
+
+	item.GatewayStatus = getString(notification, "transaction_status", "")
+	paymentStatus, known := PaymentStatusForMidtrans(item.GatewayStatus, getString(notification, "fraud_status", ""))
+	if !known {
+		return failed(fmt.Errorf("unknown transaction status %q", item.GatewayStatus))
+	}
+	if paymentStatus == subscription.PaymentStatus {
+		return nil
+	}
+
+	detail := s.createTransactionDetailFromNotification(subscription.ID, notification, raw)
+	applied, err := s.applyPaymentStatus(s.DB.WithContext(ctx), subscription, paymentStatus, detail)
+	if err != nil {
+		return failed(err)
+	}
+
+	item.Action = model.ReconciliationActionSkipped
+	if applied {
+		item.Action = model.ReconciliationActionApplied
+	}
+	return item
+}
+
+// GetPaymentReconciliations returns the reconciliation reports with their mismatches, latest first
+func (s *subscriptionService) GetPaymentReconciliations(ctx *fiber.Ctx, page, limit int) ([]model.PaymentReconciliation, int64, error) {
+	var reconciliations []model.PaymentReconciliation
+	var totalResults int64
+
+	if err := s.DB.WithContext(ctx.Context()).
+		Model(&model.PaymentReconciliation{}).
+		Count(&totalResults).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := s.DB.WithContext(ctx.Context()).
+		Preload("Items").
+		Order("started_at DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&reconciliations).Error; err != nil {
+		s.Log.Errorf("Failed to get payment reconciliations: %+v", err)
+		return nil, 0, err
+	}
+
+	return reconciliations, totalResults, nil
+}
+
+// StartPaymentReconciler reconciles the stale pending orders now and then every interval until ctx is done
+func (s *subscriptionService) StartPaymentReconciler(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if report, err := s.ReconcilePayments(ctx, time.Now()); err == nil && report.Mismatches > 0 {
+				s.Log.Infof("Payment reconciliation: %d of %d pending orders differed, %d applied, %d errors",
+					report.Mismatches, report.Checked, report.Applied, report.Errors)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
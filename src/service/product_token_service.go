@@ -109,7 +109,7 @@ func (s *productTokenService) VerifyProductToken(c *fiber.Ctx, query *validation
 		// Check if user already has an active subscription to this plan to avoid duplicates
 		var existingUserSubscription model.UserSubscription
 		err := s.DB.WithContext(c.Context()).
-			Where("user_id = ? AND plan_id = ? AND is_active = ?", user.ID, productToken.SubscriptionPlanID, true).
+			Where("user_id = ? AND plan_id = ? AND status IN ?", user.ID, productToken.SubscriptionPlanID, accessStatuses).
 			First(&existingUserSubscription).Error
 
 		if err == nil {
@@ -119,15 +119,14 @@ func (s *productTokenService) VerifyProductToken(c *fiber.Ctx, query *validation
 			var freemiumSubscription model.UserSubscription
 			freemiumErr := s.DB.WithContext(c.Context()).
 				Joins("JOIN subscription_plans ON user_subscriptions.plan_id = subscription_plans.id").
-				Where("user_subscriptions.user_id = ? AND subscription_plans.name = ? AND user_subscriptions.is_active = ?", user.ID, "Freemium Trial", true).
+				Where("user_subscriptions.user_id = ? AND subscription_plans.name = ? AND user_subscriptions.status IN ?", user.ID, "Freemium Trial", accessStatuses).
 				First(&freemiumSubscription).Error
 
 			if freemiumErr == nil {
 				// User has an active freemium subscription, deactivate it
 				s.Log.Infof("Deactivating freemium subscription for user %s to upgrade to product token plan", user.ID)
-				if err := s.DB.WithContext(c.Context()).
-					Model(&freemiumSubscription).
-					Update("is_active", false).Error; err != nil {
+				if err := TransitionSubscription(s.DB.WithContext(c.Context()), &freemiumSubscription,
+					model.SubscriptionStatusExpired, TransitionReasonReplaced, &user.ID); err != nil {
 					s.Log.Errorf("Failed to deactivate freemium subscription for user %s: %v", user.ID, err)
 				}
 			}
@@ -140,10 +139,10 @@ func (s *productTokenService) VerifyProductToken(c *fiber.Ctx, query *validation
 				EndDate:       time.Now().AddDate(0, 0, productToken.SubscriptionPlan.ValidityDays),
 				PaymentMethod: "product_token",
 				PaymentStatus: "success", // Assuming token verification implies successful "payment"
-				IsActive:      true,
+				Status:        model.SubscriptionStatusActive,
 				TransactionID: fmt.Sprintf("TOKEN-%s", productToken.ID.String()), // Link to product token
 			}
-			if err := s.DB.WithContext(c.Context()).Create(&userSubscription).Error; err != nil {
+			if err := createSubscription(s.DB.WithContext(c.Context()), &userSubscription, TransitionReasonCreated, &user.ID); err != nil {
 				s.Log.Errorf("Failed to create subscription for user %s with plan %s: %v", user.ID, *productToken.SubscriptionPlanID, err)
 				// Decide if this should be a hard error or just a warning. For now, log and continue.
 			} else {
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"app/src/model"
+	"app/src/validation"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// resolveRecipeIngredients looks every line up in TKPI through the bahan makanan service, converts it to
+// grams, applies its edible portion and returns the completed lines with the nutrients of the whole recipe
+func (s *recipesService) resolveRecipeIngredients(c *fiber.Ctx, lines []validation.RecipeIngredient) ([]model.RecipeIngredient, Nutrient, error) {
+	foods := newIngredientFoods(s.BahanMakanan)
+	ingredients := make([]model.RecipeIngredient, 0, len(lines))
+	total := emptyNutrient()
+
+	for i, line := range lines {
+		kode := strings.TrimSpace(line.Kode)
+		unitGrams, ok := model.RecipeUnitGrams[line.Unit]
+		if !ok {
+			return nil, Nutrient{}, fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Ingredient %d has an unknown unit %s", i+1, line.Unit))
+		}
+
+		food, err := foods.lookup(c, kode)
+		if err != nil {
+			return nil, Nutrient{}, err
+		}
+
+		grams := line.Quantity * unitGrams
+		bddPersen, edibleGrams := ediblePortion(food, grams)
+		nutrient := bahanMakananNutrients(*food, edibleGrams)
+		total = total.Add(nutrient)
+
+		ingredients = append(ingredients, model.RecipeIngredient{
+			Position:         i + 1,
+			Kode:             kode,
+			NamaBahanMakanan: food.NamaBahanMakanan,
+			Quantity:         line.Quantity,
+			Unit:             line.Unit,
+			Note:             line.Note,
+			Grams:            roundNutrient(grams),
+			BddPersen:        bddPersen,
+			EdibleGrams:      roundNutrient(edibleGrams),
+			Calories:         roundNutrient(nutrient.Calories.Quantity),
+			Protein:          roundNutrient(nutrient.Protein.Quantity),
+			Carbs:            roundNutrient(nutrient.Carbs.Quantity),
+			Fat:              roundNutrient(nutrient.Fat.Quantity),
+		})
+	}
+
+	return ingredients, total, nil
+}
+
+// applyRecipeNutrition sets the lines of recipe and its nutrients per serving from the recipe's total
+func applyRecipeNutrition(recipe *model.Recipe, ingredients []model.RecipeIngredient, total Nutrient) {
+	servings := recipe.Servings
+	if servings < 1 {
+		servings = 1
+	}
+	perServing := total.Scale(1 / float64(servings))
+
+	calories := roundNutrient(perServing.Calories.Quantity)
+	protein := roundNutrient(perServing.Protein.Quantity)
+	carbs := roundNutrient(perServing.Carbs.Quantity)
+	fat := roundNutrient(perServing.Fat.Quantity)
+
+	recipe.IngredientLines = ingredients
+	recipe.Calories = &calories
+	recipe.Protein = &protein
+	recipe.Carbs = &carbs
+	recipe.Fat = &fat
+	recipe.Nutrients = buildRecipeNutrients(perServing)
+}
+
+// buildRecipeNutrients turns the nutrients of a serving into rows in definition order
+func buildRecipeNutrients(perServing Nutrient) []model.RecipeNutrient {
+	quantities := nutrientQuantities(perServing.Details)
+	quantities[model.NutrientEnergy] = perServing.Calories.Quantity
+	quantities[model.NutrientProtein] = perServing.Protein.Quantity
+	quantities[model.NutrientCarbs] = perServing.Carbs.Quantity
+	quantities[model.NutrientFat] = perServing.Fat.Quantity
+
+	nutrients := make([]model.RecipeNutrient, 0, len(quantities))
+	for _, definition := range model.NutrientDefinitions {
+		quantity, ok := quantities[definition.Code]
+		if !ok {
+			continue
+		}
+		nutrients = append(nutrients, model.RecipeNutrient{
+			Code:     definition.Code,
+			Quantity: math.Round(quantity*1000) / 1000,
+			Unit:     definition.Unit,
+		})
+	}
+	return nutrients
+}
+
+// sortRecipeNutrients puts nutrients loaded from the database back in definition order
+func sortRecipeNutrients(nutrients []model.RecipeNutrient) {
+	order := make(map[string]int, len(model.NutrientDefinitions))
+	for i, definition := range model.NutrientDefinitions {
+		order[definition.Code] = i
+	}
+	sort.SliceStable(nutrients, func(i, j int) bool {
+		return order[nutrients[i].Code] < order[nutrients[j].Code]
+	})
+}
+
+// recipeIngredientRequests turns stored lines back into requests so they can be resolved again
+func recipeIngredientRequests(ingredients []model.RecipeIngredient) []validation.RecipeIngredient {
+	lines := make([]validation.RecipeIngredient, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		lines = append(lines, validation.RecipeIngredient{
+			Kode:     ingredient.Kode,
+			Quantity: ingredient.Quantity,
+			Unit:     ingredient.Unit,
+			Note:     ingredient.Note,
+		})
+	}
+	return lines
+}
+
+func buildRecipeSteps(instructions []string) []model.RecipeStep {
+	steps := make([]model.RecipeStep, 0, len(instructions))
+	for _, instruction := range instructions {
+		steps = append(steps, model.RecipeStep{
+			Position:    len(steps) + 1,
+			Instruction: strings.TrimSpace(instruction),
+		})
+	}
+	return steps
+}
+
+// recipeIngredientsText writes the lines as the markdown list kept in Recipe.Ingredients
+func recipeIngredientsText(ingredients []model.RecipeIngredient) string {
+	lines := make([]string, 0, len(ingredients))
+	for _, ingredient := range ingredients {
+		line := fmt.Sprintf("- %s %s %s", strconv.FormatFloat(ingredient.Quantity, 'f', -1, 64), ingredient.Unit, ingredient.NamaBahanMakanan)
+		if ingredient.Note != nil && *ingredient.Note != "" {
+			line += ", " + *ingredient.Note
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// recipeStepsText writes the steps as the numbered markdown list kept in Recipe.Instructions
+func recipeStepsText(steps []model.RecipeStep) string {
+	lines := make([]string, 0, len(steps))
+	for _, step := range steps {
+		lines = append(lines, fmt.Sprintf("%d. %s", step.Position, step.Instruction))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// saveRecipeIngredients replaces the ingredient lines and the nutrients per serving of a recipe
+func saveRecipeIngredients(db *gorm.DB, recipeID uuid.UUID, ingredients []model.RecipeIngredient, nutrients []model.RecipeNutrient) error {
+	if err := db.Where("recipe_id = ?", recipeID).Delete(&model.RecipeIngredient{}).Error; err != nil {
+		return err
+	}
+	if err := db.Where("recipe_id = ?", recipeID).Delete(&model.RecipeNutrient{}).Error; err != nil {
+		return err
+	}
+
+	if len(ingredients) > 0 {
+		for i := range ingredients {
+			ingredients[i].RecipeID = recipeID
+		}
+		if err := db.Create(&ingredients).Error; err != nil {
+			return err
+		}
+	}
+	if len(nutrients) > 0 {
+		for i := range nutrients {
+			nutrients[i].RecipeID = recipeID
+		}
+		if err := db.Create(&nutrients).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveRecipeSteps replaces the steps of a recipe
+func saveRecipeSteps(db *gorm.DB, recipeID uuid.UUID, steps []model.RecipeStep) error {
+	if err := db.Where("recipe_id = ?", recipeID).Delete(&model.RecipeStep{}).Error; err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return nil
+	}
+
+	for i := range steps {
+		steps[i].RecipeID = recipeID
+	}
+	return db.Create(&steps).Error
+}
+
+func orderByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"app/src/validation"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Recipe sort options, relevance is the default when searching and newest otherwise
+const (
+	RecipeSortNewest       = "newest"
+	RecipeSortOldest       = "oldest"
+	RecipeSortName         = "name"
+	RecipeSortCaloriesAsc  = "calories_asc"
+	RecipeSortCaloriesDesc = "calories_desc"
+	RecipeSortRelevance    = "relevance"
+	RecipeSortRating       = "rating"
+	RecipeSortPopular      = "popular"
+)
+
+// recipeSearchQuery parses web search syntax, quoted phrases and -excluded words, with the simple
+// configuration the search vector is built with
+const recipeSearchQuery = "websearch_to_tsquery('simple', ?)"
+
+// recipeIngredientMatch matches a recipe whose free-text ingredients or structured lines mention an
+// ingredient as whole words, so "ayam" doesn't match "bayam", or whose lines use it as bahan makanan kode
+const recipeIngredientMatch = `(recipes.ingredients ~* ? OR EXISTS (
+	SELECT 1 FROM recipe_ingredients
+	WHERE recipe_ingredients.recipe_id = recipes.id
+	AND (recipe_ingredients.kode = ? OR recipe_ingredients.nama_bahan_makanan ~* ?)
+))`
+
+// filterRecipes applies the search and filters of query
+func filterRecipes(db *gorm.DB, query *validation.QueryRecipe) *gorm.DB {
+	if search := strings.TrimSpace(query.Search); search != "" {
+		db = db.Where("recipes.search_vector @@ "+recipeSearchQuery, search)
+	}
+	if query.Day != "" {
+		db = db.Where("recipes.day = ?", query.Day)
+	}
+	if label := strings.TrimSpace(query.Label); label != "" {
+		db = db.Where("LOWER(recipes.label) = LOWER(?)", label)
+	}
+	if query.MinCalories > 0 {
+		db = db.Where("recipes.calories >= ?", query.MinCalories)
+	}
+	if query.MaxCalories > 0 {
+		db = db.Where("recipes.calories <= ?", query.MaxCalories)
+	}
+
+	// Every ingredient in contains must be in the recipe and none of the ones in excludes
+	for _, ingredient := range splitIngredients(query.Contains) {
+		pattern := wordPattern(ingredient)
+		db = db.Where(recipeIngredientMatch, pattern, strings.ToUpper(ingredient), pattern)
+	}
+	for _, ingredient := range splitIngredients(query.Excludes) {
+		pattern := wordPattern(ingredient)
+		db = db.Where("NOT "+recipeIngredientMatch, pattern, strings.ToUpper(ingredient), pattern)
+	}
+
+	return db
+}
+
+// sortRecipes orders recipes as requested, recipes without calories go last when sorting by them
+func sortRecipes(db *gorm.DB, query *validation.QueryRecipe) *gorm.DB {
+	sort := query.Sort
+	search := strings.TrimSpace(query.Search)
+	if sort == "" {
+		sort = RecipeSortNewest
+		if search != "" {
+			sort = RecipeSortRelevance
+		}
+	}
+
+	switch sort {
+	case RecipeSortOldest:
+		return db.Order("recipes.created_at ASC")
+	case RecipeSortName:
+		return db.Order("recipes.name ASC")
+	case RecipeSortCaloriesAsc:
+		return db.Order("recipes.calories ASC NULLS LAST").Order("recipes.name ASC")
+	case RecipeSortCaloriesDesc:
+		return db.Order("recipes.calories DESC NULLS LAST").Order("recipes.name ASC")
+	case RecipeSortRating:
+		return db.Order("recipes.rating_average DESC").Order("recipes.rating_count DESC").Order("recipes.created_at DESC")
+	case RecipeSortPopular:
+		return db.Order("recipes.favorite_count DESC").Order("recipes.rating_count DESC").Order("recipes.created_at DESC")
+	case RecipeSortRelevance:
+		if search == "" {
+			return db.Order("recipes.created_at DESC")
+		}
+		return db.Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "ts_rank(recipes.search_vector, " + recipeSearchQuery + ") DESC, recipes.created_at DESC",
+			Vars:               []interface{}{search},
+			WithoutParentheses: true,
+		}})
+	default:
+		return db.Order("recipes.created_at DESC")
+	}
+}
+
+// splitIngredients reads a comma separated list of ingredients
+func splitIngredients(list string) []string {
+	var ingredients []string
+	for _, ingredient := range strings.Split(list, ",") {
+		if ingredient = strings.TrimSpace(ingredient); ingredient != "" {
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+	return ingredients
+}
+
+// wordPattern is a PostgreSQL regular expression matching value literally as whole words
+func wordPattern(value string) string {
+	return `\m` + regexp.QuoteMeta(value) + `\M`
+}
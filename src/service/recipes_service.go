@@ -2,56 +2,145 @@ package service
 
 import (
 	"app/src/model"
+	"app/src/validation"
 	"errors"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 type RecipesService interface {
-	CreateRecipe(ctx *fiber.Ctx, recipe *model.Recipe) (*model.Recipe, error)
-	GetRecipes(ctx *fiber.Ctx) ([]model.Recipe, error)
+	CreateRecipe(ctx *fiber.Ctx, userID uuid.UUID, req *validation.CreateRecipe) (*model.Recipe, error)
+	GetRecipes(ctx *fiber.Ctx, query *validation.QueryRecipe) ([]model.Recipe, int64, error)
 	GetRecipeByID(ctx *fiber.Ctx, recipeID string) (*model.Recipe, error)
-	UpdateRecipe(ctx *fiber.Ctx, recipeID string, recipe *model.Recipe) (*model.Recipe, error)
+	GetRecipeBySlug(ctx *fiber.Ctx, slug string) (*model.Recipe, error)
+	UpdateRecipe(ctx *fiber.Ctx, recipeID string, req *validation.UpdateRecipe) (*model.Recipe, error)
 	DeleteRecipe(ctx *fiber.Ctx, recipeID string) error
 }
 
 type recipesService struct {
-	Log *logrus.Logger
-	DB  *gorm.DB
+	Log          *logrus.Logger
+	DB           *gorm.DB
+	Validate     *validator.Validate
+	BahanMakanan BahanMakananService
 }
 
-func NewRecipesService(db *gorm.DB) RecipesService {
+func NewRecipesService(db *gorm.DB, validate *validator.Validate, bahanMakanan BahanMakananService) RecipesService {
 	return &recipesService{
-		Log: logrus.New(),
-		DB:  db,
+		Log:          logrus.New(),
+		DB:           db,
+		Validate:     validate,
+		BahanMakanan: bahanMakanan,
 	}
 }
 
-func (s *recipesService) CreateRecipe(ctx *fiber.Ctx, recipe *model.Recipe) (*model.Recipe, error) {
+// CreateRecipe stores a recipe with its ingredient lines and steps. When there are ingredient lines the
+// nutrients per serving are computed from them, otherwise the ones in the request are kept.
+func (s *recipesService) CreateRecipe(ctx *fiber.Ctx, userID uuid.UUID, req *validation.CreateRecipe) (*model.Recipe, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
+	recipe := &model.Recipe{
+		UserID:       userID,
+		Name:         req.Name,
+		Slug:         req.Slug,
+		Image:        req.Image,
+		Description:  req.Description,
+		Ingredients:  req.Ingredients,
+		Instructions: req.Instructions,
+		Label:        req.Label,
+		Day:          req.Day,
+		Servings:     req.Servings,
+		Steps:        buildRecipeSteps(req.Steps),
+		Calories:     req.Calories,
+		Protein:      req.Protein,
+		Carbs:        req.Carbs,
+		Fat:          req.Fat,
+	}
+	if recipe.Servings == 0 {
+		recipe.Servings = 1
+	}
+
+	if len(req.IngredientLines) > 0 {
+		lines, total, err := s.resolveRecipeIngredients(ctx, req.IngredientLines)
+		if err != nil {
+			return nil, err
+		}
+		applyRecipeNutrition(recipe, lines, total)
+	}
+	if recipe.Ingredients == "" {
+		recipe.Ingredients = recipeIngredientsText(recipe.IngredientLines)
+	}
+	if recipe.Instructions == "" {
+		recipe.Instructions = recipeStepsText(recipe.Steps)
+	}
+
 	if err := s.DB.WithContext(ctx.Context()).Create(recipe).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fiber.NewError(fiber.StatusConflict, "Slug is already in use")
+		}
 		s.Log.Errorf("Failed to create recipe: %+v", err)
 		return nil, err
 	}
-	return recipe, nil
+
+	return s.GetRecipeByID(ctx, recipe.ID.String())
 }
 
-func (s *recipesService) GetRecipes(ctx *fiber.Ctx) ([]model.Recipe, error) {
+// GetRecipes returns a page of recipes matching the search and filters of query
+func (s *recipesService) GetRecipes(ctx *fiber.Ctx, query *validation.QueryRecipe) ([]model.Recipe, int64, error) {
+	if err := s.Validate.Struct(query); err != nil {
+		return nil, 0, err
+	}
+	if query.MaxCalories > 0 && query.MaxCalories < query.MinCalories {
+		return nil, 0, fiber.NewError(fiber.StatusBadRequest, "max_calories can't be below min_calories")
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 {
+		query.Limit = 10
+	}
+
+	db := s.DB.WithContext(ctx.Context()).Model(&model.Recipe{})
+	db = filterRecipes(db, query)
+
+	var totalResults int64
+	if err := db.Session(&gorm.Session{}).Count(&totalResults).Error; err != nil {
+		s.Log.Errorf("Failed to count recipes: %+v", err)
+		return nil, 0, err
+	}
+
 	var recipes []model.Recipe
-	if err := s.DB.WithContext(ctx.Context()).
-		Order("created_at DESC").
+	if err := sortRecipes(db, query).
+		Offset((query.Page - 1) * query.Limit).
+		Limit(query.Limit).
 		Find(&recipes).Error; err != nil {
 		s.Log.Errorf("Failed to get recipes: %+v", err)
-		return nil, err
+		return nil, 0, err
 	}
-	return recipes, nil
+	return recipes, totalResults, nil
 }
 
 func (s *recipesService) GetRecipeByID(ctx *fiber.Ctx, recipeID string) (*model.Recipe, error) {
+	return s.getRecipe(ctx, "id = ?", recipeID)
+}
+
+func (s *recipesService) GetRecipeBySlug(ctx *fiber.Ctx, slug string) (*model.Recipe, error) {
+	return s.getRecipe(ctx, "slug = ?", slug)
+}
+
+// getRecipe loads the recipe matching the condition with its ingredient lines, steps and nutrients
+func (s *recipesService) getRecipe(ctx *fiber.Ctx, condition string, value string) (*model.Recipe, error) {
 	var recipe model.Recipe
 	if err := s.DB.WithContext(ctx.Context()).
-		Where("id = ?", recipeID).
+		Where(condition, value).
+		Preload("IngredientLines", orderByPosition).
+		Preload("Steps", orderByPosition).
+		Preload("Nutrients").
 		First(&recipe).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fiber.NewError(fiber.StatusNotFound, "Recipe not found")
@@ -59,13 +148,22 @@ func (s *recipesService) GetRecipeByID(ctx *fiber.Ctx, recipeID string) (*model.
 		s.Log.Errorf("Failed to get recipe: %+v", err)
 		return nil, err
 	}
+	sortRecipeNutrients(recipe.Nutrients)
 	return &recipe, nil
 }
 
-func (s *recipesService) UpdateRecipe(ctx *fiber.Ctx, recipeID string, recipe *model.Recipe) (*model.Recipe, error) {
+// UpdateRecipe changes the fields given in the request. Ingredient lines and steps are replaced when
+// given, an empty list removes them. Nutrients are recomputed when the lines or the servings change, and
+// can only be set by hand on recipes without ingredient lines.
+func (s *recipesService) UpdateRecipe(ctx *fiber.Ctx, recipeID string, req *validation.UpdateRecipe) (*model.Recipe, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+
 	existingRecipe := new(model.Recipe)
 	if err := s.DB.WithContext(ctx.Context()).
 		Where("id = ?", recipeID).
+		Preload("IngredientLines", orderByPosition).
 		First(existingRecipe).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, fiber.NewError(fiber.StatusNotFound, "Recipe not found")
@@ -75,46 +173,137 @@ func (s *recipesService) UpdateRecipe(ctx *fiber.Ctx, recipeID string, recipe *m
 	}
 
 	updates := make(map[string]interface{})
-	if recipe.Name != "" {
-		updates["name"] = recipe.Name
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Slug != "" {
+		updates["slug"] = req.Slug
+	}
+	if req.Image != nil {
+		updates["image"] = req.Image
+	}
+	if req.Description != "" {
+		updates["description"] = req.Description
+	}
+	if req.Ingredients != "" {
+		updates["ingredients"] = req.Ingredients
+	}
+	if req.Instructions != "" {
+		updates["instructions"] = req.Instructions
 	}
-	if recipe.Slug != "" {
-		updates["slug"] = recipe.Slug
+	if req.Label != nil {
+		updates["label"] = req.Label
 	}
-	if recipe.Image != nil {
-		updates["image"] = recipe.Image
+	if req.Day != "" {
+		updates["day"] = req.Day
 	}
-	if recipe.Description != "" {
-		updates["description"] = recipe.Description
+	if req.Calories != nil {
+		updates["calories"] = req.Calories
 	}
-	if recipe.Ingredients != "" {
-		updates["ingredients"] = recipe.Ingredients
+	if req.Protein != nil {
+		updates["protein"] = req.Protein
 	}
-	if recipe.Instructions != "" {
-		updates["instructions"] = recipe.Instructions
+	if req.Carbs != nil {
+		updates["carbs"] = req.Carbs
 	}
-	if recipe.Label != nil {
-		updates["label"] = recipe.Label
+	if req.Fat != nil {
+		updates["fat"] = req.Fat
 	}
 
-	if len(updates) == 0 {
-		return existingRecipe, nil
+	servings := existingRecipe.Servings
+	if req.Servings != 0 && req.Servings != servings {
+		servings = req.Servings
+		updates["servings"] = servings
 	}
 
-	if err := s.DB.WithContext(ctx.Context()).
-		Model(existingRecipe).
-		Updates(updates).Error; err != nil {
+	// The stored lines are looked up again when only the servings change
+	var lineRequests []validation.RecipeIngredient
+	recompute := false
+	if req.IngredientLines != nil {
+		lineRequests = *req.IngredientLines
+		recompute = true
+	} else if servings != existingRecipe.Servings && len(existingRecipe.IngredientLines) > 0 {
+		lineRequests = recipeIngredientRequests(existingRecipe.IngredientLines)
+		recompute = true
+	}
+
+	computed := &model.Recipe{Servings: servings}
+	if recompute && len(lineRequests) > 0 {
+		lines, total, err := s.resolveRecipeIngredients(ctx, lineRequests)
+		if err != nil {
+			return nil, err
+		}
+		applyRecipeNutrition(computed, lines, total)
+
+		updates["calories"] = computed.Calories
+		updates["protein"] = computed.Protein
+		updates["carbs"] = computed.Carbs
+		updates["fat"] = computed.Fat
+		if req.IngredientLines != nil && req.Ingredients == "" {
+			updates["ingredients"] = recipeIngredientsText(computed.IngredientLines)
+		}
+	} else if !recompute && len(existingRecipe.IngredientLines) > 0 {
+		// Computed nutrients can't be overwritten by hand
+		delete(updates, "calories")
+		delete(updates, "protein")
+		delete(updates, "carbs")
+		delete(updates, "fat")
+	}
+
+	var steps []model.RecipeStep
+	if req.Steps != nil {
+		steps = buildRecipeSteps(*req.Steps)
+		if req.Instructions == "" && len(steps) > 0 {
+			updates["instructions"] = recipeStepsText(steps)
+		}
+	}
+
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(existingRecipe).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if recompute {
+			if err := saveRecipeIngredients(tx, existingRecipe.ID, computed.IngredientLines, computed.Nutrients); err != nil {
+				return err
+			}
+		}
+		if req.Steps != nil {
+			if err := saveRecipeSteps(tx, existingRecipe.ID, steps); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, fiber.NewError(fiber.StatusConflict, "Slug is already in use")
+		}
 		s.Log.Errorf("Failed to update recipe: %+v", err)
 		return nil, err
 	}
 
-	return existingRecipe, nil
+	return s.GetRecipeByID(ctx, recipeID)
 }
 
 func (s *recipesService) DeleteRecipe(ctx *fiber.Ctx, recipeID string) error {
-	if err := s.DB.WithContext(ctx.Context()).
-		Where("id = ?", recipeID).
-		Delete(&model.Recipe{}).Error; err != nil {
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("recipe_id = ?", recipeID).Delete(&model.RecipeIngredient{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("recipe_id = ?", recipeID).Delete(&model.RecipeStep{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("recipe_id = ?", recipeID).Delete(&model.RecipeNutrient{}).Error; err != nil {
+			return err
+		}
+		if err := deleteEngagements(tx, model.TargetRecipe, recipeID); err != nil {
+			return err
+		}
+		return tx.Where("id = ?", recipeID).Delete(&model.Recipe{}).Error
+	})
+	if err != nil {
 		s.Log.Errorf("Failed to delete recipe: %+v", err)
 		return err
 	}
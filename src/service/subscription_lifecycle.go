@@ -0,0 +1,287 @@
+package service
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Reasons recorded with each subscription transition
+const (
+	TransitionReasonCreated        = "created"
+	TransitionReasonPaymentSettled = "payment_settled"
+	TransitionReasonPaymentFailed  = "payment_failed"
+	TransitionReasonRefunded       = "refunded"
+	TransitionReasonLapsed         = "lapsed"
+	TransitionReasonGraceEnded     = "grace_ended"
+	TransitionReasonQueueStarted   = "queue_started"
+	TransitionReasonReplaced       = "replaced"
+	TransitionReasonAdmin          = "admin"
+)
+
+// freemiumPaymentMethod marks the trial subscriptions, which expire without a grace period
+const freemiumPaymentMethod = "freemium_trial"
+
+// subscriptionTransitions lists the statuses each status may move to, expired and refunded are final
+var subscriptionTransitions = map[string][]string{
+	model.SubscriptionStatusPending: {
+		model.SubscriptionStatusActive,
+		model.SubscriptionStatusQueued,
+		model.SubscriptionStatusExpired,
+		model.SubscriptionStatusCancelled,
+	},
+	model.SubscriptionStatusQueued: {
+		model.SubscriptionStatusActive,
+		model.SubscriptionStatusCancelled,
+		model.SubscriptionStatusRefunded,
+	},
+	model.SubscriptionStatusActive: {
+		model.SubscriptionStatusGrace,
+		model.SubscriptionStatusExpired,
+		model.SubscriptionStatusCancelled,
+		model.SubscriptionStatusRefunded,
+	},
+	model.SubscriptionStatusGrace: {
+		model.SubscriptionStatusActive,
+		model.SubscriptionStatusExpired,
+		model.SubscriptionStatusCancelled,
+		model.SubscriptionStatusRefunded,
+	},
+	model.SubscriptionStatusCancelled: {
+		model.SubscriptionStatusRefunded,
+	},
+}
+
+// accessStatuses are the statuses whose subscriptions give access to the plan's features
+var accessStatuses = []string{model.SubscriptionStatusActive, model.SubscriptionStatusGrace}
+
+// CanTransitionSubscription reports whether the lifecycle allows a subscription to move from one status to another
+func CanTransitionSubscription(from, to string) bool {
+	for _, allowed := range subscriptionTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionGivesAccess reports whether subscriptions in the status give access to their plan
+func SubscriptionGivesAccess(status string) bool {
+	return status == model.SubscriptionStatusActive || status == model.SubscriptionStatusGrace
+}
+
+// subscriptionGracePeriod is how long paid subscriptions keep access after their end date
+func subscriptionGracePeriod() time.Duration {
+	return time.Duration(config.SubscriptionGraceDays) * 24 * time.Hour
+}
+
+// TransitionSubscription is the only way a subscription changes status. It checks the move against the
+// lifecycle, keeps IsActive in step and records the transition, all in one transaction. The update is
+// conditional on the status the subscription was read with, so concurrent transitions can't both win.
+func TransitionSubscription(db *gorm.DB, subscription *model.UserSubscription, to, reason string, actorID *uuid.UUID) error {
+	from := subscription.Status
+	if from == to {
+		return nil
+	}
+
+	if !CanTransitionSubscription(from, to) {
+		return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("A %s subscription can't become %s", from, to))
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.UserSubscription{}).
+			Where("id = ? AND status = ?", subscription.ID, from).
+			Updates(map[string]interface{}{
+				"status":    to,
+				"is_active": SubscriptionGivesAccess(to),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fiber.NewError(fiber.StatusConflict, "Subscription was changed by another request")
+		}
+
+		return tx.Create(&model.SubscriptionTransition{
+			UserSubscriptionID: subscription.ID,
+			FromStatus:         from,
+			ToStatus:           to,
+			Reason:             reason,
+			ActorID:            actorID,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	subscription.Status = to
+	subscription.IsActive = SubscriptionGivesAccess(to)
+	return nil
+}
+
+// createSubscription inserts a subscription in its initial status and records it as the first transition
+func createSubscription(db *gorm.DB, subscription *model.UserSubscription, reason string, actorID *uuid.UUID) error {
+	if subscription.Status == "" {
+		subscription.Status = model.SubscriptionStatusPending
+	}
+	subscription.IsActive = SubscriptionGivesAccess(subscription.Status)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(subscription).Error; err != nil {
+			return err
+		}
+		// GORM leaves false out of the insert, so the column's default of true would win
+		if !subscription.IsActive {
+			if err := tx.Model(subscription).Update("is_active", false).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&model.SubscriptionTransition{
+			UserSubscriptionID: subscription.ID,
+			ToStatus:           subscription.Status,
+			Reason:             reason,
+			ActorID:            actorID,
+		}).Error
+	})
+}
+
+// GetSubscriptionTransitions returns the audit trail of a subscription, oldest first
+func (s *subscriptionService) GetSubscriptionTransitions(ctx *fiber.Ctx, subscriptionID uuid.UUID) ([]model.SubscriptionTransition, error) {
+	var subscription model.UserSubscription
+	if err := s.DB.WithContext(ctx.Context()).Select("id").First(&subscription, "id = ?", subscriptionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Subscription not found")
+		}
+		return nil, err
+	}
+
+	transitions := []model.SubscriptionTransition{}
+	if err := s.DB.WithContext(ctx.Context()).
+		Where("user_subscription_id = ?", subscriptionID).
+		Order("created_at ASC").
+		Find(&transitions).Error; err != nil {
+		s.Log.Errorf("Failed to get subscription transitions: %+v", err)
+		return nil, err
+	}
+
+	return transitions, nil
+}
+
+// SweepSubscriptions moves subscriptions along the lifecycle as time passes. Paid subscriptions past their
+// end date enter the grace period and expire when it is over, trials expire right away and subscriptions
+// their user cancelled end as cancelled. Queued
+// subscriptions whose start date has come are activated once their user has nothing else active.
+func (s *subscriptionService) SweepSubscriptions(ctx context.Context, now time.Time) (*model.SubscriptionSweepReport, error) {
+	report := &model.SubscriptionSweepReport{}
+	db := s.DB.WithContext(ctx)
+
+	var lapsed []model.UserSubscription
+	if err := db.Where("status = ? AND end_date <= ?", model.SubscriptionStatusActive, now).Find(&lapsed).Error; err != nil {
+		s.Log.Errorf("Failed to find lapsed subscriptions: %+v", err)
+		return nil, err
+	}
+
+	for i := range lapsed {
+		subscription := &lapsed[i]
+		// A subscription queued to follow this one takes over instead of a grace period
+		var successors int64
+		if err := db.Model(&model.UserSubscription{}).
+			Where("user_id = ? AND status = ? AND start_date <= ?", subscription.UserID, model.SubscriptionStatusQueued, now).
+			Count(&successors).Error; err != nil {
+			s.Log.Errorf("Failed to find queued subscriptions: %+v", err)
+			return nil, err
+		}
+
+		to, counter := model.SubscriptionStatusGrace, &report.Grace
+		reason := TransitionReasonLapsed
+		if subscription.CancelAtPeriodEnd {
+			to, counter, reason = model.SubscriptionStatusCancelled, &report.Cancelled, TransitionReasonUserCancelled
+		} else if successors > 0 || subscription.PaymentMethod == freemiumPaymentMethod ||
+			!now.Before(subscription.EndDate.Add(subscriptionGracePeriod())) {
+			to, counter = model.SubscriptionStatusExpired, &report.Expired
+		}
+
+		if err := TransitionSubscription(db, subscription, to, reason, nil); err != nil {
+			s.Log.Errorf("Failed to move lapsed subscription %s to %s: %+v", subscription.ID, to, err)
+			continue
+		}
+		*counter++
+	}
+
+	var graced []model.UserSubscription
+	if err := db.Where("status = ? AND end_date <= ?", model.SubscriptionStatusGrace, now.Add(-subscriptionGracePeriod())).
+		Find(&graced).Error; err != nil {
+		s.Log.Errorf("Failed to find subscriptions past their grace period: %+v", err)
+		return nil, err
+	}
+
+	for i := range graced {
+		if err := TransitionSubscription(db, &graced[i], model.SubscriptionStatusExpired, TransitionReasonGraceEnded, nil); err != nil {
+			s.Log.Errorf("Failed to expire subscription %s: %+v", graced[i].ID, err)
+			continue
+		}
+		report.Expired++
+	}
+
+	var queued []model.UserSubscription
+	if err := db.Where("status = ? AND start_date <= ? AND end_date > ?", model.SubscriptionStatusQueued, now, now).
+		Where("NOT EXISTS (?)", s.DB.Table("user_subscriptions AS active").
+			Select("1").
+			Where("active.user_id = user_subscriptions.user_id AND active.status IN ?", accessStatuses)).
+		Order("start_date ASC").
+		Find(&queued).Error; err != nil {
+		s.Log.Errorf("Failed to find queued subscriptions: %+v", err)
+		return nil, err
+	}
+
+	started := make(map[uuid.UUID]bool)
+	for i := range queued {
+		subscription := &queued[i]
+		// Only the earliest queued subscription of a user starts, the next one waits for it to end
+		if started[subscription.UserID] {
+			continue
+		}
+
+		if err := TransitionSubscription(db, subscription, model.SubscriptionStatusActive, TransitionReasonQueueStarted, nil); err != nil {
+			s.Log.Errorf("Failed to activate queued subscription %s: %+v", subscription.ID, err)
+			continue
+		}
+		started[subscription.UserID] = true
+		report.Activated++
+	}
+
+	return report, nil
+}
+
+// StartLifecycleSweeper sweeps the subscriptions now and then every interval until ctx is done
+func (s *subscriptionService) StartLifecycleSweeper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if report, err := s.SweepSubscriptions(ctx, time.Now()); err == nil && (report.Grace > 0 || report.Expired > 0 || report.Activated > 0 || report.Cancelled > 0) {
+				s.Log.Infof("Subscription sweep: %d entered grace, %d expired, %d cancelled, %d activated",
+					report.Grace, report.Expired, report.Cancelled, report.Activated)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,300 @@
+package service
+
+import (
+	"app/src/model"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TransitionReasonRenewed is recorded when a renewal is queued after, or takes over from, the subscription it renews
+const TransitionReasonRenewed = "renewed"
+
+// planChange is how a purchase changes the user's paid subscriptions, Amount is what the user pays for it
+type planChange struct {
+	Type         string
+	Replaces     *model.UserSubscription
+	Amount       int
+	CreditAmount int
+}
+
+// currentPaidSubscription returns the paid subscription giving the user access, trials don't count
+func currentPaidSubscription(db *gorm.DB, userID uuid.UUID, now time.Time) (*model.UserSubscription, error) {
+	var subscription model.UserSubscription
+	err := db.Preload("Plan").
+		Where("user_id = ? AND payment_method <> ?", userID, freemiumPaymentMethod).
+		Where("(status = ? AND end_date > ?) OR (status = ? AND end_date > ?)",
+			model.SubscriptionStatusActive, now, model.SubscriptionStatusGrace, now.Add(-subscriptionGracePeriod())).
+		Order("end_date DESC").
+		First(&subscription).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+// lastPaidSubscription returns the user's paid subscription that ends last, queued ones included
+func lastPaidSubscription(db *gorm.DB, userID uuid.UUID, now time.Time) (*model.UserSubscription, error) {
+	var subscription model.UserSubscription
+	err := db.Preload("Plan").
+		Where("user_id = ? AND payment_method <> ?", userID, freemiumPaymentMethod).
+		Where("(status IN ? AND end_date > ?) OR (status = ? AND end_date > ?)",
+			[]string{model.SubscriptionStatusActive, model.SubscriptionStatusQueued}, now,
+			model.SubscriptionStatusGrace, now.Add(-subscriptionGracePeriod())).
+		Order("end_date DESC").
+		First(&subscription).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+// planChangeFor works out what buying plan means for the user. Buying the plan the user's subscriptions
+// end with renews it, a plan pricier than the current one is an upgrade and any other plan is a downgrade.
+func planChangeFor(db *gorm.DB, userID uuid.UUID, plan *model.SubscriptionPlan, now time.Time) (*planChange, error) {
+	change := &planChange{Type: model.PlanChangeNew, Amount: plan.Price}
+
+	current, err := currentPaidSubscription(db, userID, now)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return change, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := lastPaidSubscription(db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case last.PlanID == plan.ID:
+		change.Type, change.Replaces = model.PlanChangeRenewal, last
+	case plan.Price > current.Plan.Price:
+		change.Type, change.Replaces = model.PlanChangeUpgrade, current
+		change.CreditAmount = RefundAmount(RefundTypeProrated, current.Plan.Price-current.CreditAmount, current.StartDate, current.EndDate, now)
+		change.Amount = plan.Price - change.CreditAmount
+	default:
+		change.Type, change.Replaces = model.PlanChangeDowngrade, last
+	}
+
+	return change, nil
+}
+
+// checkNoPendingPlanChange refuses an upgrade or downgrade while another one of the user waits for payment.
+// Both would be priced against the same subscription, and whichever settles second would apply to a
+// subscription the first one already changed.
+func checkNoPendingPlanChange(db *gorm.DB, userID uuid.UUID, change *planChange) error {
+	if change.Type != model.PlanChangeUpgrade && change.Type != model.PlanChangeDowngrade {
+		return nil
+	}
+
+	var pending int64
+	if err := db.Model(&model.UserSubscription{}).
+		Where("user_id = ? AND status = ? AND change_type IN ?", userID, model.SubscriptionStatusPending,
+			[]string{model.PlanChangeUpgrade, model.PlanChangeDowngrade}).
+		Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending > 0 {
+		return ErrPlanChangePending
+	}
+
+	return nil
+}
+
+// upgradeCredit is what is left of the replaced subscription at now, the credit an upgrade settling now earns
+func upgradeCredit(replaces *model.UserSubscription, now time.Time) int {
+	if replaces == nil || !SubscriptionGivesAccess(replaces.Status) {
+		return 0
+	}
+	return RefundAmount(RefundTypeProrated, replaces.Plan.Price-replaces.CreditAmount, replaces.StartDate, replaces.EndDate, now)
+}
+
+// activatePurchase starts a subscription whose payment settled according to its plan change. A renewal
+// follows the subscription it renews, a downgrade is queued after the user's last subscription and an
+// upgrade replaces the current one. Changes whose subscription is gone by now start like new purchases.
+// Subscriptions starting now are given their whole period from the settlement, an upgrade's credit is
+// worked out again for it and credit it no longer earns is taken off its period.
+func (s *subscriptionService) activatePurchase(db *gorm.DB, subscription *model.UserSubscription, actorID *uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		validity := subscription.EndDate.Sub(subscription.StartDate)
+
+		var replaces *model.UserSubscription
+		if subscription.ReplacesID != nil {
+			replaces = new(model.UserSubscription)
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Plan").First(replaces, "id = ?", *subscription.ReplacesID).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				replaces = nil
+			} else if err != nil {
+				return err
+			}
+		}
+		if replaces != nil && !SubscriptionGivesAccess(replaces.Status) && replaces.Status != model.SubscriptionStatusQueued {
+			replaces = nil
+		}
+
+		switch {
+		case subscription.ChangeType == model.PlanChangeRenewal && replaces != nil:
+			return s.applyRenewal(tx, subscription, replaces, validity, actorID)
+
+		case subscription.ChangeType == model.PlanChangeDowngrade && replaces != nil:
+			last, err := lastPaidSubscription(tx, subscription.UserID, now)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			// A subscription in its grace period is over already, the downgrade takes its place like an upgrade
+			if last != nil && last.Status == model.SubscriptionStatusGrace {
+				if err := TransitionSubscription(tx, last, model.SubscriptionStatusExpired, TransitionReasonReplaced, actorID); err != nil {
+					return err
+				}
+			} else if last != nil {
+				start, end := last.EndDate, last.EndDate.Add(validity)
+				if err := tx.Model(subscription).Updates(map[string]interface{}{
+					"start_date": start,
+					"end_date":   end,
+				}).Error; err != nil {
+					return err
+				}
+				subscription.StartDate, subscription.EndDate = start, end
+				return TransitionSubscription(tx, subscription, model.SubscriptionStatusQueued, TransitionReasonPaymentSettled, actorID)
+			}
+		}
+
+		if subscription.CreditAmount > 0 {
+			// Credit given at checkout for days the replaced subscription was used since, or for one that is
+			// gone by now, is paid for with days of the upgrade
+			var plan model.SubscriptionPlan
+			if err := tx.First(&plan, "id = ?", subscription.PlanID).Error; err != nil {
+				return err
+			}
+			if missing := subscription.CreditAmount - upgradeCredit(replaces, now); missing > 0 && plan.Price > 0 {
+				validity -= time.Duration(int64(validity) * int64(missing) / int64(plan.Price))
+			}
+		}
+
+		start, end := now, now.Add(validity)
+		if err := tx.Model(subscription).Updates(map[string]interface{}{
+			"start_date": start,
+			"end_date":   end,
+		}).Error; err != nil {
+			return err
+		}
+		subscription.StartDate, subscription.EndDate = start, end
+
+		if subscription.ChangeType == model.PlanChangeUpgrade && replaces != nil && replaces.Status != model.SubscriptionStatusQueued {
+			if err := TransitionSubscription(tx, replaces, model.SubscriptionStatusExpired, TransitionReasonReplaced, actorID); err != nil {
+				return err
+			}
+			// Subscriptions queued after the replaced one now wait for the upgrade to end
+			if err := shiftQueuedSubscriptions(tx, subscription.UserID, replaces.EndDate, end.Sub(replaces.EndDate)); err != nil {
+				return err
+			}
+		}
+
+		// The trial makes way for a paid subscription
+		var trials []model.UserSubscription
+		if err := tx.Where("user_id = ? AND payment_method = ? AND status IN ?", subscription.UserID, freemiumPaymentMethod, accessStatuses).
+			Find(&trials).Error; err != nil {
+			return err
+		}
+		for i := range trials {
+			if err := TransitionSubscription(tx, &trials[i], model.SubscriptionStatusExpired, TransitionReasonReplaced, actorID); err != nil {
+				return err
+			}
+		}
+
+		return TransitionSubscription(tx, subscription, model.SubscriptionStatusActive, TransitionReasonPaymentSettled, actorID)
+	})
+}
+
+// applyRenewal queues the renewal to start when the subscription it renews ends, so the renewal keeps its own
+// period and order and can be refunded on its own. A renewed subscription in its grace period is over already,
+// the renewal takes its place from the renewed end date.
+func (s *subscriptionService) applyRenewal(tx *gorm.DB, renewal, renewed *model.UserSubscription, validity time.Duration, actorID *uuid.UUID) error {
+	start, end := renewed.EndDate, renewed.EndDate.Add(validity)
+
+	// Subscriptions queued after the renewed one now wait for the renewal to end
+	if err := shiftQueuedSubscriptions(tx, renewed.UserID, start, validity); err != nil {
+		return err
+	}
+	if err := tx.Model(renewal).Updates(map[string]interface{}{
+		"start_date": start,
+		"end_date":   end,
+	}).Error; err != nil {
+		return err
+	}
+	renewal.StartDate, renewal.EndDate = start, end
+
+	s.Log.Infof("Renewed subscription %s until %s", renewed.ID, end.Format(time.RFC3339))
+
+	if renewed.Status == model.SubscriptionStatusGrace {
+		if err := TransitionSubscription(tx, renewed, model.SubscriptionStatusExpired, TransitionReasonRenewed, actorID); err != nil {
+			return err
+		}
+		return TransitionSubscription(tx, renewal, model.SubscriptionStatusActive, TransitionReasonRenewed, actorID)
+	}
+
+	// Renewing takes back a cancellation, the renewed subscription hands over to the renewal when it ends
+	if err := tx.Model(renewed).Update("cancel_at_period_end", false).Error; err != nil {
+		return err
+	}
+	return TransitionSubscription(tx, renewal, model.SubscriptionStatusQueued, TransitionReasonRenewed, actorID)
+}
+
+// shiftQueuedSubscriptions moves the user's subscriptions queued to start at or after from by delta
+func shiftQueuedSubscriptions(db *gorm.DB, userID uuid.UUID, from time.Time, delta time.Duration) error {
+	if delta == 0 {
+		return nil
+	}
+
+	var queued []model.UserSubscription
+	if err := db.Where("user_id = ? AND status = ? AND start_date >= ?", userID, model.SubscriptionStatusQueued, from).
+		Find(&queued).Error; err != nil {
+		return err
+	}
+
+	for _, subscription := range queued {
+		if err := db.Session(&gorm.Session{NewDB: true}).Model(&model.UserSubscription{}).
+			Where("id = ?", subscription.ID).
+			Updates(map[string]interface{}{
+				"start_date": subscription.StartDate.Add(delta),
+				"end_date":   subscription.EndDate.Add(delta),
+			}).Error; err != nil {
+			return fmt.Errorf("failed to move queued subscription %s: %w", subscription.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// upcomingSubscriptions lists the user's paid subscriptions queued after the current one, in order
+func upcomingSubscriptions(db *gorm.DB, userID uuid.UUID) ([]model.UpcomingSubscriptionResponse, error) {
+	var queued []model.UserSubscription
+	if err := db.Preload("Plan").
+		Where("user_id = ? AND status = ?", userID, model.SubscriptionStatusQueued).
+		Order("start_date ASC").
+		Find(&queued).Error; err != nil {
+		return nil, err
+	}
+
+	upcoming := make([]model.UpcomingSubscriptionResponse, 0, len(queued))
+	for _, subscription := range queued {
+		upcoming = append(upcoming, model.UpcomingSubscriptionResponse{
+			ID:        subscription.ID,
+			PlanID:    subscription.PlanID,
+			PlanName:  subscription.Plan.Name,
+			StartDate: subscription.StartDate,
+			EndDate:   subscription.EndDate,
+		})
+	}
+
+	return upcoming, nil
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"app/src/model"
+	"app/src/validation"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Refund types an admin can choose, a prorated refund only pays back the time left on the subscription
+const (
+	RefundTypeFull     = "full"
+	RefundTypeProrated = "prorated"
+)
+
+// TransitionReasonUserCancelled is recorded when a subscription ends because its user cancelled it
+const TransitionReasonUserCancelled = "user_cancelled"
+
+// RefundAmount returns how much of price a refund pays back at now. A prorated refund pays the share of the
+// subscription's period that is left, in whole Rupiah, and all of it when the period hasn't started.
+func RefundAmount(refundType string, price int, start, end, now time.Time) int {
+	if refundType == RefundTypeFull || !now.After(start) {
+		return price
+	}
+
+	period := end.Sub(start)
+	remaining := end.Sub(now)
+	if period <= 0 || remaining <= 0 {
+		return 0
+	}
+
+	return int(int64(price) * int64(remaining) / int64(period))
+}
+
+// refundKey identifies the refund of an order at Midtrans, so retrying a refund can't pay it back twice
+func refundKey(orderID string) string {
+	return orderID + "-refund"
+}
+
+// transactionStatusRefundPending marks a refund detail saved before the gateway was asked for the refund
+const transactionStatusRefundPending = "refund_pending"
+
+// pendingRefund is a refund saved before it is sent to the gateway, Status is what its detail becomes once made
+type pendingRefund struct {
+	Detail *model.TransactionDetail
+	Amount int
+	Status string
+}
+
+// RefundSubscription refunds a paid subscription through the payment gateway, records the refund as a
+// transaction detail and revokes the subscription's access. The refund is saved as pending before the gateway
+// is called and completed afterwards, so a refund the gateway made is never lost. Retrying a refund that
+// didn't complete sends the same amount and refund key again.
+func (s *subscriptionService) RefundSubscription(ctx *fiber.Ctx, subscriptionID uuid.UUID, req *validation.RefundSubscription) (*model.TransactionDetail, error) {
+	if req.Type != RefundTypeFull && req.Type != RefundTypeProrated {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Refund type must be full or prorated")
+	}
+
+	db := s.DB.WithContext(ctx.Context())
+	pending, err := s.prepareRefund(db, subscriptionID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	refund, err := s.Payment.Refund(pending.Detail.OrderID, &RefundRequest{
+		RefundKey: pending.Detail.TransactionID,
+		Amount:    pending.Amount,
+		Reason:    pending.Detail.StatusMessage,
+	})
+	if errors.Is(err, ErrRefundDuplicate) {
+		// An earlier attempt was made by the gateway but not completed here
+		refund, err = &RefundResponse{RefundKey: pending.Detail.TransactionID}, nil
+	}
+	if err != nil {
+		s.Log.Errorf("Failed to refund subscription %s: %+v", subscriptionID, err)
+		return nil, fiber.NewError(fiber.StatusBadGateway, "The payment gateway refused the refund")
+	}
+
+	if err := s.completeRefund(db, subscriptionID, pending, refund, actorFromContext(ctx)); err != nil {
+		s.Log.Errorf("Failed to complete refund of subscription %s: %+v", subscriptionID, err)
+		return nil, err
+	}
+
+	return pending.Detail, nil
+}
+
+// prepareRefund checks that the subscription can be refunded and saves its refund as pending. A pending refund
+// left by an earlier attempt is reused, so the gateway is asked for the same refund again.
+func (s *subscriptionService) prepareRefund(db *gorm.DB, subscriptionID uuid.UUID, req *validation.RefundSubscription) (*pendingRefund, error) {
+	pending := &pendingRefund{Detail: &model.TransactionDetail{}}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var subscription model.UserSubscription
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&subscription, "id = ?", subscriptionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fiber.NewError(fiber.StatusNotFound, "Subscription not found")
+			}
+			return err
+		}
+
+		if subscription.PaymentStatus != PaymentStatusSuccess || subscription.TransactionID == "" {
+			return fiber.NewError(fiber.StatusConflict, "Only paid subscriptions can be refunded")
+		}
+
+		var plan model.SubscriptionPlan
+		if err := tx.First(&plan, "id = ?", subscription.PlanID).Error; err != nil {
+			return err
+		}
+		// Upgrades were paid for less than the plan's price, the credit for the old plan isn't refunded
+		paid := plan.Price - subscription.CreditAmount
+
+		err := tx.Where("user_subscription_id = ? AND type = ? AND transaction_status = ?",
+			subscription.ID, model.TransactionTypeRefund, transactionStatusRefundPending).
+			First(pending.Detail).Error
+		switch {
+		case err == nil:
+			amount, convErr := strconv.Atoi(pending.Detail.GrossAmount)
+			if convErr != nil {
+				return fmt.Errorf("invalid amount of pending refund %s: %w", pending.Detail.ID, convErr)
+			}
+			pending.Amount = amount
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if !CanTransitionSubscription(subscription.Status, model.SubscriptionStatusRefunded) {
+				return fiber.NewError(fiber.StatusConflict, fmt.Sprintf("A %s subscription can't be refunded", subscription.Status))
+			}
+
+			now := time.Now()
+			pending.Amount = RefundAmount(req.Type, paid, subscription.StartDate, subscription.EndDate, now)
+			if pending.Amount <= 0 {
+				return fiber.NewError(fiber.StatusConflict, "Nothing is left to refund, the subscription's period is over")
+			}
+
+			*pending.Detail = model.TransactionDetail{
+				UserSubscriptionID: subscription.ID,
+				Type:               model.TransactionTypeRefund,
+				OrderID:            subscription.TransactionID,
+				TransactionID:      refundKey(subscription.TransactionID),
+				TransactionStatus:  transactionStatusRefundPending,
+				TransactionTime:    now,
+				StatusMessage:      req.Reason,
+				GrossAmount:        fmt.Sprintf("%d", pending.Amount),
+				Currency:           "IDR",
+			}
+			if err := tx.Create(pending.Detail).Error; err != nil {
+				s.Log.Errorf("Failed to save refund of subscription %s: %+v", subscription.ID, err)
+				return err
+			}
+		default:
+			return err
+		}
+
+		pending.Status = "refund"
+		if pending.Amount < paid {
+			pending.Status = "partial_refund"
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// completeRefund records a refund the gateway made and revokes the subscription's access. A refund completed
+// by a concurrent attempt is left as it is.
+func (s *subscriptionService) completeRefund(db *gorm.DB, subscriptionID uuid.UUID, pending *pendingRefund, refund *RefundResponse, actorID *uuid.UUID) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var subscription model.UserSubscription
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&subscription, "id = ?", subscriptionID).Error; err != nil {
+			return err
+		}
+
+		detail := pending.Detail
+		if err := tx.First(detail, "id = ?", detail.ID).Error; err != nil {
+			return err
+		}
+		if detail.TransactionStatus != transactionStatusRefundPending {
+			return nil
+		}
+
+		detail.TransactionStatus = pending.Status
+		if len(refund.Raw) > 0 {
+			detail.RawResponse = model.JSON(refund.Raw)
+		}
+		if err := tx.Model(detail).Select("transaction_status", "raw_response").Updates(detail).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&subscription).Update("payment_status", PaymentStatusRefunded).Error; err != nil {
+			return err
+		}
+
+		if !CanTransitionSubscription(subscription.Status, model.SubscriptionStatusRefunded) {
+			// The money is back with the user, the subscription ended some other way meanwhile
+			s.Log.Warnf("Refunded subscription %s is %s, its status is left as it is", subscription.ID, subscription.Status)
+			return nil
+		}
+		return TransitionSubscription(tx, &subscription, model.SubscriptionStatusRefunded, TransitionReasonRefunded, actorID)
+	})
+}
+
+// CancelSubscription cancels the user's subscription at the end of its period, it keeps access until then.
+// A subscription already in its grace period is over, so it is cancelled right away.
+func (s *subscriptionService) CancelSubscription(ctx *fiber.Ctx, userID uuid.UUID) (*model.UserSubscriptionResponse, error) {
+	db := s.DB.WithContext(ctx.Context())
+	subscription, err := s.findCurrentSubscription(db, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "No active subscription found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.Status == model.SubscriptionStatusGrace {
+		if err := TransitionSubscription(db, subscription, model.SubscriptionStatusCancelled, TransitionReasonUserCancelled, &userID); err != nil {
+			return nil, err
+		}
+		return s.toSubscriptionResponse(subscription)
+	}
+
+	if err := s.setCancelAtPeriodEnd(db, subscription, true); err != nil {
+		return nil, err
+	}
+	return s.toSubscriptionResponse(subscription)
+}
+
+// ResumeSubscription takes back the cancellation of a subscription that hasn't ended yet
+func (s *subscriptionService) ResumeSubscription(ctx *fiber.Ctx, userID uuid.UUID) (*model.UserSubscriptionResponse, error) {
+	db := s.DB.WithContext(ctx.Context())
+	subscription, err := s.findCurrentSubscription(db, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "No active subscription found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.Status != model.SubscriptionStatusActive {
+		return nil, fiber.NewError(fiber.StatusConflict, "Only active subscriptions can be resumed")
+	}
+
+	if err := s.setCancelAtPeriodEnd(db, subscription, false); err != nil {
+		return nil, err
+	}
+	return s.toSubscriptionResponse(subscription)
+}
+
+// setCancelAtPeriodEnd changes the cancellation of an active subscription, unless the sweeper moved it meanwhile
+func (s *subscriptionService) setCancelAtPeriodEnd(db *gorm.DB, subscription *model.UserSubscription, cancel bool) error {
+	result := db.Model(&model.UserSubscription{}).
+		Where("id = ? AND status = ?", subscription.ID, model.SubscriptionStatusActive).
+		Update("cancel_at_period_end", cancel)
+	if result.Error != nil {
+		s.Log.Errorf("Failed to update cancellation of subscription %s: %+v", subscription.ID, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fiber.NewError(fiber.StatusConflict, "Subscription was changed by another request")
+	}
+
+	subscription.CancelAtPeriodEnd = cancel
+	return nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"app/src/model"
 	"app/src/validation"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,11 +13,27 @@ import (
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrScanQuotaExceeded is returned by ReserveScan when the active plan has no scans left
+var ErrScanQuotaExceeded = errors.New("scan quota exceeded")
+
+// ErrNoActiveSubscription is returned by ReserveScan when the user has no subscription giving access
+var ErrNoActiveSubscription = errors.New("active subscription required")
+
+// ErrPaymentNotFound is returned by a PaymentGateway that has no transaction for the order
+var ErrPaymentNotFound = errors.New("payment not found")
+
+// ErrRefundDuplicate is returned by a PaymentGateway that already made a refund with the RefundKey
+var ErrRefundDuplicate = errors.New("refund already made")
+
+// ErrPlanChangePending is returned by PurchasePlan while another upgrade or downgrade of the user waits for payment
+var ErrPlanChangePending = errors.New("another plan change is waiting for payment")
+
 type PaymentGateway interface {
 	Charge(amount int, method string) (*PaymentResponse, error)
-	Refund(transactionID string) error
+	Refund(orderID string, refund *RefundRequest) (*RefundResponse, error)
 	CreateTransaction(orderID string, amount int, userDetails map[string]interface{}, paymentMethod string) (*PaymentToken, error)
 	CheckTransactionStatus(transactionID string) (interface{}, error)
 	HandleNotification(notificationJSON []byte) (interface{}, error)
@@ -27,15 +44,37 @@ type PaymentResponse struct {
 	Status        string
 }
 
+// RefundRequest is a refund of a settled order. Repeating a request with the same RefundKey doesn't refund twice.
+type RefundRequest struct {
+	RefundKey string
+	Amount    int
+	Reason    string
+}
+
+// RefundResponse is the gateway's answer to a refund, Raw is its response as JSON
+type RefundResponse struct {
+	TransactionID string
+	RefundKey     string
+	Raw           []byte
+}
+
 type SubscriptionService interface {
 	GetAllPlans(ctx *fiber.Ctx) ([]model.SubscriptionPlanResponse, error)
 	PurchasePlan(ctx *fiber.Ctx, userID uuid.UUID, planID uuid.UUID, paymentMethod string) (*model.PaymentResponse, error)
 	GetUserActiveSubscription(ctx *fiber.Ctx, userID uuid.UUID) (*model.UserSubscriptionResponse, error)
+	CancelSubscription(ctx *fiber.Ctx, userID uuid.UUID) (*model.UserSubscriptionResponse, error)
+	ResumeSubscription(ctx *fiber.Ctx, userID uuid.UUID) (*model.UserSubscriptionResponse, error)
 	CheckFeatureAccess(ctx *fiber.Ctx, userID uuid.UUID, feature string) (bool, error)
 	IncrementScanUsage(ctx *fiber.Ctx, userID uuid.UUID) error
 	GetRemainingScans(ctx *fiber.Ctx, userID uuid.UUID) (int, error)
+	ReserveScan(ctx *fiber.Ctx, userID uuid.UUID) (*model.ScanQuota, error)
+	RefundScan(ctx *fiber.Ctx, subscriptionID uuid.UUID) error
 	HandlePaymentNotification(ctx *fiber.Ctx, notificationData []byte) error
 	CreateFreemiumSubscription(ctx *fiber.Ctx, userID uuid.UUID) error
+	SweepSubscriptions(ctx context.Context, now time.Time) (*model.SubscriptionSweepReport, error)
+	StartLifecycleSweeper(ctx context.Context, interval time.Duration)
+	ReconcilePayments(ctx context.Context, now time.Time) (*model.PaymentReconciliation, error)
+	StartPaymentReconciler(ctx context.Context, interval time.Duration)
 
 	// Admin-related methods
 	GetAllUserSubscriptions(ctx *fiber.Ctx, query *validation.SubscriptionQuery) ([]model.UserSubscriptionResponse, int64, error)
@@ -44,8 +83,11 @@ type SubscriptionService interface {
 	UpdateUserSubscription(ctx *fiber.Ctx, subscriptionID uuid.UUID, req *validation.UpdateSubscription) (*model.UserSubscriptionResponse, error)
 	DeleteUserSubscription(ctx *fiber.Ctx, subscriptionID uuid.UUID) error
 	GetTransactionsBySubscriptionID(ctx *fiber.Ctx, subscriptionID uuid.UUID) ([]model.TransactionDetail, error)
+	GetSubscriptionTransitions(ctx *fiber.Ctx, subscriptionID uuid.UUID) ([]model.SubscriptionTransition, error)
 	UpdatePaymentStatus(ctx *fiber.Ctx, subscriptionID uuid.UUID, status string) (*model.UserSubscriptionResponse, error)
 	GetAllTransactions(ctx *fiber.Ctx, page, limit int) ([]model.TransactionDetail, int64, error)
+	RefundSubscription(ctx *fiber.Ctx, subscriptionID uuid.UUID, req *validation.RefundSubscription) (*model.TransactionDetail, error)
+	GetPaymentReconciliations(ctx *fiber.Ctx, page, limit int) ([]model.PaymentReconciliation, int64, error)
 	GetTransactionByID(ctx *fiber.Ctx, transactionID uuid.UUID) (*model.TransactionDetail, error)
 	GetSubscriptionPlanByID(ctx *fiber.Ctx, planID uuid.UUID) (*model.SubscriptionPlan, error)
 	UpdateSubscriptionPlan(ctx *fiber.Ctx, planID uuid.UUID, req *validation.UpdateSubscriptionPlan) (*model.SubscriptionPlan, error)
@@ -116,21 +158,50 @@ func (s *subscriptionService) PurchasePlan(ctx *fiber.Ctx, userID uuid.UUID, pla
 	// Generate a unique order ID
 	orderID := fmt.Sprintf("SUB-%s-%d", userID.String()[:8], time.Now().Unix())
 
-	// Create a new subscription with pending status
-	subscription := model.UserSubscription{
-		UserID:        userID,
-		PlanID:        planID,
-		StartDate:     time.Now(),
-		EndDate:       time.Now().AddDate(0, 0, plan.ValidityDays),
-		PaymentMethod: paymentMethod,
-		TransactionID: orderID,
-		PaymentStatus: "pending",
-		IsActive:      false, // Will be activated after payment is completed
-	}
+	var change *planChange
+	var subscription model.UserSubscription
+	err := s.DB.WithContext(ctx.Context()).Transaction(func(tx *gorm.DB) error {
+		// The user is locked so two checkouts can't both start a plan change from the same subscription
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&model.User{}, "id = ?", userID).Error; err != nil {
+			return err
+		}
 
-	// Save subscription to database
-	if err := s.DB.WithContext(ctx.Context()).Create(&subscription).Error; err != nil {
-		return nil, fmt.Errorf("failed to create subscription: %w", err)
+		// An active paid plan makes the purchase an upgrade, downgrade or renewal of it
+		var err error
+		change, err = planChangeFor(tx, userID, &plan, time.Now())
+		if err != nil {
+			s.Log.Errorf("Failed to check current subscription: %+v", err)
+			return fmt.Errorf("failed to check current subscription: %w", err)
+		}
+		if err := checkNoPendingPlanChange(tx, userID, change); err != nil {
+			return err
+		}
+
+		// Create a new subscription with pending status, its period starts when the payment settles
+		subscription = model.UserSubscription{
+			UserID:        userID,
+			PlanID:        planID,
+			StartDate:     time.Now(),
+			EndDate:       time.Now().AddDate(0, 0, plan.ValidityDays),
+			PaymentMethod: paymentMethod,
+			TransactionID: orderID,
+			PaymentStatus: "pending",
+			Status:        model.SubscriptionStatusPending, // Will be activated after payment is completed
+			ChangeType:    change.Type,
+			CreditAmount:  change.CreditAmount,
+		}
+		if change.Replaces != nil {
+			subscription.ReplacesID = &change.Replaces.ID
+		}
+
+		// Save subscription to database
+		if err := createSubscription(tx, &subscription, TransitionReasonCreated, &userID); err != nil {
+			return fmt.Errorf("failed to create subscription: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Prepare user details for Midtrans
@@ -142,10 +213,12 @@ func (s *subscriptionService) PurchasePlan(ctx *fiber.Ctx, userID uuid.UUID, pla
 	}
 
 	// Create transaction in Midtrans
-	paymentToken, err := s.Payment.CreateTransaction(orderID, plan.Price, userDetails, paymentMethod)
+	paymentToken, err := s.Payment.CreateTransaction(orderID, change.Amount, userDetails, paymentMethod)
 	if err != nil {
-		// Rollback subscription creation if payment fails
-		s.DB.WithContext(ctx.Context()).Delete(&subscription)
+		// Cancel the subscription if payment fails
+		if cancelErr := TransitionSubscription(s.DB.WithContext(ctx.Context()), &subscription, model.SubscriptionStatusCancelled, TransitionReasonPaymentFailed, nil); cancelErr != nil {
+			s.Log.Errorf("Failed to cancel subscription %s: %+v", subscription.ID, cancelErr)
+		}
 		return nil, fmt.Errorf("payment creation failed: %w", err)
 	}
 
@@ -154,6 +227,9 @@ func (s *subscriptionService) PurchasePlan(ctx *fiber.Ctx, userID uuid.UUID, pla
 		TransactionToken: paymentToken.Token,
 		RedirectURL:      paymentToken.RedirectURL,
 		OrderID:          orderID,
+		ChangeType:       change.Type,
+		Amount:           change.Amount,
+		CreditAmount:     change.CreditAmount,
 	}, nil
 }
 
@@ -213,44 +289,22 @@ func (s *subscriptionService) HandlePaymentNotification(ctx *fiber.Ctx, notifica
 	s.Log.Infof("Found subscription: ID=%s, UserID=%s, Status=%s",
 		subscription.ID, subscription.UserID, subscription.PaymentStatus)
 
-	// Process based on transaction status
-	switch transactionStatusStr {
-	case "capture", "settlement":
-		// Payment success
-		s.Log.Infof("Updating subscription %s to success status", subscription.ID)
-		subscription.PaymentStatus = "success"
-		subscription.IsActive = true
-	case "deny", "cancel", "expire":
-		// Payment failed
-		s.Log.Infof("Updating subscription %s to failed status", subscription.ID)
-		subscription.PaymentStatus = "failed"
-		subscription.IsActive = false
-	case "pending":
-		// Payment pending, no changes needed
-		s.Log.Infof("Subscription %s remains in pending status", subscription.ID)
-	default:
-		s.Log.Warnf("Unhandled transaction status for subscription %s: %s",
-			subscription.ID, transactionStatusStr)
-	}
-
-	// Update the subscription
-	if err := s.DB.WithContext(ctx.Context()).Save(&subscription).Error; err != nil {
-		s.Log.Errorf("Failed to update subscription %s: %v", subscription.ID, err)
-		return fmt.Errorf("failed to update subscription: %w", err)
-	}
-
-	// Save detailed transaction information
-	transactionDetail := s.createTransactionDetailFromNotification(subscription.ID, notification, notificationData)
-	if err := s.DB.WithContext(ctx.Context()).Create(&transactionDetail).Error; err != nil {
-		s.Log.Errorf("Failed to save transaction details: %v", err)
-		// Continue even if saving details fails
-	} else {
-		s.Log.Infof("Saved transaction details with ID: %s", transactionDetail.ID)
+	// Unknown statuses and ones that would move the payment backwards may be late or out of order,
+	// Midtrans' own record of the transaction decides what the payment status is
+	paymentStatus, known := PaymentStatusForMidtrans(transactionStatusStr, getString(notification, "fraud_status", ""))
+	if !known || (paymentStatus != subscription.PaymentStatus && !CanAdvancePaymentStatus(subscription.PaymentStatus, paymentStatus)) {
+		s.Log.Infof("Reconciling %s notification for order ID %s with Midtrans", transactionStatusStr, orderID)
+		reconciled, err := s.reconcilePaymentStatus(orderID)
+		if err != nil {
+			s.Log.Warnf("Could not reconcile order ID %s, keeping status %s: %v", orderID, subscription.PaymentStatus, err)
+			reconciled = subscription.PaymentStatus
+		}
+		paymentStatus = reconciled
 	}
 
-	s.Log.Infof("Successfully updated subscription %s to status: %s",
-		subscription.ID, subscription.PaymentStatus)
-	return nil
+	transactionDetail := s.createTransactionDetailFromNotification(subscription.ID, notification, notificationData)
+	_, err = s.applyPaymentStatus(s.DB.WithContext(ctx.Context()), &subscription, paymentStatus, transactionDetail)
+	return err
 }
 
 // createTransactionDetailFromNotification creates a TransactionDetail object from the notification data
@@ -281,6 +335,10 @@ func (s *subscriptionService) createTransactionDetailFromNotification(
 	detail.StatusMessage = getString(notification, "status_message", "")
 	detail.PaymentType = getString(notification, "payment_type", "")
 	detail.GrossAmount = getString(notification, "gross_amount", "")
+	detail.Type = model.TransactionTypePayment
+	if status, _ := PaymentStatusForMidtrans(detail.TransactionStatus, ""); status == PaymentStatusRefunded {
+		detail.Type = model.TransactionTypeRefund
+	}
 	detail.Currency = getString(notification, "currency", "")
 	detail.FraudStatus = getString(notification, "fraud_status", "")
 
@@ -352,12 +410,7 @@ func getString(data map[string]interface{}, key string, defaultValue string) str
 }
 
 func (s *subscriptionService) GetUserActiveSubscription(ctx *fiber.Ctx, userID uuid.UUID) (*model.UserSubscriptionResponse, error) {
-	var subscription model.UserSubscription
-	err := s.DB.WithContext(ctx.Context()).
-		Preload("Plan").
-		Where("user_subscriptions.user_id = ? AND user_subscriptions.end_date > ? AND user_subscriptions.is_active = ? AND user_subscriptions.payment_status IN (?)", userID, time.Now(), true, []string{"success", "completed"}).
-		First(&subscription).Error
-
+	subscription, err := s.findCurrentSubscription(s.DB.WithContext(ctx.Context()), userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			// User has no active subscription - this is normal for new users
@@ -366,7 +419,34 @@ func (s *subscriptionService) GetUserActiveSubscription(ctx *fiber.Ctx, userID u
 		return nil, err
 	}
 
-	return s.toSubscriptionResponse(&subscription)
+	response, err := s.toSubscriptionResponse(subscription)
+	if err != nil {
+		return nil, err
+	}
+	if response.Upcoming, err = upcomingSubscriptions(s.DB.WithContext(ctx.Context()), userID); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// findCurrentSubscription returns the subscription giving the user access. The dates are checked too, so
+// access stops on time even when the lifecycle sweeper hasn't run yet. Should a trial and a paid plan
+// overlap, the paid plan wins, then the one ending last.
+func (s *subscriptionService) findCurrentSubscription(db *gorm.DB, userID uuid.UUID) (*model.UserSubscription, error) {
+	var subscription model.UserSubscription
+	now := time.Now()
+	err := db.Preload("Plan").
+		Where("user_subscriptions.user_id = ?", userID).
+		Where("(user_subscriptions.status = ? AND user_subscriptions.end_date > ?) OR (user_subscriptions.status = ? AND user_subscriptions.end_date > ?)",
+			model.SubscriptionStatusActive, now, model.SubscriptionStatusGrace, now.Add(-subscriptionGracePeriod())).
+		Order(fmt.Sprintf("user_subscriptions.payment_method = '%s', user_subscriptions.end_date DESC", freemiumPaymentMethod)).
+		First(&subscription).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
 }
 
 func (s *subscriptionService) toSubscriptionResponse(sub *model.UserSubscription) (*model.UserSubscriptionResponse, error) {
@@ -394,13 +474,17 @@ func (s *subscriptionService) toSubscriptionResponse(sub *model.UserSubscription
 			ValidityDays:   sub.Plan.ValidityDays,
 			AIscanLimit:    sub.Plan.AIscanLimit,
 		},
-		AIscansUsed:   sub.AIscansUsed,
-		StartDate:     sub.StartDate,
-		EndDate:       sub.EndDate,
-		IsActive:      sub.IsActive,
-		PaymentMethod: sub.PaymentMethod,
-		PaymentStatus: sub.PaymentStatus,
-		CreatedAt:     sub.CreatedAt,
+		AIscansUsed:       sub.AIscansUsed,
+		StartDate:         sub.StartDate,
+		EndDate:           sub.EndDate,
+		IsActive:          sub.IsActive,
+		PaymentMethod:     sub.PaymentMethod,
+		PaymentStatus:     sub.PaymentStatus,
+		Status:            sub.Status,
+		CancelAtPeriodEnd: sub.CancelAtPeriodEnd,
+		ChangeType:        sub.ChangeType,
+		CreditAmount:      sub.CreditAmount,
+		CreatedAt:         sub.CreatedAt,
 	}, nil
 }
 
@@ -416,9 +500,17 @@ func (s *subscriptionService) CheckFeatureAccess(ctx *fiber.Ctx, userID uuid.UUI
 }
 
 func (s *subscriptionService) IncrementScanUsage(ctx *fiber.Ctx, userID uuid.UUID) error {
+	sub, err := s.GetUserActiveSubscription(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if sub == nil {
+		return fiber.NewError(fiber.StatusForbidden, "Active subscription required")
+	}
+
 	return s.DB.WithContext(ctx.Context()).
 		Model(&model.UserSubscription{}).
-		Where("user_id = ? AND is_active = ?", userID, true).
+		Where("id = ? AND status IN ?", sub.ID, accessStatuses).
 		Update("ai_scans_used", gorm.Expr("ai_scans_used + 1")).
 		Error
 }
@@ -431,6 +523,9 @@ func (s *subscriptionService) GetRemainingScans(ctx *fiber.Ctx, userID uuid.UUID
 	if sub == nil {
 		return 0, nil
 	}
+	if sub.Plan.AIscanLimit == model.UnlimitedScans {
+		return model.UnlimitedScans, nil
+	}
 	remaining := sub.Plan.AIscanLimit - sub.AIscansUsed
 	if remaining < 0 {
 		return 0, nil
@@ -438,6 +533,63 @@ func (s *subscriptionService) GetRemainingScans(ctx *fiber.Ctx, userID uuid.UUID
 	return remaining, nil
 }
 
+// ReserveScan consumes one scan from the active subscription. The check and the increment happen in a
+// single UPDATE so concurrent scans can't push usage over the plan limit.
+func (s *subscriptionService) ReserveScan(ctx *fiber.Ctx, userID uuid.UUID) (*model.ScanQuota, error) {
+	sub, err := s.GetUserActiveSubscription(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if sub == nil {
+		return nil, ErrNoActiveSubscription
+	}
+
+	quota := &model.ScanQuota{
+		SubscriptionID: sub.ID,
+		Limit:          sub.Plan.AIscanLimit,
+		Used:           sub.AIscansUsed,
+		ResetAt:        sub.EndDate,
+	}
+
+	var updated model.UserSubscription
+	db := s.DB.WithContext(ctx.Context()).
+		Model(&updated).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "ai_scans_used"}}}).
+		Where("id = ?", sub.ID)
+	if quota.Limit != model.UnlimitedScans {
+		db = db.Where("ai_scans_used < ?", quota.Limit)
+	}
+
+	result := db.Update("ai_scans_used", gorm.Expr("ai_scans_used + 1"))
+	if result.Error != nil {
+		s.Log.Errorf("Failed to reserve scan: %+v", result.Error)
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		quota.Remaining = 0
+		return quota, ErrScanQuotaExceeded
+	}
+
+	quota.Used = updated.AIscansUsed
+	quota.Remaining = quota.RemainingScans()
+
+	return quota, nil
+}
+
+// RefundScan gives back a scan reserved by ReserveScan when the scan itself failed
+func (s *subscriptionService) RefundScan(ctx *fiber.Ctx, subscriptionID uuid.UUID) error {
+	if err := s.DB.WithContext(ctx.Context()).
+		Model(&model.UserSubscription{}).
+		Where("id = ? AND ai_scans_used > 0", subscriptionID).
+		Update("ai_scans_used", gorm.Expr("ai_scans_used - 1")).Error; err != nil {
+		s.Log.Errorf("Failed to refund scan: %+v", err)
+		return err
+	}
+
+	return nil
+}
+
 // GetAllUserSubscriptions retrieves all user subscriptions with pagination and filtering
 func (s *subscriptionService) GetAllUserSubscriptions(ctx *fiber.Ctx, query *validation.SubscriptionQuery) ([]model.UserSubscriptionResponse, int64, error) {
 	var subscriptions []model.UserSubscription
@@ -530,7 +682,7 @@ func (s *subscriptionService) GetAllSubscriptionPlansWithUsers(ctx *fiber.Ctx, w
 		var userCount int64
 		if err := s.DB.WithContext(ctx.Context()).
 			Model(&model.UserSubscription{}).
-			Where("plan_id = ? AND is_active = ?", plan.ID, true).
+			Where("plan_id = ? AND status IN ?", plan.ID, accessStatuses).
 			Count(&userCount).Error; err != nil {
 			return nil, err
 		}
@@ -542,7 +694,7 @@ func (s *subscriptionService) GetAllSubscriptionPlansWithUsers(ctx *fiber.Ctx, w
 			var subscriptions []model.UserSubscription
 			if err := s.DB.WithContext(ctx.Context()).
 				Preload("User").
-				Where("plan_id = ? AND is_active = ?", plan.ID, true).
+				Where("plan_id = ? AND status IN ?", plan.ID, accessStatuses).
 				Find(&subscriptions).Error; err != nil {
 				return nil, err
 			}
@@ -586,10 +738,6 @@ func (s *subscriptionService) UpdateUserSubscription(ctx *fiber.Ctx, subscriptio
 		subscription.PlanID = *req.PlanID
 	}
 
-	if req.IsActive != nil {
-		subscription.IsActive = *req.IsActive
-	}
-
 	if req.AIscansUsed != nil {
 		subscription.AIscansUsed = *req.AIscansUsed
 	}
@@ -606,11 +754,24 @@ func (s *subscriptionService) UpdateUserSubscription(ctx *fiber.Ctx, subscriptio
 		subscription.PaymentMethod = *req.PaymentMethod
 	}
 
-	// Save changes
-	if err := s.DB.WithContext(ctx.Context()).Save(&subscription).Error; err != nil {
+	// Save changes, the status is left to the lifecycle
+	if err := s.DB.WithContext(ctx.Context()).Omit("status", "is_active").Save(&subscription).Error; err != nil {
 		return nil, err
 	}
 
+	// Activating or deactivating goes through the lifecycle like any other status change
+	if req.IsActive != nil {
+		to := model.SubscriptionStatusCancelled
+		if *req.IsActive {
+			to = model.SubscriptionStatusActive
+		}
+		if *req.IsActive != SubscriptionGivesAccess(subscription.Status) {
+			if err := TransitionSubscription(s.DB.WithContext(ctx.Context()), &subscription, to, TransitionReasonAdmin, actorFromContext(ctx)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Refresh subscription data
 	if err := s.DB.WithContext(ctx.Context()).
 		Preload("Plan").
@@ -673,17 +834,25 @@ func (s *subscriptionService) UpdatePaymentStatus(ctx *fiber.Ctx, subscriptionID
 
 	// Update payment status
 	subscription.PaymentStatus = status
-
-	// If status is success, activate the subscription
-	if status == "success" {
-		subscription.IsActive = true
-	} else if status == "failed" {
-		subscription.IsActive = false
+	if err := s.DB.WithContext(ctx.Context()).
+		Model(&subscription).
+		Update("payment_status", status).Error; err != nil {
+		return nil, err
 	}
 
-	// Save changes
-	if err := s.DB.WithContext(ctx.Context()).Save(&subscription).Error; err != nil {
-		return nil, err
+	// If status is success, activate the subscription, a purchase still pending applies its plan change
+	if status == "success" && subscription.Status == model.SubscriptionStatusPending {
+		if err := s.activatePurchase(s.DB.WithContext(ctx.Context()), &subscription, actorFromContext(ctx)); err != nil {
+			return nil, err
+		}
+	} else if status == "success" {
+		if err := TransitionSubscription(s.DB.WithContext(ctx.Context()), &subscription, model.SubscriptionStatusActive, TransitionReasonAdmin, actorFromContext(ctx)); err != nil {
+			return nil, err
+		}
+	} else if status == "failed" {
+		if err := TransitionSubscription(s.DB.WithContext(ctx.Context()), &subscription, model.SubscriptionStatusCancelled, TransitionReasonAdmin, actorFromContext(ctx)); err != nil {
+			return nil, err
+		}
 	}
 
 	// Create transaction record
@@ -696,7 +865,7 @@ func (s *subscriptionService) UpdatePaymentStatus(ctx *fiber.Ctx, subscriptionID
 		Currency:           "IDR",
 	}
 
-	if err := s.DB.WithContext(ctx.Context()).Create(&transactionDetail).Error; err != nil {
+	if err := s.DB.WithContext(ctx.Context()).Clauses(clause.OnConflict{DoNothing: true}).Create(&transactionDetail).Error; err != nil {
 		s.Log.Warnf("Failed to create transaction record: %v", err)
 		// Continue even if transaction record creation fails
 	}
@@ -854,13 +1023,13 @@ func (s *subscriptionService) CreateFreemiumSubscription(ctx *fiber.Ctx, userID
 		PlanID:        freemiumPlan.ID,
 		StartDate:     now,
 		EndDate:       endDate,
-		IsActive:      true,
-		PaymentMethod: "freemium_trial",
+		PaymentMethod: freemiumPaymentMethod,
 		PaymentStatus: "completed",
+		Status:        model.SubscriptionStatusActive,
 		AIscansUsed:   0,
 	}
 
-	if err := s.DB.WithContext(ctx.Context()).Create(freemiumSubscription).Error; err != nil {
+	if err := createSubscription(s.DB.WithContext(ctx.Context()), freemiumSubscription, TransitionReasonCreated, nil); err != nil {
 		s.Log.Errorf("Failed to create freemium subscription for user %s: %v", userID.String(), err)
 		return err
 	}
@@ -868,3 +1037,11 @@ func (s *subscriptionService) CreateFreemiumSubscription(ctx *fiber.Ctx, userID
 	s.Log.Infof("Successfully created freemium subscription for user %s, expires at %s", userID.String(), endDate.Format(time.RFC3339))
 	return nil
 }
+
+// actorFromContext returns the ID of the authenticated user making the request, if any
+func actorFromContext(ctx *fiber.Ctx) *uuid.UUID {
+	if user, ok := ctx.Locals("user").(*model.User); ok && user != nil {
+		return &user.ID
+	}
+	return nil
+}
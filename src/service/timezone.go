@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"app/src/config"
+	"app/src/model"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TimezoneHeader lets a client count days in another timezone than the one saved on the profile,
+// e.g. while the user is travelling
+const TimezoneHeader = "X-Timezone"
+
+// RequestLocation returns the timezone days are counted in for this request: the header when sent,
+// then the user's timezone, then the default one
+func RequestLocation(c *fiber.Ctx, user *model.User) (*time.Location, error) {
+	if name := c.Get(TimezoneHeader); name != "" {
+		location, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid timezone in "+TimezoneHeader+" header")
+		}
+		return location, nil
+	}
+
+	if user != nil && user.Timezone != "" {
+		if location, err := time.LoadLocation(user.Timezone); err == nil {
+			return location, nil
+		}
+	}
+
+	return DefaultLocation(), nil
+}
+
+// DefaultLocation is the configured default timezone, or UTC when it can't be loaded
+func DefaultLocation() *time.Location {
+	location, err := time.LoadLocation(config.DefaultTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// userLocation resolves the request timezone of userID, reusing the authenticated user when it's the same one
+func userLocation(c *fiber.Ctx, db *gorm.DB, userID uuid.UUID) (*time.Location, error) {
+	if user, ok := c.Locals("user").(*model.User); ok && user != nil && user.ID == userID {
+		return RequestLocation(c, user)
+	}
+
+	user := new(model.User)
+	if err := db.WithContext(c.Context()).Select("id", "timezone").First(user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+		}
+		return nil, err
+	}
+	return RequestLocation(c, user)
+}
+
+// startOfDay returns midnight of t's day in location
+func startOfDay(t time.Time, location *time.Location) time.Time {
+	t = t.In(location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+}
@@ -63,7 +63,7 @@ func (s *tokenService) GenerateToken(c *fiber.Ctx, user *model.User, isProductTo
 		if err == nil && subscription != nil {
 			// User has an active subscription
 			subscriptionFeatures = subscription.Plan.Features
-			subscriptionStatus = "active"
+			subscriptionStatus = subscription.Status
 			subscriptionStartDate = &subscription.StartDate
 			subscriptionEndDate = &subscription.EndDate
 
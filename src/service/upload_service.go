@@ -0,0 +1,290 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"strings"
+	"time"
+
+	"app/src/model"
+	"app/src/validation"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type UploadService interface {
+	UploadImage(c *fiber.Ctx, userID uuid.UUID, req *validation.UploadImage, file *multipart.FileHeader) (*model.Upload, error)
+	UploadProfilePicture(c *fiber.Ctx, userID uuid.UUID, file *multipart.FileHeader) (*model.Upload, error)
+	CollectOrphans(ctx context.Context) (*model.UploadGCReport, error)
+	StartOrphanCollector(ctx context.Context, interval time.Duration)
+}
+
+type uploadService struct {
+	Log      *logrus.Logger
+	DB       *gorm.DB
+	Validate *validator.Validate
+	Store    BlobStore
+	// MaxSize is the largest accepted file in bytes
+	MaxSize int64
+	// Grace is how long a new upload may stay unused before it's collected
+	Grace time.Duration
+}
+
+func NewUploadService(db *gorm.DB, validate *validator.Validate, store BlobStore, maxSize int64, grace time.Duration) UploadService {
+	return &uploadService{
+		Log:      logrus.New(),
+		DB:       db,
+		Validate: validate,
+		Store:    store,
+		MaxSize:  maxSize,
+		Grace:    grace,
+	}
+}
+
+// uploadFolders are the blob store folders of each purpose
+var uploadFolders = map[string]string{
+	model.UploadPurposeArticle: "articles",
+	model.UploadPurposeRecipe:  "recipes",
+	model.UploadPurposeProfile: "profiles",
+}
+
+// uploadReferences match the uploads a stored image URL points to, an upload is kept while any matches
+var uploadReferences = []string{
+	"SELECT 1 FROM articles WHERE articles.image LIKE '%' || uploads.prefix || '/%'",
+	"SELECT 1 FROM article_revisions WHERE article_revisions.image LIKE '%' || uploads.prefix || '/%'",
+	"SELECT 1 FROM recipes WHERE recipes.image LIKE '%' || uploads.prefix || '/%'",
+	// Meal plans copy the recipe image into the logged meal
+	"SELECT 1 FROM meal_histories WHERE meal_histories.meal_image LIKE '%' || uploads.prefix || '/%'",
+	"SELECT 1 FROM users WHERE users.profile_picture LIKE '%' || uploads.prefix || '/%'",
+}
+
+// orphanBatchSize bounds the uploads removed by one collection
+const orphanBatchSize = 500
+
+// UploadImage stores an image for an article or a recipe. The client links it by putting the URL of one
+// of its variants in the image of the article or recipe, unused uploads are collected after the grace period.
+func (s *uploadService) UploadImage(c *fiber.Ctx, userID uuid.UUID, req *validation.UploadImage, file *multipart.FileHeader) (*model.Upload, error) {
+	if err := s.Validate.Struct(req); err != nil {
+		return nil, err
+	}
+	return s.upload(c, userID, req.Purpose, file)
+}
+
+// UploadProfilePicture stores the image and makes it the user's profile picture, the previous one is
+// collected once nothing links to it anymore
+func (s *uploadService) UploadProfilePicture(c *fiber.Ctx, userID uuid.UUID, file *multipart.FileHeader) (*model.Upload, error) {
+	upload, err := s.upload(c, userID, model.UploadPurposeProfile, file)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.DB.WithContext(c.Context()).
+		Model(&model.User{}).
+		Where("id = ?", userID).
+		Update("profile_picture", upload.Variants[0].URL).Error; err != nil {
+		s.Log.Errorf("Failed to update profile picture: %+v", err)
+		return nil, err
+	}
+	return upload, nil
+}
+
+func (s *uploadService) upload(c *fiber.Ctx, userID uuid.UUID, purpose string, file *multipart.FileHeader) (*model.Upload, error) {
+	tooLarge := fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("The image can't be larger than %d MB", s.MaxSize/1024/1024))
+	if file.Size > s.MaxSize {
+		return nil, tooLarge
+	}
+
+	opened, err := file.Open()
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "The image can't be read")
+	}
+	defer opened.Close()
+
+	// The header size can't be trusted, read one byte more than allowed to tell
+	data, err := io.ReadAll(io.LimitReader(opened, s.MaxSize+1))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "The image can't be read")
+	}
+	if int64(len(data)) > s.MaxSize {
+		return nil, tooLarge
+	}
+
+	processed, err := ProcessImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &model.Upload{
+		ID:       uuid.New(),
+		UserID:   userID,
+		Purpose:  purpose,
+		Filename: file.Filename,
+		Width:    processed.Width,
+		Height:   processed.Height,
+	}
+	upload.Prefix = uploadFolders[purpose] + "/" + upload.ID.String()
+
+	ctx := c.Context()
+	for _, variant := range processed.Variants {
+		key := upload.Prefix + "/" + variant.Filename
+		if err := s.Store.Put(ctx, key, bytes.NewReader(variant.Data), int64(len(variant.Data)), variant.ContentType); err != nil {
+			s.Log.Errorf("Failed to store upload: %+v", err)
+			s.deleteBlobs(ctx, upload.Variants)
+			return nil, err
+		}
+
+		upload.Variants = append(upload.Variants, model.UploadVariant{
+			Name:        variant.Name,
+			Key:         key,
+			URL:         s.Store.URL(key),
+			ContentType: variant.ContentType,
+			Width:       variant.Width,
+			Height:      variant.Height,
+			Size:        int64(len(variant.Data)),
+		})
+		if variant.Name == ImageVariantOriginal {
+			upload.Size = int64(len(variant.Data))
+		}
+	}
+
+	if err := s.DB.WithContext(ctx).Create(upload).Error; err != nil {
+		s.Log.Errorf("Failed to save upload: %+v", err)
+		s.deleteBlobs(ctx, upload.Variants)
+		return nil, err
+	}
+	return upload, nil
+}
+
+// CollectOrphans removes the uploads older than the grace period that nothing links to, then the files
+// left in the upload folders without an upload, e.g. by a crash between storing and saving one
+func (s *uploadService) CollectOrphans(ctx context.Context) (*model.UploadGCReport, error) {
+	report := &model.UploadGCReport{}
+	cutoff := time.Now().Add(-s.Grace)
+
+	db := s.DB.WithContext(ctx).Preload("Variants").Where("uploads.created_at < ?", cutoff)
+	for _, reference := range uploadReferences {
+		db = db.Where("NOT EXISTS (" + reference + ")")
+	}
+
+	var orphans []model.Upload
+	if err := db.Order("uploads.created_at").Limit(orphanBatchSize).Find(&orphans).Error; err != nil {
+		s.Log.Errorf("Failed to find orphaned uploads: %+v", err)
+		return nil, err
+	}
+
+	for _, orphan := range orphans {
+		if err := s.deleteBlobs(ctx, orphan.Variants); err != nil {
+			// Kept so the next collection tries again
+			continue
+		}
+		if err := s.DB.WithContext(ctx).Delete(&orphan).Error; err != nil {
+			s.Log.Errorf("Failed to delete orphaned upload: %+v", err)
+			return nil, err
+		}
+		report.Uploads++
+		report.Files += len(orphan.Variants)
+	}
+
+	for _, folder := range uploadFolders {
+		files, err := s.collectStrayFiles(ctx, folder+"/", cutoff)
+		if err != nil {
+			return nil, err
+		}
+		report.Files += files
+	}
+	return report, nil
+}
+
+// collectStrayFiles deletes the files of the folder older than cutoff whose upload doesn't exist
+func (s *uploadService) collectStrayFiles(ctx context.Context, folder string, cutoff time.Time) (int, error) {
+	objects, err := s.Store.List(ctx, folder)
+	if err != nil {
+		s.Log.Errorf("Failed to list uploaded files: %+v", err)
+		return 0, err
+	}
+
+	byPrefix := make(map[string][]BlobObject)
+	for _, object := range objects {
+		// Keys are <folder>/<upload ID>/<variant>
+		parts := strings.SplitN(object.Key, "/", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		prefix := parts[0] + "/" + parts[1]
+		byPrefix[prefix] = append(byPrefix[prefix], object)
+	}
+	if len(byPrefix) == 0 {
+		return 0, nil
+	}
+
+	prefixes := make([]string, 0, len(byPrefix))
+	for prefix := range byPrefix {
+		prefixes = append(prefixes, prefix)
+	}
+	var known []string
+	if err := s.DB.WithContext(ctx).Model(&model.Upload{}).Where("prefix IN ?", prefixes).Pluck("prefix", &known).Error; err != nil {
+		s.Log.Errorf("Failed to find uploads of files: %+v", err)
+		return 0, err
+	}
+	for _, prefix := range known {
+		delete(byPrefix, prefix)
+	}
+
+	deleted := 0
+	for _, strays := range byPrefix {
+		for _, object := range strays {
+			if object.ModifiedAt.After(cutoff) {
+				continue
+			}
+			if err := s.Store.Delete(ctx, object.Key); err != nil {
+				s.Log.Errorf("Failed to delete stray file %s: %+v", object.Key, err)
+				continue
+			}
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// StartOrphanCollector collects orphaned uploads now and then every interval until ctx is done
+func (s *uploadService) StartOrphanCollector(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if report, err := s.CollectOrphans(ctx); err == nil && (report.Uploads > 0 || report.Files > 0) {
+				s.Log.Infof("Collected %d orphaned uploads and %d files", report.Uploads, report.Files)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// deleteBlobs removes the files of the variants, it goes on after a failure and returns the last error
+func (s *uploadService) deleteBlobs(ctx context.Context, variants []model.UploadVariant) error {
+	var lastErr error
+	for _, variant := range variants {
+		if err := s.Store.Delete(ctx, variant.Key); err != nil {
+			s.Log.Errorf("Failed to delete uploaded file %s: %+v", variant.Key, err)
+			lastErr = err
+		}
+	}
+	return lastErr
+}
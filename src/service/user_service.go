@@ -157,7 +157,7 @@ func (s *userService) UpdateUser(c *fiber.Ctx, req *validation.UpdateUser, id st
 
 	if req.Email == "" && req.Name == "" && req.Password == "" && req.ProfilePicture == nil &&
 		req.BirthDate == nil && req.Height == nil && req.Weight == nil &&
-		req.Gender == nil && req.ActivityLevel == nil && req.MedicalHistory == nil {
+		req.Gender == nil && req.ActivityLevel == nil && req.MedicalHistory == nil && req.Timezone == nil {
 		return nil, fiber.NewError(fiber.StatusBadRequest, "No fields to update")
 	}
 
@@ -209,6 +209,9 @@ func (s *userService) UpdateUser(c *fiber.Ctx, req *validation.UpdateUser, id st
 	if req.ProfilePicture != nil {
 		updateBody.ProfilePicture = *req.ProfilePicture
 	}
+	if req.Timezone != nil {
+		updateBody.Timezone = *req.Timezone
+	}
 
 	if err := tx.Model(&model.User{}).Where("id = ?", id).Updates(updateBody).Error; err != nil {
 		tx.Rollback()
@@ -23,6 +23,7 @@ type UsersWeightHeightService interface {
 	GetWeightHeightTargetByID(ctx *fiber.Ctx, recordID string, userID uuid.UUID) (*model.UsersWeightHeightTarget, error)
 	UpdateWeightHeightTarget(ctx *fiber.Ctx, recordID string, record *model.UsersWeightHeightTarget) (*model.UsersWeightHeightTarget, error)
 	DeleteWeightHeightTarget(ctx *fiber.Ctx, recordID string, userID uuid.UUID) error
+	GetWeightTargetProgress(ctx *fiber.Ctx, userID uuid.UUID) (*model.WeightProgress, error)
 }
 
 type usersWeightHeightService struct {
@@ -38,6 +39,10 @@ func NewUsersWeightHeightService(db *gorm.DB) UsersWeightHeightService {
 }
 
 func (s *usersWeightHeightService) AddWeightHeight(ctx *fiber.Ctx, record *model.UsersWeightHeightHistory) (*model.UsersWeightHeightHistory, error) {
+	if err := validateBodyComposition(record); err != nil {
+		return nil, err
+	}
+
 	if record.RecordedAt.IsZero() {
 		record.RecordedAt = time.Now()
 	}
@@ -82,6 +87,10 @@ func (s *usersWeightHeightService) GetWeightHeightByID(ctx *fiber.Ctx, recordID
 }
 
 func (s *usersWeightHeightService) UpdateWeightHeight(ctx *fiber.Ctx, recordID string, record *model.UsersWeightHeightHistory) (*model.UsersWeightHeightHistory, error) {
+	if err := validateBodyComposition(record); err != nil {
+		return nil, err
+	}
+
 	existingRecord := new(model.UsersWeightHeightHistory)
 	if err := s.DB.WithContext(ctx.Context()).
 		First(existingRecord, "id = ? AND user_id = ?", recordID, record.UserID).Error; err != nil {
@@ -102,6 +111,14 @@ func (s *usersWeightHeightService) UpdateWeightHeight(ctx *fiber.Ctx, recordID s
 		updates["height"] = record.Height
 	}
 
+	if record.WaistCircumference != nil {
+		updates["waist_circumference"] = *record.WaistCircumference
+	}
+
+	if record.BodyFatPercentage != nil {
+		updates["body_fat_percentage"] = *record.BodyFatPercentage
+	}
+
 	if !record.RecordedAt.IsZero() {
 		updates["recorded_at"] = record.RecordedAt
 	}
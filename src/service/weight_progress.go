@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"app/src/model"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxSafeWeeklyLoss is the fastest weight loss in kg a week considered safe
+	MaxSafeWeeklyLoss = 1.0
+	// MinHealthyBMI is where underweight starts
+	MinHealthyBMI = 18.5
+
+	// minTrendDays is the span the weight records need before a weekly rate is computed
+	minTrendDays = 7
+	// stableWeeklyRate is the change in kg a week below which the weight counts as stable
+	stableWeeklyRate = 0.1
+	// maintainTolerance is how far from a maintenance target the weight may be while still reaching it
+	maintainTolerance = 0.5
+	// maxProjectionDays bounds the projection, slower paces never reach the target in practice
+	maxProjectionDays = 3650
+)
+
+// GetWeightTargetProgress compares the weight records since the active target was set against it
+func (s *usersWeightHeightService) GetWeightTargetProgress(ctx *fiber.Ctx, userID uuid.UUID) (*model.WeightProgress, error) {
+	target := new(model.UsersWeightHeightTarget)
+	if err := s.DB.WithContext(ctx.Context()).
+		Where("user_id = ?", userID).
+		Order("target_date DESC").
+		First(target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fiber.NewError(fiber.StatusNotFound, "Weight target not found")
+		}
+		s.Log.Errorf("Failed to get weight target: %+v", err)
+		return nil, err
+	}
+
+	var records []model.UsersWeightHeightHistory
+	if err := s.DB.WithContext(ctx.Context()).
+		Where("user_id = ? AND recorded_at >= ?", userID, weightTargetStart(target)).
+		Order("recorded_at").
+		Find(&records).Error; err != nil {
+		s.Log.Errorf("Failed to get weight and height records: %+v", err)
+		return nil, err
+	}
+
+	return CalculateWeightProgress(target, records, time.Now()), nil
+}
+
+// CalculateWeightProgress measures the progress towards target from the weight records made since it was
+// set. The weight when the target was set is the starting point, or the first record when there was none.
+// The weekly rate is the slope of a least squares line through the records once they span a week, and the
+// completion date is projected from the latest weight at that rate.
+func CalculateWeightProgress(target *model.UsersWeightHeightTarget, records []model.UsersWeightHeightHistory, now time.Time) *model.WeightProgress {
+	records = append([]model.UsersWeightHeightHistory(nil), records...)
+	sort.SliceStable(records, func(i, j int) bool { return records[i].RecordedAt.Before(records[j].RecordedAt) })
+
+	start := weightTargetStart(target)
+	type point struct {
+		at     time.Time
+		weight float64
+	}
+	var points []point
+	startWeight := target.WeightHistory
+	if startWeight > 0 {
+		points = append(points, point{start, startWeight})
+	} else if len(records) > 0 {
+		startWeight = records[0].Weight
+	}
+	for _, record := range records {
+		points = append(points, point{record.RecordedAt, record.Weight})
+	}
+
+	currentWeight, currentAt := startWeight, start
+	if len(records) > 0 {
+		last := records[len(records)-1]
+		currentWeight, currentAt = last.Weight, last.RecordedAt
+	}
+
+	progress := &model.WeightProgress{
+		TargetID:      target.ID,
+		StartWeight:   startWeight,
+		CurrentWeight: currentWeight,
+		TargetWeight:  target.Weight,
+		StartDate:     start,
+		TargetDate:    target.TargetDate,
+		Change:        roundTo(currentWeight-startWeight, 2),
+		Remaining:     roundTo(target.Weight-currentWeight, 2),
+		Records:       len(records),
+	}
+
+	total := target.Weight - startWeight
+	switch {
+	case math.Abs(total) < maintainTolerance:
+		progress.Achieved = math.Abs(progress.Remaining) <= maintainTolerance
+		if progress.Achieved {
+			progress.PercentComplete = 100
+		}
+	default:
+		progress.Achieved = (total < 0 && currentWeight <= target.Weight) || (total > 0 && currentWeight >= target.Weight)
+		progress.PercentComplete = roundTo(math.Min(math.Max((currentWeight-startWeight)/total*100, 0), 100), 1)
+	}
+
+	// Least squares slope of the weight over time, in kg a day
+	if len(points) >= 2 && points[len(points)-1].at.Sub(points[0].at).Hours()/24 >= minTrendDays {
+		var meanX, meanY float64
+		for _, p := range points {
+			meanX += p.at.Sub(points[0].at).Hours() / 24
+			meanY += p.weight
+		}
+		meanX /= float64(len(points))
+		meanY /= float64(len(points))
+
+		var covariance, variance float64
+		for _, p := range points {
+			dx := p.at.Sub(points[0].at).Hours()/24 - meanX
+			covariance += dx * (p.weight - meanY)
+			variance += dx * dx
+		}
+		if variance > 0 {
+			dailyRate := covariance / variance
+			weeklyRate := roundTo(dailyRate*7, 2)
+			progress.WeeklyRate = &weeklyRate
+
+			switch {
+			case math.Abs(weeklyRate) < stableWeeklyRate:
+				progress.Trend = model.WeightTrendStable
+			case weeklyRate < 0:
+				progress.Trend = model.WeightTrendLosing
+			default:
+				progress.Trend = model.WeightTrendGaining
+			}
+
+			// Only a pace towards the target ever reaches it
+			if !progress.Achieved && dailyRate*progress.Remaining > 0 {
+				if days := progress.Remaining / dailyRate; days <= maxProjectionDays {
+					projected := currentAt.Add(time.Duration(days * 24 * float64(time.Hour)))
+					progress.ProjectedDate = &projected
+				}
+			}
+		}
+	}
+
+	if !progress.Achieved && target.TargetDate.After(now) {
+		weeks := math.Max(target.TargetDate.Sub(now).Hours()/24/7, 1.0/7)
+		required := roundTo(progress.Remaining/weeks, 2)
+		progress.RequiredWeeklyRate = &required
+	}
+	progress.OnTrack = progress.Achieved || (progress.ProjectedDate != nil && !progress.ProjectedDate.After(target.TargetDate))
+
+	height := target.Height
+	if height <= 0 {
+		height = target.HeightHistory
+	}
+	if height > 0 {
+		bmi := roundTo(target.Weight/math.Pow(height/100, 2), 1)
+		progress.TargetBMI = &bmi
+	}
+
+	progress.Warnings = weightTargetWarnings(progress, total, now)
+	progress.Safe = true
+	for _, warning := range progress.Warnings {
+		if warning.Code != model.WeightWarningTargetPassed {
+			progress.Safe = false
+		}
+	}
+
+	return progress
+}
+
+// weightTargetWarnings flags a target losing weight faster than is safe, whether as planned, at the pace it
+// now requires or at the current pace, a goal BMI in the underweight range and a target date gone by
+func weightTargetWarnings(progress *model.WeightProgress, total float64, now time.Time) []model.WeightTargetWarning {
+	warnings := []model.WeightTargetWarning{}
+
+	type pace struct {
+		rate    float64
+		message string
+	}
+	plannedWeeks := math.Max(progress.TargetDate.Sub(progress.StartDate).Hours()/24/7, 1.0/7)
+	paces := []pace{{total / plannedWeeks, "The target plans to lose %.1f kg a week"}}
+	if progress.RequiredWeeklyRate != nil {
+		paces = append(paces, pace{*progress.RequiredWeeklyRate, "Reaching the target in time needs losing %.1f kg a week"})
+	}
+	if progress.WeeklyRate != nil && !progress.Achieved {
+		paces = append(paces, pace{*progress.WeeklyRate, "The weight is going down %.1f kg a week"})
+	}
+	for _, p := range paces {
+		if -p.rate > MaxSafeWeeklyLoss {
+			warnings = append(warnings, model.WeightTargetWarning{
+				Code:    model.WeightWarningLossTooFast,
+				Message: fmt.Sprintf(p.message+", more than the safe %.0f kg", -p.rate, MaxSafeWeeklyLoss),
+			})
+			break
+		}
+	}
+
+	if progress.TargetBMI != nil && *progress.TargetBMI < MinHealthyBMI {
+		warnings = append(warnings, model.WeightTargetWarning{
+			Code:    model.WeightWarningUnderweight,
+			Message: fmt.Sprintf("The target weight is a BMI of %.1f, below the healthy %.1f", *progress.TargetBMI, MinHealthyBMI),
+		})
+	}
+
+	if !progress.Achieved && !progress.TargetDate.After(now) {
+		warnings = append(warnings, model.WeightTargetWarning{
+			Code:    model.WeightWarningTargetPassed,
+			Message: "The target date has passed, set a new target date to keep tracking",
+		})
+	}
+
+	return warnings
+}
+
+// weightTargetStart is when the target was set, falling back to its creation for targets without a record date
+func weightTargetStart(target *model.UsersWeightHeightTarget) time.Time {
+	if target.RecordDate.IsZero() {
+		return target.CreatedAt
+	}
+	return target.RecordDate
+}
+
+func roundTo(value float64, decimals int) float64 {
+	factor := math.Pow(10, float64(decimals))
+	return math.Round(value*factor) / factor
+}
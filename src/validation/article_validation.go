@@ -0,0 +1,22 @@
+package validation
+
+import "time"
+
+// QueryArticle adalah struktur untuk query pencarian, filter kategori dan tag, serta pengurutan artikel. Status hanya dipakai
+// editor, kosong berarti hanya artikel yang sudah terbit dan all berarti semua status.
+type QueryArticle struct {
+	Page        int      `validate:"omitempty,number,min=1"`
+	Limit       int      `validate:"omitempty,number,min=1,max=50"`
+	Search      string   `validate:"omitempty,max=100"`
+	CategoryIDs []string `validate:"omitempty,max=20,dive,uuid"`
+	Tags        []string `validate:"omitempty,max=20,dive,max=40"`
+	Sort        string   `validate:"omitempty,oneof=newest rating popular relevance"`
+	Status      string   `validate:"omitempty,oneof=all draft scheduled published archived"`
+}
+
+// UpdateArticleStatus adalah struktur untuk memindahkan artikel ke status lain. PublishedAt wajib diisi
+// waktu yang akan datang untuk status scheduled, dan boleh diisi waktu lampau untuk status published.
+type UpdateArticleStatus struct {
+	Status      string     `json:"status" validate:"required,oneof=draft scheduled published archived" example:"scheduled"`
+	PublishedAt *time.Time `json:"published_at" example:"2026-11-01T08:00:00+07:00"`
+}
@@ -0,0 +1,7 @@
+package validation
+
+// QueryBodyMetrics adalah struktur untuk query riwayat metrik tubuh di antara dua tanggal
+type QueryBodyMetrics struct {
+	From string `validate:"omitempty,datetime=2006-01-02" example:"2026-01-01"`
+	To   string `validate:"omitempty,datetime=2006-01-02" example:"2026-10-17"`
+}
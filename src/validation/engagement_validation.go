@@ -0,0 +1,26 @@
+package validation
+
+// RateContent adalah struktur untuk memberi rating 1 sampai 5 dan ulasan pada resep atau artikel
+type RateContent struct {
+	Stars  int     `json:"stars" validate:"required,min=1,max=5" example:"5"`
+	Review *string `json:"review" validate:"omitempty,max=2000" example:"Enak dan mudah dibuat"`
+}
+
+// HideReview adalah struktur untuk menyembunyikan ulasan yang melanggar aturan
+type HideReview struct {
+	Reason string `json:"reason" validate:"required,max=255" example:"Mengandung kata-kata kasar"`
+}
+
+// QueryReview adalah struktur untuk query daftar ulasan
+type QueryReview struct {
+	Page       int    `validate:"omitempty,number,min=1"`
+	Limit      int    `validate:"omitempty,number,min=1,max=50"`
+	TargetType string `validate:"omitempty,oneof=recipe article"`
+	Hidden     string `validate:"omitempty,oneof=true false"`
+}
+
+// QueryFavorite adalah struktur untuk query daftar favorit pengguna
+type QueryFavorite struct {
+	Page  int `validate:"omitempty,number,min=1"`
+	Limit int `validate:"omitempty,number,min=1,max=50"`
+}
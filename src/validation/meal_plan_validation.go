@@ -0,0 +1,12 @@
+package validation
+
+// CreateMealPlan adalah struktur untuk membuat rencana makan 7 hari
+type CreateMealPlan struct {
+	StartDate           string   `json:"start_date" validate:"omitempty,datetime=2006-01-02" example:"2026-10-19"`
+	ExcludedIngredients []string `json:"excluded_ingredients" validate:"omitempty,max=20,dive,required,max=50" example:"udang,kacang"`
+}
+
+// SwapMealPlanSlot adalah struktur untuk mengganti resep pada satu slot rencana makan
+type SwapMealPlanSlot struct {
+	RecipeID string `json:"recipe_id" validate:"omitempty,uuid" example:"e088d183-9eea-4a11-8d5d-74d7ec91bdf5"`
+}
@@ -0,0 +1,19 @@
+package validation
+
+// ConfirmMealScan adalah struktur untuk konfirmasi atau koreksi hasil scan makanan
+type ConfirmMealScan struct {
+	Selections []MealScanSelection `json:"selections" validate:"required,min=1,dive"`
+}
+
+// MealScanSelection adalah pilihan makanan dan porsi untuk satu segmen hasil scan
+type MealScanSelection struct {
+	Segment int     `json:"segment" validate:"min=0"`
+	Name    string  `json:"name" validate:"required,max=255"`
+	Grams   float64 `json:"grams" validate:"required,gt=0,lte=5000"`
+}
+
+// CreateFoodAlias adalah struktur untuk validasi alias nama makanan ke kode TKPI
+type CreateFoodAlias struct {
+	Alias string `json:"alias" validate:"required,max=255"`
+	Kode  string `json:"kode" validate:"required,max=20"`
+}
@@ -0,0 +1,8 @@
+package validation
+
+// QueryNutritionSummary adalah struktur untuk query ringkasan nutrisi per hari, minggu atau bulan
+type QueryNutritionSummary struct {
+	From   string `validate:"omitempty,datetime=2006-01-02" example:"2026-10-01"`
+	To     string `validate:"omitempty,datetime=2006-01-02" example:"2026-10-17"`
+	Bucket string `validate:"omitempty,oneof=day week month" example:"day"`
+}
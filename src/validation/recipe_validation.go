@@ -0,0 +1,61 @@
+package validation
+
+// CreateRecipe adalah struktur untuk membuat resep beserta bahan terstruktur dan langkah memasaknya
+type CreateRecipe struct {
+	Name            string             `json:"name" validate:"required,max=255" example:"Nasi Goreng Spesial"`
+	Slug            string             `json:"slug" validate:"required,max=255" example:"nasi-goreng-spesial"`
+	Image           *string            `json:"image" validate:"omitempty,max=2048" example:"https://example.com/nasi-goreng.jpg"`
+	Description     string             `json:"description" example:"Nasi goreng dengan bumbu rahasia"`
+	Ingredients     string             `json:"ingredients" example:"Nasi, telur, bawang, kecap"`
+	Instructions    string             `json:"instructions" example:"1. Tumis bawang..."`
+	Label           *string            `json:"label" validate:"omitempty,max=50" example:"breakfast"`
+	Day             string             `json:"day" validate:"required,oneof=sunday monday tuesday wednesday thursday friday saturday" example:"monday"`
+	Servings        int                `json:"servings" validate:"omitempty,min=1,max=100" example:"2"`
+	IngredientLines []RecipeIngredient `json:"ingredient_lines" validate:"omitempty,max=50,dive"`
+	Steps           []string           `json:"steps" validate:"omitempty,max=50,dive,required,max=2000" example:"Tumis bawang,Masukkan nasi"`
+	Calories        *float64           `json:"calories" validate:"omitempty,min=0" example:"520"`
+	Protein         *float64           `json:"protein" validate:"omitempty,min=0" example:"18.5"`
+	Carbs           *float64           `json:"carbs" validate:"omitempty,min=0" example:"68"`
+	Fat             *float64           `json:"fat" validate:"omitempty,min=0" example:"19.2"`
+}
+
+// UpdateRecipe adalah struktur untuk mengubah resep, field yang kosong tidak diubah
+type UpdateRecipe struct {
+	Name            string              `json:"name" validate:"omitempty,max=255" example:"Nasi Goreng Premium"`
+	Slug            string              `json:"slug" validate:"omitempty,max=255" example:"nasi-goreng-premium"`
+	Image           *string             `json:"image" validate:"omitempty,max=2048" example:"https://example.com/nasi-goreng-premium.jpg"`
+	Description     string              `json:"description" example:"Nasi goreng dengan bumbu premium"`
+	Ingredients     string              `json:"ingredients" example:"Nasi, telur, bawang, kecap, ayam"`
+	Instructions    string              `json:"instructions" example:"1. Tumis bawang... 2. Masukkan ayam..."`
+	Label           *string             `json:"label" validate:"omitempty,max=50" example:"lunch"`
+	Day             string              `json:"day" validate:"omitempty,oneof=sunday monday tuesday wednesday thursday friday saturday" example:"tuesday"`
+	Servings        int                 `json:"servings" validate:"omitempty,min=1,max=100" example:"4"`
+	IngredientLines *[]RecipeIngredient `json:"ingredient_lines" validate:"omitempty,max=50,dive"`
+	Steps           *[]string           `json:"steps" validate:"omitempty,max=50,dive,required,max=2000"`
+	Calories        *float64            `json:"calories" validate:"omitempty,min=0" example:"560"`
+	Protein         *float64            `json:"protein" validate:"omitempty,min=0" example:"24"`
+	Carbs           *float64            `json:"carbs" validate:"omitempty,min=0" example:"66"`
+	Fat             *float64            `json:"fat" validate:"omitempty,min=0" example:"20.5"`
+}
+
+// RecipeIngredient adalah satu baris bahan resep yang merujuk ke kode bahan makanan TKPI
+type RecipeIngredient struct {
+	Kode     string  `json:"kode" validate:"required,max=20" example:"AP001"`
+	Quantity float64 `json:"quantity" validate:"required,gt=0,lte=100000" example:"200"`
+	Unit     string  `json:"unit" validate:"required,oneof=g kg ml l sdm sdt" example:"g"`
+	Note     *string `json:"note" validate:"omitempty,max=100" example:"cincang halus"`
+}
+
+// QueryRecipe adalah struktur untuk query pencarian, filter dan pengurutan resep
+type QueryRecipe struct {
+	Page        int     `validate:"omitempty,number,min=1"`
+	Limit       int     `validate:"omitempty,number,min=1,max=50"`
+	Search      string  `validate:"omitempty,max=100"`
+	Day         string  `validate:"omitempty,oneof=sunday monday tuesday wednesday thursday friday saturday"`
+	Label       string  `validate:"omitempty,max=50"`
+	MinCalories float64 `validate:"omitempty,min=0"`
+	MaxCalories float64 `validate:"omitempty,min=0"`
+	Contains    string  `validate:"omitempty,max=200"`
+	Excludes    string  `validate:"omitempty,max=200"`
+	Sort        string  `validate:"omitempty,oneof=newest oldest name calories_asc calories_desc relevance rating popular"`
+}
@@ -28,6 +28,12 @@ type UpdatePaymentStatus struct {
 	Status string `json:"status" validate:"required,oneof=pending success failed"`
 }
 
+// RefundSubscription adalah struktur untuk refund subscription oleh admin
+type RefundSubscription struct {
+	Type   string `json:"type" validate:"required,oneof=full prorated"`
+	Reason string `json:"reason" validate:"omitempty,max=255"`
+}
+
 // UpdateSubscriptionPlan adalah struktur untuk update subscription plan
 type UpdateSubscriptionPlan struct {
 	Name         *string          `json:"name" validate:"omitempty,min=2,max=50"`
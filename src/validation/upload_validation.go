@@ -0,0 +1,6 @@
+package validation
+
+// UploadImage adalah struktur untuk mengunggah gambar artikel atau resep. Gambarnya dikirim pada field file.
+type UploadImage struct {
+	Purpose string `form:"purpose" validate:"required,oneof=article recipe" example:"article"`
+}
@@ -23,6 +23,7 @@ type UpdateUser struct {
 	ActivityLevel  *model.ActivityLevel `form:"activity_level,omitempty" validate:"omitempty,oneof=Light Medium Heavy" example:"Medium"`
 	MedicalHistory *string              `form:"medical_history,omitempty" validate:"omitempty,max=1000" example:"No known allergies"`
 	ProfilePicture *string              `form:"profile_picture,omitempty" validate:"omitempty,url" example:"https://example.com/image.jpg"`
+	Timezone       *string              `form:"timezone,omitempty" validate:"omitempty,timezone" example:"Asia/Jakarta"`
 }
 
 type UpdatePassOrVerify struct {
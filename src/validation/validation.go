@@ -18,6 +18,7 @@ var customMessages = map[string]string{
 	"alphanum": "Field %s must contain only alphanumeric characters",
 	"oneof":    "Invalid value for field %s",
 	"password": "Field %s must contain at least 1 letter and 1 number",
+	"timezone": "Field %s must be an IANA timezone such as Asia/Jakarta",
 }
 
 func CustomErrorMessages(err error) map[string]string {
@@ -47,6 +47,7 @@ func ActiveFreemiumSubscription(userID uuid.UUID, planID uuid.UUID) *model.UserS
 		IsActive:      true,
 		PaymentMethod: "freemium_trial",
 		PaymentStatus: "completed",
+		Status:        model.SubscriptionStatusActive,
 		AIscansUsed:   0,
 		CreatedAt:     now,
 	}
@@ -67,6 +68,7 @@ func ExpiredFreemiumSubscription(userID uuid.UUID, planID uuid.UUID) *model.User
 		IsActive:      false,
 		PaymentMethod: "freemium_trial",
 		PaymentStatus: "completed",
+		Status:        model.SubscriptionStatusExpired,
 		AIscansUsed:   10,
 		CreatedAt:     startDate,
 	}
@@ -86,6 +88,7 @@ func PaidSubscription(userID uuid.UUID, planID uuid.UUID) *model.UserSubscriptio
 		IsActive:      true,
 		PaymentMethod: "credit_card",
 		PaymentStatus: "completed",
+		Status:        model.SubscriptionStatusActive,
 		AIscansUsed:   5,
 		CreatedAt:     now,
 	}
@@ -185,12 +185,35 @@ func CreateFreemiumSubscription(db *gorm.DB, userID uuid.UUID) error {
 		IsActive:      true,
 		PaymentMethod: "freemium_trial",
 		PaymentStatus: "completed",
+		Status:        model.SubscriptionStatusActive,
 		AIscansUsed:   0,
 	}
 
 	return db.Create(freemiumSubscription).Error
 }
 
+// CreateSubscription gives the user an active paid subscription of the seeded plan named planName
+func CreateSubscription(db *gorm.DB, userID uuid.UUID, planName string) error {
+	var plan model.SubscriptionPlan
+	if err := db.First(&plan, "name = ? AND is_active = ?", planName, true).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	subscription := &model.UserSubscription{
+		UserID:        userID,
+		PlanID:        plan.ID,
+		StartDate:     now,
+		EndDate:       now.AddDate(0, 0, plan.ValidityDays),
+		IsActive:      true,
+		PaymentMethod: "bank_transfer",
+		PaymentStatus: "completed",
+		Status:        model.SubscriptionStatusActive,
+	}
+
+	return db.Create(subscription).Error
+}
+
 func CreateExpiredFreemiumSubscription(db *gorm.DB, userID uuid.UUID) error {
 	// Find the Freemium Trial plan
 	var freemiumPlan model.SubscriptionPlan
@@ -211,6 +234,7 @@ func CreateExpiredFreemiumSubscription(db *gorm.DB, userID uuid.UUID) error {
 		IsActive:      false,
 		PaymentMethod: "freemium_trial",
 		PaymentStatus: "completed",
+		Status:        model.SubscriptionStatusExpired,
 		AIscansUsed:   10,
 	}
 
@@ -236,6 +260,15 @@ func GetUserSubscription(db *gorm.DB, userID uuid.UUID) (*model.UserSubscription
 }
 
 func ClearSubscriptions(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.PaymentReconciliation{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear payment reconciliation data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.TransactionDetail{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear transaction detail data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.SubscriptionTransition{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear subscription transition data: %+v", err)
+	}
 	err := db.Where("id is not null").Delete(&model.UserSubscription{}).Error
 	if err != nil {
 		logrus.Fatalf("Failed to clear subscription data: %+v", err)
@@ -248,3 +281,102 @@ func ClearSubscriptionPlans(db *gorm.DB) {
 		logrus.Fatalf("Failed to clear subscription plan data: %+v", err)
 	}
 }
+
+func ClearMealPlans(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.MealPlanSlot{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear meal plan slot data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.MealPlan{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear meal plan data: %+v", err)
+	}
+}
+
+func ClearEngagements(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.UserFavorite{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear favorite data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.UsersStar{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear rating data: %+v", err)
+	}
+}
+
+func ClearRecipes(db *gorm.DB) {
+	ClearMealPlans(db)
+	ClearEngagements(db)
+	if err := db.Where("id is not null").Delete(&model.RecipeIngredient{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear recipe ingredient data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.RecipeStep{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear recipe step data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.RecipeNutrient{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear recipe nutrient data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.Recipe{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear recipe data: %+v", err)
+	}
+}
+
+func ClearArticles(db *gorm.DB) {
+	ClearEngagements(db)
+	if err := db.Where("id is not null").Delete(&model.ArticleRevision{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear article revision data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.ArticleRead{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear article read data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.ArticleTag{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear article tag data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.Article{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear article data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.ArticleCategory{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear article category data: %+v", err)
+	}
+}
+
+func ClearWeightHeights(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.UsersWeightHeightTarget{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear weight target data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.UsersWeightHeightHistory{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear weight history data: %+v", err)
+	}
+}
+
+func ClearUploads(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.UploadVariant{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear upload variant data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.Upload{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear upload data: %+v", err)
+	}
+}
+
+func ClearMeals(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.MealScanJob{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear meal scan job data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.MealHistoryFood{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear meal food data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.MealHistoryIngredient{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear meal ingredient data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.MealHistoryNutrient{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear meal nutrient data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.MealHistoryDetail{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear meal detail data: %+v", err)
+	}
+	if err := db.Where("id is not null").Delete(&model.MealHistory{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear meal data: %+v", err)
+	}
+}
+
+func ClearFoodAliases(db *gorm.DB) {
+	if err := db.Where("id is not null").Delete(&model.FoodAlias{}).Error; err != nil {
+		logrus.Fatalf("Failed to clear food alias data: %+v", err)
+	}
+}
@@ -1,9 +1,13 @@
 package test
 
 import (
+	"app/src/config"
 	"app/src/database"
 	"app/src/router"
 	"app/src/utils"
+	"context"
+	"os"
+	"path/filepath"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
@@ -18,7 +22,15 @@ var Log = utils.Log
 
 func init() {
 	// TODO: You can modify host and database configuration for tests
+	// Scans in tests use canned results instead of calling LogMeal
+	config.FoodRecognizer = "fake"
+	// Uploaded images go to a temporary folder on the local disk
+	config.UploadStorage = "local"
+	config.UploadDir = filepath.Join(os.TempDir(), "nutribox-test-uploads")
+
 	DB = database.Connect("localhost", "testdb")
-	router.Routes(App, DB)
+	background := router.Routes(App, DB)
 	App.Use(utils.NotFoundHandler)
+	// Async scans need the workers, the other loops stay off so they don't change data under the tests
+	background.Meals.StartScanWorkers(context.Background(), config.MealScanWorkers, config.MealScanMaxAttempts)
 }
@@ -0,0 +1,395 @@
+package integration
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type articleOption func(*model.Article)
+
+func withCategory(categoryID uuid.UUID) articleOption {
+	return func(article *model.Article) { article.CategoryID = &categoryID }
+}
+
+func withContent(content string) articleOption {
+	return func(article *model.Article) { article.Content = content }
+}
+
+func withTags(tags ...string) articleOption {
+	return func(article *model.Article) {
+		for _, tag := range tags {
+			article.ArticleTags = append(article.ArticleTags, model.ArticleTag{Tag: tag})
+		}
+	}
+}
+
+func insertArticle(t *testing.T, userID uuid.UUID, title, status string, publishedAt *time.Time, options ...articleOption) *model.Article {
+	article := &model.Article{
+		UserID:      userID,
+		Title:       title,
+		Slug:        strings.ReplaceAll(strings.ToLower(title), " ", "-"),
+		Content:     "Paragraf pertama\nParagraf kedua\n",
+		Status:      status,
+		PublishedAt: publishedAt,
+	}
+	for _, option := range options {
+		option(article)
+	}
+	assert.Nil(t, test.DB.Create(article).Error)
+	return article
+}
+
+func insertCategory(t *testing.T, userID uuid.UUID, name string) uuid.UUID {
+	category := &model.ArticleCategory{UserID: userID, Name: name}
+	assert.Nil(t, test.DB.Create(category).Error)
+	return category.ID
+}
+
+func TestArticleRoutes(t *testing.T) {
+	type session struct {
+		reader   string
+		readerID uuid.UUID
+		editor   string
+		editorID uuid.UUID
+	}
+
+	setup := func(t *testing.T) session {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		helper.ClearArticles(test.DB)
+
+		reader := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, reader, fixture.Admin)
+		assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, reader.ID))
+
+		readerToken, err := fixture.AccessToken(reader)
+		assert.Nil(t, err)
+		editorToken, err := fixture.AccessToken(fixture.Admin)
+		assert.Nil(t, err)
+		return session{reader: readerToken, readerID: reader.ID, editor: editorToken, editorID: fixture.Admin.ID}
+	}
+
+	send := func(t *testing.T, accessToken, method, url, body string) *http.Response {
+		var request *http.Request
+		if body == "" {
+			request = httptest.NewRequest(method, url, nil)
+		} else {
+			request = httptest.NewRequest(method, url, strings.NewReader(body))
+			request.Header.Set("Content-Type", "application/json")
+		}
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+		return apiResponse
+	}
+
+	decode := func(t *testing.T, apiResponse *http.Response, target interface{}) {
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+		assert.Nil(t, json.Unmarshal(bytes, target))
+	}
+
+	list := func(t *testing.T, accessToken, url string) *response.SuccessWithPaginate[model.ArticleResponse] {
+		apiResponse := send(t, accessToken, http.MethodGet, url, "")
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		responseBody := new(response.SuccessWithPaginate[model.ArticleResponse])
+		decode(t, apiResponse, responseBody)
+		return responseBody
+	}
+
+	titles := func(articles []model.ArticleResponse) []string {
+		result := make([]string, 0, len(articles))
+		for _, article := range articles {
+			result = append(result, article.Title)
+		}
+		return result
+	}
+
+	t.Run("GET /v1/articles", func(t *testing.T) {
+		t.Run("should only list published articles", func(t *testing.T) {
+			s := setup(t)
+			past := time.Now().Add(-time.Hour)
+			future := time.Now().Add(24 * time.Hour)
+			insertArticle(t, s.editorID, "Sarapan sehat", model.ArticleStatusPublished, &past)
+			insertArticle(t, s.editorID, "Jadwal lewat", model.ArticleStatusScheduled, &past)
+			insertArticle(t, s.editorID, "Jadwal besok", model.ArticleStatusScheduled, &future)
+			insertArticle(t, s.editorID, "Masih draf", model.ArticleStatusDraft, nil)
+			insertArticle(t, s.editorID, "Arsip lama", model.ArticleStatusArchived, &past)
+
+			page := list(t, s.reader, "/v1/articles")
+
+			for _, article := range page.Results {
+				assert.Equal(t, model.ArticleStatusPublished, article.Status)
+			}
+			assert.ElementsMatch(t, []string{"Sarapan sehat", "Jadwal lewat"}, titles(page.Results))
+
+			editorPage := list(t, s.editor, "/v1/admin/articles?status=draft")
+			assert.Equal(t, []string{"Masih draf"}, titles(editorPage.Results))
+		})
+
+		t.Run("should page through articles with excerpts", func(t *testing.T) {
+			s := setup(t)
+			for i, title := range []string{"Satu", "Dua", "Tiga"} {
+				publishedAt := time.Now().Add(-time.Duration(i+1) * time.Hour)
+				insertArticle(t, s.editorID, title, model.ArticleStatusPublished, &publishedAt)
+			}
+
+			page := list(t, s.reader, "/v1/articles?page=2&limit=2")
+
+			assert.Equal(t, int64(3), page.TotalResults)
+			assert.Equal(t, int64(2), page.TotalPages)
+			assert.Equal(t, []string{"Tiga"}, titles(page.Results))
+			assert.Empty(t, page.Results[0].Content)
+			assert.Equal(t, "Paragraf pertama Paragraf kedua", page.Results[0].Excerpt)
+		})
+
+		t.Run("should filter by categories and search title and content", func(t *testing.T) {
+			s := setup(t)
+			past := time.Now().Add(-time.Hour)
+			diet := insertCategory(t, s.editorID, "Diet")
+			sport := insertCategory(t, s.editorID, "Olahraga")
+			insertArticle(t, s.editorID, "Diet rendah gula", model.ArticleStatusPublished, &past, withCategory(diet), withContent("Kurangi gula dan nasi putih"))
+			insertArticle(t, s.editorID, "Lari pagi", model.ArticleStatusPublished, &past, withCategory(sport), withContent("Lari membakar gula darah"))
+			insertArticle(t, s.editorID, "Tidur cukup", model.ArticleStatusPublished, &past, withContent("Tidur delapan jam"))
+
+			page := list(t, s.reader, "/v1/articles?category_ids="+diet.String()+","+sport.String())
+			assert.ElementsMatch(t, []string{"Diet rendah gula", "Lari pagi"}, titles(page.Results))
+
+			page = list(t, s.reader, "/v1/articles?search=gula")
+			assert.ElementsMatch(t, []string{"Diet rendah gula", "Lari pagi"}, titles(page.Results))
+			// Title matches rank first
+			assert.Equal(t, "Diet rendah gula", page.Results[0].Title)
+
+			page = list(t, s.reader, "/v1/articles?search=gula&category_ids="+sport.String())
+			assert.Equal(t, []string{"Lari pagi"}, titles(page.Results))
+		})
+
+		t.Run("should return 400 for an invalid category ID", func(t *testing.T) {
+			s := setup(t)
+
+			apiResponse := send(t, s.reader, http.MethodGet, "/v1/articles?category_ids=diet", "")
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/articles/:id/related", func(t *testing.T) {
+		t.Run("should return articles sharing the category or title words", func(t *testing.T) {
+			s := setup(t)
+			past := time.Now().Add(-time.Hour)
+			diet := insertCategory(t, s.editorID, "Diet")
+			source := insertArticle(t, s.editorID, "Diet rendah gula", model.ArticleStatusPublished, &past, withCategory(diet))
+			insertArticle(t, s.editorID, "Menu diet harian", model.ArticleStatusPublished, &past, withCategory(diet))
+			insertArticle(t, s.editorID, "Bahaya gula berlebih", model.ArticleStatusPublished, &past)
+			insertArticle(t, s.editorID, "Tidur cukup", model.ArticleStatusPublished, &past)
+			insertArticle(t, s.editorID, "Diet draf", model.ArticleStatusDraft, nil, withCategory(diet))
+
+			apiResponse := send(t, s.reader, http.MethodGet, "/v1/articles/"+source.ID.String()+"/related", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithArticleList)
+			decode(t, apiResponse, responseBody)
+
+			// Sharing the category and a title word beats sharing a title word only
+			assert.Equal(t, []string{"Menu diet harian", "Bahaya gula berlebih"}, titles(responseBody.Data))
+		})
+	})
+
+	t.Run("article tags", func(t *testing.T) {
+		t.Run("should normalize tags and filter by them", func(t *testing.T) {
+			s := setup(t)
+			past := time.Now().Add(-time.Hour)
+			article := insertArticle(t, s.editorID, "Kurangi garam", model.ArticleStatusPublished, &past, withTags(model.TagHealthyEating))
+			insertArticle(t, s.editorID, "Tidur cukup", model.ArticleStatusPublished, &past)
+
+			apiResponse := send(t, s.editor, http.MethodPut, "/v1/articles/"+article.ID.String(), `{"tags":["Low Sodium","hypertension","low_sodium"]}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			page := list(t, s.reader, "/v1/articles?tags=low-sodium")
+			assert.Equal(t, []string{"Kurangi garam"}, titles(page.Results))
+			assert.Equal(t, []string{"hypertension", "low-sodium"}, page.Results[0].Tags)
+
+			// An empty list removes the tags
+			apiResponse = send(t, s.editor, http.MethodPut, "/v1/articles/"+article.ID.String(), `{"tags":[]}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Empty(t, list(t, s.reader, "/v1/articles?tags=hypertension").Results)
+		})
+
+		t.Run("should return 400 for an invalid tag", func(t *testing.T) {
+			s := setup(t)
+			past := time.Now().Add(-time.Hour)
+			article := insertArticle(t, s.editorID, "Kurangi garam", model.ArticleStatusPublished, &past)
+
+			apiResponse := send(t, s.editor, http.MethodPut, "/v1/articles/"+article.ID.String(), `{"tags":["garam/gula"]}`)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/articles/recommended", func(t *testing.T) {
+		t.Run("should rank by medical history and put articles already read last", func(t *testing.T) {
+			s := setup(t)
+			assert.Nil(t, test.DB.Model(&model.User{}).Where("id = ?", s.readerID).Update("medical_history", "Hipertensi sejak 2022").Error)
+
+			hourAgo := time.Now().Add(-time.Hour)
+			dayAgo := time.Now().Add(-24 * time.Hour)
+			salt := insertArticle(t, s.editorID, "Kurangi garam", model.ArticleStatusPublished, &hourAgo, withTags(model.TagHypertension, model.TagLowSodium))
+			insertArticle(t, s.editorID, "Tekanan darah", model.ArticleStatusPublished, &dayAgo, withTags(model.TagHypertension))
+			insertArticle(t, s.editorID, "Diet gula", model.ArticleStatusPublished, &hourAgo, withTags(model.TagDiabetes))
+			insertArticle(t, s.editorID, "Olahraga pagi", model.ArticleStatusPublished, &dayAgo)
+			insertArticle(t, s.editorID, "Hipertensi draf", model.ArticleStatusDraft, nil, withTags(model.TagHypertension))
+
+			// Reading the article records it
+			assert.Equal(t, http.StatusOK, send(t, s.reader, http.MethodGet, "/v1/articles/"+salt.ID.String(), "").StatusCode)
+			assert.Equal(t, http.StatusOK, send(t, s.reader, http.MethodGet, "/v1/articles/slug/kurangi-garam", "").StatusCode)
+			var read model.ArticleRead
+			assert.Nil(t, test.DB.Where("user_id = ? AND article_id = ?", s.readerID, salt.ID).First(&read).Error)
+			assert.Equal(t, 2, read.ReadCount)
+
+			apiResponse := send(t, s.reader, http.MethodGet, "/v1/articles/recommended", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithArticleRecommendations)
+			decode(t, apiResponse, responseBody)
+
+			recommended := make([]string, 0, len(responseBody.Data))
+			for _, recommendation := range responseBody.Data {
+				recommended = append(recommended, recommendation.Title)
+			}
+			// Matched articles first, then popular ones the reader hasn't read
+			assert.Equal(t, []string{"Tekanan darah", "Kurangi garam", "Diet gula", "Olahraga pagi"}, recommended)
+			assert.Equal(t, []string{model.TagHypertension}, responseBody.Data[0].Reasons)
+			assert.True(t, responseBody.Data[1].Read)
+			assert.Empty(t, responseBody.Data[2].Reasons)
+		})
+	})
+
+	t.Run("GET /v1/articles/slug/:slug", func(t *testing.T) {
+		t.Run("should return a published article", func(t *testing.T) {
+			s := setup(t)
+			past := time.Now().Add(-time.Hour)
+			insertArticle(t, s.editorID, "Sarapan sehat", model.ArticleStatusPublished, &past)
+
+			apiResponse := send(t, s.reader, http.MethodGet, "/v1/articles/slug/sarapan-sehat", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithArticle)
+			decode(t, apiResponse, responseBody)
+			assert.Equal(t, "Sarapan sehat", responseBody.Data.Title)
+		})
+
+		t.Run("should return 404 for a draft", func(t *testing.T) {
+			s := setup(t)
+			insertArticle(t, s.editorID, "Masih draf", model.ArticleStatusDraft, nil)
+
+			apiResponse := send(t, s.reader, http.MethodGet, "/v1/articles/slug/masih-draf", "")
+
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("PATCH /v1/articles/:id/status", func(t *testing.T) {
+		t.Run("should schedule a draft", func(t *testing.T) {
+			s := setup(t)
+			article := insertArticle(t, s.editorID, "Masih draf", model.ArticleStatusDraft, nil)
+			publishAt := time.Now().Add(48 * time.Hour).UTC().Format(time.RFC3339)
+
+			apiResponse := send(t, s.editor, http.MethodPatch, "/v1/articles/"+article.ID.String()+"/status",
+				`{"status":"scheduled","published_at":"`+publishAt+`"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithArticle)
+			decode(t, apiResponse, responseBody)
+			assert.Equal(t, model.ArticleStatusScheduled, responseBody.Data.Status)
+
+			// Not public until then
+			apiResponse = send(t, s.reader, http.MethodGet, "/v1/articles/"+article.ID.String(), "")
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 400 when scheduling in the past", func(t *testing.T) {
+			s := setup(t)
+			article := insertArticle(t, s.editorID, "Masih draf", model.ArticleStatusDraft, nil)
+			publishAt := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+			apiResponse := send(t, s.editor, http.MethodPatch, "/v1/articles/"+article.ID.String()+"/status",
+				`{"status":"scheduled","published_at":"`+publishAt+`"}`)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 409 for a transition that isn't allowed", func(t *testing.T) {
+			s := setup(t)
+			past := time.Now().Add(-time.Hour)
+			article := insertArticle(t, s.editorID, "Arsip lama", model.ArticleStatusArchived, &past)
+			publishAt := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+
+			apiResponse := send(t, s.editor, http.MethodPatch, "/v1/articles/"+article.ID.String()+"/status",
+				`{"status":"scheduled","published_at":"`+publishAt+`"}`)
+
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("article revisions", func(t *testing.T) {
+		t.Run("should keep, diff and restore revisions of a published article", func(t *testing.T) {
+			s := setup(t)
+			past := time.Now().Add(-time.Hour)
+			article := insertArticle(t, s.editorID, "Sarapan sehat", model.ArticleStatusPublished, &past)
+			articleURL := "/v1/articles/" + article.ID.String()
+
+			apiResponse := send(t, s.editor, http.MethodPut, articleURL, `{"content":"Paragraf pertama\nParagraf baru\n"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			apiResponse = send(t, s.editor, http.MethodGet, articleURL+"/revisions", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			revisions := new(response.SuccessWithArticleRevisions)
+			decode(t, apiResponse, revisions)
+			assert.Len(t, revisions.Data, 1)
+			assert.Equal(t, 1, revisions.Data[0].Version)
+			revisionID := revisions.Data[0].ID.String()
+
+			apiResponse = send(t, s.editor, http.MethodGet, articleURL+"/revisions/"+revisionID+"/diff", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			diff := new(response.SuccessWithArticleRevisionDiff)
+			decode(t, apiResponse, diff)
+			assert.Equal(t, []string{"content"}, diff.Data.ChangedFields)
+			assert.Contains(t, diff.Data.ContentDiff, "-Paragraf kedua")
+			assert.Contains(t, diff.Data.ContentDiff, "+Paragraf baru")
+
+			apiResponse = send(t, s.editor, http.MethodPost, articleURL+"/revisions/"+revisionID+"/restore", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			restored := new(response.SuccessWithArticle)
+			decode(t, apiResponse, restored)
+			assert.Equal(t, "Paragraf pertama\nParagraf kedua\n", restored.Data.Content)
+
+			// The replaced version is kept too
+			var count int64
+			assert.Nil(t, test.DB.Model(&model.ArticleRevision{}).Where("article_id = ?", article.ID).Count(&count).Error)
+			assert.Equal(t, int64(2), count)
+		})
+
+		t.Run("should not keep revisions of drafts", func(t *testing.T) {
+			s := setup(t)
+			article := insertArticle(t, s.editorID, "Masih draf", model.ArticleStatusDraft, nil)
+
+			apiResponse := send(t, s.editor, http.MethodPut, "/v1/articles/"+article.ID.String(), `{"content":"Isi baru\n"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			var count int64
+			assert.Nil(t, test.DB.Model(&model.ArticleRevision{}).Where("article_id = ?", article.ID).Count(&count).Error)
+			assert.Equal(t, int64(0), count)
+		})
+	})
+}
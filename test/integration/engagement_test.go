@@ -0,0 +1,221 @@
+package integration
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngagementRoutes(t *testing.T) {
+	type session struct {
+		user        string
+		other       string
+		admin       string
+		sotoID      string
+		pepesID     string
+		otherUserID string
+	}
+
+	setup := func(t *testing.T) session {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		helper.ClearRecipes(test.DB)
+
+		user := fixture.UserWithFreemium()
+		other := fixture.UserWithPaidSubscription()
+		helper.InsertUser(test.DB, user, other, fixture.Admin)
+		assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+		assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, other.ID))
+
+		insertPlanRecipe(t, user.ID, "Soto ayam", model.MealLabelLunch, "ayam, bihun", 500)
+		insertPlanRecipe(t, user.ID, "Pepes ikan", model.MealLabelDinner, "ikan kembung, kemangi", 400)
+
+		var soto, pepes model.Recipe
+		assert.Nil(t, test.DB.Where("slug = ?", "soto-ayam").First(&soto).Error)
+		assert.Nil(t, test.DB.Where("slug = ?", "pepes-ikan").First(&pepes).Error)
+
+		userToken, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+		otherToken, err := fixture.AccessToken(other)
+		assert.Nil(t, err)
+		adminToken, err := fixture.AccessToken(fixture.Admin)
+		assert.Nil(t, err)
+
+		return session{
+			user:        userToken,
+			other:       otherToken,
+			admin:       adminToken,
+			sotoID:      soto.ID.String(),
+			pepesID:     pepes.ID.String(),
+			otherUserID: other.ID.String(),
+		}
+	}
+
+	send := func(t *testing.T, accessToken, method, url, body string) *http.Response {
+		var request *http.Request
+		if body == "" {
+			request = httptest.NewRequest(method, url, nil)
+		} else {
+			request = httptest.NewRequest(method, url, strings.NewReader(body))
+			request.Header.Set("Content-Type", "application/json")
+		}
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+		return apiResponse
+	}
+
+	decode := func(t *testing.T, apiResponse *http.Response, target interface{}) {
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+		assert.Nil(t, json.Unmarshal(bytes, target))
+	}
+
+	recipe := func(t *testing.T, recipeID string) model.Recipe {
+		var stored model.Recipe
+		assert.Nil(t, test.DB.Where("id = ?", recipeID).First(&stored).Error)
+		return stored
+	}
+
+	t.Run("PUT /v1/recipes/:id/rating", func(t *testing.T) {
+		t.Run("should keep the rating aggregates up to date", func(t *testing.T) {
+			s := setup(t)
+
+			assert.Equal(t, http.StatusOK, send(t, s.user, http.MethodPut, "/v1/recipes/"+s.sotoID+"/rating", `{"stars":5,"review":"Enak"}`).StatusCode)
+			assert.Equal(t, http.StatusOK, send(t, s.other, http.MethodPut, "/v1/recipes/"+s.sotoID+"/rating", `{"stars":2}`).StatusCode)
+
+			stored := recipe(t, s.sotoID)
+			assert.Equal(t, 3.5, stored.RatingAverage)
+			assert.Equal(t, 2, stored.RatingCount)
+
+			// Rating again replaces the previous rating
+			apiResponse := send(t, s.other, http.MethodPut, "/v1/recipes/"+s.sotoID+"/rating", `{"stars":4,"review":"Lumayan"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithReview)
+			decode(t, apiResponse, responseBody)
+			assert.Equal(t, 4, responseBody.Data.Stars)
+			assert.Equal(t, "Lumayan", *responseBody.Data.Review)
+			assert.Equal(t, "Paid User", responseBody.Data.UserName)
+
+			stored = recipe(t, s.sotoID)
+			assert.Equal(t, 4.5, stored.RatingAverage)
+			assert.Equal(t, 2, stored.RatingCount)
+
+			assert.Equal(t, http.StatusOK, send(t, s.other, http.MethodDelete, "/v1/recipes/"+s.sotoID+"/rating", "").StatusCode)
+			stored = recipe(t, s.sotoID)
+			assert.Equal(t, 5.0, stored.RatingAverage)
+			assert.Equal(t, 1, stored.RatingCount)
+		})
+
+		t.Run("should return 400 for stars out of range", func(t *testing.T) {
+			s := setup(t)
+
+			apiResponse := send(t, s.user, http.MethodPut, "/v1/recipes/"+s.sotoID+"/rating", `{"stars":6}`)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should return 404 for an unknown recipe", func(t *testing.T) {
+			s := setup(t)
+
+			apiResponse := send(t, s.user, http.MethodPut, "/v1/recipes/"+s.otherUserID+"/rating", `{"stars":4}`)
+
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/recipes?sort=rating", func(t *testing.T) {
+		t.Run("should list the best rated recipes first", func(t *testing.T) {
+			s := setup(t)
+			send(t, s.user, http.MethodPut, "/v1/recipes/"+s.sotoID+"/rating", `{"stars":2}`)
+			send(t, s.user, http.MethodPut, "/v1/recipes/"+s.pepesID+"/rating", `{"stars":5}`)
+
+			apiResponse := send(t, s.user, http.MethodGet, "/v1/recipes?sort=rating", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithPaginate[model.Recipe])
+			decode(t, apiResponse, responseBody)
+
+			assert.Len(t, responseBody.Results, 2)
+			assert.Equal(t, "Pepes ikan", responseBody.Results[0].Name)
+			assert.Equal(t, "Soto ayam", responseBody.Results[1].Name)
+		})
+	})
+
+	t.Run("POST /v1/recipes/:id/favorite", func(t *testing.T) {
+		t.Run("should add the recipe to my favorites once", func(t *testing.T) {
+			s := setup(t)
+
+			assert.Equal(t, http.StatusOK, send(t, s.user, http.MethodPost, "/v1/recipes/"+s.pepesID+"/favorite", "").StatusCode)
+			assert.Equal(t, http.StatusOK, send(t, s.user, http.MethodPost, "/v1/recipes/"+s.pepesID+"/favorite", "").StatusCode)
+			assert.Equal(t, 1, recipe(t, s.pepesID).FavoriteCount)
+
+			apiResponse := send(t, s.user, http.MethodGet, "/v1/favorites/recipes", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithPaginate[model.Recipe])
+			decode(t, apiResponse, responseBody)
+			assert.Equal(t, int64(1), responseBody.TotalResults)
+			assert.Equal(t, "Pepes ikan", responseBody.Results[0].Name)
+
+			// Another user's favorites are their own
+			apiResponse = send(t, s.other, http.MethodGet, "/v1/favorites/recipes", "")
+			otherBody := new(response.SuccessWithPaginate[model.Recipe])
+			decode(t, apiResponse, otherBody)
+			assert.Equal(t, int64(0), otherBody.TotalResults)
+
+			assert.Equal(t, http.StatusOK, send(t, s.user, http.MethodDelete, "/v1/recipes/"+s.pepesID+"/favorite", "").StatusCode)
+			assert.Equal(t, 0, recipe(t, s.pepesID).FavoriteCount)
+		})
+	})
+
+	t.Run("PATCH /v1/admin/reviews/:id/hide", func(t *testing.T) {
+		hideable := func(t *testing.T, s session) string {
+			send(t, s.user, http.MethodPut, "/v1/recipes/"+s.sotoID+"/rating", `{"stars":5,"review":"Enak"}`)
+			apiResponse := send(t, s.other, http.MethodPut, "/v1/recipes/"+s.sotoID+"/rating", `{"stars":1,"review":"Sampah"}`)
+			responseBody := new(response.SuccessWithReview)
+			decode(t, apiResponse, responseBody)
+			return responseBody.Data.ID.String()
+		}
+
+		t.Run("should hide the review from listings and aggregates", func(t *testing.T) {
+			s := setup(t)
+			reviewID := hideable(t, s)
+
+			apiResponse := send(t, s.admin, http.MethodPatch, "/v1/admin/reviews/"+reviewID+"/hide", `{"reason":"Kasar"}`)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			stored := recipe(t, s.sotoID)
+			assert.Equal(t, 5.0, stored.RatingAverage)
+			assert.Equal(t, 1, stored.RatingCount)
+
+			apiResponse = send(t, s.user, http.MethodGet, "/v1/recipes/"+s.sotoID+"/reviews", "")
+			responseBody := new(response.SuccessWithPaginate[model.UsersStar])
+			decode(t, apiResponse, responseBody)
+			assert.Equal(t, int64(1), responseBody.TotalResults)
+			assert.Equal(t, "Enak", *responseBody.Results[0].Review)
+
+			apiResponse = send(t, s.admin, http.MethodPatch, "/v1/admin/reviews/"+reviewID+"/unhide", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			assert.Equal(t, 2, recipe(t, s.sotoID).RatingCount)
+		})
+
+		t.Run("should return 403 for regular users", func(t *testing.T) {
+			s := setup(t)
+			reviewID := hideable(t, s)
+
+			apiResponse := send(t, s.user, http.MethodPatch, "/v1/admin/reviews/"+reviewID+"/hide", `{"reason":"Kasar"}`)
+
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+}
@@ -156,6 +156,7 @@ func TestFreemiumFlow(t *testing.T) {
 			IsActive:      true,
 			PaymentMethod: "credit_card",
 			PaymentStatus: "completed",
+			Status:        model.SubscriptionStatusActive,
 			AIscansUsed:   0,
 		}
 		test.DB.Create(paidSubscription)
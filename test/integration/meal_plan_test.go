@@ -0,0 +1,158 @@
+package integration
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func insertPlanRecipe(t *testing.T, userID uuid.UUID, name, label, ingredients string, calories float64) {
+	protein, carbs, fat := calories*0.2/4, calories*0.5/4, calories*0.3/9
+	recipe := &model.Recipe{
+		UserID:       userID,
+		Name:         name,
+		Slug:         strings.ReplaceAll(strings.ToLower(name), " ", "-"),
+		Description:  name,
+		Ingredients:  ingredients,
+		Instructions: "Masak sampai matang",
+		Label:        &label,
+		Day:          "monday",
+		Calories:     &calories,
+		Protein:      &protein,
+		Carbs:        &carbs,
+		Fat:          &fat,
+	}
+	assert.Nil(t, test.DB.Create(recipe).Error)
+}
+
+func TestMealPlanRoutes(t *testing.T) {
+	setup := func(t *testing.T) string {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		helper.ClearMeals(test.DB)
+		helper.ClearRecipes(test.DB)
+
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+		assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+
+		insertPlanRecipe(t, user.ID, "Bubur ayam", model.MealLabelBreakfast, "beras, ayam", 350)
+		insertPlanRecipe(t, user.ID, "Nasi uduk", model.MealLabelBreakfast, "beras, santan, telur", 450)
+		insertPlanRecipe(t, user.ID, "Soto ayam", model.MealLabelLunch, "ayam, bihun", 500)
+		insertPlanRecipe(t, user.ID, "Udang balado", model.MealLabelDinner, "udang, cabai", 450)
+		insertPlanRecipe(t, user.ID, "Pepes ikan", model.MealLabelDinner, "ikan kembung, kemangi", 400)
+
+		accessToken, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+		return accessToken
+	}
+
+	send := func(t *testing.T, accessToken, method, url, body string) *http.Response {
+		request := httptest.NewRequest(method, url, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+		request.Header.Set("X-Timezone", "Asia/Jakarta")
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+		return apiResponse
+	}
+
+	generate := func(t *testing.T, accessToken, body string) *model.MealPlan {
+		apiResponse := send(t, accessToken, http.MethodPost, "/v1/meal-plans", body)
+		assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+
+		responseBody := new(response.SuccessWithMealPlan)
+		assert.Nil(t, json.Unmarshal(bytes, responseBody))
+		return &responseBody.Data
+	}
+
+	t.Run("POST /v1/meal-plans", func(t *testing.T) {
+		t.Run("should plan a week without excluded ingredients", func(t *testing.T) {
+			accessToken := setup(t)
+
+			plan := generate(t, accessToken, `{"excluded_ingredients": ["Udang"]}`)
+
+			assert.Len(t, plan.Slots, 21)
+			for _, slot := range plan.Slots {
+				assert.NotNil(t, slot.Recipe)
+				assert.NotEqual(t, "Udang balado", slot.Recipe.Name)
+			}
+		})
+
+		t.Run("should return 422 when every recipe is excluded", func(t *testing.T) {
+			accessToken := setup(t)
+
+			apiResponse := send(t, accessToken, http.MethodPost, "/v1/meal-plans", `{"excluded_ingredients": ["a"]}`)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/meal-plans/:planId/slots/:slotId/swap", func(t *testing.T) {
+		t.Run("should replace the slot's recipe", func(t *testing.T) {
+			accessToken := setup(t)
+			plan := generate(t, accessToken, "")
+			slot := plan.Slots[0]
+
+			apiResponse := send(t, accessToken, http.MethodPost, "/v1/meal-plans/"+plan.ID.String()+"/slots/"+slot.ID.String()+"/swap", "")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+			responseBody := new(response.SuccessWithMealPlanSlot)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.NotEqual(t, slot.RecipeID, responseBody.Data.RecipeID)
+		})
+	})
+
+	t.Run("POST /v1/meal-plans/:planId/slots/:slotId/log", func(t *testing.T) {
+		t.Run("should log today's slot once as a meal", func(t *testing.T) {
+			accessToken := setup(t)
+			plan := generate(t, accessToken, "")
+			slot := plan.Slots[1]
+			url := "/v1/meal-plans/" + plan.ID.String() + "/slots/" + slot.ID.String() + "/log"
+
+			apiResponse := send(t, accessToken, http.MethodPost, url, "")
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+			responseBody := new(response.SuccessWithMeal)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.Equal(t, slot.Recipe.Name, responseBody.Meal.Title)
+			assert.Equal(t, slot.Calories, responseBody.Meal.Calories)
+
+			logged := new(model.MealPlanSlot)
+			assert.Nil(t, test.DB.First(logged, "id = ?", slot.ID).Error)
+			assert.Equal(t, responseBody.Meal.ID, *logged.MealHistoryID)
+
+			apiResponse = send(t, accessToken, http.MethodPost, url, "")
+			assert.Equal(t, http.StatusConflict, apiResponse.StatusCode)
+		})
+
+		t.Run("should refuse slots planned for a later day", func(t *testing.T) {
+			accessToken := setup(t)
+			plan := generate(t, accessToken, "")
+			slot := plan.Slots[len(plan.Slots)-1]
+
+			apiResponse := send(t, accessToken, http.MethodPost, "/v1/meal-plans/"+plan.ID.String()+"/slots/"+slot.ID.String()+"/log", "")
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+}
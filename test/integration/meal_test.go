@@ -0,0 +1,287 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newScanRequest(t *testing.T, target, accessToken string) *http.Request {
+	return newScanUploadRequest(t, target, accessToken, "meal.png", pngImage(t, 8, 8))
+}
+
+func newScanUploadRequest(t *testing.T, target, accessToken, filename string, data []byte) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("image", filename)
+	assert.Nil(t, err)
+	_, err = part.Write(data)
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+
+	request := httptest.NewRequest(http.MethodPost, target, body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	return request
+}
+
+func TestMealScanRoutes(t *testing.T) {
+	// Scanned images are written inside the test UPLOAD_DIR
+	t.Cleanup(func() { _ = os.RemoveAll(service.MealImageDir()) })
+
+	t.Run("POST /v1/meals/scan", func(t *testing.T) {
+		t.Run("should return 200 and save the scanned meal using the configured recognizer", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearSubscriptions(test.DB)
+			helper.ClearMeals(test.DB)
+
+			user := fixture.UserWithFreemium()
+			helper.InsertUser(test.DB, user)
+			assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+
+			accessToken, err := fixture.AccessToken(user)
+			assert.Nil(t, err)
+
+			apiResponse, err := test.App.Test(newScanRequest(t, "/v1/meals/scan", accessToken))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(service.MealScanResponse)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+			expected := service.NewFakeFoodRecognizer()
+			assert.Len(t, responseBody.Foods, len(expected.Recognition.Segments))
+			assert.Equal(t, expected.Nutrition.Calories.Quantity, responseBody.TotalNutr.Calories.Quantity)
+
+			var meals []model.MealHistory
+			test.DB.Where("user_id = ?", user.ID).Find(&meals)
+			assert.Len(t, meals, 1)
+			assert.True(t, strings.HasPrefix(meals[0].MealImage, "/uploads/meals/"))
+			// The URL points at the file inside UPLOAD_DIR, where the uploads route serves it from
+			_, err = os.Stat(filepath.Join(config.UploadDir, strings.TrimPrefix(meals[0].MealImage, "/uploads/")))
+			assert.Nil(t, err)
+		})
+
+		t.Run("should return 202 with a job that completes in the background when async is set", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearSubscriptions(test.DB)
+			helper.ClearMeals(test.DB)
+
+			user := fixture.UserWithFreemium()
+			helper.InsertUser(test.DB, user)
+			assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+
+			accessToken, err := fixture.AccessToken(user)
+			assert.Nil(t, err)
+
+			apiResponse, err := test.App.Test(newScanRequest(t, "/v1/meals/scan?async=true", accessToken))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusAccepted, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithMealScanJob)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.Equal(t, model.ScanJobQueued, responseBody.Job.Status)
+
+			job := new(model.MealScanJob)
+			assert.Eventually(t, func() bool {
+				request := httptest.NewRequest(http.MethodGet, "/v1/meals/scan/jobs/"+responseBody.Job.ID.String(), nil)
+				request.Header.Set("Authorization", "Bearer "+accessToken)
+
+				apiResponse, err := test.App.Test(request)
+				if err != nil || apiResponse.StatusCode != http.StatusOK {
+					return false
+				}
+
+				polled := new(response.SuccessWithMealScanJob)
+				bytes, _ := io.ReadAll(apiResponse.Body)
+				if err := json.Unmarshal(bytes, polled); err != nil {
+					return false
+				}
+
+				*job = polled.Job
+				return job.Status == model.ScanJobCompleted
+			}, 5*time.Second, 100*time.Millisecond)
+
+			assert.NotNil(t, job.MealHistoryID)
+			assert.Equal(t, 1, job.Attempts)
+
+			var meals []model.MealHistory
+			test.DB.Where("user_id = ?", user.ID).Find(&meals)
+			assert.Len(t, meals, 1)
+		})
+
+		t.Run("should return 415 and store nothing if the file isn't an image", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearSubscriptions(test.DB)
+			helper.ClearMeals(test.DB)
+
+			user := fixture.UserWithFreemium()
+			helper.InsertUser(test.DB, user)
+			assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+
+			accessToken, err := fixture.AccessToken(user)
+			assert.Nil(t, err)
+
+			page := []byte("<html><script>alert(document.cookie)</script></html>")
+			apiResponse, err := test.App.Test(newScanUploadRequest(t, "/v1/meals/scan", accessToken, "meal.html", page))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusUnsupportedMediaType, apiResponse.StatusCode)
+
+			var meals []model.MealHistory
+			test.DB.Where("user_id = ?", user.ID).Find(&meals)
+			assert.Empty(t, meals)
+
+			stored, _ := os.ReadDir(service.MealImageDir())
+			for _, entry := range stored {
+				assert.NotEqual(t, ".html", filepath.Ext(entry.Name()))
+			}
+		})
+
+		t.Run("should return 400 if image is missing", func(t *testing.T) {
+			helper.ClearAll(test.DB)
+			helper.ClearSubscriptions(test.DB)
+
+			user := fixture.UserWithFreemium()
+			helper.InsertUser(test.DB, user)
+			assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+
+			accessToken, err := fixture.AccessToken(user)
+			assert.Nil(t, err)
+
+			request := httptest.NewRequest(http.MethodPost, "/v1/meals/scan", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+}
+
+func TestConfirmMealScanRoutes(t *testing.T) {
+	t.Cleanup(func() { _ = os.RemoveAll(service.MealImageDir()) })
+
+	scanMeal := func(t *testing.T) (string, model.MealHistory) {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		helper.ClearMeals(test.DB)
+
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+		assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+
+		accessToken, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+
+		apiResponse, err := test.App.Test(newScanRequest(t, "/v1/meals/scan", accessToken))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		meal := model.MealHistory{}
+		assert.Nil(t, test.DB.Where("user_id = ?", user.ID).First(&meal).Error)
+		return accessToken, meal
+	}
+
+	newConfirmRequest := func(t *testing.T, mealID, accessToken string, body validation.ConfirmMealScan) *http.Request {
+		bodyJSON, err := json.Marshal(body)
+		assert.Nil(t, err)
+
+		request := httptest.NewRequest(http.MethodPost, "/v1/meals/"+mealID+"/scan-detail/confirm", strings.NewReader(string(bodyJSON)))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+		return request
+	}
+
+	t.Run("POST /v1/meals/:mealId/scan-detail/confirm", func(t *testing.T) {
+		t.Run("should return 200 and recompute nutrients from the selected foods and portions", func(t *testing.T) {
+			accessToken, meal := scanMeal(t)
+
+			apiResponse, err := test.App.Test(newConfirmRequest(t, meal.ID.String(), accessToken, validation.ConfirmMealScan{
+				Selections: []validation.MealScanSelection{
+					{Segment: 0, Name: "fried rice", Grams: 200},
+					{Segment: 1, Name: "fried chicken", Grams: 100},
+				},
+			}))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithMeal)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.Equal(t, "fried rice, fried chicken", responseBody.Meal.Title)
+			assert.InDelta(t, 163*2+246, responseBody.Meal.Calories, 0.01)
+			assert.Equal(t, meal.MealImage, responseBody.Meal.MealImage)
+		})
+
+		t.Run("should keep sodium and iron in the meal nutrients", func(t *testing.T) {
+			accessToken, meal := scanMeal(t)
+
+			apiResponse, err := test.App.Test(newConfirmRequest(t, meal.ID.String(), accessToken, validation.ConfirmMealScan{
+				Selections: []validation.MealScanSelection{
+					{Segment: 0, Name: "fried rice", Grams: 200},
+				},
+			}))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/meals/"+meal.ID.String(), nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err = test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+
+			responseBody := new(response.SuccessWithMeal)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+			codes := make(map[string]float64)
+			for _, nutrient := range responseBody.Meal.Nutrients {
+				codes[nutrient.Code] = nutrient.Quantity
+			}
+			assert.InDelta(t, 163*2, codes[model.NutrientEnergy], 0.01)
+			assert.InDelta(t, 400*2, codes[model.NutrientSodium], 0.01)
+			assert.InDelta(t, 0.9*2, codes[model.NutrientIron], 0.01)
+		})
+
+		t.Run("should return 400 if the food is not a candidate of the segment", func(t *testing.T) {
+			accessToken, meal := scanMeal(t)
+
+			apiResponse, err := test.App.Test(newConfirmRequest(t, meal.ID.String(), accessToken, validation.ConfirmMealScan{
+				Selections: []validation.MealScanSelection{
+					{Segment: 0, Name: "fried chicken", Grams: 100},
+				},
+			}))
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+}
@@ -0,0 +1,127 @@
+package integration
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func insertSummaryMeal(t *testing.T, userID uuid.UUID, mealTime time.Time, calories, sodium float64) {
+	meal := &model.MealHistory{
+		UserID:   userID,
+		Title:    "Nasi goreng",
+		MealTime: mealTime,
+		Calories: calories,
+		Nutrients: []model.MealHistoryNutrient{
+			{Code: model.NutrientEnergy, Quantity: calories, Unit: "kcal"},
+			{Code: model.NutrientSodium, Quantity: sodium, Unit: "mg"},
+		},
+	}
+	assert.Nil(t, test.DB.Create(meal).Error)
+}
+
+func TestNutritionSummaryRoutes(t *testing.T) {
+	setup := func(t *testing.T) (string, *model.User) {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		helper.ClearMeals(test.DB)
+
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+		assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+
+		accessToken, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+		return accessToken, user
+	}
+
+	getSummary := func(t *testing.T, accessToken, query string) *response.SuccessWithNutritionSummary {
+		request := httptest.NewRequest(http.MethodGet, "/v1/nutrition/summary?"+query, nil)
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+		request.Header.Set("X-Timezone", "Asia/Jakarta")
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+
+		responseBody := new(response.SuccessWithNutritionSummary)
+		assert.Nil(t, json.Unmarshal(bytes, responseBody))
+		return responseBody
+	}
+
+	amount := func(bucket model.NutritionBucket, code string) float64 {
+		for _, nutrient := range bucket.Nutrients {
+			if nutrient.Code == code {
+				return nutrient.Quantity
+			}
+		}
+		return -1
+	}
+
+	t.Run("GET /v1/nutrition/summary", func(t *testing.T) {
+		t.Run("should group meals per local day and fill empty days with zeros", func(t *testing.T) {
+			accessToken, user := setup(t)
+
+			// 23:30 UTC on the 10th is already 06:30 on the 11th in Jakarta
+			insertSummaryMeal(t, user.ID, time.Date(2026, 10, 10, 23, 30, 0, 0, time.UTC), 400, 600)
+			insertSummaryMeal(t, user.ID, time.Date(2026, 10, 11, 5, 0, 0, 0, time.UTC), 700, 900)
+
+			body := getSummary(t, accessToken, "from=2026-10-10&to=2026-10-12&bucket=day")
+
+			assert.Equal(t, "Asia/Jakarta", body.Data.Timezone)
+			assert.Len(t, body.Data.Buckets, 3)
+			assert.Equal(t, 0, body.Data.Buckets[0].Meals)
+			assert.Equal(t, 0.0, amount(body.Data.Buckets[0], model.NutrientEnergy))
+			assert.Equal(t, 2, body.Data.Buckets[1].Meals)
+			assert.Equal(t, 1100.0, amount(body.Data.Buckets[1], model.NutrientEnergy))
+			assert.Equal(t, 1500.0, amount(body.Data.Buckets[1], model.NutrientSodium))
+			assert.Equal(t, 0.0, amount(body.Data.Buckets[2], model.NutrientIron))
+		})
+
+		t.Run("should compare weekly buckets with the targets of their days", func(t *testing.T) {
+			accessToken, user := setup(t)
+
+			insertSummaryMeal(t, user.ID, time.Date(2026, 10, 14, 5, 0, 0, 0, time.UTC), 500, 3000)
+
+			// The 14th is a Wednesday, so the first week only has 5 days in range
+			body := getSummary(t, accessToken, "from=2026-10-14&to=2026-10-25&bucket=week")
+
+			assert.Len(t, body.Data.Buckets, 2)
+			assert.Equal(t, 5, body.Data.Buckets[0].Days)
+			assert.Equal(t, 7, body.Data.Buckets[1].Days)
+
+			for _, nutrient := range body.Data.Buckets[0].Nutrients {
+				if nutrient.Code == model.NutrientSodium {
+					assert.InDelta(t, 2000*5, *nutrient.Target, 0.1)
+					assert.True(t, *nutrient.OnTarget)
+				}
+			}
+			assert.Nil(t, body.Data.Buckets[1].Nutrients[0].OnTarget)
+		})
+
+		t.Run("should return 400 if from is after to", func(t *testing.T) {
+			accessToken, _ := setup(t)
+
+			request := httptest.NewRequest(http.MethodGet, "/v1/nutrition/summary?from=2026-10-12&to=2026-10-10", nil)
+			request.Header.Set("Authorization", "Bearer "+accessToken)
+
+			apiResponse, err := test.App.Test(request)
+			assert.Nil(t, err)
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+}
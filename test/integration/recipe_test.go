@@ -0,0 +1,150 @@
+package integration
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecipeRoutes(t *testing.T) {
+	setup := func(t *testing.T) string {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		helper.ClearRecipes(test.DB)
+
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+		assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+
+		insertPlanRecipe(t, user.ID, "Bubur ayam", model.MealLabelBreakfast, "beras, ayam", 350)
+		insertPlanRecipe(t, user.ID, "Soto ayam", model.MealLabelLunch, "ayam, bihun", 500)
+		insertPlanRecipe(t, user.ID, "Udang balado", model.MealLabelDinner, "udang, cabai", 450)
+		insertPlanRecipe(t, user.ID, "Pepes ikan", model.MealLabelDinner, "ikan kembung, kemangi", 400)
+
+		accessToken, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+		return accessToken
+	}
+
+	get := func(t *testing.T, accessToken, url string) *http.Response {
+		request := httptest.NewRequest(http.MethodGet, url, nil)
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+		return apiResponse
+	}
+
+	search := func(t *testing.T, accessToken, query string) *response.SuccessWithPaginate[model.Recipe] {
+		apiResponse := get(t, accessToken, "/v1/recipes?"+query)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+
+		responseBody := new(response.SuccessWithPaginate[model.Recipe])
+		assert.Nil(t, json.Unmarshal(bytes, responseBody))
+		return responseBody
+	}
+
+	names := func(recipes []model.Recipe) []string {
+		result := make([]string, 0, len(recipes))
+		for _, recipe := range recipes {
+			result = append(result, recipe.Name)
+		}
+		return result
+	}
+
+	t.Run("GET /v1/recipes", func(t *testing.T) {
+		t.Run("should page through recipes", func(t *testing.T) {
+			accessToken := setup(t)
+
+			page := search(t, accessToken, "page=2&limit=3&sort=name")
+
+			assert.Equal(t, int64(4), page.TotalResults)
+			assert.Equal(t, int64(2), page.TotalPages)
+			assert.Equal(t, []string{"Udang balado"}, names(page.Results))
+		})
+
+		t.Run("should search names and ingredients", func(t *testing.T) {
+			accessToken := setup(t)
+
+			page := search(t, accessToken, "search=ayam&sort=name")
+
+			assert.Equal(t, []string{"Bubur ayam", "Soto ayam"}, names(page.Results))
+		})
+
+		t.Run("should filter by label and calories", func(t *testing.T) {
+			accessToken := setup(t)
+
+			page := search(t, accessToken, "label=dinner&max_calories=420")
+
+			assert.Equal(t, []string{"Pepes ikan"}, names(page.Results))
+		})
+
+		t.Run("should filter by contained and excluded ingredients", func(t *testing.T) {
+			accessToken := setup(t)
+
+			assert.Equal(t, []string{"Udang balado"}, names(search(t, accessToken, "contains=udang").Results))
+			assert.Equal(t, []string{"Pepes ikan", "Udang balado"}, names(search(t, accessToken, "excludes=ayam&sort=name").Results))
+		})
+
+		t.Run("should match ingredients as whole words", func(t *testing.T) {
+			accessToken := setup(t)
+			var bubur model.Recipe
+			assert.Nil(t, test.DB.First(&bubur, "name = ?", "Bubur ayam").Error)
+			insertPlanRecipe(t, bubur.UserID, "Sayur bayam", model.MealLabelLunch, "bayam, jagung", 150)
+
+			assert.Equal(t, []string{"Bubur ayam", "Soto ayam"}, names(search(t, accessToken, "contains=ayam&sort=name").Results))
+			assert.Equal(t, []string{"Pepes ikan", "Sayur bayam", "Udang balado"}, names(search(t, accessToken, "excludes=ayam&sort=name").Results))
+		})
+
+		t.Run("should sort by calories", func(t *testing.T) {
+			accessToken := setup(t)
+
+			page := search(t, accessToken, "sort=calories_desc")
+
+			assert.Equal(t, []string{"Soto ayam", "Udang balado", "Pepes ikan", "Bubur ayam"}, names(page.Results))
+		})
+
+		t.Run("should return 400 for an unknown sort", func(t *testing.T) {
+			accessToken := setup(t)
+
+			apiResponse := get(t, accessToken, "/v1/recipes?sort=random")
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("GET /v1/recipes/slug/:slug", func(t *testing.T) {
+		t.Run("should return the recipe with that slug", func(t *testing.T) {
+			accessToken := setup(t)
+
+			apiResponse := get(t, accessToken, "/v1/recipes/slug/soto-ayam")
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+			responseBody := new(response.SuccessWithRecipe)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+			assert.Equal(t, "Soto ayam", responseBody.Data.Name)
+		})
+
+		t.Run("should return 404 for an unknown slug", func(t *testing.T) {
+			accessToken := setup(t)
+
+			apiResponse := get(t, accessToken, "/v1/recipes/slug/rendang")
+
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+}
@@ -0,0 +1,258 @@
+package integration
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/response"
+	"app/src/service"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func pngImage(t *testing.T, width, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 120, A: 255})
+		}
+	}
+
+	var buffer bytes.Buffer
+	assert.Nil(t, png.Encode(&buffer, img))
+	return buffer.Bytes()
+}
+
+func TestUploadRoutes(t *testing.T) {
+	type session struct {
+		reader   string
+		readerID uuid.UUID
+		editor   string
+		editorID uuid.UUID
+	}
+
+	setup := func(t *testing.T) session {
+		helper.ClearAll(test.DB)
+		helper.ClearArticles(test.DB)
+		helper.ClearUploads(test.DB)
+		assert.Nil(t, os.RemoveAll(config.UploadDir))
+
+		reader := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, reader, fixture.Admin)
+
+		readerToken, err := fixture.AccessToken(reader)
+		assert.Nil(t, err)
+		editorToken, err := fixture.AccessToken(fixture.Admin)
+		assert.Nil(t, err)
+		return session{reader: readerToken, readerID: reader.ID, editor: editorToken, editorID: fixture.Admin.ID}
+	}
+
+	send := func(t *testing.T, accessToken, url string, fields map[string]string, filename string, data []byte) *http.Response {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		for name, value := range fields {
+			assert.Nil(t, writer.WriteField(name, value))
+		}
+		if data != nil {
+			part, err := writer.CreateFormFile("file", filename)
+			assert.Nil(t, err)
+			_, err = part.Write(data)
+			assert.Nil(t, err)
+		}
+		assert.Nil(t, writer.Close())
+
+		request := httptest.NewRequest(http.MethodPost, url, &body)
+		request.Header.Set("Content-Type", writer.FormDataContentType())
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+
+		apiResponse, err := test.App.Test(request, -1)
+		assert.Nil(t, err)
+		return apiResponse
+	}
+
+	decode := func(t *testing.T, apiResponse *http.Response, target interface{}) {
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+		assert.Nil(t, json.Unmarshal(bytes, target))
+	}
+
+	uploadFile := func(key string) string {
+		return filepath.Join(config.UploadDir, filepath.FromSlash(key))
+	}
+
+	t.Run("POST /v1/uploads/images", func(t *testing.T) {
+		t.Run("should store the image with its thumbnail and WebP variants", func(t *testing.T) {
+			s := setup(t)
+
+			apiResponse := send(t, s.editor, "/v1/uploads/images", map[string]string{"purpose": "article"}, "sarapan.png", pngImage(t, 640, 480))
+
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithUpload)
+			decode(t, apiResponse, responseBody)
+			assert.Equal(t, model.UploadPurposeArticle, responseBody.Data.Purpose)
+			assert.Equal(t, 640, responseBody.Data.Width)
+
+			variants := make(map[string]model.UploadVariant)
+			for _, variant := range responseBody.Data.Variants {
+				variants[variant.Name] = variant
+			}
+			assert.Len(t, variants, 4)
+			assert.Equal(t, "image/jpeg", variants[service.ImageVariantOriginal].ContentType)
+			assert.Equal(t, service.ThumbnailDimension, variants[service.ImageVariantThumbnail].Width)
+			assert.Equal(t, 240, variants[service.ImageVariantThumbnail].Height)
+			assert.Equal(t, "image/webp", variants[service.ImageVariantThumbnailWebP].ContentType)
+
+			stored := new(model.Upload)
+			assert.Nil(t, test.DB.Preload("Variants").First(stored, "id = ?", responseBody.Data.ID).Error)
+			assert.Len(t, stored.Variants, 4)
+			for _, variant := range stored.Variants {
+				assert.True(t, strings.HasPrefix(variant.URL, config.UploadBaseURL+"/articles/"))
+				assert.FileExists(t, uploadFile(variant.Key))
+			}
+		})
+
+		t.Run("should reject files that aren't images", func(t *testing.T) {
+			s := setup(t)
+
+			apiResponse := send(t, s.editor, "/v1/uploads/images", map[string]string{"purpose": "article"}, "foto.png", []byte("bukan gambar"))
+
+			assert.Equal(t, http.StatusUnsupportedMediaType, apiResponse.StatusCode)
+			var count int64
+			test.DB.Model(&model.Upload{}).Count(&count)
+			assert.Zero(t, count)
+		})
+
+		t.Run("should reject images larger than the limit", func(t *testing.T) {
+			s := setup(t)
+			data := append(pngImage(t, 8, 8), make([]byte, (config.UploadMaxSizeMB+1)*1024*1024)...)
+
+			apiResponse := send(t, s.editor, "/v1/uploads/images", map[string]string{"purpose": "recipe"}, "besar.png", data)
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, apiResponse.StatusCode)
+		})
+
+		t.Run("should require a known purpose", func(t *testing.T) {
+			s := setup(t)
+
+			apiResponse := send(t, s.editor, "/v1/uploads/images", map[string]string{"purpose": "banner"}, "foto.png", pngImage(t, 8, 8))
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+		})
+
+		t.Run("should be forbidden to readers", func(t *testing.T) {
+			s := setup(t)
+
+			apiResponse := send(t, s.reader, "/v1/uploads/images", map[string]string{"purpose": "article"}, "foto.png", pngImage(t, 8, 8))
+
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/uploads/profile-picture", func(t *testing.T) {
+		t.Run("should update the profile picture", func(t *testing.T) {
+			s := setup(t)
+
+			apiResponse := send(t, s.reader, "/v1/uploads/profile-picture", nil, "saya.png", pngImage(t, 100, 100))
+
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithUpload)
+			decode(t, apiResponse, responseBody)
+
+			user := new(model.User)
+			assert.Nil(t, test.DB.First(user, "id = ?", s.readerID).Error)
+			assert.Equal(t, responseBody.Data.Variants[0].URL, user.ProfilePicture)
+			assert.True(t, strings.Contains(user.ProfilePicture, "/profiles/"))
+		})
+	})
+
+	t.Run("POST /v1/admin/uploads/collect", func(t *testing.T) {
+		upload := func(t *testing.T, s session) *model.Upload {
+			apiResponse := send(t, s.editor, "/v1/uploads/images", map[string]string{"purpose": "article"}, "foto.png", pngImage(t, 64, 64))
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithUpload)
+			decode(t, apiResponse, responseBody)
+
+			stored := new(model.Upload)
+			assert.Nil(t, test.DB.Preload("Variants").First(stored, "id = ?", responseBody.Data.ID).Error)
+			return stored
+		}
+
+		collect := func(t *testing.T, s session) model.UploadGCReport {
+			apiResponse := send(t, s.editor, "/v1/admin/uploads/collect", nil, "", nil)
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithUploadGCReport)
+			decode(t, apiResponse, responseBody)
+			return responseBody.Data
+		}
+
+		t.Run("should delete old uploads nothing links to", func(t *testing.T) {
+			s := setup(t)
+			linked := upload(t, s)
+			orphan := upload(t, s)
+			recent := upload(t, s)
+			old := time.Now().Add(-time.Duration(config.UploadGraceHours+1) * time.Hour)
+			assert.Nil(t, test.DB.Model(&model.Upload{}).Where("id IN ?", []uuid.UUID{linked.ID, orphan.ID}).Update("created_at", old).Error)
+			published := time.Now()
+			article := insertArticle(t, s.editorID, "Sarapan sehat", model.ArticleStatusPublished, &published)
+			assert.Nil(t, test.DB.Model(article).Update("image", linked.Variants[1].URL).Error)
+
+			report := collect(t, s)
+
+			assert.Equal(t, 1, report.Uploads)
+			assert.Equal(t, 4, report.Files)
+			var remaining []uuid.UUID
+			test.DB.Model(&model.Upload{}).Pluck("id", &remaining)
+			assert.ElementsMatch(t, []uuid.UUID{linked.ID, recent.ID}, remaining)
+			for _, variant := range orphan.Variants {
+				assert.NoFileExists(t, uploadFile(variant.Key))
+			}
+			for _, variant := range linked.Variants {
+				assert.FileExists(t, uploadFile(variant.Key))
+			}
+		})
+
+		t.Run("should delete old files without an upload", func(t *testing.T) {
+			s := setup(t)
+			stray := uploadFile("recipes/" + uuid.NewString() + "/original.jpg")
+			fresh := uploadFile("recipes/" + uuid.NewString() + "/original.jpg")
+			for _, file := range []string{stray, fresh} {
+				assert.Nil(t, os.MkdirAll(filepath.Dir(file), 0o755))
+				assert.Nil(t, os.WriteFile(file, []byte("sisa"), 0o644))
+			}
+			old := time.Now().Add(-time.Duration(config.UploadGraceHours+1) * time.Hour)
+			assert.Nil(t, os.Chtimes(stray, old, old))
+
+			report := collect(t, s)
+
+			assert.Equal(t, 0, report.Uploads)
+			assert.Equal(t, 1, report.Files)
+			assert.NoFileExists(t, stray)
+			assert.FileExists(t, fresh)
+		})
+
+		t.Run("should be forbidden to readers", func(t *testing.T) {
+			s := setup(t)
+
+			apiResponse := send(t, s.reader, "/v1/admin/uploads/collect", nil, "", nil)
+
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+}
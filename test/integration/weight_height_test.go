@@ -0,0 +1,243 @@
+package integration
+
+import (
+	"app/src/model"
+	"app/src/response"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightHeightTargetRoutes(t *testing.T) {
+	type session struct {
+		token  string
+		userID uuid.UUID
+	}
+
+	setup := func(t *testing.T) session {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		helper.ClearWeightHeights(test.DB)
+
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+		assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+
+		token, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+		return session{token: token, userID: user.ID}
+	}
+
+	send := func(t *testing.T, accessToken, method, url, body string) *http.Response {
+		var request *http.Request
+		if body == "" {
+			request = httptest.NewRequest(method, url, nil)
+		} else {
+			request = httptest.NewRequest(method, url, strings.NewReader(body))
+			request.Header.Set("Content-Type", "application/json")
+		}
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+		return apiResponse
+	}
+
+	decode := func(t *testing.T, apiResponse *http.Response, target interface{}) {
+		bytes, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+		assert.Nil(t, json.Unmarshal(bytes, target))
+	}
+
+	insertRecord := func(t *testing.T, userID uuid.UUID, weight float64, recordedAt time.Time) {
+		record := &model.UsersWeightHeightHistory{UserID: userID, Weight: weight, Height: 170, RecordedAt: recordedAt}
+		assert.Nil(t, test.DB.Create(record).Error)
+	}
+
+	t.Run("POST /v1/weight-height/target", func(t *testing.T) {
+		t.Run("should keep the target date", func(t *testing.T) {
+			s := setup(t)
+			targetDate := time.Now().AddDate(0, 3, 0).UTC().Truncate(time.Second)
+
+			apiResponse := send(t, s.token, http.MethodPost, "/v1/weight-height/target",
+				`{"weight": 65, "height": 170, "target_date": "`+targetDate.Format(time.RFC3339)+`"}`)
+
+			assert.Equal(t, http.StatusCreated, apiResponse.StatusCode)
+			stored := new(model.UsersWeightHeightTarget)
+			assert.Nil(t, test.DB.First(stored, "user_id = ?", s.userID).Error)
+			assert.True(t, targetDate.Equal(stored.TargetDate), "target date %s stored as %s", targetDate, stored.TargetDate)
+		})
+	})
+
+	t.Run("GET /v1/weight-height/target/progress", func(t *testing.T) {
+		t.Run("should compare the records since the target was set", func(t *testing.T) {
+			s := setup(t)
+			started := time.Now().AddDate(0, 0, -28)
+			target := &model.UsersWeightHeightTarget{
+				UserID:        s.userID,
+				Weight:        66,
+				Height:        170,
+				WeightHistory: 70,
+				HeightHistory: 170,
+				TargetDate:    time.Now().AddDate(0, 0, 56),
+				RecordDate:    started,
+			}
+			assert.Nil(t, test.DB.Create(target).Error)
+			insertRecord(t, s.userID, 72, started.AddDate(0, 0, -7))
+			for week, weight := range []float64{69.5, 69, 68.5, 68} {
+				insertRecord(t, s.userID, weight, started.AddDate(0, 0, 7*(week+1)))
+			}
+
+			apiResponse := send(t, s.token, http.MethodGet, "/v1/weight-height/target/progress", "")
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithWeightProgress)
+			decode(t, apiResponse, responseBody)
+			progress := responseBody.Data
+			assert.Equal(t, target.ID, progress.TargetID)
+			// The record before the target was set isn't part of the progress
+			assert.Equal(t, 4, progress.Records)
+			assert.Equal(t, 68.0, progress.CurrentWeight)
+			assert.Equal(t, 50.0, progress.PercentComplete)
+			assert.InDelta(t, -0.5, *progress.WeeklyRate, 0.01)
+			assert.Equal(t, model.WeightTrendLosing, progress.Trend)
+			assert.NotNil(t, progress.ProjectedDate)
+			assert.True(t, progress.OnTrack)
+			assert.True(t, progress.Safe)
+		})
+
+		t.Run("should flag an unsafe target", func(t *testing.T) {
+			s := setup(t)
+			target := &model.UsersWeightHeightTarget{
+				UserID:        s.userID,
+				Weight:        50,
+				Height:        170,
+				WeightHistory: 70,
+				TargetDate:    time.Now().AddDate(0, 0, 30),
+				RecordDate:    time.Now(),
+			}
+			assert.Nil(t, test.DB.Create(target).Error)
+
+			apiResponse := send(t, s.token, http.MethodGet, "/v1/weight-height/target/progress", "")
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			responseBody := new(response.SuccessWithWeightProgress)
+			decode(t, apiResponse, responseBody)
+			assert.False(t, responseBody.Data.Safe)
+			codes := []string{}
+			for _, warning := range responseBody.Data.Warnings {
+				codes = append(codes, warning.Code)
+			}
+			assert.ElementsMatch(t, []string{model.WeightWarningLossTooFast, model.WeightWarningUnderweight}, codes)
+		})
+
+		t.Run("should return 404 without a target", func(t *testing.T) {
+			s := setup(t)
+
+			apiResponse := send(t, s.token, http.MethodGet, "/v1/weight-height/target/progress", "")
+
+			assert.Equal(t, http.StatusNotFound, apiResponse.StatusCode)
+		})
+	})
+}
+
+func TestBodyMetricsRoutes(t *testing.T) {
+	setup := func(t *testing.T, planName string) (string, uuid.UUID) {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		helper.ClearWeightHeights(test.DB)
+
+		user := fixture.UserWithFreemium()
+		gender := model.Female
+		user.Gender = &gender
+		helper.InsertUser(test.DB, user)
+		if planName == "" {
+			assert.Nil(t, helper.CreateFreemiumSubscription(test.DB, user.ID))
+		} else {
+			assert.Nil(t, helper.CreateSubscription(test.DB, user.ID, planName))
+		}
+
+		token, err := fixture.AccessToken(user)
+		assert.Nil(t, err)
+		return token, user.ID
+	}
+
+	send := func(t *testing.T, accessToken, method, url, body string) *http.Response {
+		var request *http.Request
+		if body == "" {
+			request = httptest.NewRequest(method, url, nil)
+		} else {
+			request = httptest.NewRequest(method, url, strings.NewReader(body))
+			request.Header.Set("Content-Type", "application/json")
+		}
+		request.Header.Set("Authorization", "Bearer "+accessToken)
+
+		apiResponse, err := test.App.Test(request)
+		assert.Nil(t, err)
+		return apiResponse
+	}
+
+	t.Run("GET /v1/body-metrics", func(t *testing.T) {
+		t.Run("should classify every record", func(t *testing.T) {
+			token, _ := setup(t, "")
+			for _, body := range []string{
+				`{"weight": 60, "height": 160, "recorded_at": "2026-09-01T08:00:00Z"}`,
+				`{"weight": 58, "height": 160, "waist_circumference": 85, "body_fat_percentage": 30, "recorded_at": "2026-10-01T08:00:00Z"}`,
+			} {
+				assert.Equal(t, http.StatusCreated, send(t, token, http.MethodPost, "/v1/weight-height", body).StatusCode)
+			}
+
+			apiResponse := send(t, token, http.MethodGet, "/v1/body-metrics?from=2026-08-01&to=2026-10-31", "")
+
+			assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+			bytes, err := io.ReadAll(apiResponse.Body)
+			assert.Nil(t, err)
+			responseBody := new(response.SuccessWithBodyMetrics)
+			assert.Nil(t, json.Unmarshal(bytes, responseBody))
+
+			series := responseBody.Data.Series
+			assert.Len(t, series, 2)
+			assert.Equal(t, 23.4, series[0].BMI)
+			assert.Equal(t, model.BMIOverweight, series[0].BMICategory)
+			assert.Equal(t, 22.7, series[1].BMI)
+			assert.Equal(t, model.BMINormal, series[1].BMICategory)
+			assert.Equal(t, 0.53, *series[1].WaistToHeightRatio)
+			assert.True(t, *series[1].CentralObesity)
+			assert.Equal(t, 17.4, *series[1].FatMass)
+			assert.Equal(t, series[1].RecordID, responseBody.Data.Latest.RecordID)
+			assert.True(t, responseBody.Data.Estimated)
+			assert.Equal(t, []string{"birth_date"}, responseBody.Data.Missing)
+		})
+
+		t.Run("should require a plan with bmi_check", func(t *testing.T) {
+			token, _ := setup(t, "Hemat")
+
+			apiResponse := send(t, token, http.MethodGet, "/v1/body-metrics", "")
+
+			assert.Equal(t, http.StatusForbidden, apiResponse.StatusCode)
+		})
+	})
+
+	t.Run("POST /v1/weight-height", func(t *testing.T) {
+		t.Run("should reject an impossible body fat percentage", func(t *testing.T) {
+			token, userID := setup(t, "")
+
+			apiResponse := send(t, token, http.MethodPost, "/v1/weight-height", `{"weight": 60, "height": 160, "body_fat_percentage": 90}`)
+
+			assert.Equal(t, http.StatusBadRequest, apiResponse.StatusCode)
+			var count int64
+			test.DB.Model(&model.UsersWeightHeightHistory{}).Where("user_id = ?", userID).Count(&count)
+			assert.Zero(t, count)
+		})
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"app/src/response"
 	"app/src/validation"
 	"app/test/fixture"
+	"context"
 	"net/http/httptest"
 	"testing"
 	"time"
@@ -188,6 +189,19 @@ func (m *MockSubscriptionService) GetRemainingScans(c *fiber.Ctx, userID uuid.UU
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockSubscriptionService) ReserveScan(c *fiber.Ctx, userID uuid.UUID) (*model.ScanQuota, error) {
+	args := m.Called(c, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.ScanQuota), args.Error(1)
+}
+
+func (m *MockSubscriptionService) RefundScan(c *fiber.Ctx, subscriptionID uuid.UUID) error {
+	args := m.Called(c, subscriptionID)
+	return args.Error(0)
+}
+
 func (m *MockSubscriptionService) HandlePaymentNotification(c *fiber.Ctx, notificationData []byte) error {
 	args := m.Called(c, notificationData)
 	return args.Error(0)
@@ -236,6 +250,70 @@ func (m *MockSubscriptionService) GetTransactionsBySubscriptionID(c *fiber.Ctx,
 	return args.Get(0).([]model.TransactionDetail), args.Error(1)
 }
 
+func (m *MockSubscriptionService) GetSubscriptionTransitions(c *fiber.Ctx, subscriptionID uuid.UUID) ([]model.SubscriptionTransition, error) {
+	args := m.Called(c, subscriptionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.SubscriptionTransition), args.Error(1)
+}
+
+func (m *MockSubscriptionService) SweepSubscriptions(ctx context.Context, now time.Time) (*model.SubscriptionSweepReport, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.SubscriptionSweepReport), args.Error(1)
+}
+
+func (m *MockSubscriptionService) StartLifecycleSweeper(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+func (m *MockSubscriptionService) ReconcilePayments(ctx context.Context, now time.Time) (*model.PaymentReconciliation, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.PaymentReconciliation), args.Error(1)
+}
+
+func (m *MockSubscriptionService) RefundSubscription(c *fiber.Ctx, subscriptionID uuid.UUID, req *validation.RefundSubscription) (*model.TransactionDetail, error) {
+	args := m.Called(c, subscriptionID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.TransactionDetail), args.Error(1)
+}
+
+func (m *MockSubscriptionService) CancelSubscription(c *fiber.Ctx, userID uuid.UUID) (*model.UserSubscriptionResponse, error) {
+	args := m.Called(c, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserSubscriptionResponse), args.Error(1)
+}
+
+func (m *MockSubscriptionService) ResumeSubscription(c *fiber.Ctx, userID uuid.UUID) (*model.UserSubscriptionResponse, error) {
+	args := m.Called(c, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*model.UserSubscriptionResponse), args.Error(1)
+}
+
+func (m *MockSubscriptionService) StartPaymentReconciler(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+func (m *MockSubscriptionService) GetPaymentReconciliations(c *fiber.Ctx, page, limit int) ([]model.PaymentReconciliation, int64, error) {
+	args := m.Called(c, page, limit)
+	if args.Get(0) == nil {
+		return nil, 0, args.Error(2)
+	}
+	return args.Get(0).([]model.PaymentReconciliation), args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockSubscriptionService) UpdatePaymentStatus(c *fiber.Ctx, subscriptionID uuid.UUID, status string) (*model.UserSubscriptionResponse, error) {
 	args := m.Called(c, subscriptionID, status)
 	if args.Get(0) == nil {
@@ -342,6 +420,45 @@ func TestFreemiumOrAccess(t *testing.T) {
 		assert.Equal(t, 401, resp.StatusCode)
 	})
 
+	t.Run("should allow access during the grace period of a paid subscription", func(t *testing.T) {
+		app := fiber.New()
+		mockUserService := &MockUserService{}
+		mockProductTokenService := &MockProductTokenService{}
+		mockSubscriptionService := &MockSubscriptionService{}
+
+		userID := uuid.New().String()
+		user := fixture.UserWithFreemium()
+		user.ID = uuid.MustParse(userID)
+
+		subscription := &model.UserSubscriptionResponse{
+			ID:       uuid.New(),
+			UserID:   user.ID,
+			Plan:     model.SubscriptionPlanResponse{Name: "Sehat"},
+			IsActive: true,
+			Status:   model.SubscriptionStatusGrace,
+			EndDate:  time.Now().AddDate(0, 0, -1), // Lapsed yesterday
+		}
+
+		mockUserService.On("GetUserByID", mock.Anything, userID).Return(user, nil)
+		mockSubscriptionService.On("GetUserActiveSubscription", mock.Anything, user.ID).Return(subscription, nil)
+
+		token := generateTestToken(userID)
+
+		app.Get("/test", middleware.FreemiumOrAccess(mockUserService, mockProductTokenService, mockSubscriptionService), func(c *fiber.Ctx) error {
+			return c.JSON(fiber.Map{"status": "success"})
+		})
+
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		mockUserService.AssertExpectations(t)
+		mockSubscriptionService.AssertExpectations(t)
+	})
+
 	t.Run("should deny access with expired freemium subscription", func(t *testing.T) {
 		app := fiber.New()
 		mockUserService := &MockUserService{}
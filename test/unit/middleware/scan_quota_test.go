@@ -0,0 +1,204 @@
+package middleware_test
+
+import (
+	"app/src/middleware"
+	"app/src/model"
+	"app/src/service"
+	"app/test/fixture"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newScanQuotaApp(user *model.User, subService *MockSubscriptionService, handler fiber.Handler) *fiber.App {
+	app := fiber.New()
+	app.Post("/scan", func(c *fiber.Ctx) error {
+		c.Locals("user", user)
+		return c.Next()
+	}, middleware.ScanQuota(subService), handler)
+	return app
+}
+
+func TestScanQuota(t *testing.T) {
+	t.Run("should reserve a scan and expose quota headers", func(t *testing.T) {
+		mockSubscriptionService := &MockSubscriptionService{}
+		user := fixture.UserWithFreemium()
+		quota := &model.ScanQuota{SubscriptionID: uuid.New(), Limit: 10, Used: 3, Remaining: 7, ResetAt: time.Now().AddDate(0, 0, 7)}
+
+		mockSubscriptionService.On("ReserveScan", mock.Anything, user.ID).Return(quota, nil)
+
+		app := newScanQuotaApp(user, mockSubscriptionService, func(c *fiber.Ctx) error {
+			return c.JSON(fiber.Map{"status": "success"})
+		})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/scan", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "10", resp.Header.Get("X-Scan-Quota-Limit"))
+		assert.Equal(t, "7", resp.Header.Get("X-Scan-Quota-Remaining"))
+		assert.NotEmpty(t, resp.Header.Get("X-Scan-Quota-Reset"))
+
+		mockSubscriptionService.AssertExpectations(t)
+		mockSubscriptionService.AssertNotCalled(t, "RefundScan", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should refund the scan when the handler fails", func(t *testing.T) {
+		mockSubscriptionService := &MockSubscriptionService{}
+		user := fixture.UserWithFreemium()
+		quota := &model.ScanQuota{SubscriptionID: uuid.New(), Limit: 10, Used: 3, Remaining: 7, ResetAt: time.Now().AddDate(0, 0, 7)}
+
+		mockSubscriptionService.On("ReserveScan", mock.Anything, user.ID).Return(quota, nil)
+		mockSubscriptionService.On("RefundScan", mock.Anything, quota.SubscriptionID).Return(nil)
+
+		app := newScanQuotaApp(user, mockSubscriptionService, func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"status": "error"})
+		})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/scan", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 500, resp.StatusCode)
+		assert.Equal(t, "8", resp.Header.Get("X-Scan-Quota-Remaining"))
+
+		mockSubscriptionService.AssertExpectations(t)
+	})
+
+	t.Run("should return scan_quota_exceeded when no scans are left", func(t *testing.T) {
+		mockSubscriptionService := &MockSubscriptionService{}
+		user := fixture.UserWithFreemium()
+		quota := &model.ScanQuota{SubscriptionID: uuid.New(), Limit: 10, Used: 10, Remaining: 0, ResetAt: time.Now().AddDate(0, 0, 7)}
+
+		mockSubscriptionService.On("ReserveScan", mock.Anything, user.ID).Return(quota, service.ErrScanQuotaExceeded)
+
+		app := newScanQuotaApp(user, mockSubscriptionService, func(c *fiber.Ctx) error {
+			t.Fatal("handler should not run when the quota is exceeded")
+			return nil
+		})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/scan", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 429, resp.StatusCode)
+		assert.Equal(t, "0", resp.Header.Get("X-Scan-Quota-Remaining"))
+
+		bytes, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+
+		body := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal(bytes, &body))
+		assert.Equal(t, "scan_quota_exceeded", body["code"])
+		assert.Equal(t, float64(0), body["remaining"])
+		assert.NotEmpty(t, body["reset_at"])
+	})
+
+	t.Run("should return subscription_required without an active subscription", func(t *testing.T) {
+		mockSubscriptionService := &MockSubscriptionService{}
+		user := fixture.UserWithFreemium()
+
+		mockSubscriptionService.On("ReserveScan", mock.Anything, user.ID).Return(nil, service.ErrNoActiveSubscription)
+
+		app := newScanQuotaApp(user, mockSubscriptionService, func(c *fiber.Ctx) error {
+			t.Fatal("handler should not run without a subscription")
+			return nil
+		})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/scan", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+
+		bytes, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+
+		body := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal(bytes, &body))
+		assert.Equal(t, "subscription_required", body["code"])
+	})
+
+	t.Run("should return a server error when the quota can't be checked", func(t *testing.T) {
+		mockSubscriptionService := &MockSubscriptionService{}
+		user := fixture.UserWithFreemium()
+
+		mockSubscriptionService.On("ReserveScan", mock.Anything, user.ID).Return(nil, errors.New("connection refused"))
+
+		app := newScanQuotaApp(user, mockSubscriptionService, func(c *fiber.Ctx) error {
+			t.Fatal("handler should not run when the quota can't be checked")
+			return nil
+		})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/scan", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 500, resp.StatusCode)
+	})
+
+	t.Run("should report unlimited plans as -1", func(t *testing.T) {
+		mockSubscriptionService := &MockSubscriptionService{}
+		user := fixture.UserWithFreemium()
+		quota := &model.ScanQuota{SubscriptionID: uuid.New(), Limit: model.UnlimitedScans, Used: 42, Remaining: model.UnlimitedScans, ResetAt: time.Now().AddDate(0, 1, 0)}
+
+		mockSubscriptionService.On("ReserveScan", mock.Anything, user.ID).Return(quota, nil)
+
+		app := newScanQuotaApp(user, mockSubscriptionService, func(c *fiber.Ctx) error {
+			return c.JSON(fiber.Map{"status": "success"})
+		})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/scan", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "-1", resp.Header.Get("X-Scan-Quota-Remaining"))
+	})
+}
+
+func TestScanQuotaHeaders(t *testing.T) {
+	newApp := func(user *model.User, subService *MockSubscriptionService) *fiber.App {
+		app := fiber.New()
+		app.Get("/scan/jobs/:jobId", func(c *fiber.Ctx) error {
+			c.Locals("user", user)
+			return c.Next()
+		}, middleware.ScanQuotaHeaders(subService), func(c *fiber.Ctx) error {
+			return c.JSON(fiber.Map{"status": "success"})
+		})
+		return app
+	}
+
+	t.Run("should expose quota headers when polling a scan job", func(t *testing.T) {
+		mockSubscriptionService := &MockSubscriptionService{}
+		user := fixture.UserWithFreemium()
+		subscription := &model.UserSubscriptionResponse{
+			ID:          uuid.New(),
+			Plan:        model.SubscriptionPlanResponse{AIscanLimit: 10},
+			AIscansUsed: 4,
+			EndDate:     time.Now().AddDate(0, 0, 7),
+		}
+
+		mockSubscriptionService.On("GetUserActiveSubscription", mock.Anything, user.ID).Return(subscription, nil)
+		mockSubscriptionService.On("GetRemainingScans", mock.Anything, user.ID).Return(6, nil)
+
+		resp, err := newApp(user, mockSubscriptionService).Test(httptest.NewRequest("GET", "/scan/jobs/"+uuid.NewString(), nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "10", resp.Header.Get("X-Scan-Quota-Limit"))
+		assert.Equal(t, "6", resp.Header.Get("X-Scan-Quota-Remaining"))
+		assert.Equal(t, subscription.EndDate.UTC().Format(time.RFC3339), resp.Header.Get("X-Scan-Quota-Reset"))
+
+		mockSubscriptionService.AssertExpectations(t)
+		mockSubscriptionService.AssertNotCalled(t, "ReserveScan", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should leave the headers out when the quota can't be read", func(t *testing.T) {
+		mockSubscriptionService := &MockSubscriptionService{}
+		user := fixture.UserWithFreemium()
+
+		mockSubscriptionService.On("GetUserActiveSubscription", mock.Anything, user.ID).Return(nil, errors.New("connection refused"))
+
+		resp, err := newApp(user, mockSubscriptionService).Test(httptest.NewRequest("GET", "/scan/jobs/"+uuid.NewString(), nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get("X-Scan-Quota-Remaining"))
+	})
+}
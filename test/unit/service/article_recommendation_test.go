@@ -0,0 +1,137 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArticleInterests(t *testing.T) {
+	now := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	targets := service.CalculateNutritionTargets(&model.User{}, nil, now)
+
+	t.Run("should match medical history keywords once per condition", func(t *testing.T) {
+		history := "Diabetes tipe 2, gula darah tinggi sejak 2020"
+		user := &model.User{MedicalHistory: &history}
+
+		interests := service.ArticleInterests(user, targets, nil, 0, nil)
+
+		assert.Equal(t, 3.0, interests[model.TagDiabetes])
+		assert.Equal(t, 3.0, interests[model.TagLowSugar])
+		assert.Equal(t, 1.0, interests[model.TagHealthyEating])
+		assert.NotContains(t, interests, model.TagHypertension)
+	})
+
+	t.Run("should match medical history keywords as whole words", func(t *testing.T) {
+		history := "Heartburn after meals, bergerdang; no other conditions"
+		user := &model.User{MedicalHistory: &history}
+
+		interests := service.ArticleInterests(user, targets, nil, 0, nil)
+
+		assert.NotContains(t, interests, model.TagHeartDisease)
+		assert.NotContains(t, interests, model.TagLowSodium)
+		assert.NotContains(t, interests, model.TagDigestive)
+	})
+
+	t.Run("should follow a high sodium trend", func(t *testing.T) {
+		intake := map[string]float64{model.NutrientSodium: 3200, model.NutrientFiber: 25}
+
+		interests := service.ArticleInterests(&model.User{}, targets, intake, 5, nil)
+
+		assert.Equal(t, 2.0, interests[model.TagLowSodium])
+		assert.Equal(t, 2.0, interests[model.TagHypertension])
+		assert.NotContains(t, interests, model.TagHighFiber)
+	})
+
+	t.Run("should ignore trends of too few logged days", func(t *testing.T) {
+		intake := map[string]float64{model.NutrientSodium: 3200}
+
+		interests := service.ArticleInterests(&model.User{}, targets, intake, 2, nil)
+
+		assert.NotContains(t, interests, model.TagLowSodium)
+	})
+
+	t.Run("should add the goal and the tags already read", func(t *testing.T) {
+		weight := 80.0
+		user := &model.User{Weight: &weight}
+		target := &model.UsersWeightHeightTarget{Weight: 70, TargetDate: now.AddDate(0, 3, 0)}
+		loseTargets := service.CalculateNutritionTargets(user, target, now)
+
+		interests := service.ArticleInterests(user, loseTargets, nil, 0, []string{"gout", "gout", "gout"})
+
+		assert.Equal(t, 1.0, interests[model.TagWeightLoss])
+		// Read tags count twice at most
+		assert.Equal(t, 1.0, interests[model.TagGout])
+	})
+}
+
+func TestRankArticleRecommendations(t *testing.T) {
+	article := func(title string, tags ...string) model.Article {
+		a := model.Article{ID: uuid.New(), Title: title}
+		for _, tag := range tags {
+			a.ArticleTags = append(a.ArticleTags, model.ArticleTag{Tag: tag})
+		}
+		return a
+	}
+
+	interests := map[string]float64{model.TagDiabetes: 3, model.TagLowSugar: 3, model.TagWeightLoss: 1}
+	sugar := article("Mengurangi gula", model.TagDiabetes, model.TagLowSugar)
+	diet := article("Diet seimbang", model.TagWeightLoss)
+	insulin := article("Mengenal insulin", model.TagDiabetes)
+	sleep := article("Tidur cukup", "sleep")
+
+	t.Run("should rank by matching tags and skip unrelated articles", func(t *testing.T) {
+		recommendations := service.RankArticleRecommendations([]model.Article{diet, insulin, sugar, sleep}, interests, nil, 10)
+
+		assert.Len(t, recommendations, 3)
+		assert.Equal(t, "Mengurangi gula", recommendations[0].Title)
+		assert.Equal(t, 6.0, recommendations[0].Score)
+		assert.ElementsMatch(t, []string{model.TagDiabetes, model.TagLowSugar}, recommendations[0].Reasons)
+		assert.Equal(t, "Mengenal insulin", recommendations[1].Title)
+		assert.Equal(t, "Diet seimbang", recommendations[2].Title)
+	})
+
+	t.Run("should put articles already read after new ones", func(t *testing.T) {
+		read := map[uuid.UUID]bool{sugar.ID: true}
+
+		recommendations := service.RankArticleRecommendations([]model.Article{sugar, insulin}, interests, read, 10)
+
+		assert.Equal(t, "Mengenal insulin", recommendations[0].Title)
+		assert.Equal(t, "Mengurangi gula", recommendations[1].Title)
+		assert.True(t, recommendations[1].Read)
+		assert.Equal(t, 1.5, recommendations[1].Score)
+	})
+
+	t.Run("should keep at most limit articles", func(t *testing.T) {
+		recommendations := service.RankArticleRecommendations([]model.Article{sugar, insulin, diet}, interests, nil, 2)
+
+		assert.Len(t, recommendations, 2)
+	})
+}
+
+func TestNormalizeArticleTags(t *testing.T) {
+	t.Run("should lowercase, dash and dedupe tags", func(t *testing.T) {
+		tags, err := service.NormalizeArticleTags([]string{"Weight Loss", "weight_loss", " Diabetes ", ""})
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"diabetes", "weight-loss"}, tags)
+	})
+
+	t.Run("should keep an empty list to clear the tags", func(t *testing.T) {
+		tags, err := service.NormalizeArticleTags([]string{})
+
+		assert.Nil(t, err)
+		assert.NotNil(t, tags)
+		assert.Empty(t, tags)
+	})
+
+	t.Run("should reject tags with other characters", func(t *testing.T) {
+		_, err := service.NormalizeArticleTags([]string{"gula/garam"})
+
+		assert.NotNil(t, err)
+	})
+}
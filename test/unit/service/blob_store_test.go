@@ -0,0 +1,91 @@
+package service_test
+
+import (
+	"app/src/service"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// testBlobStore checks the behaviour every BlobStore shares
+func testBlobStore(t *testing.T, store service.BlobStore, prefix string) {
+	ctx := context.Background()
+	put := func(key, body string) {
+		assert.Nil(t, store.Put(ctx, key, strings.NewReader(body), int64(len(body)), "text/plain"))
+	}
+
+	t.Run("should put, get and list blobs", func(t *testing.T) {
+		put(prefix+"/a/original.jpg", "asli")
+		put(prefix+"/a/thumbnail.jpg", "kecil")
+		put(prefix+"/b/original.jpg", "lain")
+
+		body, err := store.Get(ctx, prefix+"/a/original.jpg")
+		assert.Nil(t, err)
+		data, err := io.ReadAll(body)
+		assert.Nil(t, body.Close())
+		assert.Nil(t, err)
+		assert.Equal(t, "asli", string(data))
+
+		objects, err := store.List(ctx, prefix+"/a/")
+		assert.Nil(t, err)
+		keys := make([]string, 0, len(objects))
+		for _, object := range objects {
+			keys = append(keys, object.Key)
+			assert.False(t, object.ModifiedAt.IsZero())
+		}
+		assert.ElementsMatch(t, []string{prefix + "/a/original.jpg", prefix + "/a/thumbnail.jpg"}, keys)
+		assert.True(t, strings.HasSuffix(store.URL(prefix+"/a/original.jpg"), "/"+prefix+"/a/original.jpg"))
+	})
+
+	t.Run("should delete blobs and ignore missing ones", func(t *testing.T) {
+		put(prefix+"/c/original.jpg", "hapus")
+
+		assert.Nil(t, store.Delete(ctx, prefix+"/c/original.jpg"))
+		assert.Nil(t, store.Delete(ctx, prefix+"/c/original.jpg"))
+
+		_, err := store.Get(ctx, prefix+"/c/original.jpg")
+		assert.True(t, errors.Is(err, service.ErrBlobNotFound))
+		objects, err := store.List(ctx, prefix+"/c/")
+		assert.Nil(t, err)
+		assert.Empty(t, objects)
+	})
+
+	t.Run("should reject keys leaving the store", func(t *testing.T) {
+		err := store.Put(ctx, "../luar.jpg", strings.NewReader("x"), 1, "text/plain")
+
+		assert.NotNil(t, err)
+	})
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	store := service.NewLocalBlobStore(t.TempDir(), "/uploads/")
+
+	testBlobStore(t, store, "articles")
+
+	assert.Equal(t, "/uploads/articles/a/original.jpg", store.URL("articles/a/original.jpg"))
+}
+
+// TestS3BlobStore runs against an S3 compatible storage such as MinIO started with
+// `docker compose --profile minio up -d minio`, it's skipped unless S3_TEST_ENDPOINT is set
+func TestS3BlobStore(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT isn't set")
+	}
+
+	store, err := service.NewS3BlobStore(context.Background(), service.S3BlobStoreConfig{
+		Endpoint:  endpoint,
+		AccessKey: os.Getenv("S3_TEST_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_TEST_SECRET_KEY"),
+		Bucket:    "nutribox-test",
+	})
+	assert.Nil(t, err)
+
+	testBlobStore(t, store, "test-"+uuid.NewString())
+}
@@ -0,0 +1,88 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyBMI(t *testing.T) {
+	cases := map[float64]string{
+		18.4: model.BMIUnderweight,
+		18.5: model.BMINormal,
+		22.9: model.BMINormal,
+		// Overweight under the Asia-Pacific cutoffs, still normal under the international ones
+		23.0: model.BMIOverweight,
+		24.9: model.BMIOverweight,
+		25.0: model.BMIObese1,
+		30.0: model.BMIObese2,
+	}
+	for bmi, category := range cases {
+		assert.Equal(t, category, service.ClassifyBMI(bmi), "BMI %.1f", bmi)
+	}
+}
+
+func TestCalculateBodyMetrics(t *testing.T) {
+	birthDate := time.Date(1996, 3, 1, 0, 0, 0, 0, time.UTC)
+	gender := model.Male
+	user := &model.User{BirthDate: &birthDate, Gender: &gender}
+	record := model.UsersWeightHeightHistory{
+		ID:         uuid.New(),
+		Weight:     70,
+		Height:     170,
+		RecordedAt: time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("should compute the BMI, ideal weight range and BMR at the time of the record", func(t *testing.T) {
+		entry := service.CalculateBodyMetrics(user, record)
+
+		assert.Equal(t, 24.2, entry.BMI)
+		assert.Equal(t, model.BMIOverweight, entry.BMICategory)
+		assert.Equal(t, 53.5, entry.IdealWeightMin)
+		assert.Equal(t, 66.2, entry.IdealWeightMax)
+		// 10*70 + 6.25*170 - 5*30 + 5 is 1617.5
+		assert.Equal(t, 1618.0, entry.BMR)
+		assert.Nil(t, entry.WaistToHeightRatio)
+		assert.Nil(t, entry.FatMass)
+	})
+
+	t.Run("should use the age at the record", func(t *testing.T) {
+		older := record
+		older.RecordedAt = time.Date(2016, 10, 17, 8, 0, 0, 0, time.UTC)
+
+		entry := service.CalculateBodyMetrics(user, older)
+
+		// Ten years younger is 50 kcal more
+		assert.Equal(t, 1668.0, entry.BMR)
+	})
+
+	t.Run("should derive the waist and body fat metrics", func(t *testing.T) {
+		waist, bodyFat := 92.0, 25.0
+		measured := record
+		measured.WaistCircumference = &waist
+		measured.BodyFatPercentage = &bodyFat
+
+		entry := service.CalculateBodyMetrics(user, measured)
+
+		assert.Equal(t, 0.54, *entry.WaistToHeightRatio)
+		assert.Equal(t, model.WaistRatioIncreased, entry.WaistCategory)
+		assert.True(t, *entry.CentralObesity)
+		assert.Equal(t, 17.5, *entry.FatMass)
+		assert.Equal(t, 52.5, *entry.LeanMass)
+	})
+
+	t.Run("should skip central obesity without a gender", func(t *testing.T) {
+		waist := 80.0
+		measured := record
+		measured.WaistCircumference = &waist
+
+		entry := service.CalculateBodyMetrics(&model.User{}, measured)
+
+		assert.Equal(t, model.WaistRatioHealthy, entry.WaistCategory)
+		assert.Nil(t, entry.CentralObesity)
+	})
+}
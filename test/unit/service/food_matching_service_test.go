@@ -0,0 +1,240 @@
+package service_test
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/service"
+	"app/test"
+	"app/test/helper"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeFoodCatalog serves a fixed TKPI catalog and counts how often it was listed. A non-nil release
+// channel holds every listing until it is closed, a listing whose context is done by then fails.
+type fakeFoodCatalog struct {
+	foods   []model.BahanMakanan
+	err     error
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func (f *fakeFoodCatalog) ListBahanMakanan(ctx context.Context) ([]model.BahanMakanan, error) {
+	f.calls.Add(1)
+	if f.release != nil {
+		<-f.release
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return f.foods, nil
+}
+
+func newFakeFoodCatalog() *fakeFoodCatalog {
+	return &fakeFoodCatalog{foods: []model.BahanMakanan{
+		{ID: 1, Kode: "AP001", NamaBahanMakanan: "Nasi goreng"},
+		{ID: 2, Kode: "AP002", NamaBahanMakanan: "Nasi putih"},
+		{ID: 3, Kode: "FP001", NamaBahanMakanan: "Ayam goreng paha"},
+		{ID: 4, Kode: "AR001", NamaBahanMakanan: "Nasi uduk"},
+	}}
+}
+
+func TestFoodNameSimilarity(t *testing.T) {
+	t.Run("should score identical names as 1", func(t *testing.T) {
+		assert.InDelta(t, 1, service.FoodNameSimilarity([]string{"ayam", "goreng"}, []string{"ayam", "goreng"}), 0.0001)
+	})
+
+	t.Run("should prefer names sharing more words", func(t *testing.T) {
+		query := []string{"ayam", "goreng"}
+
+		exact := service.FoodNameSimilarity(query, []string{"ayam", "goreng", "paha"})
+		partial := service.FoodNameSimilarity(query, []string{"ayam", "bakar"})
+		unrelated := service.FoodNameSimilarity(query, []string{"nasi", "putih"})
+
+		assert.Greater(t, exact, partial)
+		assert.Greater(t, partial, unrelated)
+	})
+
+	t.Run("should still score spelling variants", func(t *testing.T) {
+		assert.Greater(t, service.FoodNameSimilarity([]string{"tempe"}, []string{"tempeh"}), 0.0)
+	})
+
+	t.Run("should return 0 for empty names", func(t *testing.T) {
+		assert.Equal(t, 0.0, service.FoodNameSimilarity(nil, []string{"nasi"}))
+	})
+}
+
+func TestMatchRecognition(t *testing.T) {
+	t.Run("should rank the TKPI foods of every segment by the recognizer's confidence", func(t *testing.T) {
+		helper.ClearFoodAliases(test.DB)
+		matching := service.NewFoodMatchingService(test.DB, validator.New(), newFakeFoodCatalog())
+
+		foods, err := matching.MatchRecognition(context.Background(), &service.FoodRecognition{Segments: []service.FoodSegment{
+			{Candidates: []service.FoodCandidate{{Name: "fried rice", Probability: 0.9}, {Name: "white rice", Probability: 0.1}}},
+			{Candidates: []service.FoodCandidate{{Name: "fried chicken", Probability: 0.8}}},
+		}})
+
+		assert.Nil(t, err)
+		assert.NotEmpty(t, foods)
+		assert.Equal(t, 0, foods[0].Segment)
+		assert.Equal(t, 1, foods[0].Rank)
+		assert.Equal(t, "AP001", foods[0].Kode)
+
+		var chicken *model.MealHistoryFood
+		for i := range foods {
+			if foods[i].Segment == 1 && foods[i].Rank == 1 {
+				chicken = &foods[i]
+			}
+		}
+		assert.NotNil(t, chicken)
+		assert.Equal(t, "FP001", chicken.Kode)
+	})
+
+	t.Run("should leave out segments without a matching food", func(t *testing.T) {
+		helper.ClearFoodAliases(test.DB)
+		matching := service.NewFoodMatchingService(test.DB, validator.New(), newFakeFoodCatalog())
+
+		foods, err := matching.MatchRecognition(context.Background(), &service.FoodRecognition{Segments: []service.FoodSegment{
+			{Candidates: []service.FoodCandidate{{Name: "xyzzy", Probability: 1}}},
+		}})
+
+		assert.Nil(t, err)
+		assert.Empty(t, foods)
+	})
+}
+
+func TestMatchSelections(t *testing.T) {
+	t.Run("should match a curated alias before the name", func(t *testing.T) {
+		helper.ClearFoodAliases(test.DB)
+		assert.Nil(t, test.DB.Create(&model.FoodAlias{Alias: "coconut rice", Kode: "AR001"}).Error)
+		matching := service.NewFoodMatchingService(test.DB, validator.New(), newFakeFoodCatalog())
+
+		foods, err := matching.MatchSelections(context.Background(), []service.FoodSelection{
+			{Segment: 0, Name: "Coconut Rice", Grams: 150},
+			{Segment: 1, Name: "xyzzy", Grams: 50},
+		})
+
+		assert.Nil(t, err)
+		assert.Len(t, foods, 1)
+		assert.Equal(t, "AR001", foods[0].Kode)
+		assert.Equal(t, 1.0, foods[0].Score)
+		assert.Equal(t, 150.0, *foods[0].Grams)
+	})
+}
+
+func TestFoodCatalogCache(t *testing.T) {
+	withCatalogTTL := func(t *testing.T, minutes int) {
+		original := config.FoodCatalogTTLMinutes
+		t.Cleanup(func() { config.FoodCatalogTTLMinutes = original })
+		config.FoodCatalogTTLMinutes = minutes
+	}
+
+	t.Run("should list the catalog once while it is fresh", func(t *testing.T) {
+		withCatalogTTL(t, 60)
+		catalog := newFakeFoodCatalog()
+		matching := service.NewFoodMatchingService(nil, validator.New(), catalog)
+
+		for i := 0; i < 3; i++ {
+			food, err := matching.GetBahanMakanan(context.Background(), "AP002")
+			assert.Nil(t, err)
+			assert.Equal(t, "Nasi putih", food.NamaBahanMakanan)
+		}
+
+		assert.Equal(t, int32(1), catalog.calls.Load())
+	})
+
+	t.Run("should list the catalog again once it expired", func(t *testing.T) {
+		withCatalogTTL(t, 0)
+		catalog := newFakeFoodCatalog()
+		matching := service.NewFoodMatchingService(nil, validator.New(), catalog)
+
+		_, err := matching.GetBahanMakanan(context.Background(), "AP002")
+		assert.Nil(t, err)
+		catalog.foods = append(catalog.foods, model.BahanMakanan{ID: 5, Kode: "BP001", NamaBahanMakanan: "Tempe"})
+
+		food, err := matching.GetBahanMakanan(context.Background(), "BP001")
+		assert.Nil(t, err)
+		assert.NotNil(t, food)
+		assert.Equal(t, int32(2), catalog.calls.Load())
+	})
+
+	t.Run("should keep serving the expired catalog when the refresh fails", func(t *testing.T) {
+		withCatalogTTL(t, 0)
+		catalog := newFakeFoodCatalog()
+		matching := service.NewFoodMatchingService(nil, validator.New(), catalog)
+
+		_, err := matching.GetBahanMakanan(context.Background(), "AP002")
+		assert.Nil(t, err)
+		catalog.err = errors.New("bahan makanan service is down")
+
+		food, err := matching.GetBahanMakanan(context.Background(), "AP002")
+		assert.Nil(t, err)
+		assert.Equal(t, "Nasi putih", food.NamaBahanMakanan)
+	})
+
+	t.Run("should fail without a catalog to fall back on", func(t *testing.T) {
+		withCatalogTTL(t, 60)
+		catalog := newFakeFoodCatalog()
+		catalog.err = errors.New("bahan makanan service is down")
+		matching := service.NewFoodMatchingService(nil, validator.New(), catalog)
+
+		_, err := matching.GetBahanMakanan(context.Background(), "AP002")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("should share one listing between concurrent callers", func(t *testing.T) {
+		withCatalogTTL(t, 60)
+		catalog := newFakeFoodCatalog()
+		catalog.release = make(chan struct{})
+		matching := service.NewFoodMatchingService(nil, validator.New(), catalog)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				food, err := matching.GetBahanMakanan(context.Background(), "AP001")
+				assert.Nil(t, err)
+				assert.NotNil(t, food)
+			}()
+		}
+		close(catalog.release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), catalog.calls.Load())
+	})
+	t.Run("should finish a shared listing when the caller that started it goes away", func(t *testing.T) {
+		withCatalogTTL(t, 60)
+		catalog := newFakeFoodCatalog()
+		catalog.release = make(chan struct{})
+		matching := service.NewFoodMatchingService(nil, validator.New(), catalog)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = matching.GetBahanMakanan(ctx, "AP001")
+		}()
+		for catalog.calls.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+		close(catalog.release)
+		<-done
+
+		food, err := matching.GetBahanMakanan(context.Background(), "AP001")
+		assert.Nil(t, err)
+		assert.NotNil(t, food)
+		assert.Equal(t, int32(1), catalog.calls.Load())
+	})
+}
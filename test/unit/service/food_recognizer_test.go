@@ -0,0 +1,26 @@
+package service_test
+
+import (
+	"app/src/service"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFoodRecognizer(t *testing.T) {
+	t.Run("should return the recognizer named by the provider", func(t *testing.T) {
+		recognizer, err := service.NewFoodRecognizer("Fake", "", "")
+		assert.NoError(t, err)
+		assert.IsType(t, service.NewFakeFoodRecognizer(), recognizer)
+
+		recognizer, err = service.NewFoodRecognizer(service.FoodRecognizerLogMeal, "key", "https://api.logmeal.com")
+		assert.NoError(t, err)
+		assert.NotNil(t, recognizer)
+	})
+
+	t.Run("should reject an unknown provider instead of falling back to LogMeal", func(t *testing.T) {
+		recognizer, err := service.NewFoodRecognizer("fake ", "key", "https://api.logmeal.com")
+		assert.Nil(t, recognizer)
+		assert.ErrorContains(t, err, `"logmeal" or "fake"`)
+	})
+}
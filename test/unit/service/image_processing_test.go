@@ -0,0 +1,133 @@
+package service_test
+
+import (
+	"app/src/service"
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeTestImage(t *testing.T, width, height int, alpha uint8, encode func(*bytes.Buffer, image.Image) error) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: uint8(y), B: uint8(x), A: alpha})
+		}
+	}
+
+	var buffer bytes.Buffer
+	assert.Nil(t, encode(&buffer, img))
+	return buffer.Bytes()
+}
+
+func encodePNG(buffer *bytes.Buffer, img image.Image) error { return png.Encode(buffer, img) }
+
+func encodeJPEG(buffer *bytes.Buffer, img image.Image) error { return jpeg.Encode(buffer, img, nil) }
+
+// withExifOrientation puts an EXIF segment holding only the orientation tag right after the JPEG start
+func withExifOrientation(data []byte, orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	result := append([]byte{}, data[:2]...)
+	result = append(result, app1...)
+	return append(result, data[2:]...)
+}
+
+func TestProcessImage(t *testing.T) {
+	variants := func(processed *service.ProcessedImage) map[string]service.ImageVariant {
+		result := make(map[string]service.ImageVariant)
+		for _, variant := range processed.Variants {
+			result[variant.Name] = variant
+		}
+		return result
+	}
+
+	t.Run("should store opaque images as JPEG with a thumbnail and WebP versions", func(t *testing.T) {
+		processed, err := service.ProcessImage(encodeTestImage(t, 800, 400, 255, encodePNG))
+
+		assert.Nil(t, err)
+		byName := variants(processed)
+		assert.Len(t, byName, 4)
+		assert.Equal(t, "image/jpeg", byName[service.ImageVariantOriginal].ContentType)
+		assert.Equal(t, "original.jpg", byName[service.ImageVariantOriginal].Filename)
+		assert.Equal(t, 800, byName[service.ImageVariantOriginal].Width)
+		assert.Equal(t, service.ThumbnailDimension, byName[service.ImageVariantThumbnail].Width)
+		assert.Equal(t, 160, byName[service.ImageVariantThumbnail].Height)
+		assert.Equal(t, "image/webp", byName[service.ImageVariantOriginalWebP].ContentType)
+		assert.Equal(t, "thumbnail.webp", byName[service.ImageVariantThumbnailWebP].Filename)
+
+		webp, format, err := image.DecodeConfig(bytes.NewReader(byName[service.ImageVariantThumbnailWebP].Data))
+		assert.Nil(t, err)
+		assert.Equal(t, "webp", format)
+		assert.Equal(t, service.ThumbnailDimension, webp.Width)
+	})
+
+	t.Run("should keep transparency as PNG", func(t *testing.T) {
+		processed, err := service.ProcessImage(encodeTestImage(t, 50, 50, 128, encodePNG))
+
+		assert.Nil(t, err)
+		original := variants(processed)[service.ImageVariantOriginal]
+		assert.Equal(t, "image/png", original.ContentType)
+		// Small images aren't scaled up
+		assert.Equal(t, 50, original.Width)
+	})
+
+	t.Run("should scale large images down", func(t *testing.T) {
+		processed, err := service.ProcessImage(encodeTestImage(t, 3000, 1500, 255, encodeJPEG))
+
+		assert.Nil(t, err)
+		assert.Equal(t, service.ImageMaxDimension, processed.Width)
+		assert.Equal(t, 1024, processed.Height)
+	})
+
+	t.Run("should apply the EXIF orientation and drop the EXIF data", func(t *testing.T) {
+		data := withExifOrientation(encodeTestImage(t, 120, 60, 255, encodeJPEG), 6)
+
+		processed, err := service.ProcessImage(data)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 60, processed.Width)
+		assert.Equal(t, 120, processed.Height)
+		for _, variant := range processed.Variants {
+			assert.False(t, bytes.Contains(variant.Data, []byte("Exif\x00\x00")), variant.Name)
+		}
+	})
+
+	t.Run("should reject files that aren't JPEG, PNG or WebP", func(t *testing.T) {
+		for _, data := range [][]byte{[]byte("bukan gambar"), []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")} {
+			_, err := service.ProcessImage(data)
+
+			var fiberErr *fiber.Error
+			assert.ErrorAs(t, err, &fiberErr)
+			assert.Equal(t, fiber.StatusUnsupportedMediaType, fiberErr.Code)
+		}
+	})
+
+	t.Run("should reject broken images", func(t *testing.T) {
+		data := encodeTestImage(t, 20, 20, 255, encodePNG)
+
+		_, err := service.ProcessImage(data[:40])
+
+		var fiberErr *fiber.Error
+		assert.ErrorAs(t, err, &fiberErr)
+		assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code)
+	})
+}
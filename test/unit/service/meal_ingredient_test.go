@@ -0,0 +1,193 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubBahanMakananService serves a fixed TKPI table instead of calling the gRPC server, or fails every
+// lookup with err when it is set
+type stubBahanMakananService struct {
+	foods map[string]model.BahanMakanan
+	err   error
+}
+
+func (s *stubBahanMakananService) GetAllBahanMakanan(_ *fiber.Ctx) ([]model.BahanMakanan, error) {
+	var foods []model.BahanMakanan
+	for _, food := range s.foods {
+		foods = append(foods, food)
+	}
+	return foods, nil
+}
+
+func (s *stubBahanMakananService) GetBahanMakananByKode(_ *fiber.Ctx, kode string) (*model.BahanMakanan, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	food, ok := s.foods[kode]
+	if !ok {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Bahan makanan not found")
+	}
+	return &food, nil
+}
+
+func (s *stubBahanMakananService) GetBahanMakananById(_ *fiber.Ctx, _ uint32) (*model.BahanMakanan, error) {
+	return nil, fiber.NewError(fiber.StatusNotFound, "Bahan makanan not found")
+}
+
+func (s *stubBahanMakananService) GetBahanMakananByMentahOlahan(_ *fiber.Ctx, _ string) ([]model.BahanMakanan, error) {
+	return nil, nil
+}
+
+func (s *stubBahanMakananService) GetBahanMakananByKelompok(_ *fiber.Ctx, _ string) ([]model.BahanMakanan, error) {
+	return nil, nil
+}
+
+func (s *stubBahanMakananService) UpdateBahanMakanan(_ *fiber.Ctx, _ uint32, bahanMakanan *model.BahanMakanan) (*model.BahanMakanan, error) {
+	return bahanMakanan, nil
+}
+
+func newStubBahanMakananService() *stubBahanMakananService {
+	sodium := 2.0
+	iron := 0.6
+	return &stubBahanMakananService{foods: map[string]model.BahanMakanan{
+		"AR001": {Kode: "AR001", NamaBahanMakanan: "Nasi", EnergiKal: 180, ProteinG: 3, KarbohidratG: 39.8, LemakG: 0.3, BddPersen: 100, NatriumNaMg: &sodium},
+		"ER001": {Kode: "ER001", NamaBahanMakanan: "Pisang ambon", EnergiKal: 108, ProteinG: 1, KarbohidratG: 24.3, LemakG: 0.8, BddPersen: 75, BesiFeMg: &iron},
+	}}
+}
+
+// runAsUser calls fn inside a fiber handler that has user in its locals, like the auth middleware leaves it
+func runAsUser(t *testing.T, user *model.User, fn func(c *fiber.Ctx) error) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Locals("user", user)
+		return fn(c)
+	})
+
+	_, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	assert.NoError(t, err)
+}
+
+func TestCompositeMeals(t *testing.T) {
+	mealService := service.NewMealService(test.DB, validator.New(), service.NewFakeFoodRecognizer(), nil, newStubBahanMakananService())
+
+	setup := func() *model.User {
+		helper.ClearAll(test.DB)
+		helper.ClearMeals(test.DB)
+
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+		return user
+	}
+
+	t.Run("should compute totals from the edible portion of every ingredient", func(t *testing.T) {
+		user := setup()
+
+		runAsUser(t, user, func(c *fiber.Ctx) error {
+			meal, err := mealService.AddMeal(c, &model.MealHistory{
+				MealTime: time.Now(),
+				Calories: 999,
+				Ingredients: []model.MealHistoryIngredient{
+					{Kode: "AR001", Grams: 150},
+					{Kode: "ER001", Grams: 100},
+				},
+			})
+			assert.NoError(t, err)
+
+			// Only 75 g of the 100 g banana is edible
+			assert.InDelta(t, 180*1.5+108*0.75, meal.Calories, 0.01)
+			assert.InDelta(t, 3*1.5+1*0.75, meal.Protein, 0.01)
+			assert.Equal(t, "Nasi, Pisang ambon", meal.Title)
+			assert.Len(t, meal.Ingredients, 2)
+			assert.InDelta(t, 75, meal.Ingredients[1].EdibleGrams, 0.01)
+
+			nutrients := make(map[string]float64)
+			for _, nutrient := range meal.Nutrients {
+				nutrients[nutrient.Code] = nutrient.Quantity
+			}
+			assert.InDelta(t, 3, nutrients[model.NutrientSodium], 0.001)
+			assert.InDelta(t, 0.45, nutrients[model.NutrientIron], 0.001)
+			return nil
+		})
+	})
+
+	t.Run("should recompute totals when the ingredients are edited", func(t *testing.T) {
+		user := setup()
+
+		runAsUser(t, user, func(c *fiber.Ctx) error {
+			meal, err := mealService.AddMeal(c, &model.MealHistory{
+				Title:       "Sarapan",
+				MealTime:    time.Now(),
+				Ingredients: []model.MealHistoryIngredient{{Kode: "AR001", Grams: 100}},
+			})
+			assert.NoError(t, err)
+
+			updated, err := mealService.UpdateMeal(c, meal.ID.String(), &model.MealHistory{
+				Title:       "Sarapan",
+				MealTime:    meal.MealTime,
+				Ingredients: []model.MealHistoryIngredient{{Kode: "AR001", Grams: 200}},
+			})
+			assert.NoError(t, err)
+			assert.InDelta(t, 360, updated.Calories, 0.01)
+
+			// Macros sent without ingredients don't override a composite meal
+			updated, err = mealService.UpdateMeal(c, meal.ID.String(), &model.MealHistory{
+				Title:    "Sarapan",
+				MealTime: meal.MealTime,
+				Calories: 10,
+			})
+			assert.NoError(t, err)
+			assert.InDelta(t, 360, updated.Calories, 0.01)
+
+			var ingredients []model.MealHistoryIngredient
+			assert.NoError(t, test.DB.Where("meal_history_id = ?", meal.ID).Find(&ingredients).Error)
+			assert.Len(t, ingredients, 1)
+			assert.InDelta(t, 200, ingredients[0].Grams, 0.01)
+			return nil
+		})
+	})
+
+	t.Run("should return 400 for an unknown kode", func(t *testing.T) {
+		user := setup()
+
+		runAsUser(t, user, func(c *fiber.Ctx) error {
+			_, err := mealService.AddMeal(c, &model.MealHistory{
+				MealTime:    time.Now(),
+				Ingredients: []model.MealHistoryIngredient{{Kode: "XX999", Grams: 100}},
+			})
+
+			var fiberErr *fiber.Error
+			assert.ErrorAs(t, err, &fiberErr)
+			assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code)
+			return nil
+		})
+	})
+	t.Run("should return 503 when the bahan makanan service is unreachable", func(t *testing.T) {
+		user := setup()
+		unreachable := newStubBahanMakananService()
+		unreachable.err = fiber.NewError(fiber.StatusServiceUnavailable, "Bahan makanan service is unavailable")
+		mealService := service.NewMealService(test.DB, validator.New(), service.NewFakeFoodRecognizer(), nil, unreachable)
+
+		runAsUser(t, user, func(c *fiber.Ctx) error {
+			_, err := mealService.AddMeal(c, &model.MealHistory{
+				MealTime:    time.Now(),
+				Ingredients: []model.MealHistoryIngredient{{Kode: "AR001", Grams: 100}},
+			})
+
+			var fiberErr *fiber.Error
+			assert.ErrorAs(t, err, &fiberErr)
+			assert.Equal(t, fiber.StatusServiceUnavailable, fiberErr.Code)
+			return nil
+		})
+	})
+}
@@ -0,0 +1,122 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func planRecipe(name, label, ingredients string, calories, protein, carbs, fat float64) model.Recipe {
+	return model.Recipe{
+		ID:          uuid.New(),
+		Name:        name,
+		Label:       &label,
+		Ingredients: ingredients,
+		Day:         "monday",
+		Calories:    &calories,
+		Protein:     &protein,
+		Carbs:       &carbs,
+		Fat:         &fat,
+	}
+}
+
+func TestPlanMealSlots(t *testing.T) {
+	start := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	newPlan := func() *model.MealPlan {
+		return &model.MealPlan{
+			StartDate:     start,
+			CalorieTarget: 2000,
+			ProteinTarget: 100,
+			CarbsTarget:   250,
+			FatTarget:     67,
+		}
+	}
+
+	recipes := []model.Recipe{
+		planRecipe("Bubur ayam", model.MealLabelBreakfast, "beras, ayam, daun bawang", 250, 12, 35, 7),
+		planRecipe("Nasi uduk", model.MealLabelBreakfast, "beras, santan, telur", 450, 14, 60, 17),
+		planRecipe("Gado-gado", model.MealLabelLunch, "sayur, tahu, saus kacang", 350, 15, 40, 14),
+		planRecipe("Soto ayam", model.MealLabelLunch, "ayam, bihun, kol", 400, 25, 45, 13),
+		planRecipe("Udang balado", model.MealLabelDinner, "udang, cabai, bawang", 300, 28, 12, 15),
+		planRecipe("Pepes ikan", model.MealLabelDinner, "ikan kembung, kemangi", 320, 30, 10, 17),
+	}
+
+	t.Run("should plan three meals for each of seven days", func(t *testing.T) {
+		plan := newPlan()
+		assert.Nil(t, service.PlanMealSlots(plan, recipes, nil))
+
+		assert.Len(t, plan.Slots, 21)
+		assert.Equal(t, model.MealLabelBreakfast, plan.Slots[0].Label)
+		assert.Equal(t, "monday", plan.Slots[0].Day)
+		assert.Equal(t, start.AddDate(0, 0, 6), plan.Slots[20].Date)
+		assert.Equal(t, model.MealLabelDinner, plan.Slots[20].Label)
+		for i, slot := range plan.Slots {
+			assert.Equal(t, i, slot.Position)
+		}
+	})
+
+	t.Run("should scale servings towards each meal's share of the calories", func(t *testing.T) {
+		plan := newPlan()
+		assert.Nil(t, service.PlanMealSlots(plan, recipes, nil))
+
+		day := 0.0
+		for _, slot := range plan.Slots[:3] {
+			assert.GreaterOrEqual(t, slot.Servings, 0.5)
+			assert.LessOrEqual(t, slot.Servings, 3.0)
+			assert.InDelta(t, *slot.Recipe.Calories*slot.Servings, slot.Calories, 0.01)
+			day += slot.Calories
+		}
+		// Breakfast, lunch and dinner cover 90% of the day, half servings keep it within 15%
+		assert.InDelta(t, 1800, day, 270)
+	})
+
+	t.Run("should prefer recipes labelled for the meal and avoid repeating them within a day", func(t *testing.T) {
+		plan := newPlan()
+		assert.Nil(t, service.PlanMealSlots(plan, recipes, nil))
+
+		for i := 0; i < len(plan.Slots); i += 3 {
+			assert.Equal(t, plan.Slots[i].Label, *plan.Slots[i].Recipe.Label)
+			assert.NotEqual(t, plan.Slots[i].RecipeID, plan.Slots[i+1].RecipeID)
+			assert.NotEqual(t, plan.Slots[i+1].RecipeID, plan.Slots[i+2].RecipeID)
+		}
+	})
+
+	t.Run("should never pick recipes with an excluded ingredient", func(t *testing.T) {
+		plan := newPlan()
+		assert.Nil(t, service.PlanMealSlots(plan, recipes, []string{"udang"}))
+
+		for _, slot := range plan.Slots {
+			assert.NotEqual(t, "Udang balado", slot.Recipe.Name)
+		}
+	})
+
+	t.Run("should match excluded ingredients as whole words", func(t *testing.T) {
+		plan := newPlan()
+		withSpinach := append([]model.Recipe{
+			planRecipe("Sayur bayam", model.MealLabelLunch, "bayam, jagung, bawang merah", 150, 6, 25, 2),
+		}, recipes...)
+		assert.Nil(t, service.PlanMealSlots(plan, withSpinach, []string{"ayam"}))
+
+		picked := false
+		for _, slot := range plan.Slots {
+			assert.NotEqual(t, "Bubur ayam", slot.Recipe.Name)
+			assert.NotEqual(t, "Soto ayam", slot.Recipe.Name)
+			picked = picked || slot.Recipe.Name == "Sayur bayam"
+		}
+		assert.True(t, picked)
+	})
+
+	t.Run("should fail when no recipe is left", func(t *testing.T) {
+		plan := newPlan()
+		err := service.PlanMealSlots(plan, recipes[:1], []string{"ayam"})
+
+		var fiberErr *fiber.Error
+		assert.ErrorAs(t, err, &fiberErr)
+		assert.Equal(t, fiber.StatusUnprocessableEntity, fiberErr.Code)
+	})
+}
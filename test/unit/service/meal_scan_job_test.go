@@ -0,0 +1,71 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+// panickingRecognizer panics on every segmentation, like a recognizer hitting a bug
+type panickingRecognizer struct {
+	service.FoodRecognizer
+}
+
+func (panickingRecognizer) Segment(_ context.Context, _ io.Reader, _ string) (*service.FoodRecognition, error) {
+	panic("segmentation blew up")
+}
+
+func TestScanWorkers(t *testing.T) {
+	t.Run("should fail a job whose steps panic and refund its scan", func(t *testing.T) {
+		helper.ClearAll(test.DB)
+		helper.ClearMeals(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+
+		now := time.Now()
+		subscription := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusActive, "gopay", now.AddDate(0, 0, -1), now.AddDate(0, 0, 29))
+		assert.Nil(t, test.DB.Model(subscription).Update("ai_scans_used", 1).Error)
+
+		imagePath := filepath.Join(t.TempDir(), "meal.jpg")
+		assert.Nil(t, os.WriteFile(imagePath, []byte("not really a jpeg"), 0o644))
+
+		job := &model.MealScanJob{
+			UserID:         user.ID,
+			SubscriptionID: &subscription.ID,
+			Status:         model.ScanJobQueued,
+			Step:           model.ScanStepSegmentation,
+			ImagePath:      imagePath,
+			Filename:       "meal.jpg",
+		}
+		assert.Nil(t, test.DB.Create(job).Error)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		mealService := service.NewMealService(test.DB, validator.New(), panickingRecognizer{}, nil, newStubBahanMakananService())
+		mealService.StartScanWorkers(ctx, 1, 1)
+
+		failed := new(model.MealScanJob)
+		assert.Eventually(t, func() bool {
+			return test.DB.First(failed, "id = ?", job.ID).Error == nil && failed.Status == model.ScanJobFailed
+		}, 5*time.Second, 50*time.Millisecond)
+
+		assert.NotNil(t, failed.Error)
+		if failed.Error != nil {
+			assert.True(t, strings.Contains(*failed.Error, "panicked"))
+		}
+		assert.Equal(t, 0, reloadSubscription(t, subscription.ID).AIscansUsed)
+	})
+}
@@ -0,0 +1,86 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateNutritionTargets(t *testing.T) {
+	now := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	birthDate := time.Date(1996, 3, 1, 0, 0, 0, 0, time.UTC)
+	weight, height := 70.0, 175.0
+	gender, activityLevel := model.Male, model.Medium
+
+	user := &model.User{
+		BirthDate:     &birthDate,
+		Weight:        &weight,
+		Height:        &height,
+		Gender:        &gender,
+		ActivityLevel: &activityLevel,
+	}
+
+	t.Run("should compute BMR with Mifflin-St Jeor and TDEE from the activity level", func(t *testing.T) {
+		targets := service.CalculateNutritionTargets(user, nil, now)
+
+		// 10*70 + 6.25*175 - 5*30 + 5
+		assert.Equal(t, 1649.0, targets.BMR)
+		assert.Equal(t, 2556.0, targets.TDEE)
+		assert.Equal(t, 2556.0, targets.Calories)
+		assert.Equal(t, model.GoalMaintainWeight, targets.Goal)
+		assert.False(t, targets.Estimated)
+		assert.InDelta(t, 2556*0.2/4, targets.GetNutrientTarget(model.NutrientProtein).Target, 0.1)
+		assert.True(t, targets.GetNutrientTarget(model.NutrientSodium).Limit)
+		assert.Equal(t, 9.0, targets.GetNutrientTarget(model.NutrientIron).Target)
+	})
+
+	t.Run("should lower calories to reach a weight target by its date", func(t *testing.T) {
+		target := &model.UsersWeightHeightTarget{Weight: 65, TargetDate: now.AddDate(0, 0, 70)}
+
+		targets := service.CalculateNutritionTargets(user, target, now)
+
+		// 5 kg in 70 days is 550 kcal a day
+		assert.Equal(t, model.GoalLoseWeight, targets.Goal)
+		assert.Equal(t, -550.0, targets.Adjustment)
+		assert.Equal(t, 2006.0, targets.Calories)
+	})
+
+	t.Run("should cap the deficit of a target that is too close", func(t *testing.T) {
+		target := &model.UsersWeightHeightTarget{Weight: 50, TargetDate: now.AddDate(0, 0, 30)}
+
+		targets := service.CalculateNutritionTargets(user, target, now)
+		assert.Equal(t, -1000.0, targets.Adjustment)
+	})
+
+	t.Run("should use defaults and report missing profile fields", func(t *testing.T) {
+		targets := service.CalculateNutritionTargets(&model.User{}, nil, now)
+
+		assert.True(t, targets.Estimated)
+		assert.ElementsMatch(t, []string{"weight", "height", "birth_date", "gender", "activity_level"}, targets.Missing)
+		assert.Equal(t, 1372.0, targets.BMR)
+		assert.Equal(t, 1646.0, targets.TDEE)
+	})
+}
+
+func TestCompareNutrition(t *testing.T) {
+	targets := &model.NutritionTargets{Nutrients: []model.NutrientTarget{
+		{Code: model.NutrientEnergy, Target: 2000, Unit: "kcal"},
+		{Code: model.NutrientSodium, Target: 2000, Unit: "mg", Limit: true},
+	}}
+	daily := &model.DailyNutrition{
+		Calories:  1500,
+		Nutrients: []model.NutrientTotal{{Code: model.NutrientSodium, Quantity: 2300}},
+	}
+
+	progress := service.CompareNutrition(targets, daily)
+
+	assert.Len(t, progress, 2)
+	assert.Equal(t, 500.0, progress[0].Remaining)
+	assert.Equal(t, 75.0, progress[0].Percent)
+	assert.False(t, progress[0].Exceeded)
+	assert.Equal(t, 0.0, progress[1].Remaining)
+	assert.True(t, progress[1].Exceeded)
+}
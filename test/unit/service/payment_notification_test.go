@@ -0,0 +1,179 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaymentStatusForMidtrans(t *testing.T) {
+	tests := []struct {
+		transactionStatus, fraudStatus string
+		expected                       string
+		known                          bool
+	}{
+		{"pending", "", service.PaymentStatusPending, true},
+		{"capture", "accept", service.PaymentStatusSuccess, true},
+		{"capture", "challenge", service.PaymentStatusPending, true},
+		{"capture", "deny", service.PaymentStatusFailed, true},
+		{"settlement", "", service.PaymentStatusSuccess, true},
+		{"expire", "", service.PaymentStatusFailed, true},
+		{"cancel", "", service.PaymentStatusFailed, true},
+		{"refund", "", service.PaymentStatusRefunded, true},
+		{"chargeback", "", "", false},
+	}
+
+	for _, tt := range tests {
+		status, known := service.PaymentStatusForMidtrans(tt.transactionStatus, tt.fraudStatus)
+		assert.Equal(t, tt.expected, status, tt.transactionStatus)
+		assert.Equal(t, tt.known, known, tt.transactionStatus)
+	}
+}
+
+func TestCanAdvancePaymentStatus(t *testing.T) {
+	assert.True(t, service.CanAdvancePaymentStatus(service.PaymentStatusPending, service.PaymentStatusSuccess))
+	assert.True(t, service.CanAdvancePaymentStatus(service.PaymentStatusPending, service.PaymentStatusFailed))
+	assert.True(t, service.CanAdvancePaymentStatus(service.PaymentStatusSuccess, service.PaymentStatusRefunded))
+	assert.False(t, service.CanAdvancePaymentStatus(service.PaymentStatusSuccess, service.PaymentStatusPending))
+	assert.False(t, service.CanAdvancePaymentStatus(service.PaymentStatusSuccess, service.PaymentStatusFailed))
+	assert.False(t, service.CanAdvancePaymentStatus(service.PaymentStatusFailed, service.PaymentStatusSuccess))
+}
+
+// statusGateway is a payment gateway whose transactions all have the same status at Midtrans
+type statusGateway struct {
+	service.MockPayment
+	status string
+	checks int
+}
+
+func (g *statusGateway) CheckTransactionStatus(orderID string) (interface{}, error) {
+	g.checks++
+	return map[string]string{"order_id": orderID, "transaction_status": g.status}, nil
+}
+
+func TestHandlePaymentNotification(t *testing.T) {
+	type session struct {
+		app          *fiber.App
+		gateway      *statusGateway
+		subscription *model.UserSubscription
+	}
+
+	setup := func(t *testing.T) session {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+
+		var plan model.SubscriptionPlan
+		assert.Nil(t, test.DB.First(&plan, "name = ?", "Sehat").Error)
+		subscription := &model.UserSubscription{
+			UserID:        user.ID,
+			PlanID:        plan.ID,
+			StartDate:     time.Now(),
+			EndDate:       time.Now().AddDate(0, 0, plan.ValidityDays),
+			PaymentMethod: "gopay",
+			TransactionID: "SUB-" + user.ID.String()[:8],
+			PaymentStatus: service.PaymentStatusPending,
+			Status:        model.SubscriptionStatusPending,
+		}
+		assert.Nil(t, test.DB.Create(subscription).Error)
+
+		gateway := &statusGateway{status: "settlement"}
+		subscriptionService := service.NewSubscriptionService(test.DB, gateway)
+		app := fiber.New()
+		app.Post("/notification", func(c *fiber.Ctx) error {
+			return subscriptionService.HandlePaymentNotification(c, c.Body())
+		})
+		return session{app: app, gateway: gateway, subscription: subscription}
+	}
+
+	notify := func(t *testing.T, s session, transactionID, status string) {
+		body, err := json.Marshal(map[string]string{
+			"order_id":           s.subscription.TransactionID,
+			"transaction_id":     transactionID,
+			"transaction_status": status,
+			"gross_amount":       "50000.00",
+			"transaction_time":   "2026-10-17 10:00:00",
+		})
+		assert.Nil(t, err)
+
+		request := httptest.NewRequest(http.MethodPost, "/notification", strings.NewReader(string(body)))
+		request.Header.Set("Content-Type", "application/json")
+		apiResponse, err := s.app.Test(request, -1)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+	}
+
+	stored := func(t *testing.T, s session) *model.UserSubscription {
+		subscription := new(model.UserSubscription)
+		assert.Nil(t, test.DB.First(subscription, "id = ?", s.subscription.ID).Error)
+		return subscription
+	}
+
+	count := func(value interface{}, s session) int64 {
+		var total int64
+		test.DB.Model(value).Where("user_subscription_id = ?", s.subscription.ID).Count(&total)
+		return total
+	}
+
+	t.Run("should activate the subscription once when a settlement is repeated", func(t *testing.T) {
+		s := setup(t)
+
+		notify(t, s, "tx-1", "settlement")
+		notify(t, s, "tx-1", "settlement")
+
+		subscription := stored(t, s)
+		assert.Equal(t, service.PaymentStatusSuccess, subscription.PaymentStatus)
+		assert.Equal(t, model.SubscriptionStatusActive, subscription.Status)
+		assert.Equal(t, int64(1), count(&model.TransactionDetail{}, s))
+		assert.Equal(t, int64(1), count(&model.SubscriptionTransition{}, s))
+	})
+
+	t.Run("should not move a settled payment back when a late notification arrives", func(t *testing.T) {
+		s := setup(t)
+
+		notify(t, s, "tx-1", "settlement")
+		notify(t, s, "tx-1", "pending")
+		notify(t, s, "tx-1", "expire")
+
+		subscription := stored(t, s)
+		assert.Equal(t, service.PaymentStatusSuccess, subscription.PaymentStatus)
+		assert.Equal(t, model.SubscriptionStatusActive, subscription.Status)
+		assert.Equal(t, int64(3), count(&model.TransactionDetail{}, s))
+		assert.Equal(t, 2, s.gateway.checks)
+	})
+
+	t.Run("should reconcile unknown statuses with Midtrans", func(t *testing.T) {
+		s := setup(t)
+
+		notify(t, s, "tx-1", "mystery")
+
+		subscription := stored(t, s)
+		assert.Equal(t, 1, s.gateway.checks)
+		assert.Equal(t, service.PaymentStatusSuccess, subscription.PaymentStatus)
+		assert.Equal(t, model.SubscriptionStatusActive, subscription.Status)
+	})
+
+	t.Run("should cancel the subscription when the payment expires", func(t *testing.T) {
+		s := setup(t)
+
+		notify(t, s, "tx-1", "pending")
+		notify(t, s, "tx-1", "expire")
+
+		subscription := stored(t, s)
+		assert.Equal(t, service.PaymentStatusFailed, subscription.PaymentStatus)
+		assert.Equal(t, model.SubscriptionStatusCancelled, subscription.Status)
+		assert.Zero(t, s.gateway.checks)
+	})
+}
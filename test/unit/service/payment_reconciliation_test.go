@@ -0,0 +1,193 @@
+package service_test
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/service"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// midtransStandIn serves the Midtrans status API for the orders in statuses, any other order doesn't exist
+func midtransStandIn(t *testing.T, statuses map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orderID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/"), "/status")
+		w.Header().Set("Content-Type", "application/json")
+
+		status, exists := statuses[orderID]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"status_code":    "404",
+				"status_message": "Transaction doesn't exist.",
+			})
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"status_code":        "200",
+			"order_id":           orderID,
+			"transaction_id":     "tx-" + orderID,
+			"transaction_status": status,
+			"gross_amount":       "50000.00",
+			"payment_type":       "gopay",
+			"transaction_time":   "2026-10-17 10:00:00",
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestReconcilePayments(t *testing.T) {
+	originalURL, originalKey := config.MidtransAPIURL, config.MidtransServerKey
+	t.Cleanup(func() {
+		config.MidtransAPIURL, config.MidtransServerKey = originalURL, originalKey
+	})
+	config.MidtransServerKey = "SB-Mid-server-test"
+
+	setup := func(t *testing.T, statuses map[string]string) (*model.User, service.SubscriptionService) {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+
+		config.MidtransAPIURL = midtransStandIn(t, statuses).URL
+		return user, service.NewSubscriptionService(test.DB, service.NewMidtransPaymentService())
+	}
+
+	insertOrder := func(t *testing.T, user *model.User, orderID string, age time.Duration) *model.UserSubscription {
+		var plan model.SubscriptionPlan
+		assert.Nil(t, test.DB.First(&plan, "name = ?", "Sehat").Error)
+		subscription := &model.UserSubscription{
+			UserID:        user.ID,
+			PlanID:        plan.ID,
+			StartDate:     time.Now(),
+			EndDate:       time.Now().AddDate(0, 0, plan.ValidityDays),
+			PaymentMethod: "gopay",
+			TransactionID: orderID,
+			PaymentStatus: service.PaymentStatusPending,
+			Status:        model.SubscriptionStatusPending,
+		}
+		assert.Nil(t, test.DB.Create(subscription).Error)
+		assert.Nil(t, test.DB.Model(subscription).Update("created_at", time.Now().Add(-age)).Error)
+		return subscription
+	}
+
+	t.Run("should apply a settlement whose notification never arrived", func(t *testing.T) {
+		user, subscriptionService := setup(t, map[string]string{"SUB-settled": "settlement"})
+		subscription := insertOrder(t, user, "SUB-settled", time.Hour)
+
+		report, err := subscriptionService.ReconcilePayments(context.Background(), time.Now())
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, report.Checked)
+		assert.Equal(t, 1, report.Mismatches)
+		assert.Equal(t, 1, report.Applied)
+		assert.Equal(t, "settlement", report.Items[0].GatewayStatus)
+		assert.Equal(t, model.ReconciliationActionApplied, report.Items[0].Action)
+
+		stored := reloadSubscription(t, subscription.ID)
+		assert.Equal(t, service.PaymentStatusSuccess, stored.PaymentStatus)
+		assert.Equal(t, model.SubscriptionStatusActive, stored.Status)
+
+		var details int64
+		test.DB.Model(&model.TransactionDetail{}).Where("user_subscription_id = ?", subscription.ID).Count(&details)
+		assert.Equal(t, int64(1), details)
+	})
+
+	t.Run("should leave orders that are still pending at Midtrans out of the mismatches", func(t *testing.T) {
+		user, subscriptionService := setup(t, map[string]string{"SUB-pending": "pending"})
+		subscription := insertOrder(t, user, "SUB-pending", time.Hour)
+
+		report, err := subscriptionService.ReconcilePayments(context.Background(), time.Now())
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, report.Checked)
+		assert.Zero(t, report.Mismatches)
+		assert.Equal(t, model.SubscriptionStatusPending, reloadSubscription(t, subscription.ID).Status)
+	})
+
+	t.Run("should not check orders that are not stale yet", func(t *testing.T) {
+		user, subscriptionService := setup(t, map[string]string{"SUB-fresh": "settlement"})
+		subscription := insertOrder(t, user, "SUB-fresh", time.Minute)
+
+		report, err := subscriptionService.ReconcilePayments(context.Background(), time.Now())
+
+		assert.Nil(t, err)
+		assert.Zero(t, report.Checked)
+		assert.Equal(t, service.PaymentStatusPending, reloadSubscription(t, subscription.ID).PaymentStatus)
+
+		var reports int64
+		test.DB.Model(&model.PaymentReconciliation{}).Count(&reports)
+		assert.Zero(t, reports)
+	})
+
+	t.Run("should fail orders unknown to Midtrans only after the pending expiry", func(t *testing.T) {
+		user, subscriptionService := setup(t, map[string]string{})
+		recent := insertOrder(t, user, "SUB-recent", time.Hour)
+		abandoned := insertOrder(t, user, "SUB-abandoned", time.Duration(config.PaymentPendingExpiryHours+1)*time.Hour)
+
+		report, err := subscriptionService.ReconcilePayments(context.Background(), time.Now())
+
+		assert.Nil(t, err)
+		assert.Equal(t, 2, report.Checked)
+		assert.Equal(t, 1, report.Mismatches)
+		assert.Equal(t, model.ReconciliationActionExpired, report.Items[0].Action)
+		assert.Equal(t, abandoned.ID, report.Items[0].UserSubscriptionID)
+
+		assert.Equal(t, model.SubscriptionStatusPending, reloadSubscription(t, recent.ID).Status)
+		stored := reloadSubscription(t, abandoned.ID)
+		assert.Equal(t, service.PaymentStatusFailed, stored.PaymentStatus)
+		assert.Equal(t, model.SubscriptionStatusCancelled, stored.Status)
+	})
+
+	t.Run("should store the report with its mismatches", func(t *testing.T) {
+		user, subscriptionService := setup(t, map[string]string{"SUB-expired": "expire"})
+		insertOrder(t, user, "SUB-expired", time.Hour)
+
+		report, err := subscriptionService.ReconcilePayments(context.Background(), time.Now())
+		assert.Nil(t, err)
+
+		stored := new(model.PaymentReconciliation)
+		assert.Nil(t, test.DB.Preload("Items").First(stored, "id = ?", report.ID).Error)
+		assert.Equal(t, 1, stored.Mismatches)
+		assert.Len(t, stored.Items, 1)
+		assert.Equal(t, "SUB-expired", stored.Items[0].OrderID)
+		assert.Equal(t, service.PaymentStatusPending, stored.Items[0].LocalStatus)
+		assert.Equal(t, "expire", stored.Items[0].GatewayStatus)
+	})
+
+	t.Run("should get to newer orders when a full batch stays pending at Midtrans", func(t *testing.T) {
+		// One more order than a run checks, all still pending at Midtrans
+		statuses := map[string]string{}
+		for i := 0; i <= 100; i++ {
+			statuses[fmt.Sprintf("SUB-pending-%03d", i)] = "pending"
+		}
+		user, subscriptionService := setup(t, statuses)
+
+		var newest *model.UserSubscription
+		for i := 0; i <= 100; i++ {
+			newest = insertOrder(t, user, fmt.Sprintf("SUB-pending-%03d", i), time.Duration(200-i)*time.Hour)
+		}
+
+		report, err := subscriptionService.ReconcilePayments(context.Background(), time.Now())
+		assert.Nil(t, err)
+		assert.Equal(t, 100, report.Checked)
+		assert.Nil(t, reloadSubscription(t, newest.ID).LastReconciledAt)
+
+		_, err = subscriptionService.ReconcilePayments(context.Background(), time.Now())
+		assert.Nil(t, err)
+		assert.NotNil(t, reloadSubscription(t, newest.ID).LastReconciledAt)
+	})
+}
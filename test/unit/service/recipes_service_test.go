@@ -0,0 +1,128 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructuredRecipes(t *testing.T) {
+	recipesService := service.NewRecipesService(test.DB, validator.New(), newStubBahanMakananService())
+
+	setup := func() *model.User {
+		helper.ClearAll(test.DB)
+		helper.ClearRecipes(test.DB)
+
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+		return user
+	}
+
+	newRecipe := func() *validation.CreateRecipe {
+		return &validation.CreateRecipe{
+			Name:     "Nasi pisang",
+			Slug:     "nasi-pisang",
+			Day:      "monday",
+			Servings: 2,
+			IngredientLines: []validation.RecipeIngredient{
+				{Kode: "AR001", Quantity: 0.3, Unit: "kg"},
+				{Kode: "ER001", Quantity: 200, Unit: "g"},
+			},
+			Steps: []string{"Tanak nasi", "Iris pisang"},
+		}
+	}
+
+	t.Run("should compute nutrients per serving from the edible part of every line", func(t *testing.T) {
+		user := setup()
+
+		runAsUser(t, user, func(c *fiber.Ctx) error {
+			recipe, err := recipesService.CreateRecipe(c, user.ID, newRecipe())
+			assert.NoError(t, err)
+
+			// 300 g of rice and 150 g of edible banana, split in two servings
+			assert.Len(t, recipe.IngredientLines, 2)
+			assert.Equal(t, 300.0, recipe.IngredientLines[0].Grams)
+			assert.Equal(t, 150.0, recipe.IngredientLines[1].EdibleGrams)
+			assert.InDelta(t, (540+162)/2.0, *recipe.Calories, 0.01)
+			assert.InDelta(t, (9+1.5)/2.0, *recipe.Protein, 0.01)
+
+			sodium, iron := -1.0, -1.0
+			for _, nutrient := range recipe.Nutrients {
+				switch nutrient.Code {
+				case model.NutrientSodium:
+					sodium = nutrient.Quantity
+				case model.NutrientIron:
+					iron = nutrient.Quantity
+				}
+			}
+			assert.InDelta(t, 3, sodium, 0.001)
+			assert.InDelta(t, 0.45, iron, 0.001)
+
+			assert.Len(t, recipe.Steps, 2)
+			assert.Equal(t, "Iris pisang", recipe.Steps[1].Instruction)
+			assert.Contains(t, recipe.Ingredients, "0.3 kg Nasi")
+			return nil
+		})
+	})
+
+	t.Run("should reject kodes the bahan makanan service doesn't know", func(t *testing.T) {
+		user := setup()
+
+		runAsUser(t, user, func(c *fiber.Ctx) error {
+			req := newRecipe()
+			req.IngredientLines[1].Kode = "XX999"
+
+			_, err := recipesService.CreateRecipe(c, user.ID, req)
+
+			var fiberErr *fiber.Error
+			assert.ErrorAs(t, err, &fiberErr)
+			assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code)
+			return nil
+		})
+	})
+
+	t.Run("should not blame the recipe when the bahan makanan service is unreachable", func(t *testing.T) {
+		user := setup()
+		unreachable := newStubBahanMakananService()
+		unreachable.err = fiber.NewError(fiber.StatusServiceUnavailable, "Bahan makanan service is unavailable")
+		recipesService := service.NewRecipesService(test.DB, validator.New(), unreachable)
+
+		runAsUser(t, user, func(c *fiber.Ctx) error {
+			_, err := recipesService.CreateRecipe(c, user.ID, newRecipe())
+
+			var fiberErr *fiber.Error
+			assert.ErrorAs(t, err, &fiberErr)
+			assert.Equal(t, fiber.StatusServiceUnavailable, fiberErr.Code)
+			return nil
+		})
+	})
+
+	t.Run("should recompute nutrients when the servings change", func(t *testing.T) {
+		user := setup()
+
+		runAsUser(t, user, func(c *fiber.Ctx) error {
+			recipe, err := recipesService.CreateRecipe(c, user.ID, newRecipe())
+			assert.NoError(t, err)
+
+			manualCalories := 10.0
+			updated, err := recipesService.UpdateRecipe(c, recipe.ID.String(), &validation.UpdateRecipe{
+				Servings: 3,
+				Calories: &manualCalories,
+			})
+			assert.NoError(t, err)
+
+			assert.Equal(t, 3, updated.Servings)
+			assert.InDelta(t, 702/3.0, *updated.Calories, 0.01)
+			assert.Len(t, updated.IngredientLines, 2)
+			return nil
+		})
+	})
+}
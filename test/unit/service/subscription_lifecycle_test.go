@@ -0,0 +1,205 @@
+package service_test
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/service"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanTransitionSubscription(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{model.SubscriptionStatusPending, model.SubscriptionStatusActive, true},
+		{model.SubscriptionStatusPending, model.SubscriptionStatusQueued, true},
+		{model.SubscriptionStatusPending, model.SubscriptionStatusGrace, false},
+		{model.SubscriptionStatusPending, model.SubscriptionStatusExpired, true},
+		{model.SubscriptionStatusQueued, model.SubscriptionStatusActive, true},
+		{model.SubscriptionStatusActive, model.SubscriptionStatusGrace, true},
+		{model.SubscriptionStatusActive, model.SubscriptionStatusPending, false},
+		{model.SubscriptionStatusGrace, model.SubscriptionStatusActive, true},
+		{model.SubscriptionStatusGrace, model.SubscriptionStatusExpired, true},
+		{model.SubscriptionStatusCancelled, model.SubscriptionStatusRefunded, true},
+		{model.SubscriptionStatusCancelled, model.SubscriptionStatusActive, false},
+		{model.SubscriptionStatusExpired, model.SubscriptionStatusActive, false},
+		{model.SubscriptionStatusRefunded, model.SubscriptionStatusActive, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.allowed, service.CanTransitionSubscription(tt.from, tt.to), "%s -> %s", tt.from, tt.to)
+	}
+}
+
+func insertLifecycleSubscription(t *testing.T, userID uuid.UUID, status, paymentMethod string, start, end time.Time) *model.UserSubscription {
+	var plan model.SubscriptionPlan
+	assert.Nil(t, test.DB.First(&plan, "name = ?", "Sehat").Error)
+
+	subscription := &model.UserSubscription{
+		UserID:        userID,
+		PlanID:        plan.ID,
+		StartDate:     start,
+		EndDate:       end,
+		IsActive:      service.SubscriptionGivesAccess(status),
+		PaymentMethod: paymentMethod,
+		PaymentStatus: "success",
+		Status:        status,
+	}
+	assert.Nil(t, test.DB.Create(subscription).Error)
+	return subscription
+}
+
+func reloadSubscription(t *testing.T, id uuid.UUID) *model.UserSubscription {
+	subscription := new(model.UserSubscription)
+	assert.Nil(t, test.DB.First(subscription, "id = ?", id).Error)
+	return subscription
+}
+
+func TestTransitionSubscription(t *testing.T) {
+	setup := func(t *testing.T) *model.User {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+		return user
+	}
+
+	t.Run("should move the subscription and record the transition", func(t *testing.T) {
+		user := setup(t)
+		now := time.Now()
+		subscription := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusPending, "gopay", now, now.AddDate(0, 0, 30))
+
+		err := service.TransitionSubscription(test.DB, subscription, model.SubscriptionStatusActive, service.TransitionReasonPaymentSettled, &user.ID)
+
+		assert.Nil(t, err)
+		stored := reloadSubscription(t, subscription.ID)
+		assert.Equal(t, model.SubscriptionStatusActive, stored.Status)
+		assert.True(t, stored.IsActive)
+
+		var transitions []model.SubscriptionTransition
+		test.DB.Where("user_subscription_id = ?", subscription.ID).Find(&transitions)
+		assert.Len(t, transitions, 1)
+		assert.Equal(t, model.SubscriptionStatusPending, transitions[0].FromStatus)
+		assert.Equal(t, model.SubscriptionStatusActive, transitions[0].ToStatus)
+		assert.Equal(t, service.TransitionReasonPaymentSettled, transitions[0].Reason)
+		assert.Equal(t, user.ID, *transitions[0].ActorID)
+	})
+
+	t.Run("should reject moves the lifecycle doesn't allow", func(t *testing.T) {
+		user := setup(t)
+		now := time.Now()
+		subscription := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusExpired, "gopay", now.AddDate(0, 0, -40), now.AddDate(0, 0, -10))
+
+		err := service.TransitionSubscription(test.DB, subscription, model.SubscriptionStatusActive, service.TransitionReasonAdmin, nil)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, model.SubscriptionStatusExpired, reloadSubscription(t, subscription.ID).Status)
+		var count int64
+		test.DB.Model(&model.SubscriptionTransition{}).Where("user_subscription_id = ?", subscription.ID).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("should fail when the subscription was moved meanwhile", func(t *testing.T) {
+		user := setup(t)
+		now := time.Now()
+		subscription := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusActive, "gopay", now, now.AddDate(0, 0, 30))
+		stale := *subscription
+		assert.Nil(t, service.TransitionSubscription(test.DB, subscription, model.SubscriptionStatusCancelled, service.TransitionReasonAdmin, nil))
+
+		err := service.TransitionSubscription(test.DB, &stale, model.SubscriptionStatusGrace, service.TransitionReasonLapsed, nil)
+
+		assert.NotNil(t, err)
+		assert.Equal(t, model.SubscriptionStatusCancelled, reloadSubscription(t, subscription.ID).Status)
+	})
+}
+
+func TestSweepSubscriptions(t *testing.T) {
+	setup := func(t *testing.T) (*model.User, service.SubscriptionService) {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+		return user, service.NewSubscriptionService(test.DB, nil)
+	}
+	grace := time.Duration(config.SubscriptionGraceDays) * 24 * time.Hour
+
+	t.Run("should put lapsed paid subscriptions in their grace period", func(t *testing.T) {
+		user, subscriptionService := setup(t)
+		now := time.Now()
+		subscription := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusActive, "gopay", now.AddDate(0, 0, -30), now.Add(-time.Hour))
+
+		report, err := subscriptionService.SweepSubscriptions(context.Background(), now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, report.Grace)
+		stored := reloadSubscription(t, subscription.ID)
+		assert.Equal(t, model.SubscriptionStatusGrace, stored.Status)
+		assert.True(t, stored.IsActive)
+	})
+
+	t.Run("should expire subscriptions past their grace period", func(t *testing.T) {
+		user, subscriptionService := setup(t)
+		now := time.Now()
+		graced := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusGrace, "gopay", now.AddDate(0, 0, -40), now.Add(-grace-time.Hour))
+
+		report, err := subscriptionService.SweepSubscriptions(context.Background(), now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, report.Expired)
+		stored := reloadSubscription(t, graced.ID)
+		assert.Equal(t, model.SubscriptionStatusExpired, stored.Status)
+		assert.False(t, stored.IsActive)
+	})
+
+	t.Run("should expire lapsed trials without a grace period", func(t *testing.T) {
+		user, subscriptionService := setup(t)
+		now := time.Now()
+		trial := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusActive, "freemium_trial", now.AddDate(0, 0, -14), now.Add(-time.Minute))
+
+		report, err := subscriptionService.SweepSubscriptions(context.Background(), now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, report.Grace)
+		assert.Equal(t, 1, report.Expired)
+		assert.Equal(t, model.SubscriptionStatusExpired, reloadSubscription(t, trial.ID).Status)
+	})
+
+	t.Run("should start a queued subscription once the previous one has ended", func(t *testing.T) {
+		user, subscriptionService := setup(t)
+		now := time.Now()
+		current := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusActive, "gopay", now.AddDate(0, 0, -30), now.Add(-time.Hour))
+		next := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusQueued, "gopay", now.Add(-time.Hour), now.AddDate(0, 0, 30))
+		later := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusQueued, "gopay", now.AddDate(0, 0, 30), now.AddDate(0, 0, 60))
+
+		report, err := subscriptionService.SweepSubscriptions(context.Background(), now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, report.Expired)
+		assert.Equal(t, 1, report.Activated)
+		assert.Equal(t, model.SubscriptionStatusExpired, reloadSubscription(t, current.ID).Status)
+		assert.Equal(t, model.SubscriptionStatusActive, reloadSubscription(t, next.ID).Status)
+		assert.Equal(t, model.SubscriptionStatusQueued, reloadSubscription(t, later.ID).Status)
+	})
+
+	t.Run("should keep a queued subscription waiting while another is active", func(t *testing.T) {
+		user, subscriptionService := setup(t)
+		now := time.Now()
+		insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusActive, "gopay", now.AddDate(0, 0, -10), now.AddDate(0, 0, 20))
+		queued := insertLifecycleSubscription(t, user.ID, model.SubscriptionStatusQueued, "gopay", now.Add(-time.Hour), now.AddDate(0, 0, 30))
+
+		report, err := subscriptionService.SweepSubscriptions(context.Background(), now)
+
+		assert.Nil(t, err)
+		assert.Equal(t, 0, report.Activated)
+		assert.Equal(t, model.SubscriptionStatusQueued, reloadSubscription(t, queued.ID).Status)
+	})
+}
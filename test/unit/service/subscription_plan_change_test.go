@@ -0,0 +1,271 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// chargeGateway is a payment gateway that keeps the amount of every transaction it creates
+type chargeGateway struct {
+	service.MockPayment
+	amounts []int
+}
+
+func (g *chargeGateway) CreateTransaction(orderID string, amount int, userDetails map[string]interface{}, paymentMethod string) (*service.PaymentToken, error) {
+	g.amounts = append(g.amounts, amount)
+	return g.MockPayment.CreateTransaction(orderID, amount, userDetails, paymentMethod)
+}
+
+func TestPurchasePlanChanges(t *testing.T) {
+	type session struct {
+		app     *fiber.App
+		gateway *chargeGateway
+		user    *model.User
+	}
+
+	setup := func(t *testing.T) session {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+
+		gateway := &chargeGateway{}
+		subscriptionService := service.NewSubscriptionService(test.DB, gateway)
+		app := fiber.New()
+		app.Post("/purchase/:plan", func(c *fiber.Ctx) error {
+			payment, err := subscriptionService.PurchasePlan(c, user.ID, uuid.MustParse(c.Params("plan")), "gopay")
+			if err != nil {
+				return err
+			}
+			return c.JSON(payment)
+		})
+		app.Post("/notification", func(c *fiber.Ctx) error {
+			return subscriptionService.HandlePaymentNotification(c, c.Body())
+		})
+		app.Get("/me", func(c *fiber.Ctx) error {
+			subscription, err := subscriptionService.GetUserActiveSubscription(c, user.ID)
+			if err != nil {
+				return err
+			}
+			return c.JSON(subscription)
+		})
+		return session{app: app, gateway: gateway, user: user}
+	}
+
+	plan := func(t *testing.T, name string) model.SubscriptionPlan {
+		var plan model.SubscriptionPlan
+		assert.Nil(t, test.DB.First(&plan, "name = ?", name).Error)
+		return plan
+	}
+
+	paidSubscription := func(t *testing.T, s session, planName string, start, end time.Time) *model.UserSubscription {
+		subscription := insertLifecycleSubscription(t, s.user.ID, model.SubscriptionStatusActive, "gopay", start, end)
+		assert.Nil(t, test.DB.Model(subscription).Update("plan_id", plan(t, planName).ID).Error)
+		return subscription
+	}
+
+	checkout := func(t *testing.T, s session, planName string) *http.Response {
+		request := httptest.NewRequest(http.MethodPost, "/purchase/"+plan(t, planName).ID.String(), nil)
+		apiResponse, err := s.app.Test(request, -1)
+		assert.Nil(t, err)
+		return apiResponse
+	}
+
+	// settle settles the payment of the order, it returns the purchased subscription
+	settle := func(t *testing.T, s session, payment *model.PaymentResponse) *model.UserSubscription {
+		body, _ := json.Marshal(map[string]string{
+			"order_id":           payment.OrderID,
+			"transaction_id":     "tx-" + payment.OrderID,
+			"transaction_status": "settlement",
+			"transaction_time":   "2026-10-17 10:00:00",
+		})
+		request := httptest.NewRequest(http.MethodPost, "/notification", strings.NewReader(string(body)))
+		request.Header.Set("Content-Type", "application/json")
+		apiResponse, err := s.app.Test(request, -1)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		subscription := new(model.UserSubscription)
+		assert.Nil(t, test.DB.First(subscription, "transaction_id = ?", payment.OrderID).Error)
+		return subscription
+	}
+
+	// purchase buys the plan and settles its payment, it returns the purchased subscription
+	purchase := func(t *testing.T, s session, planName string) (*model.PaymentResponse, *model.UserSubscription) {
+		apiResponse := checkout(t, s, planName)
+		assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+		payment := new(model.PaymentResponse)
+		assert.Nil(t, json.NewDecoder(apiResponse.Body).Decode(payment))
+		return payment, settle(t, s, payment)
+	}
+
+	current := func(t *testing.T, s session) *model.UserSubscriptionResponse {
+		apiResponse, err := s.app.Test(httptest.NewRequest(http.MethodGet, "/me", nil), -1)
+		assert.Nil(t, err)
+		subscription := new(model.UserSubscriptionResponse)
+		assert.Nil(t, json.NewDecoder(apiResponse.Body).Decode(subscription))
+		return subscription
+	}
+
+	t.Run("should start an upgrade now and credit the unused value of the old plan", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		old := paidSubscription(t, s, "Sehat", now.AddDate(0, 0, -15), now.AddDate(0, 0, 15))
+
+		payment, upgrade := purchase(t, s, "Sultan")
+
+		assert.Equal(t, model.PlanChangeUpgrade, payment.ChangeType)
+		assert.InDelta(t, 15000, payment.CreditAmount, 1)
+		assert.Equal(t, 120000-payment.CreditAmount, s.gateway.amounts[0])
+		assert.Equal(t, model.SubscriptionStatusActive, upgrade.Status)
+		assert.Equal(t, model.SubscriptionStatusExpired, reloadSubscription(t, old.ID).Status)
+		assert.Equal(t, upgrade.ID, current(t, s).ID)
+	})
+
+	t.Run("should queue a downgrade until the current plan ends", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		old := paidSubscription(t, s, "Sultan", now.AddDate(0, 0, -10), now.AddDate(0, 0, 80))
+
+		payment, downgrade := purchase(t, s, "Sehat")
+
+		assert.Equal(t, model.PlanChangeDowngrade, payment.ChangeType)
+		assert.Equal(t, 30000, s.gateway.amounts[0])
+		assert.Equal(t, model.SubscriptionStatusQueued, downgrade.Status)
+		assert.WithinDuration(t, old.EndDate, downgrade.StartDate, time.Second)
+		assert.WithinDuration(t, old.EndDate.AddDate(0, 0, 30), downgrade.EndDate, time.Second)
+		assert.Equal(t, model.SubscriptionStatusActive, reloadSubscription(t, old.ID).Status)
+
+		active := current(t, s)
+		assert.Equal(t, old.ID, active.ID)
+		assert.Len(t, active.Upcoming, 1)
+		assert.Equal(t, downgrade.ID, active.Upcoming[0].ID)
+	})
+
+	t.Run("should queue a renewal of the same plan after the renewed subscription", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		old := paidSubscription(t, s, "Sehat", now.AddDate(0, 0, -20), now.AddDate(0, 0, 10))
+
+		payment, renewal := purchase(t, s, "Sehat")
+
+		assert.Equal(t, model.PlanChangeRenewal, payment.ChangeType)
+		assert.Equal(t, 30000, s.gateway.amounts[0])
+		assert.Equal(t, model.SubscriptionStatusQueued, renewal.Status)
+		assert.WithinDuration(t, old.EndDate, renewal.StartDate, time.Second)
+		assert.WithinDuration(t, old.EndDate.AddDate(0, 0, 30), renewal.EndDate, time.Second)
+
+		renewed := reloadSubscription(t, old.ID)
+		assert.Equal(t, model.SubscriptionStatusActive, renewed.Status)
+		assert.WithinDuration(t, old.EndDate, renewed.EndDate, time.Second)
+
+		active := current(t, s)
+		assert.Equal(t, old.ID, active.ID)
+		assert.Len(t, active.Upcoming, 1)
+		assert.Equal(t, renewal.ID, active.Upcoming[0].ID)
+	})
+
+	t.Run("should start a renewal right away when the renewed subscription is in its grace period", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		old := paidSubscription(t, s, "Sehat", now.AddDate(0, 0, -31), now.AddDate(0, 0, -1))
+		assert.Nil(t, test.DB.Model(old).Update("status", model.SubscriptionStatusGrace).Error)
+
+		_, renewal := purchase(t, s, "Sehat")
+
+		assert.Equal(t, model.SubscriptionStatusActive, renewal.Status)
+		assert.WithinDuration(t, old.EndDate, renewal.StartDate, time.Second)
+		assert.Equal(t, model.SubscriptionStatusExpired, reloadSubscription(t, old.ID).Status)
+		assert.Equal(t, renewal.ID, current(t, s).ID)
+	})
+
+	t.Run("should refuse a second upgrade while the first waits for payment", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		paidSubscription(t, s, "Sehat", now.AddDate(0, 0, -15), now.AddDate(0, 0, 15))
+
+		assert.Equal(t, http.StatusOK, checkout(t, s, "Sultan").StatusCode)
+		apiResponse := checkout(t, s, "Sultan")
+
+		body, err := io.ReadAll(apiResponse.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, service.ErrPlanChangePending.Error(), string(body))
+		assert.Len(t, s.gateway.amounts, 1)
+
+		var pending int64
+		assert.Nil(t, test.DB.Model(&model.UserSubscription{}).
+			Where("user_id = ? AND status = ?", s.user.ID, model.SubscriptionStatusPending).
+			Count(&pending).Error)
+		assert.Equal(t, int64(1), pending)
+	})
+
+	t.Run("should start a purchase from when its payment settles", func(t *testing.T) {
+		s := setup(t)
+		apiResponse := checkout(t, s, "Sehat")
+		payment := new(model.PaymentResponse)
+		assert.Nil(t, json.NewDecoder(apiResponse.Body).Decode(payment))
+
+		// A bank transfer paid three days after checkout
+		checkedOut := time.Now().AddDate(0, 0, -3)
+		assert.Nil(t, test.DB.Model(&model.UserSubscription{}).
+			Where("transaction_id = ?", payment.OrderID).
+			Updates(map[string]interface{}{"start_date": checkedOut, "end_date": checkedOut.AddDate(0, 0, 30)}).Error)
+
+		subscription := settle(t, s, payment)
+
+		assert.Equal(t, model.SubscriptionStatusActive, subscription.Status)
+		assert.WithinDuration(t, time.Now(), subscription.StartDate, time.Minute)
+		assert.WithinDuration(t, time.Now().AddDate(0, 0, 30), subscription.EndDate, time.Minute)
+	})
+
+	t.Run("should take credit an upgrade no longer earns off its period", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		old := paidSubscription(t, s, "Sehat", now.AddDate(0, 0, -15), now.AddDate(0, 0, 15))
+
+		apiResponse := checkout(t, s, "Sultan")
+		payment := new(model.PaymentResponse)
+		assert.Nil(t, json.NewDecoder(apiResponse.Body).Decode(payment))
+		assert.InDelta(t, 15000, payment.CreditAmount, 1)
+
+		// The replaced subscription ran out before the payment settled
+		assert.Nil(t, test.DB.Model(old).Update("status", model.SubscriptionStatusExpired).Error)
+
+		upgrade := settle(t, s, payment)
+
+		sultan := plan(t, "Sultan")
+		validity := time.Duration(sultan.ValidityDays) * 24 * time.Hour
+		paid := validity - time.Duration(int64(validity)*int64(payment.CreditAmount)/int64(sultan.Price))
+		assert.Equal(t, model.SubscriptionStatusActive, upgrade.Status)
+		assert.WithinDuration(t, upgrade.StartDate.Add(paid), upgrade.EndDate, time.Minute)
+		assert.Equal(t, upgrade.ID, current(t, s).ID)
+	})
+
+	t.Run("should start a first purchase now and end the trial", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		trial := insertLifecycleSubscription(t, s.user.ID, model.SubscriptionStatusActive, "freemium_trial", now, now.AddDate(0, 0, 14))
+
+		payment, subscription := purchase(t, s, "Sehat")
+
+		assert.Equal(t, model.PlanChangeNew, payment.ChangeType)
+		assert.Equal(t, model.SubscriptionStatusActive, subscription.Status)
+		assert.Equal(t, model.SubscriptionStatusExpired, reloadSubscription(t, trial.ID).Status)
+		assert.Equal(t, subscription.ID, current(t, s).ID)
+	})
+}
@@ -0,0 +1,384 @@
+package service_test
+
+import (
+	"app/src/config"
+	"app/src/model"
+	"app/src/service"
+	"app/src/validation"
+	"app/test"
+	"app/test/fixture"
+	"app/test/helper"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefundAmount(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+
+	assert.Equal(t, 30000, service.RefundAmount(service.RefundTypeFull, 30000, start, end, start.AddDate(0, 0, 20)))
+	assert.Equal(t, 10000, service.RefundAmount(service.RefundTypeProrated, 30000, start, end, start.AddDate(0, 0, 20)))
+	assert.Equal(t, 30000, service.RefundAmount(service.RefundTypeProrated, 30000, start, end, start.AddDate(0, 0, -1)))
+	assert.Equal(t, 0, service.RefundAmount(service.RefundTypeProrated, 30000, start, end, end.Add(time.Hour)))
+}
+
+// refundRequest is a refund received by the Midtrans stand-in
+type refundRequest struct {
+	OrderID   string
+	RefundKey string `json:"refund_key"`
+	Amount    int64  `json:"amount"`
+	Reason    string `json:"reason"`
+}
+
+// midtransRefundStandIn serves the Midtrans refund API and keeps the refunds it received. Like Midtrans it
+// rejects a refund key it has seen before.
+func midtransRefundStandIn(t *testing.T) *[]refundRequest {
+	refunds := &[]refundRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var refund refundRequest
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&refund))
+		refund.OrderID = strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/"), "/refund")
+
+		w.Header().Set("Content-Type", "application/json")
+		for _, earlier := range *refunds {
+			if earlier.RefundKey == refund.RefundKey {
+				_ = json.NewEncoder(w).Encode(map[string]string{
+					"status_code":    "406",
+					"status_message": "Duplicate refund_key",
+				})
+				return
+			}
+		}
+		*refunds = append(*refunds, refund)
+
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"status_code":        "200",
+			"status_message":     "Success, refund request is approved",
+			"order_id":           refund.OrderID,
+			"transaction_id":     "tx-" + refund.OrderID,
+			"transaction_status": "refund",
+			"refund_amount":      strconv.FormatInt(refund.Amount, 10),
+			"refund_key":         refund.RefundKey,
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	originalURL, originalKey := config.MidtransAPIURL, config.MidtransServerKey
+	t.Cleanup(func() {
+		config.MidtransAPIURL, config.MidtransServerKey = originalURL, originalKey
+	})
+	config.MidtransAPIURL, config.MidtransServerKey = server.URL, "SB-Mid-server-test"
+	return refunds
+}
+
+// settledPurchase buys the plan for the user through a gateway stand-in and settles its payment
+func settledPurchase(t *testing.T, userID, planID uuid.UUID) *model.UserSubscription {
+	subscriptionService := service.NewSubscriptionService(test.DB, &service.MockPayment{})
+	app := fiber.New()
+	app.Post("/purchase", func(c *fiber.Ctx) error {
+		payment, err := subscriptionService.PurchasePlan(c, userID, planID, "gopay")
+		if err != nil {
+			return err
+		}
+		return c.JSON(payment)
+	})
+	app.Post("/notification", func(c *fiber.Ctx) error {
+		return subscriptionService.HandlePaymentNotification(c, c.Body())
+	})
+
+	apiResponse, err := app.Test(httptest.NewRequest(http.MethodPost, "/purchase", nil), -1)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+	payment := new(model.PaymentResponse)
+	assert.Nil(t, json.NewDecoder(apiResponse.Body).Decode(payment))
+
+	body, _ := json.Marshal(map[string]string{
+		"order_id":           payment.OrderID,
+		"transaction_id":     "tx-" + payment.OrderID,
+		"transaction_status": "settlement",
+		"transaction_time":   "2026-10-17 10:00:00",
+	})
+	request := httptest.NewRequest(http.MethodPost, "/notification", strings.NewReader(string(body)))
+	request.Header.Set("Content-Type", "application/json")
+	apiResponse, err = app.Test(request, -1)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, apiResponse.StatusCode)
+
+	subscription := new(model.UserSubscription)
+	assert.Nil(t, test.DB.First(subscription, "transaction_id = ?", payment.OrderID).Error)
+	return subscription
+}
+
+func TestRefundSubscription(t *testing.T) {
+	type session struct {
+		app     *fiber.App
+		refunds *[]refundRequest
+		user    *model.User
+	}
+
+	setup := func(t *testing.T) session {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+
+		refunds := midtransRefundStandIn(t)
+		subscriptionService := service.NewSubscriptionService(test.DB, service.NewMidtransPaymentService())
+		app := fiber.New()
+		app.Post("/subscriptions/:id/refund", func(c *fiber.Ctx) error {
+			req := new(validation.RefundSubscription)
+			if err := c.BodyParser(req); err != nil {
+				return err
+			}
+			refund, err := subscriptionService.RefundSubscription(c, uuid.MustParse(c.Params("id")), req)
+			if err != nil {
+				return err
+			}
+			return c.JSON(refund)
+		})
+		return session{app: app, refunds: refunds, user: user}
+	}
+
+	paidSubscription := func(t *testing.T, s session, start, end time.Time) *model.UserSubscription {
+		subscription := insertLifecycleSubscription(t, s.user.ID, model.SubscriptionStatusActive, "gopay", start, end)
+		subscription.TransactionID = "SUB-" + subscription.ID.String()[:8]
+		assert.Nil(t, test.DB.Model(subscription).Update("transaction_id", subscription.TransactionID).Error)
+		return subscription
+	}
+
+	refund := func(t *testing.T, s session, subscriptionID uuid.UUID, refundType string) int {
+		request := httptest.NewRequest(http.MethodPost, "/subscriptions/"+subscriptionID.String()+"/refund",
+			strings.NewReader(`{"type":"`+refundType+`","reason":"requested by user"}`))
+		request.Header.Set("Content-Type", "application/json")
+		apiResponse, err := s.app.Test(request, -1)
+		assert.Nil(t, err)
+		return apiResponse.StatusCode
+	}
+
+	refundDetails := func(subscriptionID uuid.UUID) []model.TransactionDetail {
+		var details []model.TransactionDetail
+		test.DB.Where("user_subscription_id = ? AND type = ?", subscriptionID, model.TransactionTypeRefund).Find(&details)
+		return details
+	}
+
+	t.Run("should refund the whole price and revoke access", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		subscription := paidSubscription(t, s, now.AddDate(0, 0, -10), now.AddDate(0, 0, 20))
+
+		assert.Equal(t, http.StatusOK, refund(t, s, subscription.ID, service.RefundTypeFull))
+
+		var plan model.SubscriptionPlan
+		test.DB.First(&plan, "id = ?", subscription.PlanID)
+		assert.Len(t, *s.refunds, 1)
+		assert.Equal(t, subscription.TransactionID, (*s.refunds)[0].OrderID)
+		assert.Equal(t, int64(plan.Price), (*s.refunds)[0].Amount)
+		assert.Equal(t, subscription.TransactionID+"-refund", (*s.refunds)[0].RefundKey)
+
+		stored := reloadSubscription(t, subscription.ID)
+		assert.Equal(t, model.SubscriptionStatusRefunded, stored.Status)
+		assert.Equal(t, service.PaymentStatusRefunded, stored.PaymentStatus)
+		assert.False(t, stored.IsActive)
+
+		details := refundDetails(subscription.ID)
+		assert.Len(t, details, 1)
+		assert.Equal(t, "refund", details[0].TransactionStatus)
+	})
+
+	t.Run("should refund only the time left when prorated", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		subscription := paidSubscription(t, s, now.AddDate(0, 0, -15), now.AddDate(0, 0, 15))
+
+		assert.Equal(t, http.StatusOK, refund(t, s, subscription.ID, service.RefundTypeProrated))
+
+		var plan model.SubscriptionPlan
+		test.DB.First(&plan, "id = ?", subscription.PlanID)
+		assert.Len(t, *s.refunds, 1)
+		assert.InDelta(t, float64(plan.Price)/2, float64((*s.refunds)[0].Amount), 1)
+
+		details := refundDetails(subscription.ID)
+		assert.Len(t, details, 1)
+		assert.Equal(t, "partial_refund", details[0].TransactionStatus)
+	})
+
+	t.Run("should not refund a subscription twice", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		subscription := paidSubscription(t, s, now.AddDate(0, 0, -10), now.AddDate(0, 0, 20))
+
+		assert.Equal(t, http.StatusOK, refund(t, s, subscription.ID, service.RefundTypeFull))
+		assert.Equal(t, http.StatusConflict, refund(t, s, subscription.ID, service.RefundTypeFull))
+
+		assert.Len(t, *s.refunds, 1)
+		assert.Len(t, refundDetails(subscription.ID), 1)
+	})
+
+	t.Run("should refund a renewed subscription and its renewal each for their own order", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		renewed := paidSubscription(t, s, now.AddDate(0, 0, -20), now.AddDate(0, 0, 10))
+		renewal := settledPurchase(t, s.user.ID, renewed.PlanID)
+		assert.Equal(t, model.SubscriptionStatusQueued, renewal.Status)
+
+		assert.Equal(t, http.StatusOK, refund(t, s, renewed.ID, service.RefundTypeProrated))
+		assert.Equal(t, http.StatusOK, refund(t, s, renewal.ID, service.RefundTypeProrated))
+
+		var plan model.SubscriptionPlan
+		test.DB.First(&plan, "id = ?", renewed.PlanID)
+		assert.Len(t, *s.refunds, 2)
+		assert.Equal(t, renewed.TransactionID, (*s.refunds)[0].OrderID)
+		assert.InDelta(t, float64(plan.Price)/3, float64((*s.refunds)[0].Amount), 1)
+		// The renewal hasn't started yet, so all of it is paid back
+		assert.Equal(t, renewal.TransactionID, (*s.refunds)[1].OrderID)
+		assert.Equal(t, int64(plan.Price), (*s.refunds)[1].Amount)
+
+		assert.Equal(t, model.SubscriptionStatusRefunded, reloadSubscription(t, renewed.ID).Status)
+		assert.Equal(t, model.SubscriptionStatusRefunded, reloadSubscription(t, renewal.ID).Status)
+		assert.Len(t, refundDetails(renewal.ID), 1)
+	})
+
+	t.Run("should complete a refund the gateway made before it was recorded", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		subscription := paidSubscription(t, s, now.AddDate(0, 0, -10), now.AddDate(0, 0, 20))
+
+		// An earlier attempt saved its pending refund and reached Midtrans, then failed to complete
+		assert.Nil(t, test.DB.Create(&model.TransactionDetail{
+			UserSubscriptionID: subscription.ID,
+			Type:               model.TransactionTypeRefund,
+			OrderID:            subscription.TransactionID,
+			TransactionID:      subscription.TransactionID + "-refund",
+			TransactionStatus:  "refund_pending",
+			TransactionTime:    now,
+			GrossAmount:        "30000",
+			Currency:           "IDR",
+		}).Error)
+		_, err := service.NewMidtransPaymentService().Refund(subscription.TransactionID, &service.RefundRequest{
+			RefundKey: subscription.TransactionID + "-refund",
+			Amount:    30000,
+		})
+		assert.Nil(t, err)
+
+		assert.Equal(t, http.StatusOK, refund(t, s, subscription.ID, service.RefundTypeFull))
+
+		assert.Len(t, *s.refunds, 1)
+		assert.Equal(t, model.SubscriptionStatusRefunded, reloadSubscription(t, subscription.ID).Status)
+		details := refundDetails(subscription.ID)
+		assert.Len(t, details, 1)
+		assert.Equal(t, "refund", details[0].TransactionStatus)
+	})
+
+	t.Run("should not refund trials", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		trial := insertLifecycleSubscription(t, s.user.ID, model.SubscriptionStatusActive, "freemium_trial", now, now.AddDate(0, 0, 14))
+		assert.Nil(t, test.DB.Model(trial).Update("payment_status", service.PaymentStatusCompleted).Error)
+
+		assert.Equal(t, http.StatusConflict, refund(t, s, trial.ID, service.RefundTypeFull))
+		assert.Empty(t, *s.refunds)
+		assert.Equal(t, model.SubscriptionStatusActive, reloadSubscription(t, trial.ID).Status)
+	})
+}
+
+func TestCancelSubscription(t *testing.T) {
+	type session struct {
+		app                 *fiber.App
+		user                *model.User
+		subscriptionService service.SubscriptionService
+	}
+
+	setup := func(t *testing.T) session {
+		helper.ClearAll(test.DB)
+		helper.ClearSubscriptions(test.DB)
+		user := fixture.UserWithFreemium()
+		helper.InsertUser(test.DB, user)
+
+		subscriptionService := service.NewSubscriptionService(test.DB, nil)
+		app := fiber.New()
+		app.Post("/cancel", func(c *fiber.Ctx) error {
+			subscription, err := subscriptionService.CancelSubscription(c, user.ID)
+			if err != nil {
+				return err
+			}
+			return c.JSON(subscription)
+		})
+		app.Post("/resume", func(c *fiber.Ctx) error {
+			subscription, err := subscriptionService.ResumeSubscription(c, user.ID)
+			if err != nil {
+				return err
+			}
+			return c.JSON(subscription)
+		})
+		return session{app: app, user: user, subscriptionService: subscriptionService}
+	}
+
+	call := func(t *testing.T, s session, path string) int {
+		apiResponse, err := s.app.Test(httptest.NewRequest(http.MethodPost, path, nil), -1)
+		assert.Nil(t, err)
+		return apiResponse.StatusCode
+	}
+
+	t.Run("should keep access until the end of the period and then cancel", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		subscription := insertLifecycleSubscription(t, s.user.ID, model.SubscriptionStatusActive, "gopay", now.AddDate(0, 0, -10), now.AddDate(0, 0, 20))
+
+		assert.Equal(t, http.StatusOK, call(t, s, "/cancel"))
+
+		stored := reloadSubscription(t, subscription.ID)
+		assert.True(t, stored.CancelAtPeriodEnd)
+		assert.Equal(t, model.SubscriptionStatusActive, stored.Status)
+		assert.True(t, stored.IsActive)
+
+		report, err := s.subscriptionService.SweepSubscriptions(context.Background(), now.AddDate(0, 0, 21))
+
+		assert.Nil(t, err)
+		assert.Equal(t, 1, report.Cancelled)
+		assert.Equal(t, 0, report.Grace)
+		stored = reloadSubscription(t, subscription.ID)
+		assert.Equal(t, model.SubscriptionStatusCancelled, stored.Status)
+		assert.False(t, stored.IsActive)
+
+		var transition model.SubscriptionTransition
+		assert.Nil(t, test.DB.Where("user_subscription_id = ? AND to_status = ?", subscription.ID, model.SubscriptionStatusCancelled).First(&transition).Error)
+		assert.Equal(t, service.TransitionReasonUserCancelled, transition.Reason)
+	})
+
+	t.Run("should take back the cancellation when resumed", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		subscription := insertLifecycleSubscription(t, s.user.ID, model.SubscriptionStatusActive, "gopay", now.AddDate(0, 0, -10), now.AddDate(0, 0, 20))
+
+		assert.Equal(t, http.StatusOK, call(t, s, "/cancel"))
+		assert.Equal(t, http.StatusOK, call(t, s, "/resume"))
+
+		assert.False(t, reloadSubscription(t, subscription.ID).CancelAtPeriodEnd)
+	})
+
+	t.Run("should cancel a subscription in its grace period right away", func(t *testing.T) {
+		s := setup(t)
+		now := time.Now()
+		subscription := insertLifecycleSubscription(t, s.user.ID, model.SubscriptionStatusGrace, "gopay", now.AddDate(0, 0, -30), now.Add(-time.Hour))
+
+		assert.Equal(t, http.StatusOK, call(t, s, "/cancel"))
+
+		assert.Equal(t, model.SubscriptionStatusCancelled, reloadSubscription(t, subscription.ID).Status)
+	})
+
+	t.Run("should return not found without a subscription", func(t *testing.T) {
+		s := setup(t)
+
+		assert.Equal(t, http.StatusNotFound, call(t, s, "/cancel"))
+	})
+}
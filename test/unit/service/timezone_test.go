@@ -0,0 +1,60 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func requestLocation(t *testing.T, user *model.User, header string) (*time.Location, error) {
+	var location *time.Location
+	var locationErr error
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		location, locationErr = service.RequestLocation(c, user)
+		return nil
+	})
+
+	request := httptest.NewRequest("GET", "/", nil)
+	if header != "" {
+		request.Header.Set(service.TimezoneHeader, header)
+	}
+	_, err := app.Test(request)
+	assert.NoError(t, err)
+
+	return location, locationErr
+}
+
+func TestRequestLocation(t *testing.T) {
+	t.Run("should use the user's timezone", func(t *testing.T) {
+		location, err := requestLocation(t, &model.User{Timezone: "Asia/Jayapura"}, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "Asia/Jayapura", location.String())
+	})
+
+	t.Run("should let the header override the user's timezone", func(t *testing.T) {
+		location, err := requestLocation(t, &model.User{Timezone: "Asia/Jayapura"}, "Asia/Makassar")
+		assert.NoError(t, err)
+		assert.Equal(t, "Asia/Makassar", location.String())
+	})
+
+	t.Run("should fall back to Asia/Jakarta", func(t *testing.T) {
+		location, err := requestLocation(t, &model.User{}, "")
+		assert.NoError(t, err)
+		assert.Equal(t, "Asia/Jakarta", location.String())
+	})
+
+	t.Run("should return 400 for an unknown timezone in the header", func(t *testing.T) {
+		_, err := requestLocation(t, &model.User{}, "Mars/Olympus")
+
+		var fiberErr *fiber.Error
+		assert.ErrorAs(t, err, &fiberErr)
+		assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code)
+	})
+}
@@ -0,0 +1,142 @@
+package service_test
+
+import (
+	"app/src/model"
+	"app/src/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateWeightProgress(t *testing.T) {
+	start := time.Date(2026, 9, 1, 8, 0, 0, 0, time.UTC)
+
+	records := func(weights ...float64) []model.UsersWeightHeightHistory {
+		result := make([]model.UsersWeightHeightHistory, 0, len(weights))
+		for i, weight := range weights {
+			result = append(result, model.UsersWeightHeightHistory{Weight: weight, RecordedAt: start.AddDate(0, 0, 7*(i+1))})
+		}
+		return result
+	}
+
+	target := &model.UsersWeightHeightTarget{
+		Weight:        75,
+		Height:        175,
+		WeightHistory: 80,
+		TargetDate:    start.AddDate(0, 0, 70),
+		RecordDate:    start,
+	}
+
+	codes := func(progress *model.WeightProgress) []string {
+		result := []string{}
+		for _, warning := range progress.Warnings {
+			result = append(result, warning.Code)
+		}
+		return result
+	}
+
+	t.Run("should project the completion date from the regression rate", func(t *testing.T) {
+		// Half a kg a week from 80 kg
+		now := start.AddDate(0, 0, 28)
+
+		progress := service.CalculateWeightProgress(target, records(79.5, 79, 78.5, 78), now)
+
+		assert.Equal(t, 80.0, progress.StartWeight)
+		assert.Equal(t, 78.0, progress.CurrentWeight)
+		assert.Equal(t, -2.0, progress.Change)
+		assert.Equal(t, -3.0, progress.Remaining)
+		assert.Equal(t, 40.0, progress.PercentComplete)
+		assert.Equal(t, -0.5, *progress.WeeklyRate)
+		assert.Equal(t, model.WeightTrendLosing, progress.Trend)
+		// 3 kg left at half a kg a week is 6 weeks after the latest record
+		assert.Equal(t, start.AddDate(0, 0, 28+42), *progress.ProjectedDate)
+		assert.True(t, progress.OnTrack)
+		assert.Equal(t, -0.5, *progress.RequiredWeeklyRate)
+		assert.Equal(t, 24.5, *progress.TargetBMI)
+		assert.True(t, progress.Safe)
+		assert.Empty(t, progress.Warnings)
+	})
+
+	t.Run("should not be on track when the pace is too slow", func(t *testing.T) {
+		now := start.AddDate(0, 0, 28)
+
+		progress := service.CalculateWeightProgress(target, records(79.8, 79.6, 79.4, 79.2), now)
+
+		assert.Equal(t, -0.2, *progress.WeeklyRate)
+		assert.True(t, progress.ProjectedDate.After(target.TargetDate))
+		assert.False(t, progress.OnTrack)
+	})
+
+	t.Run("should not project a pace away from the target", func(t *testing.T) {
+		now := start.AddDate(0, 0, 14)
+
+		progress := service.CalculateWeightProgress(target, records(80.5, 81), now)
+
+		assert.Equal(t, model.WeightTrendGaining, progress.Trend)
+		assert.Zero(t, progress.PercentComplete)
+		assert.Nil(t, progress.ProjectedDate)
+		assert.False(t, progress.OnTrack)
+	})
+
+	t.Run("should wait for a week of records before computing a rate", func(t *testing.T) {
+		progress := service.CalculateWeightProgress(target, nil, start.AddDate(0, 0, 2))
+
+		assert.Equal(t, 80.0, progress.CurrentWeight)
+		assert.Nil(t, progress.WeeklyRate)
+		assert.Empty(t, progress.Trend)
+		assert.False(t, progress.OnTrack)
+	})
+
+	t.Run("should be complete once the target is reached", func(t *testing.T) {
+		progress := service.CalculateWeightProgress(target, records(78, 76, 74.8), start.AddDate(0, 0, 21))
+
+		assert.True(t, progress.Achieved)
+		assert.True(t, progress.OnTrack)
+		assert.Equal(t, 100.0, progress.PercentComplete)
+		assert.Nil(t, progress.ProjectedDate)
+		assert.Nil(t, progress.RequiredWeeklyRate)
+	})
+
+	t.Run("should start from the first record without a weight when the target was set", func(t *testing.T) {
+		withoutWeight := *target
+		withoutWeight.WeightHistory = 0
+
+		progress := service.CalculateWeightProgress(&withoutWeight, records(79, 78), start.AddDate(0, 0, 14))
+
+		assert.Equal(t, 79.0, progress.StartWeight)
+		assert.Equal(t, 25.0, progress.PercentComplete)
+	})
+
+	t.Run("should flag losing more than a kg a week", func(t *testing.T) {
+		crash := *target
+		crash.Weight = 70
+		crash.TargetDate = start.AddDate(0, 0, 28)
+
+		progress := service.CalculateWeightProgress(&crash, nil, start)
+
+		assert.False(t, progress.Safe)
+		assert.Equal(t, []string{model.WeightWarningLossTooFast}, codes(progress))
+	})
+
+	t.Run("should flag a goal BMI below 18.5", func(t *testing.T) {
+		thin := *target
+		thin.Weight = 55
+		thin.TargetDate = start.AddDate(1, 0, 0)
+
+		progress := service.CalculateWeightProgress(&thin, nil, start)
+
+		// 55 kg at 175 cm
+		assert.Equal(t, 18.0, *progress.TargetBMI)
+		assert.False(t, progress.Safe)
+		assert.Equal(t, []string{model.WeightWarningUnderweight}, codes(progress))
+	})
+
+	t.Run("should tell when the target date passed", func(t *testing.T) {
+		progress := service.CalculateWeightProgress(target, records(79), start.AddDate(0, 0, 80))
+
+		assert.Nil(t, progress.RequiredWeeklyRate)
+		assert.True(t, progress.Safe)
+		assert.Equal(t, []string{model.WeightWarningTargetPassed}, codes(progress))
+	})
+}